@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"strings"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The values of -aggregate, which can be given several times, like
+// "frontend=~/src/frontend" or just "~/src/frontend"
+type repositoryList []changelog.Repository
+
+func (r *repositoryList) String() string {
+	if r == nil {
+		return ""
+	}
+	var values []string
+	for _, repository := range *r {
+		if repository.Label != "" {
+			values = append(values, repository.Label+"="+repository.Dir)
+		} else {
+			values = append(values, repository.Dir)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+func (r *repositoryList) Set(value string) error {
+	label, dir, ok := strings.Cut(value, "=")
+	if !ok {
+		label, dir = "", value
+	}
+	if dir == "" {
+		return errors.New("the directory of the working copy is empty")
+	}
+	*r = append(*r, changelog.Repository{Dir: dir, Label: label})
+	return nil
+}
+
+// The flags for aggregating the logs of several working copies
+type aggregateFlags struct {
+	repositories *repositoryList
+	labels       *string
+}
+
+// Add the -aggregate and -repo-labels flags
+func addAggregateFlags(fs *flag.FlagSet) *aggregateFlags {
+	flags := &aggregateFlags{repositories: &repositoryList{}}
+	fs.Var(flags.repositories, "aggregate", "aggregate the log of this working copy, as `label=directory` or only the directory, with the others given with -aggregate, instead of -repo")
+	flags.labels = fs.String("repo-labels", changelog.REPOSITORY_LABELS_MESSAGES, "how the entries of each repository are labeled with -aggregate: `messages` for \"[frontend] Fix the build\", sections for the label in the header of each section, or none")
+	return flags
+}
+
+// Check the way of labeling the repositories, and use the repositories
+func (flags *aggregateFlags) apply(opts *changelog.Options) error {
+	labels := *flags.labels
+	if labels == "none" {
+		labels = ""
+	}
+	if err := changelog.CheckRepositoryLabels(labels); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if len(*flags.repositories) == 1 {
+		return withCode(EXIT_USAGE, errors.New("Please give -aggregate more than once, or use -repo for one working copy"))
+	}
+	opts.Repositories = *flags.repositories
+	opts.RepositoryLabels = labels
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The value of -anonymize: off, on with pseudonyms, or on with another way
+// of anonymizing the authors. It is a boolean flag, so that -anonymize can
+// be given without a way.
+type anonymizeStyle struct {
+	style string
+}
+
+func (a *anonymizeStyle) String() string {
+	if a == nil {
+		return ""
+	}
+	return a.style
+}
+
+func (a *anonymizeStyle) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "", "false", "0", "no":
+		a.style = ""
+	case "true", "1", "yes":
+		a.style = changelog.ANONYMIZE_PSEUDONYMS
+	default:
+		if err := changelog.CheckAnonymize(value); err != nil {
+			return err
+		}
+		a.style = value
+	}
+	return nil
+}
+
+func (a *anonymizeStyle) IsBoolFlag() bool {
+	return true
+}
+
+// Add the -anonymize flag, for publishing the ChangeLogs of internal
+// repositories without the names and e-mail addresses of the authors
+func addAnonymizeFlag(fs *flag.FlagSet) *anonymizeStyle {
+	anonymize := &anonymizeStyle{}
+	fs.Var(anonymize, "anonymize", "replace the authors with pseudonyms, like \"Author 1\", or with their initials with -anonymize=initials")
+	return anonymize
+}
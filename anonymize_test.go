@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestAnonymizeFlag(t *testing.T) {
+	for _, test := range []struct {
+		args  []string
+		style string
+	}{
+		{nil, ""},
+		{[]string{"-anonymize"}, changelog.ANONYMIZE_PSEUDONYMS},
+		{[]string{"-anonymize=initials"}, changelog.ANONYMIZE_INITIALS},
+		{[]string{"-anonymize=false"}, ""},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		anonymize := addAnonymizeFlag(fs)
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		if anonymize.style != test.style {
+			t.Fatalf("expected %q for %v, got %q", test.style, test.args, anonymize.style)
+		}
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addAnonymizeFlag(fs)
+	if err := fs.Parse([]string{"-anonymize=names"}); err == nil {
+		t.Fatal("expected an error for an unknown way of anonymizing")
+	}
+}
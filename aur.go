@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The AUR, with a git repository for each pkgbase
+const AUR_URL = "https://aur.archlinux.org"
+
+// The AUR user that made a commit: the one whose username is the name of
+// the git author, or the start of the e-mail address, ignoring case.
+// Returns "" if none of them match.
+func aurUser(entry changelog.Entry, users []string) string {
+	id := changelog.ParseIdentity(entry.Name)
+	local, _, _ := strings.Cut(id.Email, "@")
+	for _, user := range users {
+		if strings.EqualFold(user, entry.Author) || strings.EqualFold(user, id.Name) || strings.EqualFold(user, local) {
+			return user
+		}
+	}
+	return ""
+}
+
+// Add the AUR usernames to the names of the authors, like "Alice A (alice) <alice@example.org>",
+// for the commits of the maintainers, co-maintainers and the submitter of the package
+func withAURUsers(entries []changelog.Entry, users []string) {
+	for i, entry := range entries {
+		user := aurUser(entry, users)
+		if user == "" {
+			continue
+		}
+		id := changelog.ParseIdentity(entry.Name)
+		if !strings.EqualFold(id.Name, user) {
+			id.Name += " (" + user + ")"
+		}
+		entries[i].Name = id.String()
+	}
+}
+
+// archlog aur
+func runAur(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var aur_url *string = fs.String("aur-url", AUR_URL, "the `URL` of the AUR, for the git repositories and the RPC")
+	var clone_dir *string = fs.String("clone-dir", filepath.Join(defaultCacheDir(), "aur"), "the `directory` where the AUR git repositories are cloned and kept up to date")
+	var depth *int = fs.Int("depth", 0, "only fetch this `number` of the newest commits, or 0 for all of them")
+	var output *string = fs.String("o", "", "write the ChangeLog to this `file` (atomically replaced) instead of stdout")
+	var format *string = fs.String("format", "plain", "the output `format`: "+strings.Join(changelog.FormatterNames(), ", "))
+	var versions *bool = fs.Bool("versions", false, "group the entries by the version of the package they were released in")
+	var upgrades *bool = fs.Bool("upgrades", false, "add an \"Upgraded to\" line to the commits that changed the version of the package")
+	var git_bin *string = addGitBinFlag(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	var unknown_author *string = addUnknownAuthorFlag(fs)
+	var lang *string = addLanguageFlag(fs)
+	var no_progress *bool = fs.Bool("no-progress", false, "do not show the progress on stderr")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	language, err := checkLanguage(*lang)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return withCode(EXIT_USAGE, errors.New("Please provide the name of an AUR package.\nUse --help for more info."))
+	}
+	name := fs.Arg(0)
+	if !validPkgname.MatchString(name) {
+		return withCode(EXIT_USAGE, fmt.Errorf("Invalid package name: %s", name))
+	}
+	n, err := parseEntries(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	if *depth < 0 {
+		return withCode(EXIT_USAGE, errors.New("-depth can not be negative"))
+	}
+	if _, err := changelog.NewFormatter(*format, &changelog.Options{}); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if err := checkURL("aur-url", *aur_url); err != nil {
+		return err
+	}
+	if *clone_dir == "" {
+		return withCode(EXIT_USAGE, errors.New("Please provide a directory for the AUR git repositories with -clone-dir"))
+	}
+	base := strings.TrimSuffix(*aur_url, "/")
+	client := &http.Client{Timeout: *timeout}
+	status.Enable(!*no_progress)
+
+	// The git repository is named after the pkgbase, which is not the name
+	// of the package for split packages
+	web := &changelog.ArchWeb{Client: client, Retries: changelog.DEFAULT_RETRIES, AURURL: base + "/rpc/v5/info"}
+	pkg, err := web.FindAURPackage(ctx, name)
+	switch {
+	case errors.Is(err, changelog.ErrUnknownPackage):
+		return withCode(EXIT_NO_REPO, fmt.Errorf("There is no AUR package named %s", name))
+	case err != nil:
+		return withCode(EXIT_NETWORK, fmt.Errorf("Could not look up %s in the AUR: %w", name, err))
+	case !validPkgname.MatchString(pkg.Base):
+		return fmt.Errorf("The AUR has an invalid pkgbase for %s: %s", name, pkg.Base)
+	}
+	opts := &changelog.Options{
+		Repo:          filepath.Join(*clone_dir, pkg.Base),
+		VCS:           "git",
+		GitBin:        *git_bin,
+		Timeout:       *timeout,
+		Entries:       n,
+		Format:        *format,
+		Versions:      *versions,
+		Upgrades:      *upgrades,
+		UnknownAuthor: *unknown_author,
+		Language:      language,
+		Progress:      status.Report,
+	}
+	status.Printf("Fetching %s.git", pkg.Base)
+	err = changelog.CloneGit(ctx, opts, base+"/"+pkg.Base+".git", opts.Repo, *depth)
+	status.Done()
+	if err != nil {
+		return err
+	}
+	g := changelog.New(opts)
+	// The git commits already have the names and e-mail addresses
+	g.Names.Resolver = changelog.AuthorsFile{}
+	entries, err := g.Entries(ctx)
+	status.Done()
+	if err != nil {
+		return err
+	}
+	withAURUsers(entries, pkg.Users())
+	return writeEntries(ctx, &Destination{Filename: *output}, g, entries)
+}
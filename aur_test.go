@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestAURUsers(t *testing.T) {
+	entries := []changelog.Entry{
+		{Author: "Alice A", Name: "Alice A <alice@example.org>"},
+		{Author: "bob", Name: "bob <bob@localhost>"},
+		{Author: "Carol", Name: "Carol <carol@example.org>"},
+	}
+	withAURUsers(entries, []string{"Bob", "alice"})
+	for i, expected := range []string{"Alice A (alice) <alice@example.org>", "bob <bob@localhost>", "Carol <carol@example.org>"} {
+		if entries[i].Name != expected {
+			t.Fatalf("expected %q, got %q", expected, entries[i].Name)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// Write the contributors as an AUTHORS file, one per line, with the number
+// of commits and when they were made
+func writeAuthors(w io.Writer, contributors []changelog.Contributor) error {
+	for _, c := range contributors {
+		commits := fmt.Sprintf("%d commits", c.Commits)
+		if c.Commits == 1 {
+			commits = "1 commit"
+		}
+		dates := c.First
+		if c.Last != c.First {
+			dates += " to " + c.Last
+		}
+		if _, err := fmt.Fprintf(w, "%s (%s, %s)\n", c.Name, commits, dates); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archlog authors
+func runAuthors(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	repo, vcs, svn_bin, git_bin := addSourceFlags(fs)
+	mock_data, svn_path, externals := addLogFlags(fs)
+	svn_auth := addSvnAuthFlags(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	jobs, stop_on_copy := addFetchFlags(fs)
+	branch_flags := addBranchFlags(fs)
+	var output *string = fs.String("o", "", "write the list to this `file`, like AUTHORS, instead of to stdout")
+	var as_json *bool = fs.Bool("json", false, "write the list as JSON, with the nicks of each contributor")
+	var no_progress *bool = fs.Bool("no-progress", false, "do not show the progress on stderr")
+	cache_dir, no_cache := addCacheFlags(fs)
+	resolver_flags := addResolverFlags(fs)
+	var unknown_author *string = addUnknownAuthorFlag(fs)
+	var timing *bool = fs.Bool("timing", false, "show the time spent in each phase, like fetching the log and resolving names, on stderr")
+	strict, lenient, input_encoding := addParseFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	parsing, err := parseMode(*strict, *lenient)
+	if err != nil {
+		return err
+	}
+	encoding, err := changelog.CheckEncoding(*input_encoding)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+
+	n, err := parseEntries(fs.Args())
+	if err != nil {
+		return err
+	}
+	if _, err := changelog.LookupSource(*vcs); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	status.Enable(!*no_progress)
+	g := changelog.New(&changelog.Options{Repo: *repo, VCS: *vcs, SvnBin: *svn_bin, GitBin: *git_bin, MockData: *mock_data, Timeout: *timeout, Jobs: *jobs, StopOnCopy: *stop_on_copy, SvnPath: *svn_path, Externals: *externals, Entries: n, Parsing: parsing, InputEncoding: encoding, UnknownAuthor: *unknown_author, Progress: status.Report})
+	svn_auth.apply(g.Options)
+	if err := branch_flags.apply(g.Options); err != nil {
+		return err
+	}
+	if err := checkSvnOptions(g.Options); err != nil {
+		return err
+	}
+	g.Names.Client.Timeout = *timeout
+	if *timing {
+		g.Options.Timings = &changelog.Timings{}
+		defer writeTimings(g.Options.Timings, time.Now())
+	}
+	if err := setupResolvers(g.Names, resolver_flags); err != nil {
+		return err
+	}
+	if err := setupNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	setupPageCache(g.Names, *cache_dir, *no_cache)
+	entries, err := g.Entries(ctx)
+	if err != nil {
+		status.Done()
+		return err
+	}
+	contributors, err := g.Contributors(ctx, entries)
+	status.Done()
+	if err != nil {
+		return err
+	}
+	write := func(w io.Writer) error {
+		if *as_json {
+			enc := json.NewEncoder(w)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			return enc.Encode(contributors)
+		}
+		return writeAuthors(w, contributors)
+	}
+	if *output != "" {
+		err = changelog.WriteFileAtomic(*output, write)
+	} else {
+		err = write(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if err := storeNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	return g.Names.NetworkError()
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestWriteAuthors(t *testing.T) {
+	var buf bytes.Buffer
+	contributors := []changelog.Contributor{
+		{Name: "Alice <alice@example.org>", Commits: 3, First: "2024-03-01", Last: "2024-03-05"},
+		{Name: "bob", Commits: 1, First: "2024-03-04", Last: "2024-03-04"},
+	}
+	if err := writeAuthors(&buf, contributors); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Alice <alice@example.org> (3 commits, 2024-03-01 to 2024-03-05)\nbob (1 commit, 2024-03-04)\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The values of -branch, which can be given several times
+type branchList []string
+
+func (b *branchList) String() string {
+	if b == nil {
+		return ""
+	}
+	return strings.Join(*b, ",")
+}
+
+func (b *branchList) Set(value string) error {
+	if value == "" {
+		return errors.New("the name of the branch is empty")
+	}
+	if err := checkRef(value); err != nil {
+		return err
+	}
+	*b = append(*b, value)
+	return nil
+}
+
+// Check that a branch, tag or commit does not start with "-", since it is
+// given to git before the "--", where git would take it for an option
+func checkRef(ref string) error {
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("%s can not start with -", ref)
+	}
+	return nil
+}
+
+// The flags for selecting the git branches and their history
+type branchFlags struct {
+	branches    *branchList
+	labels      *bool
+	firstParent *bool
+}
+
+// Add the -branch, -branch-labels and -first-parent flags
+func addBranchFlags(fs *flag.FlagSet) *branchFlags {
+	flags := &branchFlags{branches: &branchList{}}
+	fs.Var(flags.branches, "branch", "fetch the history of this git `branch` instead of HEAD, can be given several times for the commits of all of them")
+	flags.labels = fs.Bool("branch-labels", false, "start each message with the branches the commit is on, like \"[main, 1.x]\", with several -branch")
+	flags.firstParent = fs.Bool("first-parent", false, "only use the mainline history of git, like git log --first-parent, so the merge commits summarize the branches")
+	return flags
+}
+
+// Check that the branches are given for git, and use them. One branch is
+// fetched like -ref, and several are fetched together.
+func (flags *branchFlags) apply(opts *changelog.Options) error {
+	branches := *flags.branches
+	if (len(branches) > 0 || *flags.firstParent) && opts.VCS != "git" {
+		return withCode(EXIT_USAGE, errors.New("-branch and -first-parent only work with -vcs git"))
+	}
+	if *flags.labels && len(branches) < 2 {
+		return withCode(EXIT_USAGE, errors.New("-branch-labels needs -branch to be given more than once"))
+	}
+	switch len(branches) {
+	case 0:
+	case 1:
+		opts.Ref = branches[0]
+	default:
+		opts.Branches = branches
+	}
+	opts.BranchLabels = *flags.labels
+	opts.FirstParent = *flags.firstParent
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestBranchFlags(t *testing.T) {
+	for _, test := range []struct {
+		args     []string
+		ref      string
+		branches []string
+	}{
+		{nil, "", nil},
+		{[]string{"-branch", "1.x"}, "1.x", nil},
+		{[]string{"-branch", "main", "-branch", "1.x", "-branch-labels"}, "", []string{"main", "1.x"}},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags := addBranchFlags(fs)
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		opts := &changelog.Options{VCS: "git"}
+		if err := flags.apply(opts); err != nil {
+			t.Fatal(err)
+		}
+		if opts.Ref != test.ref || !reflect.DeepEqual(opts.Branches, test.branches) {
+			t.Fatalf("unexpected ref %q and branches %q for %v", opts.Ref, opts.Branches, test.args)
+		}
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := addBranchFlags(fs)
+	if err := fs.Parse([]string{"-branch", "1.x"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := flags.apply(&changelog.Options{VCS: "svn"}); err == nil {
+		t.Fatal("expected an error for -branch with svn")
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	flags = addBranchFlags(fs)
+	if err := fs.Parse([]string{"-first-parent"}); err != nil {
+		t.Fatal(err)
+	}
+	opts := &changelog.Options{VCS: "git"}
+	if err := flags.apply(opts); err != nil || !opts.FirstParent {
+		t.Fatalf("expected -first-parent to be used, got %v", err)
+	}
+	if err := flags.apply(&changelog.Options{VCS: "svn"}); err == nil {
+		t.Fatal("expected an error for -first-parent with svn")
+	}
+	// A branch that git would take for an option
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addBranchFlags(fs)
+	if err := fs.Parse([]string{"-branch=--output=/tmp/x"}); err == nil {
+		t.Fatal("expected an error for a branch that starts with -")
+	}
+}
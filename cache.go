@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// The default directory for cached data. This follows the XDG base
+// directory spec on Linux, and uses %LocalAppData% on Windows.
+func defaultCacheDir() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "archlog")
+	}
+	return ""
+}
+
+// The file where resolved nicks are cached between runs
+func nickCacheFilename(cacheDir string) string {
+	return filepath.Join(cacheDir, "nicks")
+}
+
+// The directory where fetched web pages are cached between runs
+func pageCacheDir(cacheDir string) string {
+	return filepath.Join(cacheDir, "pages")
+}
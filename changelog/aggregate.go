@@ -0,0 +1,122 @@
+package changelog
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A working copy whose log is aggregated with the logs of others, with
+// Options.Repositories
+type Repository struct {
+	Dir   string // The directory of the working copy
+	VCS   string // The name of a registered Source, or "" for Options.VCS
+	Label string // The name of the repository in the ChangeLog, like "frontend", or "" for the name of Dir
+}
+
+// The label of the repository, which is the name of the directory, or of
+// the directory above trunk, if there is no Label
+func (r Repository) label() string {
+	if r.Label != "" {
+		return r.Label
+	}
+	return GuessPackageName(r.Dir)
+}
+
+// The ways of labeling the entries of each repository, for Options.RepositoryLabels
+const (
+	REPOSITORY_LABELS_MESSAGES = "messages" // Start each message with the label, like "[frontend] Fix the build"
+	REPOSITORY_LABELS_SECTIONS = "sections" // Give each repository its own sections, with the label in the header
+)
+
+// Check the way of labeling the repositories, where "" is no labels
+func CheckRepositoryLabels(labels string) error {
+	switch labels {
+	case "", REPOSITORY_LABELS_MESSAGES, REPOSITORY_LABELS_SECTIONS:
+		return nil
+	}
+	return fmt.Errorf("Unknown way of labeling the repositories: %s (available: %s, %s)", labels, REPOSITORY_LABELS_MESSAGES, REPOSITORY_LABELS_SECTIONS)
+}
+
+// Format the labels of the repositories of an entry or a section, like "[frontend]"
+func repositoryLabel(repositories []string) string {
+	if len(repositories) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(repositories, ", ") + "]"
+}
+
+// The keys that tell that entries in several repositories are the same
+// commit: the commit hash, and the author, the time and the message, which
+// are kept when a repository is mirrored or vendored with its history
+func duplicateKeys(entry Entry) []string {
+	keys := []string{"author\x00" + entry.Author + "\x00" + entry.Date.Format(time.RFC3339) + "\x00" + strings.TrimSpace(entry.Message)}
+	if entry.Commit != "" {
+		keys = append(keys, "commit\x00"+entry.Commit)
+	}
+	return keys
+}
+
+// The index of the entry that has one of the keys, if any
+func findDuplicate(seen map[string]int, keys []string) (int, bool) {
+	for _, key := range keys {
+		if i, ok := seen[key]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Fetch the entries of each of Options.Repositories and merge them,
+// ordered from the newest to the oldest by date, with the label of the
+// repository in each entry. The revision numbers are from each repository.
+// A commit that is in several of the repositories, like in a mirror, is
+// only included once, with the labels of all of them.
+func (g *Generator) aggregateEntries(ctx context.Context) (iter.Seq2[Entry, error], error) {
+	var entries []Entry
+	seen := make(map[string]int)
+	for _, repository := range g.Options.Repositories {
+		opts := *g.Options
+		opts.Repo, opts.Repositories = repository.Dir, nil
+		if repository.VCS != "" {
+			opts.VCS = repository.VCS
+		}
+		single := *g
+		single.Options = &opts
+		source, err := single.source()
+		if err != nil {
+			return nil, err
+		}
+		repositoryEntries, err := collectEntries(source.Entries(ctx, &opts))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", repository.label(), err)
+		}
+		label := repository.label()
+		for _, entry := range repositoryEntries {
+			keys := duplicateKeys(entry)
+			// Only the same commit in another repository is a duplicate
+			if i, ok := findDuplicate(seen, keys); ok && !slices.Contains(entries[i].Repositories, label) {
+				entries[i].Repositories = append(entries[i].Repositories, label)
+				continue
+			}
+			for _, key := range keys {
+				if _, ok := seen[key]; !ok {
+					seen[key] = len(entries)
+				}
+			}
+			entry.Repositories = []string{label}
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.After(entries[j].Date)
+	})
+	if g.Options.Entries != -1 && g.Options.Entries < len(entries) {
+		entries = entries[:g.Options.Entries]
+	}
+	return sliceEntries(entries), nil
+}
@@ -0,0 +1,104 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	git := func(repo string, args ...string) {
+		if _, err := runGit(ctx, &Options{Repo: repo}, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(repo, message, date string) {
+		git(repo, "-c", "user.name=Alice A", "-c", "user.email=alice@example.org", "commit", "--quiet", "--allow-empty", "--date", date, "-m", message)
+	}
+	frontend, backend := filepath.Join(dir, "frontend"), filepath.Join(dir, "backend")
+	for _, repo := range []string{frontend, backend} {
+		if err := os.Mkdir(repo, 0755); err != nil {
+			t.Fatal(err)
+		}
+		git(repo, "init", "--quiet")
+	}
+	commit(frontend, "Add the page", "2024-03-01T10:00:00Z")
+	commit(backend, "Add the API", "2024-03-01T11:00:00Z")
+	commit(frontend, "Use the API", "2024-03-01T12:00:00Z")
+
+	for labels, expected := range map[string]string{
+		REPOSITORY_LABELS_MESSAGES: "2024-03-01 Alice A <alice@example.org>\n    * [frontend] Add the page\n    * [api] Add the API\n    * [frontend] Use the API\n\n",
+		REPOSITORY_LABELS_SECTIONS: "2024-03-01 Alice A <alice@example.org> [frontend]\n    * Use the API\n\n2024-03-01 Alice A <alice@example.org> [api]\n    * Add the API\n\n2024-03-01 Alice A <alice@example.org> [frontend]\n    * Add the page\n\n",
+	} {
+		g := New(&Options{VCS: "git", Entries: -1, RepositoryLabels: labels, Repositories: []Repository{{Dir: frontend}, {Dir: backend, Label: "api"}}})
+		g.Names.Resolver = AuthorsFile{}
+		entries, err := g.Entries(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := g.Write(ctx, &buf, entries); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected {
+			t.Fatalf("unexpected ChangeLog with %s labels:\n%s", labels, buf.String())
+		}
+	}
+}
+
+func TestAggregateDuplicates(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	git := func(repo string, args ...string) {
+		if _, err := runGit(ctx, &Options{Repo: repo}, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	upstream, mirror := filepath.Join(dir, "upstream"), filepath.Join(dir, "mirror")
+	if err := os.Mkdir(upstream, 0755); err != nil {
+		t.Fatal(err)
+	}
+	git(upstream, "init", "--quiet")
+	git(upstream, "-c", "user.name=Alice A", "-c", "user.email=alice@example.org", "commit", "--quiet", "--allow-empty", "-m", "Initial import")
+	git(dir, "clone", "--quiet", upstream, mirror)
+	git(mirror, "-c", "user.name=Bob B", "-c", "user.email=bob@example.org", "commit", "--quiet", "--allow-empty", "-m", "Only in the mirror")
+
+	g := New(&Options{VCS: "git", Entries: -1, Repositories: []Repository{{Dir: upstream}, {Dir: mirror}}})
+	entries, err := g.Entries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected the commit in both repositories once, got %+v", entries)
+	}
+	for _, entry := range entries {
+		expected := "[mirror]"
+		if strings.HasPrefix(entry.Message, "Initial import") {
+			expected = "[upstream, mirror]"
+		}
+		if label := repositoryLabel(entry.Repositories); label != expected {
+			t.Errorf("expected %s for %q, got %s", expected, entry.Message, label)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package changelog
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The ways of anonymizing the authors, for Options.Anonymize
+const (
+	ANONYMIZE_PSEUDONYMS = "pseudonyms" // Like "Author 1", numbered from the newest entry
+	ANONYMIZE_INITIALS   = "initials"   // Like "A. R.", from the name without the e-mail address
+)
+
+// Check the way of anonymizing the authors, where "" is not anonymizing them
+func CheckAnonymize(style string) error {
+	switch style {
+	case "", ANONYMIZE_PSEUDONYMS, ANONYMIZE_INITIALS:
+		return nil
+	}
+	return fmt.Errorf("Unknown way of anonymizing the authors: %s (available: %s, %s)", style, ANONYMIZE_PSEUDONYMS, ANONYMIZE_INITIALS)
+}
+
+// Replaces the names, e-mail addresses and nicks of the authors while a
+// ChangeLog is written. The same author always gets the same replacement,
+// and two authors never get the same one, so the entries can still be told
+// apart.
+type anonymizer struct {
+	style   string
+	pattern string            // The pseudonyms, with %d for the number
+	byNick  map[string]string // The replacement of each nick
+	taken   map[string]bool
+}
+
+// An anonymizer for Options.Anonymize, or nil if the authors are shown as they are
+func (opts *Options) anonymizer() *anonymizer {
+	if opts.Anonymize == "" {
+		return nil
+	}
+	return &anonymizer{style: opts.Anonymize, pattern: opts.catalog().Pseudonym, byNick: make(map[string]string), taken: make(map[string]bool)}
+}
+
+// The initials of a name, like "A. R." for "Alexander Rødseth <rodseth@gmail.com>",
+// or "A." for a nick like "arodseth"
+func initials(name string) string {
+	if i := strings.Index(name, " <"); i >= 0 {
+		name = name[:i]
+	}
+	name, _, _ = strings.Cut(name, "@")
+	var letters []string
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == '-' || r == '.' }) {
+		if r, _ := utf8.DecodeRuneInString(word); unicode.IsLetter(r) {
+			letters = append(letters, string(unicode.ToUpper(r))+".")
+		}
+	}
+	if len(letters) == 0 {
+		return "?"
+	}
+	return strings.Join(letters, " ")
+}
+
+// The replacement for the author with the nick and the resolved name
+func (a *anonymizer) replace(nick, name string) string {
+	if replacement, ok := a.byNick[nick]; ok {
+		return replacement
+	}
+	var replacement string
+	if a.style == ANONYMIZE_INITIALS {
+		replacement = initials(name)
+		for n := 2; a.taken[replacement]; n++ {
+			replacement = fmt.Sprintf("%s %d", initials(name), n)
+		}
+	} else {
+		replacement = fmt.Sprintf(a.pattern, len(a.byNick)+1)
+	}
+	a.byNick[nick], a.taken[replacement] = replacement, true
+	return replacement
+}
@@ -0,0 +1,56 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnonymize(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Revision: 4, Author: "alice", Date: day, Message: "Update"},
+		{Revision: 3, Author: "", Date: day.AddDate(0, 0, -1), Message: "Set a property"},
+		{Revision: 2, Author: "anne", Date: day.AddDate(0, 0, -2), Message: "Fix the build"},
+		{Revision: 1, Author: "alice", Date: day.AddDate(0, 0, -3), Message: "Initial import"},
+	}
+	for style, expected := range map[string]string{
+		ANONYMIZE_PSEUDONYMS: "2024-03-01 Author 1\n    * Update\n\n2024-02-29 unknown\n    * Set a property\n\n2024-02-28 Author 2\n    * Fix the build\n\n2024-02-27 Author 1\n    * Initial import\n\n",
+		ANONYMIZE_INITIALS:   "2024-03-01 A. A.\n    * Update\n\n2024-02-29 unknown\n    * Set a property\n\n2024-02-28 A. A. 2\n    * Fix the build\n\n2024-02-27 A. A.\n    * Initial import\n\n",
+	} {
+		g := New(&Options{Anonymize: style})
+		g.Names.Resolver = AuthorsFile{
+			"alice": {Name: "Alice Andersen", Email: "alice@example.org"},
+			"anne":  {Name: "Anne Aas", Email: "anne@example.org"},
+		}
+		var buf bytes.Buffer
+		if err := g.Write(context.Background(), &buf, entries); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != expected {
+			t.Fatalf("unexpected ChangeLog with %s:\n%s", style, got)
+		}
+		if strings.Contains(buf.String(), "example.org") {
+			t.Fatalf("expected no e-mail addresses with %s", style)
+		}
+	}
+	if err := CheckAnonymize("names"); err == nil {
+		t.Fatal("expected an error for an unknown way of anonymizing")
+	}
+}
+
+func TestInitials(t *testing.T) {
+	for name, want := range map[string]string{
+		"Alexander Rødseth <rodseth@gmail.com>": "A. R.",
+		"arodseth":                              "A.",
+		"jean-luc picard":                       "J. L. P.",
+		"øyvind@example.org":                    "Ø.",
+		"123":                                   "?",
+	} {
+		if got := initials(name); got != want {
+			t.Fatalf("expected %q for %q, got %q", want, name, got)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/scanner"
+)
+
+const (
+	TU_URL  = "https://www.archlinux.org/people/trusted-users/"
+	DEV_URL = "https://www.archlinux.org/people/developers/"
+	FEL_URL = "https://www.archlinux.org/people/developer-fellows/"
+	PKG_URL = "https://www.archlinux.org/packages/"
+)
+
+// Looks up Arch Linux related nicks on the Arch Linux web pages.
+// Each page is only fetched once, and if CacheDir is set, the pages are
+// kept there between runs and only downloaded again if they have changed.
+// Failed requests are retried with an exponential backoff, and the
+// Retry-After header of the server is honored.
+type ArchWeb struct {
+	Client    *http.Client
+	CacheDir  string  // The directory for cached pages, or "" for no cache
+	Rate      float64 // The maximum number of requests per second, or 0 for no limit
+	Retries   int     // The number of times to retry a request that failed for a temporary reason
+	UserAgent string  // The User-Agent header, or "" for DEFAULT_USER_AGENT
+	Robots    bool    // Honor the Crawl-delay in the robots.txt of each host
+	// The package search, for FindPackage, or "" for PACKAGES_API_URL
+	PackagesURL string
+	// The AUR RPC, for FindAURPackage, or "" for AUR_RPC_URL
+	AURURL string
+
+	mu      sync.Mutex
+	pages   map[string]*webPage
+	robots  map[string]*robotsRules
+	limiter rateLimiter
+}
+
+// A fetched web page
+type webPage struct {
+	mu      sync.Mutex // Held while the page is being fetched
+	fetched bool
+	body    []byte
+}
+
+// A web page in the cache directory
+type cachedPage struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Body         string `json:"body"`
+}
+
+// The file in the cache directory for a web page
+func (a *ArchWeb) cacheFilename(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(a.CacheDir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// Read a web page from the cache directory, if it is there
+func (a *ArchWeb) loadPage(url string) *cachedPage {
+	if a.CacheDir == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(a.cacheFilename(url))
+	if err != nil {
+		return nil
+	}
+	var page cachedPage
+	if err := json.Unmarshal(data, &page); err != nil || page.URL != url {
+		slog.Debug("Ignoring the cached page for "+url, "err", err)
+		return nil
+	}
+	return &page
+}
+
+// Write a web page to the cache directory
+func (a *ArchWeb) storePage(page *cachedPage) {
+	if a.CacheDir == "" || (page.ETag == "" && page.LastModified == "") {
+		return
+	}
+	err := os.MkdirAll(a.CacheDir, 0755)
+	if err == nil {
+		err = WriteFileAtomic(a.cacheFilename(page.URL), func(w io.Writer) error {
+			return json.NewEncoder(w).Encode(page)
+		})
+	}
+	if err != nil {
+		slog.Warn("Could not cache "+page.URL, "err", err)
+	}
+}
+
+// Download a web page, at most Rate requests per second, and retry up to
+// Retries times if it fails for a reason that may be temporary
+func (a *ArchWeb) download(ctx context.Context, address string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		if err := a.limiter.wait(ctx, a.rate(ctx, address)); err != nil {
+			return nil, err
+		}
+		body, err := a.request(ctx, address)
+		if err == nil || attempt >= a.Retries || !temporary(ctx, err) {
+			return body, err
+		}
+		delay := retryDelay(err, attempt)
+		slog.Debug("Retrying "+address, "in", delay, "err", err)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Send one request for a web page. If it is in the cache directory, it is
+// only downloaded again if it has changed since then.
+func (a *ArchWeb) request(ctx context.Context, address string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	userAgent := a.UserAgent
+	if userAgent == "" {
+		userAgent = DEFAULT_USER_AGENT
+	}
+	req.Header.Set("User-Agent", userAgent)
+	cached := a.loadPage(address)
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	resp, err := a.Client.Do(req)
+	if err != nil && isCertificateError(err) {
+		return nil, &CertificateError{URL: address, Err: err}
+	} else if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		slog.Debug("Using the cached page for " + address)
+		return []byte(cached.Body), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &url.Error{Op: "Get", URL: address, Err: newStatusError(resp)}
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &url.Error{Op: "Get", URL: address, Err: err}
+	}
+	a.storePage(&cachedPage{URL: address, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified"), Body: string(body)})
+	return body, nil
+}
+
+// Get the contents of a web page. Each page is only downloaded once, also
+// when several lookups need it at the same time.
+func (a *ArchWeb) get(ctx context.Context, url string) ([]byte, error) {
+	a.mu.Lock()
+	if a.pages == nil {
+		a.pages = make(map[string]*webPage)
+	}
+	page, ok := a.pages[url]
+	if !ok {
+		page = &webPage{}
+		a.pages[url] = page
+	}
+	a.mu.Unlock()
+
+	page.mu.Lock()
+	defer page.mu.Unlock()
+	if page.fetched {
+		return page.body, nil
+	}
+	body, err := a.download(ctx, url)
+	if err != nil {
+		slog.Debug("Could not retrieve "+url, "err", err)
+		return nil, err
+	}
+	page.body, page.fetched = body, true
+	return body, nil
+}
+
+// Get the contents from an URL and return a tokenizer
+func (a *ArchWeb) getWebPageTokenizer(ctx context.Context, url string) (*scanner.Scanner, error) {
+	body, err := a.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	var tokenizer scanner.Scanner
+	tokenizer.Init(bytes.NewReader(body))
+	return &tokenizer, nil
+}
+
+// Skip N tokens, if possible. Returns true if it worked out.
+func skip(tokenizer *scanner.Scanner, n int) bool {
+	for counter := 0; counter < n; counter++ {
+		toktype := tokenizer.Next()
+		if toktype == scanner.EOF {
+			return false
+		}
+	}
+	return true
+}
+
+// Keep only the ASCII letters, for nicks
+func asciiLettersOnly(letter rune) rune {
+	if ((letter >= 'A') && (letter <= 'Z')) || ((letter >= 'a') && (letter <= 'z')) {
+		return letter
+	}
+	return -1
+}
+
+// Generates a nick from the name
+func generateNick(name string) string {
+	if strings.Index(name, " ") == -1 {
+		return name
+	}
+	var names []string
+	// If the english-friendly name is in parenthesis
+	if (strings.Index(name, "(") != -1) && (strings.Index(name, ")") != -1) {
+		a := strings.Index(name, "(")
+		b := strings.LastIndex(name, ")")
+		centerpart := name[a+1 : b]
+		names = strings.SplitN(centerpart, " ", -1)
+	} else {
+		names = strings.SplitN(name, " ", -1)
+	}
+	firstname := strings.Map(asciiLettersOnly, Transliterate(names[0]))
+	lastname := strings.Map(asciiLettersOnly, Transliterate(names[len(names)-1]))
+	if firstname != "" {
+		firstname = firstname[:1]
+	}
+	return strings.ToLower(firstname + lastname)
+}
+
+// Find the name and email based on a nick name and an URL to an
+// ArchLinux related list of people, formatted in a particular way.
+func (a *ArchWeb) nickToNameAndEmailWithUrl(ctx context.Context, nick string, url string) (string, error) {
+	slog.Debug("Looking up "+nick, "url", url)
+	b, err := a.get(ctx, url)
+	if err != nil {
+		return "", err
+	}
+
+	name := ""
+	email := ""
+	counter := 0
+	email_index := -1
+	found := false
+	for i, tag := range strings.Split(string(b), "<") {
+		if strings.Contains(tag, "schema.org/Person") {
+			name = ""
+			email = ""
+			counter = 30 // Examine the next 30 tags
+		}
+		if counter > 0 {
+			if strings.Contains(tag, "itemprop=\"name") && !strings.Contains(tag, "Arch Linux") {
+				name = strings.Split(tag, "\"")[3]
+			} else if strings.Contains(tag, nick) {
+				found = true
+			} else if strings.Contains(tag, "Email") {
+				email_index = i + 2
+			} else if i == email_index {
+				email = strings.Split(tag, ">")[1]
+				// If there's no "@" in the email, replace the first "." with "@"
+				if !strings.Contains(email, "@") && strings.Contains(email, ".") {
+					email = strings.Replace(email, ".", "@", 1)
+				}
+				if found {
+					break
+				}
+			}
+			counter--
+		}
+	}
+	if found {
+		//fmt.Println("FOUND!")
+		//fmt.Println("NICK", nick)
+		//fmt.Println("EMAIL", email)
+		//fmt.Println("NAME", name)
+		// Format the name and email nicely, then return
+		return fmt.Sprintf("%s <%s>", name, email), nil
+	}
+	return "", ErrNotFound
+}
+
+// Find the name from an ArchLinux related list of people and nicks
+func (a *ArchWeb) nickToNameFromListBox(ctx context.Context, nick string, url string) (string, error) {
+	tokerror := errors.New("Out of tokens")
+	tokenizer, err := a.getWebPageTokenizer(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if !skip(tokenizer, 1) {
+			return "", tokerror
+		}
+		tagname := tokenizer.TokenText() // TagName()
+		if tagname == "option" {
+			// Find Nick
+			foundnick := tokenizer.TokenText() // TagAttr()
+			if nick != foundnick {
+				continue
+			}
+			if !skip(tokenizer, 1) {
+				return "", tokerror
+			}
+			name := tokenizer.TokenText()
+			return name, nil
+		}
+	}
+}
+
+// Find the email based on a name and an URL to an
+// ArchLinux related list of people, formatted in a particular way.
+func (a *ArchWeb) nameToEmailWithUrl(ctx context.Context, fullname string, url string) (string, error) {
+	tokerror := errors.New("Out of tokens")
+	tokenizer, err := a.getWebPageTokenizer(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if !skip(tokenizer, 1) {
+			return "", tokerror
+		}
+		tagname := tokenizer.TokenText() // TagName?
+		if tagname == "a" {
+			// Find Name
+			text := ""
+			for text != "Name:" {
+				if !skip(tokenizer, 1) {
+					return "", tokerror
+				}
+				text = tokenizer.TokenText()
+			}
+			if !skip(tokenizer, 4) {
+				return "", tokerror
+			}
+			name := tokenizer.TokenText()
+			// Check if this is the one we're looking for or skip
+			if strings.ToLower(name) != strings.ToLower(fullname) {
+				// Skipping this person if names doesn't match
+				continue
+			}
+			// Find Alias
+			text = ""
+			for text != "Alias:" {
+				if !skip(tokenizer, 1) {
+					return "", tokerror
+				}
+				text = tokenizer.TokenText()
+			}
+			if !skip(tokenizer, 4) {
+				return "", tokerror
+			}
+			_ = tokenizer.TokenText()
+			//alias := bytes.NewBuffer(bval).String()
+			// Find Email
+			text = ""
+			for text != "Email:" {
+				if !skip(tokenizer, 1) {
+					return "", tokerror
+				}
+				text = tokenizer.TokenText()
+			}
+			if !skip(tokenizer, 4) {
+				return "", tokerror
+			}
+			email := tokenizer.TokenText()
+			// If there's no "@" in the email, replace the first "." with "@"
+			if strings.Index(email, "@") == -1 {
+				if strings.Count(email, ".") > 1 {
+					email = strings.Replace(email, ".", "@", 1)
+				}
+			}
+			// Return the email and no error
+			return email, nil
+		}
+	}
+}
+
+// Look up a nick on the Arch Linux web pages. If the nick could not be
+// found and any of the web lookups failed, the last failure is returned
+// instead of ErrNotFound.
+func (a *ArchWeb) Resolve(ctx context.Context, nick string) (Identity, error) {
+	id, _, err := a.ResolveFrom(ctx, nick)
+	return id, err
+}
+
+// Resolve the nick like Resolve, and also return the URL of the page that
+// it was found on
+func (a *ArchWeb) ResolveFrom(ctx context.Context, nick string) (Identity, string, error) {
+	var failure error
+	check := func(err error) {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			failure = err
+		}
+	}
+	// Try searching on the trusted user webpage
+	nameEmail, err := a.nickToNameAndEmailWithUrl(ctx, nick, TU_URL)
+	check(err)
+	if err == nil {
+		// Found it
+		return ParseIdentity(nameEmail), TU_URL, nil
+	}
+	// Try searching on the developer webpage
+	nameEmail, err = a.nickToNameAndEmailWithUrl(ctx, nick, DEV_URL)
+	check(err)
+	if err == nil {
+		// Found it
+		return ParseIdentity(nameEmail), DEV_URL, nil
+	}
+	// Try searching the package search webpage
+	name, err := a.nickToNameFromListBox(ctx, nick, PKG_URL)
+	check(err)
+	if err == nil {
+		// Found it, try to find the mail too
+		email, err := a.nameToEmailWithUrl(ctx, name, TU_URL)
+		check(err)
+		if err != nil {
+			email, err = a.nameToEmailWithUrl(ctx, name, DEV_URL)
+			check(err)
+		}
+		if err != nil {
+			email = ""
+		}
+		return Identity{Name: name, Email: email}, PKG_URL, nil
+	}
+	// Try searching on the fellows webpage
+	nameEmail, err = a.nickToNameAndEmailWithUrl(ctx, nick, FEL_URL)
+	check(err)
+	if err == nil {
+		// Found it
+		return ParseIdentity(nameEmail), FEL_URL, nil
+	}
+	// Could not get name and email from nick
+	if failure != nil {
+		return Identity{}, "", failure
+	}
+	return Identity{}, "", ErrNotFound
+}
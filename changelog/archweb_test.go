@@ -0,0 +1,54 @@
+package changelog
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestArchWebPageCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	downloads, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("people"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	web := &ArchWeb{Client: server.Client(), CacheDir: dir}
+	for i := 0; i < 2; i++ {
+		body, err := web.get(ctx, server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "people" {
+			t.Fatalf("expected people, got %q", body)
+		}
+	}
+	if downloads != 1 || notModified != 0 {
+		t.Fatalf("expected the page to be downloaded once, got %d downloads", downloads)
+	}
+	// The next run sends a conditional request, and uses the cached page
+	web = &ArchWeb{Client: server.Client(), CacheDir: dir}
+	body, err := web.get(ctx, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "people" || downloads != 1 || notModified != 1 {
+		t.Fatalf("expected the cached page, got %q after %d downloads", body, downloads)
+	}
+}
@@ -0,0 +1,123 @@
+package changelog
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// The letters that are written as one ASCII letter without the accent,
+// like "ø" as "o", grouped by that letter
+var asciiLetters = map[string]string{
+	"A":  "ÀÁÂÃÄÅĀĂĄ",
+	"a":  "àáâãäåāăą",
+	"C":  "ÇĆĈĊČ",
+	"c":  "çćĉċč",
+	"D":  "ÐĎĐ",
+	"d":  "ðďđ",
+	"E":  "ÈÉÊËĒĔĖĘĚ",
+	"e":  "èéêëēĕėęě",
+	"G":  "ĜĞĠĢ",
+	"g":  "ĝğġģ",
+	"H":  "ĤĦ",
+	"h":  "ĥħ",
+	"I":  "ÌÍÎÏĨĪĬĮİ",
+	"i":  "ìíîïĩīĭįı",
+	"J":  "Ĵ",
+	"j":  "ĵ",
+	"K":  "Ķ",
+	"k":  "ķĸ",
+	"L":  "ĹĻĽĿŁ",
+	"l":  "ĺļľŀł",
+	"N":  "ÑŃŅŇŊ",
+	"n":  "ñńņňŉŋ",
+	"O":  "ÒÓÔÕÖØŌŎŐ",
+	"o":  "òóôõöøōŏő",
+	"R":  "ŔŖŘ",
+	"r":  "ŕŗř",
+	"S":  "ŚŜŞŠȘ",
+	"s":  "śŝşšșſ",
+	"T":  "ŢŤŦȚ",
+	"t":  "ţťŧț",
+	"U":  "ÙÚÛÜŨŪŬŮŰŲ",
+	"u":  "ùúûüũūŭůűų",
+	"W":  "Ŵ",
+	"w":  "ŵ",
+	"Y":  "ÝŶŸ",
+	"y":  "ýÿŷ",
+	"Z":  "ŹŻŽ",
+	"z":  "źżž",
+	"'":  "‘’‚′",
+	"\"": "“”„″",
+	"-":  "‐‑–—",
+	" ":  " ",
+}
+
+// The letters and the Cyrillic alphabet that are written as several ASCII
+// letters, or none, like "æ" as "ae"
+var asciiSpellings = map[rune]string{
+	'Æ': "AE", 'æ': "ae", 'Ĳ': "IJ", 'ĳ': "ij", 'Œ': "OE", 'œ': "oe",
+	'Þ': "TH", 'þ': "th", 'ß': "ss", '…': "...",
+
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Е': "E", 'Ё': "E",
+	'Ж': "Zh", 'З': "Z", 'И': "I", 'Й': "J", 'К': "K", 'Л': "L", 'М': "M",
+	'Н': "N", 'О': "O", 'П': "P", 'Р': "R", 'С': "S", 'Т': "T", 'У': "U",
+	'Ф': "F", 'Х': "H", 'Ц': "C", 'Ч': "Ch", 'Ш': "Sh", 'Щ': "Shch",
+	'Ъ': "", 'Ы': "Y", 'Ь': "", 'Э': "E", 'Ю': "Yu", 'Я': "Ya",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "h", 'ц': "c", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+func init() {
+	for letter, accented := range asciiLetters {
+		for _, r := range accented {
+			asciiSpellings[r] = letter
+		}
+	}
+}
+
+// Write a name with ASCII only, like "Rodseth" for "Rødseth", for
+// Options.ASCII and for generating nicks. The accents are left out, the
+// letters like "æ" and "ß" are spelled out and the Cyrillic alphabet is
+// transliterated. Anything else that is not ASCII becomes "?".
+func Transliterate(s string) string {
+	if isASCII(s) {
+		return s
+	}
+	var sb strings.Builder
+	for _, r := range s {
+		switch spelling, ok := asciiSpellings[r]; {
+		case r < utf8.RuneSelf:
+			sb.WriteRune(r)
+		case ok:
+			sb.WriteString(spelling)
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
+
+// The maintainers to write at the top of the ChangeLog, with Options.ASCII
+func (opts *Options) maintainers() []string {
+	if !opts.ASCII {
+		return opts.Maintainers
+	}
+	maintainers := make([]string, len(opts.Maintainers))
+	for i, maintainer := range opts.Maintainers {
+		maintainers[i] = Transliterate(maintainer)
+	}
+	return maintainers
+}
+
+// Check if a string is ASCII only
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
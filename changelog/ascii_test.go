@@ -0,0 +1,48 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTransliterate(t *testing.T) {
+	for name, want := range map[string]string{
+		"Alexander Rødseth":       "Alexander Rodseth",
+		"Ævar Arnfjörð Bjarmason": "AEvar Arnfjord Bjarmason",
+		"Łukasz Straße":           "Lukasz Strasse",
+		"Сергей Шевченко":         "Sergej Shevchenko",
+		"Bob <bob@example.org>":   "Bob <bob@example.org>",
+		"李小龙":                     "???",
+	} {
+		if got := Transliterate(name); got != want {
+			t.Errorf("expected %q for %q, got %q", want, name, got)
+		}
+	}
+	for name, want := range map[string]string{
+		"Alexander Rødseth":       "arodseth",
+		"Ævar Arnfjörð Bjarmason": "abjarmason",
+		"Øyvind Ødegård":          "oodegard",
+		"Сергей Шевченко":         "sshevchenko",
+	} {
+		if got := generateNick(name); got != want {
+			t.Errorf("expected the nick %q for %q, got %q", want, name, got)
+		}
+	}
+}
+
+func TestASCII(t *testing.T) {
+	entries := []Entry{{Revision: 1, Author: "arodseth", Date: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Message: "Rødt"}}
+	g := New(&Options{ASCII: true, Maintainers: []string{"Ævar Bjarmason <avar@example.org>"}})
+	g.Names.Resolver = AuthorsFile{"arodseth": {Name: "Alexander Rødseth", Email: "rodseth@example.org"}}
+	var buf bytes.Buffer
+	if err := g.Write(context.Background(), &buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	// The messages are left as they are
+	expected := "Maintainer: AEvar Bjarmason <avar@example.org>\n\n2024-03-01 Alexander Rodseth <rodseth@example.org>\n    * Rødt\n\n"
+	if got := buf.String(); got != expected {
+		t.Fatalf("unexpected ChangeLog:\n%s", got)
+	}
+}
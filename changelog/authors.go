@@ -0,0 +1,98 @@
+package changelog
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// Someone who has commits in the log, for an AUTHORS file
+type Contributor struct {
+	Name    string   `json:"name"`    // The name and e-mail address, or the nick if it could not be resolved
+	Nicks   []string `json:"nicks"`   // The nicks or git names that the commits were made as, sorted
+	Commits int      `json:"commits"` // The number of commits
+	First   string   `json:"first"`   // The date of the first commit (YYYY-MM-DD)
+	Last    string   `json:"last"`    // The date of the latest commit (YYYY-MM-DD)
+}
+
+// The key that the same person has for all of their nicks and names: the
+// e-mail address if there is one, or else the name, regardless of case
+func contributorKey(name string) string {
+	id := ParseIdentity(name)
+	if id.Email != "" {
+		return strings.ToLower(id.Email)
+	}
+	return strings.ToLower(strings.Join(strings.Fields(id.Name), " "))
+}
+
+// Find the contributors of the entries, with their names looked up like
+// for the ChangeLog. The nicks that resolve to the same e-mail address, or
+// to the same name if there is none, are counted as one contributor, with
+// the name of their latest commit. Sorted by the number of commits, then
+// by name. Stops with the error from ctx if it is canceled.
+func (g *Generator) Contributors(ctx context.Context, entries []Entry) ([]Contributor, error) {
+	var (
+		nicks []string
+		seen  = make(map[string]bool)
+	)
+	for _, entry := range entries {
+		if entry.Name == "" && strings.TrimSpace(entry.Author) != "" && !seen[entry.Author] {
+			seen[entry.Author] = true
+			nicks = append(nicks, entry.Author)
+		}
+	}
+	sort.Strings(nicks)
+	stop := g.Options.Timings.Start("resolve")
+	err := g.Names.ResolveAll(ctx, nicks, func(done, total int) {
+		g.Options.progress("Resolving names", done, total)
+	})
+	stop()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		contributors []*Contributor
+		byKey        = make(map[string]*Contributor)
+		nicksOf      = make(map[*Contributor]map[string]bool)
+	)
+	// The entries are ordered from the newest to the oldest
+	for _, entry := range entries {
+		name := entry.Name
+		if strings.TrimSpace(entry.Author) == "" {
+			// Property edits and anonymous commits have no author
+			entry.Author = g.Options.unknownAuthor()
+			if name == "" {
+				name = entry.Author
+			}
+		}
+		if name == "" {
+			name = g.Names.Resolve(ctx, entry.Author)
+		}
+		name, author := Sanitize(name, false), Sanitize(entry.Author, false)
+		key := contributorKey(name)
+		c, ok := byKey[key]
+		if !ok {
+			c = &Contributor{Name: name, Last: entry.Day()}
+			byKey[key], nicksOf[c] = c, make(map[string]bool)
+			contributors = append(contributors, c)
+		}
+		c.Commits++
+		c.First = entry.Day()
+		nicksOf[c][author] = true
+	}
+	sorted := make([]Contributor, len(contributors))
+	for i, c := range contributors {
+		for nick := range nicksOf[c] {
+			c.Nicks = append(c.Nicks, nick)
+		}
+		sort.Strings(c.Nicks)
+		sorted[i] = *c
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Commits != sorted[j].Commits {
+			return sorted[i].Commits > sorted[j].Commits
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted, nil
+}
@@ -0,0 +1,42 @@
+package changelog
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestContributors(t *testing.T) {
+	g := New(nil)
+	g.Names.Resolver = AuthorsFile{
+		"alice":  {Name: "Alice", Email: "alice@example.org"},
+		"alice2": {Name: "Alice Doe", Email: "Alice@example.org"},
+	}
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	// From the newest to the oldest, like from a Source
+	entries := []Entry{
+		{Revision: 5, Author: "alice", Date: day(5), Message: "Change"},
+		{Revision: 4, Author: "bob", Date: day(4), Message: "Change"},
+		{Revision: 3, Author: "alice2", Date: day(3), Message: "Change"},
+		{Revision: 2, Author: "", Date: day(2), Message: "Property edit"},
+		{Revision: 1, Author: "alice", Date: day(1), Message: "Initial import"},
+	}
+	contributors, err := g.Contributors(context.Background(), entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contributors) != 3 {
+		t.Fatalf("expected 3 contributors, got %+v", contributors)
+	}
+	alice := contributors[0]
+	if alice.Name != "Alice <alice@example.org>" || alice.Commits != 3 || alice.First != "2024-03-01" || alice.Last != "2024-03-05" {
+		t.Fatalf("unexpected contributor: %+v", alice)
+	}
+	if len(alice.Nicks) != 2 || alice.Nicks[0] != "alice" || alice.Nicks[1] != "alice2" {
+		t.Fatalf("unexpected nicks: %v", alice.Nicks)
+	}
+	// Sorted by name when they have as many commits
+	if contributors[1].Name != "bob" || contributors[2].Name != DEFAULT_UNKNOWN_AUTHOR {
+		t.Fatalf("unexpected order: %+v", contributors)
+	}
+}
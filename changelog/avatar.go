@@ -0,0 +1,39 @@
+package changelog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// The services for the avatars of the authors, for Options.Avatars. Both
+// find the avatar by the SHA-256 hash of the e-mail address, and show a
+// generated picture for the addresses that have no avatar.
+var avatarURLs = map[string]string{
+	"gravatar":   "https://www.gravatar.com/avatar/%s?s=%d&d=identicon",
+	"libravatar": "https://seccdn.libravatar.org/avatar/%s?s=%d&d=identicon",
+}
+
+// The size of the avatars, in pixels
+const AVATAR_SIZE = 40
+
+// Check the service for the avatars, where "" is no avatars
+func CheckAvatars(service string) error {
+	if _, ok := avatarURLs[service]; !ok && service != "" {
+		return fmt.Errorf("Unknown avatar service: %s (available: gravatar, libravatar)", service)
+	}
+	return nil
+}
+
+// The URL of the avatar for the name and e-mail address, like
+// "Name <email>", or "" if there is no service or no e-mail address
+func avatarURL(service, name string) string {
+	email := strings.ToLower(strings.TrimSpace(ParseIdentity(name).Email))
+	pattern, ok := avatarURLs[service]
+	if !ok || email == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(email))
+	return fmt.Sprintf(pattern, hex.EncodeToString(sum[:]), AVATAR_SIZE)
+}
@@ -0,0 +1,46 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAvatarURL(t *testing.T) {
+	const hash = "7a64adf28737ea90719cbdf0b1a87a5effff3753b79c91d717f4f4153ead0498"
+	if got := avatarURL("gravatar", "Alice <Alice@Example.org>"); got != "https://www.gravatar.com/avatar/"+hash+"?s=40&d=identicon" {
+		t.Fatalf("unexpected gravatar URL: %s", got)
+	}
+	if got := avatarURL("libravatar", "Alice <alice@example.org>"); !strings.Contains(got, "libravatar.org/avatar/"+hash) {
+		t.Fatalf("unexpected libravatar URL: %s", got)
+	}
+	for _, test := range [][2]string{{"gravatar", "alice"}, {"", "Alice <alice@example.org>"}} {
+		if got := avatarURL(test[0], test[1]); got != "" {
+			t.Fatalf("expected no avatar for %v, got %s", test, got)
+		}
+	}
+	if err := CheckAvatars("myspace"); err == nil {
+		t.Fatal("expected an error for an unknown avatar service")
+	}
+}
+
+func TestAvatars(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{{Revision: 2, Author: "alice", Date: day, Message: "Update"}, {Revision: 1, Author: "bob", Date: day, Message: "Initial import"}}
+	for format, want := range map[string]string{
+		"html": `"><img class="avatar" src="https://www.gravatar.com/avatar/`,
+		"json": `"avatar": "https://www.gravatar.com/avatar/`,
+	} {
+		g := New(&Options{Format: format, Avatars: "gravatar"})
+		g.Names.Resolver = AuthorsFile{"alice": {Name: "Alice", Email: "alice@example.org"}}
+		var buf bytes.Buffer
+		if err := g.Write(context.Background(), &buf, entries); err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(buf.String(), want); n != 1 {
+			t.Fatalf("expected an avatar for the author with an e-mail address only, got %d in:\n%s", n, buf.String())
+		}
+	}
+}
@@ -0,0 +1,461 @@
+// Package changelog generates a ChangeLog based on svn log or git log, and tries to
+// find names and e-mail addresses for Arch Linux related usernames.
+//
+// This is the library behind the archlog utility, so that other tools,
+// like packaging bots, can embed the functionality.
+package changelog
+
+import (
+	"context"
+	"io"
+	"iter"
+	"strings"
+	"time"
+)
+
+// The start of each message in the ChangeLog
+const LEAD_STAR = "    * "
+
+// A log entry, with the author resolved to a name and e-mail address, if found
+type Entry struct {
+	Revision     int       `json:"revision"`
+	Author       string    `json:"author"`         // The nick of the author, or the name for git
+	Name         string    `json:"name,omitempty"` // The name and e-mail address, the nick if it could not be resolved, or "" if not resolved yet
+	Date         time.Time `json:"date"`           // The time of the commit, in UTC
+	Message      string    `json:"message"`
+	Commit       string    `json:"commit,omitempty"`       // The commit hash, for git
+	Version      string    `json:"version,omitempty"`      // The version of the package the entry was released in, with Options.Versions
+	Branches     []string  `json:"branches,omitempty"`     // The git branches the commit is on, with Options.Branches
+	Repositories []string  `json:"repositories,omitempty"` // The labels of the repositories the entry is from, with Options.Repositories
+	File         string    `json:"-"`                      // The name the file had at the revision, for FileSource, if it is known
+}
+
+// The date of the entry, as used in the ChangeLog headers (YYYY-MM-DD)
+func (e *Entry) Day() string {
+	return e.Date.Format("2006-01-02")
+}
+
+// Settings for generating a ChangeLog
+type Options struct {
+	Repo         string        // The directory of the working copy, or "" for the current directory
+	VCS          string        // The name of a registered Source, or "" for "svn"
+	SvnBin       string        // The svn executable, or "" for "svn" in the PATH
+	GitBin       string        // The git executable, or "" for "git" in the PATH
+	Timeout      time.Duration // The timeout for running svn or git, or 0 for no timeout
+	Entries      int           // The number of log entries to fetch, -1 for all
+	FromRevision int           // The oldest revision to fetch, 0 for all
+	Ref          string        // The git commit, branch or tag whose history is fetched, or "" for HEAD
+	Branches     []string      // Several git branches whose histories are fetched together, instead of Ref
+	BranchLabels bool          // Start each message with the Branches the commit is on, like "[main, 1.x]"
+	Submodules   bool          // Add the commits of the git submodules that each commit updated to its message
+	FirstParent  bool          // Only follow the first parent of the git merge commits, so the merges summarize the branches
+	Jobs         int           // The number of concurrent svn log invocations for fetching all entries, 0 or 1 for one
+	StopOnCopy   bool          // Stop the svn log at the revision where the branch or tag of the working copy was copied
+	SvnPath      string        // Fetch the svn log of this path in the project of the working copy instead, like "branches/1.x" or "trunk@1234"
+	Externals    bool          // Include the svn log of the svn:externals in the working copy, with where they are before the messages
+
+	// Several working copies whose logs are aggregated, instead of Repo
+	Repositories []Repository
+	// How the entries of each of the Repositories are labeled, with
+	// REPOSITORY_LABELS_MESSAGES or REPOSITORY_LABELS_SECTIONS, or "" for no labels
+	RepositoryLabels string
+
+	Normalization *Normalization // Optional commit message normalization
+	Since         string         // Skip entries older than this date (YYYY-MM-DD)
+	Existing      string         // The contents of an existing ChangeLog, for skipping recorded entries
+	Format        string         // The name of a registered Formatter, or "" for "plain"
+	Color         bool           // Color the plain output for terminals
+	Parsing       ParseMode      // How to handle a log that can not be fully parsed
+	InputEncoding string         // The encoding of the log: auto, utf-8, latin1 or windows-1252, or "" for auto
+	EOL           string         // The line endings of the ChangeLog, EOL_LF or EOL_CRLF, or "" for EOL_LF
+	BOM           bool           // Start the ChangeLog with UTF8_BOM
+	ASCII         bool           // Transliterate the names of the authors and the maintainers to ASCII, like "Rodseth" for "Rødseth"
+	GeneratedAt   time.Time      // Add a "Generated by archlog" footer with this time, unless it is zero
+	Footer        *Footer        // Add a footer with what generated the ChangeLog from which revisions, or nil for none
+	RelativeTo    time.Time      // Add how long before this time each entry was to the headers, like "(3 days ago)", unless it is zero
+	UnknownAuthor string         // Shown for entries without an author, or "" for DEFAULT_UNKNOWN_AUTHOR in the Language
+	Versions      bool           // Group the entries by the version of the package they were released in
+	Upgrades      bool           // Add an "Upgraded to" line to the entries that changed the version of the package
+	Pkgbuild      string         // The PKGBUILD for Versions and Upgrades, relative to the working copy, or "" for PKGBUILD
+	Maintainers   []string       // The current maintainers of the package, written at the top of the ChangeLog
+	Archives      []string       // The files with the older entries, written at the end of the ChangeLog
+	Language      string         // The language of the headings, like "de", or "" for English. The dates are always YYYY-MM-DD.
+	Anonymize     string         // Replace the authors with ANONYMIZE_PSEUDONYMS or ANONYMIZE_INITIALS, or "" to show them
+	Obfuscate     string         // Obfuscate the e-mail addresses with OBFUSCATE_AT, OBFUSCATE_ENTITIES or OBFUSCATE_DROP, or "" to show them
+	Avatars       string         // Add the URLs of the avatars of the authors to the html and json formats from "gravatar" or "libravatar", or "" for none
+	Search        bool           // Add a search by text, author and date to the html format, which needs JavaScript
+	Metadata      *Metadata      // Add what generated the ChangeLog to the json format, or nil to leave it out, for reproducible output
+
+	// Add a link to each section and entry in the html format, where {id},
+	// {revision} and {date} are replaced, like "#{id}", or "" for none
+	PermalinkTemplate string
+
+	// The username and password for svn, or "" for the ones svn would use
+	SvnUsername string
+	SvnPassword string
+	// A .netrc file with the svn login for the host of the repository, used
+	// if no username or password is given, or "" for none
+	Netrc string
+	// Make svn fail instead of prompting for a username, a password or
+	// whether to trust a server certificate
+	NonInteractive bool
+	// Make svn trust the server certificate, also when it is self-signed or
+	// has expired, which also makes svn non-interactive
+	TrustServerCert bool
+
+	// The file with the entries for the "mock" Source, as a JSON array like
+	// the json format writes, or a dump of "svn log --xml" or "git log"
+	MockData string
+
+	// Rules for rewriting, dropping or retagging entries, applied before the hooks
+	Transform *Transform
+
+	// A shell command that gets each entry as JSON on stdin and outputs
+	// the modified entry, or nothing to drop it
+	PreEntryHook string
+	// A shell command that gets all the entries as a JSON array on stdin,
+	// once they have been fetched, and outputs the modified array
+	PostGenerateHook string
+
+	// Records the time spent in each phase, if set
+	Timings *Timings
+
+	// Called with the progress of long operations, like "Fetching the log"
+	// or "Resolving names", with the number of done items and the total,
+	// which is 0 if unknown. Can be nil.
+	Progress func(stage string, done, total int)
+}
+
+// Generates ChangeLogs
+type Generator struct {
+	Options   *Options
+	Names     *Names
+	Source    Source    // Where the log entries come from, or nil for the Source named by Options.VCS
+	Formatter Formatter // How the ChangeLog is written, or nil for the Formatter named by Options.Format
+}
+
+// Create a Generator. If opts is nil, all log entries are fetched from
+// the working copy in the current directory.
+func New(opts *Options) *Generator {
+	if opts == nil {
+		opts = &Options{Entries: -1}
+	}
+	return &Generator{Options: opts, Names: NewNames()}
+}
+
+// Report the progress, if there is a Progress callback
+func (opts *Options) progress(stage string, done, total int) {
+	if opts.Progress != nil {
+		opts.Progress(stage, done, total)
+	}
+}
+
+// The name for entries without an author, by default
+const DEFAULT_UNKNOWN_AUTHOR = "unknown"
+
+// The name to show for entries without an author. DEFAULT_UNKNOWN_AUTHOR
+// is written in the Language.
+func (opts *Options) unknownAuthor() string {
+	if opts.UnknownAuthor == "" || opts.UnknownAuthor == DEFAULT_UNKNOWN_AUTHOR {
+		return opts.catalog().UnknownAuthor
+	}
+	return opts.UnknownAuthor
+}
+
+// Find the Source to fetch the log entries from
+func (g *Generator) source() (Source, error) {
+	if g.Source != nil {
+		return g.Source, nil
+	}
+	if g.Options.VCS == "" {
+		return LookupSource("svn")
+	}
+	return LookupSource(g.Options.VCS)
+}
+
+// Fetch the log entries, ordered from the newest to the oldest, and run
+// the hooks. The names of the authors are not resolved yet, unless the
+// Source or a hook already knows them.
+func (g *Generator) Entries(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	for entry, err := range g.Stream(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if g.Options.PostGenerateHook != "" {
+		defer g.Options.Timings.Start("hooks")()
+		return runPostGenerateHook(ctx, g.Options, entries)
+	}
+	return entries, nil
+}
+
+// Stream the log entries as they arrive from the Source, ordered from the
+// newest to the oldest, without collecting them in memory. The pre-entry
+// hook is run for each entry, but not the post-generate hook. An error
+// ends the stream and is yielded together with an empty Entry.
+func (g *Generator) Stream(ctx context.Context) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		source, err := g.source()
+		if err != nil {
+			yield(Entry{}, err)
+			return
+		}
+		var seq iter.Seq2[Entry, error]
+		if len(g.Options.Repositories) > 0 {
+			seq, err = g.aggregateEntries(ctx)
+		} else {
+			seq, err = source.Entries(ctx, g.Options)
+		}
+		if err != nil {
+			yield(Entry{}, err)
+			return
+		}
+		if g.Options.BranchLabels && len(g.Options.Branches) > 1 {
+			seq = withBranchLabels(seq)
+		}
+		if g.Options.Versions || g.Options.Upgrades {
+			versions, err := g.Versions(ctx)
+			if err != nil {
+				yield(Entry{}, err)
+				return
+			}
+			if g.Options.Versions {
+				seq = withVersions(seq, versions)
+			}
+			if g.Options.Upgrades {
+				seq = withUpgrades(seq, versions, g.Options.catalog().UpgradedTo)
+			}
+		}
+		for entry, err := range seq {
+			if err == nil {
+				err = ctx.Err()
+			}
+			keep := true
+			if err == nil && g.Options.Transform != nil {
+				entry, keep, err = g.Options.Transform.Apply(entry)
+			}
+			if err == nil && keep && g.Options.PreEntryHook != "" {
+				stop := g.Options.Timings.Start("hooks")
+				entry, keep, err = runPreEntryHook(ctx, g.Options, entry)
+				stop()
+			}
+			if !keep {
+				continue
+			}
+			if !yield(entry, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Find the Formatter to write the ChangeLog with
+func (g *Generator) formatter() (Formatter, error) {
+	if g.Formatter != nil {
+		return g.Formatter, nil
+	}
+	if g.Options.Format == "" {
+		return NewFormatter("plain", g.Options)
+	}
+	return NewFormatter(g.Options.Format, g.Options)
+}
+
+// The number of entries that are gathered before the names in them are
+// looked up and they are written, when writing a ChangeLog
+const WRITE_WINDOW = 1000
+
+// Write the log entries as a ChangeLog, resolving the names of the
+// authors along the way. The entries of the same author on the same day
+// are gathered in one section, with the oldest message first.
+// Stops with the error from ctx if it is canceled.
+func (g *Generator) Write(ctx context.Context, w io.Writer, entries []Entry) error {
+	return g.WriteStream(ctx, w, sliceEntries(entries))
+}
+
+// Write the log entries as a ChangeLog, like Write, while they arrive.
+// The entries are handled WRITE_WINDOW at a time, so that only the
+// current window and section are kept in memory, and the names of the
+// new authors in each window are looked up concurrently.
+func (g *Generator) WriteStream(ctx context.Context, w io.Writer, entries iter.Seq2[Entry, error]) error {
+	opts := g.Options
+	w = newLineEndingWriter(w, opts)
+	// The time spent here is "format", except for the time spent waiting
+	// for the entries and resolving the names
+	start, resolving := time.Now(), opts.Timings.Get("resolve")
+	var waiting time.Duration
+	entries = producerTime(entries, func(d time.Duration) { waiting = d })
+	defer func() {
+		opts.Timings.Add("format", time.Since(start)-waiting-(opts.Timings.Get("resolve")-resolving))
+	}()
+	f, err := g.formatter()
+	if err != nil {
+		return err
+	}
+	if err := f.Begin(w); err != nil {
+		return err
+	}
+	var section *Section
+	anonymize := opts.anonymizer()
+	// Output the gathered messages, in reverse order
+	flush := func() error {
+		if section == nil {
+			return nil
+		}
+		for i, j := 0, len(section.Messages)-1; i < j; i, j = i+1, j-1 {
+			section.Messages[i], section.Messages[j] = section.Messages[j], section.Messages[i]
+			section.Revisions[i], section.Revisions[j] = section.Revisions[j], section.Revisions[i]
+			section.IDs[i], section.IDs[j] = section.IDs[j], section.IDs[i]
+		}
+		section.ID = sectionID(section)
+		err := f.Entry(w, section)
+		section = nil
+		return err
+	}
+	// The entries and messages of the current window, and the distinct
+	// authors in it that have not been resolved yet
+	type item struct {
+		entry Entry
+		msg   string
+	}
+	var (
+		window   = make([]item, 0, WRITE_WINDOW)
+		pending  []string
+		seen     = make(map[string]bool)
+		resolved int
+	)
+	// Look up the new names in the window concurrently, then write the entries
+	writeWindow := func() error {
+		stop := opts.Timings.Start("resolve")
+		err := g.Names.ResolveAll(ctx, pending, func(done, total int) {
+			opts.progress("Resolving names", resolved+done, len(seen))
+		})
+		stop()
+		if err != nil {
+			return err
+		}
+		resolved += len(pending)
+		for _, item := range window {
+			entry, date := item.entry, item.entry.Day()
+			if entry.Name == "" {
+				entry.Name = g.Names.Resolve(ctx, entry.Author)
+			}
+			entry.Name, entry.Author = Sanitize(entry.Name, false), Sanitize(entry.Author, false)
+			if anonymize != nil && entry.Author != opts.unknownAuthor() {
+				entry.Name = anonymize.replace(entry.Author, entry.Name)
+				entry.Author = entry.Name
+			}
+			if opts.ASCII {
+				entry.Name, entry.Author = Transliterate(entry.Name), Transliterate(entry.Author)
+			}
+			var repositories []string
+			if opts.RepositoryLabels == REPOSITORY_LABELS_SECTIONS {
+				repositories = entry.Repositories
+			}
+			// Start a new section if it's not the same date again, not the same name, another version or another repository
+			if section != nil && (section.Date != date || section.Name != entry.Name || section.Version != entry.Version || repositoryLabel(section.Repositories) != repositoryLabel(repositories)) {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			if section == nil {
+				section = &Section{Date: date, Name: entry.Name, Author: entry.Author, Version: entry.Version, Avatar: avatarURL(opts.Avatars, entry.Name), Repositories: repositories}
+			}
+			section.Messages = append(section.Messages, item.msg)
+			section.Revisions = append(section.Revisions, entry.Revision)
+			section.IDs = append(section.IDs, entryID(entry))
+		}
+		window, pending = window[:0], nil
+		return nil
+	}
+	for entry, err := range entries {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		date := entry.Day()
+		if opts.Since != "" && date < opts.Since {
+			// Skip entries that are older than the existing ChangeLog
+			continue
+		}
+		msg := opts.Normalization.Apply(strings.TrimSpace(Sanitize(entry.Message, true)))
+		if msg == "" {
+			// Skip empty messages
+			continue
+		}
+		if opts.RepositoryLabels == REPOSITORY_LABELS_MESSAGES && len(entry.Repositories) > 0 {
+			msg = repositoryLabel(entry.Repositories) + " " + msg
+		}
+		// Where there is one blank line, remove it
+		if strings.Count(msg, "\n\n") == 1 {
+			msg = strings.Replace(msg, "\n\n", "\n", 1)
+		}
+		if date == opts.Since && strings.Contains(opts.Existing, plainMessage(obfuscateEmails(msg, opts.Obfuscate))+"\n") {
+			// Skip entries from the same day that are already recorded
+			continue
+		}
+		if strings.TrimSpace(entry.Author) == "" {
+			// Property edits and anonymous commits have no author
+			entry.Author = opts.unknownAuthor()
+			if entry.Name == "" {
+				entry.Name = entry.Author
+			}
+		}
+		window = append(window, item{entry, msg})
+		if entry.Name == "" && !seen[entry.Author] {
+			if _, ok := g.Names.Cached(entry.Author); !ok {
+				seen[entry.Author] = true
+				pending = append(pending, entry.Author)
+			}
+		}
+		if len(window) == WRITE_WINDOW {
+			if err := writeWindow(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := writeWindow(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return f.End(w)
+}
+
+// Count the entries in a generated ChangeLog
+func CountEntries(contents string) int {
+	return strings.Count("\n"+contents, "\n"+LEAD_STAR)
+}
+
+// Gathers the sections instead of writing them
+type sectionCollector struct {
+	sections []Section
+}
+
+func (c *sectionCollector) Begin(w io.Writer) error { return nil }
+func (c *sectionCollector) End(w io.Writer) error   { return nil }
+
+func (c *sectionCollector) Entry(w io.Writer, section *Section) error {
+	c.sections = append(c.sections, *section)
+	return nil
+}
+
+// The sections that Write would write for the entries, from the newest to
+// the oldest, with the same entries skipped, like the ones that are
+// already in Options.Existing
+func (g *Generator) Sections(ctx context.Context, entries []Entry) ([]Section, error) {
+	collector := &sectionCollector{}
+	// The time is not counted as formatting
+	opts := *g.Options
+	opts.Timings = nil
+	collecting := *g
+	collecting.Options, collecting.Formatter = &opts, collector
+	if err := collecting.Write(ctx, io.Discard, entries); err != nil {
+		return nil, err
+	}
+	return collector.sections, nil
+}
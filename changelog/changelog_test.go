@@ -0,0 +1,122 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"iter"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A Source with the given entries, that fails after them if err is set
+type testSource struct {
+	entries []Entry
+	err     error
+}
+
+func (s testSource) Entries(ctx context.Context, opts *Options) (iter.Seq2[Entry, error], error) {
+	return func(yield func(Entry, error) bool) {
+		for entry := range sliceEntries(s.entries) {
+			if !yield(entry, nil) {
+				return
+			}
+		}
+		if s.err != nil {
+			yield(Entry{}, s.err)
+		}
+	}, nil
+}
+
+func TestStream(t *testing.T) {
+	g := New(nil)
+	g.Source = testSource{entries: []Entry{{Revision: 3}, {Revision: 2}, {Revision: 1}}}
+	var revisions []int
+	for entry, err := range g.Stream(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		revisions = append(revisions, entry.Revision)
+		if len(revisions) == 2 {
+			break
+		}
+	}
+	if len(revisions) != 2 || revisions[0] != 3 || revisions[1] != 2 {
+		t.Fatalf("unexpected revisions: %v", revisions)
+	}
+	failure := errors.New("svn failed")
+	g.Source = testSource{entries: []Entry{{Revision: 1}}, err: failure}
+	if _, err := g.Entries(context.Background()); err != failure {
+		t.Fatalf("expected the error from the source, got %v", err)
+	}
+}
+
+func TestWriteStream(t *testing.T) {
+	g := New(nil)
+	g.Names.Resolver = AuthorsFile{"alice": {Name: "Alice", Email: "alice@example.org"}}
+	// More entries than fit in one window, by the same author on the same day
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var entries []Entry
+	for revision := WRITE_WINDOW + 10; revision > 0; revision-- {
+		entries = append(entries, Entry{Revision: revision, Author: "alice", Date: day, Message: "Change"})
+	}
+	entries = append(entries, Entry{Revision: 0, Author: "bob", Date: day.AddDate(0, 0, -1), Message: "Initial import"})
+	var buf bytes.Buffer
+	if err := g.WriteStream(context.Background(), &buf, sliceEntries(entries)); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+	if n := strings.Count(output, "2024-03-01 Alice <alice@example.org>\n"); n != 1 {
+		t.Fatalf("expected one section across the windows, got %d", n)
+	}
+	if n := strings.Count(output, "* Change\n"); n != WRITE_WINDOW+10 {
+		t.Fatalf("expected %d messages, got %d", WRITE_WINDOW+10, n)
+	}
+	if !strings.HasSuffix(output, "2024-02-29 bob\n    * Initial import\n\n") {
+		t.Fatalf("unexpected end of the ChangeLog: %q", output[len(output)-60:])
+	}
+}
+
+func TestSections(t *testing.T) {
+	g := New(&Options{Since: "2024-03-01", Existing: "2024-03-01 alice\n    * Fix the build\n\n"})
+	g.Names.Resolver = AuthorsFile{"alice": {Name: "Alice", Email: "alice@example.org"}}
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Revision: 3, Author: "alice", Date: day, Message: "Add a test"},
+		{Revision: 2, Author: "alice", Date: day, Message: "Fix the build"},
+		{Revision: 1, Author: "alice", Date: day.AddDate(0, 0, -1), Message: "Initial import"},
+	}
+	sections, err := g.Sections(context.Background(), entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 1 || sections[0].Name != "Alice <alice@example.org>" || len(sections[0].Messages) != 1 || sections[0].Messages[0] != "Add a test" {
+		t.Fatalf("expected only the new entry, got %+v", sections)
+	}
+}
+
+func TestMissingAuthor(t *testing.T) {
+	g := New(&Options{UnknownAuthor: "nobody"})
+	lookups := 0
+	g.Names.Resolver = ResolverFunc(func(ctx context.Context, nick string) (Identity, error) {
+		lookups++
+		return Identity{}, ErrNotFound
+	})
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{{Revision: 2, Date: day, Message: "Set svn:ignore"}, {Revision: 1, Author: " ", Date: day, Message: "Initial import"}}
+	var buf bytes.Buffer
+	if err := g.Write(context.Background(), &buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	expected := "2024-03-01 nobody\n    * Initial import\n    * Set svn:ignore\n\n"
+	if buf.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buf.String())
+	}
+	if lookups != 0 {
+		t.Fatalf("expected no lookups for missing authors, got %d", lookups)
+	}
+	if gitName("", "someone@example.org") != "" {
+		t.Fatal("expected no name for a git commit without an author name")
+	}
+}
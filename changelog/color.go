@@ -0,0 +1,36 @@
+package changelog
+
+import (
+	"strings"
+)
+
+// ANSI escape codes for the colored terminal output
+const (
+	colorReset  = "\033[0m"
+	colorDate   = "\033[34m"   // blue
+	colorName   = "\033[1m"    // bold
+	colorNick   = "\033[1;33m" // bold yellow, for nicks that could not be resolved
+	colorBullet = "\033[32m"   // green
+)
+
+// Wrap the text in the given color
+func colorize(color, text string) string {
+	return color + text + colorReset
+}
+
+// Color the date and the name of a header. The name is highlighted if it
+// is just the nick, because it could not be resolved.
+func colorHeader(date, name, nick string) string {
+	nameColor := colorName
+	if name == nick {
+		nameColor = colorNick
+	}
+	return colorize(colorDate, date) + " " + colorize(nameColor, name)
+}
+
+// Color the bullet at the start of a formatted message
+func colorMessage(msg, leadStar string) string {
+	star := strings.TrimSpace(leadStar)
+	indent := leadStar[:strings.Index(leadStar, star)]
+	return indent + colorize(colorBullet, star) + strings.TrimPrefix(msg, indent+star)
+}
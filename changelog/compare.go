@@ -0,0 +1,123 @@
+package changelog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Read the entries of a plain ChangeLog back into sections, with the date,
+// the name and the messages of each entry. The messages of hand-written
+// ChangeLogs may also start with a tab, like "\t* Fix the build", as GNU
+// writes them. Hand-written sections between BEGIN_MARKER and END_MARKER
+// are left out, and the Version of the sections is taken from the version
+// headings, in any of the languages.
+func ParseChangeLog(contents string) []Section {
+	var (
+		sections []Section
+		current  = -1 // The section that the lines are in, or -1 if none
+		version  string
+		manual   bool
+	)
+	for _, line := range strings.Split(contents, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case manual:
+			manual = trimmed != END_MARKER
+		case trimmed == BEGIN_MARKER:
+			manual, current = true, -1
+		case IsHeader(line):
+			// GNU has two spaces between the name and the e-mail address
+			name := strings.Join(strings.Fields(line[10:]), " ")
+			sections = append(sections, Section{Date: line[:10], Name: name, Version: version})
+			current = len(sections) - 1
+		case hasCatalogPrefix(line, func(c *Catalog) string { return c.Version }):
+			version, _ = cutCatalogPrefix(trimmed, func(c *Catalog) string { return c.Version })
+			current = -1
+		case current < 0:
+		case strings.HasPrefix(line, LEAD_STAR) || strings.HasPrefix(line, "\t* "):
+			sections[current].Messages = append(sections[current].Messages, strings.TrimSpace(strings.TrimPrefix(trimmed, "*")))
+		case trimmed != "" && len(sections[current].Messages) > 0:
+			// A line of the message above
+			messages := sections[current].Messages
+			messages[len(messages)-1] += "\n" + trimmed
+		}
+	}
+	return sections
+}
+
+// How an entry differs between two ChangeLogs
+type EntryChange struct {
+	Kind    string   // "added", "removed" or "modified"
+	Date    string   // YYYY-MM-DD
+	Name    string   // The name in the header
+	Added   []string // The messages that are only in the new ChangeLog
+	Removed []string // The messages that are only in the old ChangeLog
+}
+
+// The same message, regardless of how it is wrapped and indented
+func normalizedMessage(msg string) string {
+	return strings.Join(strings.Fields(msg), " ")
+}
+
+// The messages of a that are not in b, counting repeated messages
+func missingMessages(a, b []string) []string {
+	counts := make(map[string]int)
+	for _, msg := range b {
+		counts[normalizedMessage(msg)]++
+	}
+	var missing []string
+	for _, msg := range a {
+		if key := normalizedMessage(msg); counts[key] > 0 {
+			counts[key]--
+		} else {
+			missing = append(missing, msg)
+		}
+	}
+	return missing
+}
+
+// Compare the entries of two ChangeLogs, as read by ParseChangeLog. The
+// entries are matched by their date and name, and the messages are
+// compared without regard to how they are wrapped, so that only the
+// changes to what the ChangeLog says are reported, and not changes to how
+// it is formatted. The changes are in the order of the new ChangeLog, with
+// the removed entries last.
+func CompareChangeLogs(before, after []Section) []EntryChange {
+	// The same author may have several entries on the same day, if they are
+	// split by other entries, so the entries are numbered by their header
+	key := func(sections []Section) (map[string]*Section, []string) {
+		seen := make(map[string]int)
+		byKey := make(map[string]*Section, len(sections))
+		keys := make([]string, 0, len(sections))
+		for i := range sections {
+			header := sections[i].Date + " " + sections[i].Name
+			seen[header]++
+			k := fmt.Sprintf("%s#%d", header, seen[header])
+			byKey[k] = &sections[i]
+			keys = append(keys, k)
+		}
+		return byKey, keys
+	}
+	oldByKey, oldKeys := key(before)
+	newByKey, newKeys := key(after)
+	var changes []EntryChange
+	for _, k := range newKeys {
+		s := newByKey[k]
+		o, ok := oldByKey[k]
+		if !ok {
+			changes = append(changes, EntryChange{Kind: "added", Date: s.Date, Name: s.Name, Added: s.Messages})
+			continue
+		}
+		added, removed := missingMessages(s.Messages, o.Messages), missingMessages(o.Messages, s.Messages)
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, EntryChange{Kind: "modified", Date: s.Date, Name: s.Name, Added: added, Removed: removed})
+		}
+	}
+	for _, k := range oldKeys {
+		if _, ok := newByKey[k]; !ok {
+			o := oldByKey[k]
+			changes = append(changes, EntryChange{Kind: "removed", Date: o.Date, Name: o.Name, Removed: o.Messages})
+		}
+	}
+	return changes
+}
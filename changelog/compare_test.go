@@ -0,0 +1,68 @@
+package changelog
+
+import "testing"
+
+func TestParseChangeLog(t *testing.T) {
+	sections := ParseChangeLog(`Maintainer: arodseth
+
+Version 4.3.3-1
+
+2014-04-01 arodseth
+    * upgpkg: python-cx_freeze 4.3.3-1
+    * A message on
+      two lines
+
+# archlog:begin
+2014-03-30 someone
+    * Not an entry
+# archlog:end
+
+2014-03-17  Alexander Rødseth  <rodseth@gmail.com>
+
+	* PKGBUILD: Fix the build
+`)
+	if len(sections) != 2 {
+		t.Fatalf("expected 2 sections, got %+v", sections)
+	}
+	if s := sections[0]; s.Date != "2014-04-01" || s.Version != "4.3.3-1" || len(s.Messages) != 2 || s.Messages[1] != "A message on\ntwo lines" {
+		t.Fatalf("unexpected section: %+v", s)
+	}
+	if s := sections[1]; s.Name != "Alexander Rødseth <rodseth@gmail.com>" || len(s.Messages) != 1 || s.Messages[0] != "PKGBUILD: Fix the build" {
+		t.Fatalf("unexpected section: %+v", s)
+	}
+}
+
+func TestCompareChangeLogs(t *testing.T) {
+	old := ParseChangeLog(`2014-03-17 arodseth
+    * A message on
+      two lines
+    * Removed
+
+2014-01-06 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-1
+`)
+	updated := ParseChangeLog(`2014-04-01 arodseth
+    * upgpkg: python-cx_freeze 4.3.3-1
+
+2014-03-17 arodseth
+    * A message on two lines
+    * Added
+`)
+	changes := CompareChangeLogs(old, updated)
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	if c := changes[0]; c.Kind != "added" || c.Date != "2014-04-01" || len(c.Added) != 1 {
+		t.Fatalf("unexpected change: %+v", c)
+	}
+	// Only the messages that changed, and not the one that was wrapped differently
+	if c := changes[1]; c.Kind != "modified" || len(c.Added) != 1 || c.Added[0] != "Added" || len(c.Removed) != 1 || c.Removed[0] != "Removed" {
+		t.Fatalf("unexpected change: %+v", c)
+	}
+	if c := changes[2]; c.Kind != "removed" || c.Date != "2014-01-06" {
+		t.Fatalf("unexpected change: %+v", c)
+	}
+	if changes := CompareChangeLogs(updated, updated); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
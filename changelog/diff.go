@@ -0,0 +1,189 @@
+package changelog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The number of unchanged lines to show around each change
+const DIFF_CONTEXT = 3
+
+// A line in a diff, with ' ', '-' or '+' as the operation
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// Split text into lines, without a trailing empty line
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Find the line differences between a and b, with the linear space
+// variant of the Myers diff algorithm. The common prefix and suffix are
+// trimmed first, which keeps this cheap for the typical case of entries
+// added at the top.
+func diffLines(a, b []string) []diffLine {
+	return appendDiff(nil, a, b)
+}
+
+// Append the line differences between a and b to lines
+func appendDiff(lines []diffLine, a, b []string) []diffLine {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		lines = append(lines, diffLine{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+	switch {
+	case len(a) == 0:
+		for _, text := range b {
+			lines = append(lines, diffLine{'+', text})
+		}
+	case len(b) == 0:
+		for _, text := range a {
+			lines = append(lines, diffLine{'-', text})
+		}
+	default:
+		// There are at least two differences when neither is empty and the
+		// ends differ, so both halves around the middle snake have fewer
+		x, y, u, v := middleSnake(a, b)
+		lines = appendDiff(lines, a[:x], b[:y])
+		for _, text := range a[x:u] {
+			lines = append(lines, diffLine{' ', text})
+		}
+		lines = appendDiff(lines, a[u:], b[v:])
+	}
+	for _, text := range common {
+		lines = append(lines, diffLine{' ', text})
+	}
+	return lines
+}
+
+// Find the middle snake of the shortest edit script between a and b, by
+// searching from both ends at once until the paths overlap. The snake goes
+// from a[x], b[y] to a[u], b[v], where the lines in between are equal.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	// forward[offset+k] is how far along a the furthest forward path on
+	// diagonal k (x - y) got, and backward is the same from the ends
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	// The paths overlap within limit differences
+	for d := 0; ; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			forward[offset+k] = u
+			if r := delta - k; odd && r >= -(d-1) && r <= d-1 && u+backward[offset+r] >= n {
+				return x, y, u, v
+			}
+		}
+		for r := -d; r <= d; r += 2 {
+			var rx int
+			if r == -d || (r != d && backward[offset+r-1] < backward[offset+r+1]) {
+				rx = backward[offset+r+1]
+			} else {
+				rx = backward[offset+r-1] + 1
+			}
+			ry := rx - r
+			ru, rv := rx, ry
+			for ru < n && rv < m && a[n-1-ru] == b[m-1-rv] {
+				ru++
+				rv++
+			}
+			backward[offset+r] = ru
+			if k := delta - r; !odd && k >= -d && k <= d && forward[offset+k]+ru >= n {
+				return n - ru, m - rv, n - rx, m - ry
+			}
+		}
+	}
+}
+
+// Create a unified diff between two texts, or return "" if they are equal
+func UnifiedDiff(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	lines := diffLines(splitLines(a), splitLines(b))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+	for start := 0; start < len(lines); {
+		// Find the next change
+		for start < len(lines) && lines[start].op == ' ' {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+		// Extend the hunk until there are more unchanged lines than the context on both sides
+		first := start - DIFF_CONTEXT
+		if first < 0 {
+			first = 0
+		}
+		end, unchanged := start, 0
+		for end < len(lines) && unchanged <= 2*DIFF_CONTEXT {
+			if lines[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			end++
+		}
+		if unchanged > DIFF_CONTEXT {
+			end -= unchanged - DIFF_CONTEXT
+		}
+		// Find the line numbers where the hunk starts
+		aStart, bStart, aCount, bCount := 1, 1, 0, 0
+		for _, line := range lines[:first] {
+			if line.op != '+' {
+				aStart++
+			}
+			if line.op != '-' {
+				bStart++
+			}
+		}
+		for _, line := range lines[first:end] {
+			if line.op != '+' {
+				aCount++
+			}
+			if line.op != '-' {
+				bCount++
+			}
+		}
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, line := range lines[first:end] {
+			sb.WriteByte(line.op)
+			sb.WriteString(line.text)
+			sb.WriteByte('\n')
+		}
+		start = end
+	}
+	return sb.String()
+}
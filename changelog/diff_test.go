@@ -0,0 +1,78 @@
+package changelog
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "2014-01-06 arodseth\n    * upgpkg: python-cx_freeze 4.3.2-1\n\n"
+	b := "2014-03-17 arodseth\n    * upgpkg: python-cx_freeze 4.3.2-2\n\n" + a
+	expected := `--- ChangeLog
++++ ChangeLog (expected)
+@@ -1,3 +1,6 @@
++2014-03-17 arodseth
++    * upgpkg: python-cx_freeze 4.3.2-2
++
+ 2014-01-06 arodseth
+     * upgpkg: python-cx_freeze 4.3.2-1
+ 
+`
+	if got := UnifiedDiff("ChangeLog", "ChangeLog (expected)", a, b); got != expected {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+	if got := UnifiedDiff("a", "b", a, a); got != "" {
+		t.Fatalf("expected no diff, got:\n%s", got)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// The length of the longest common subsequence, for the smallest number of changes
+	lcs := func(a, b []string) int {
+		lengths := make([][]int, len(a)+1)
+		for i := range lengths {
+			lengths[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lengths[i][j] = lengths[i+1][j+1] + 1
+				} else {
+					lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+				}
+			}
+		}
+		return lengths[0][0]
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for n := 0; n < 500; n++ {
+		a := make([]string, rng.IntN(20))
+		for i := range a {
+			a[i] = string(rune('a' + rng.IntN(4)))
+		}
+		b := make([]string, rng.IntN(20))
+		for i := range b {
+			b[i] = string(rune('a' + rng.IntN(4)))
+		}
+		var gotA, gotB []string
+		changes := 0
+		for _, line := range diffLines(a, b) {
+			if line.op != '+' {
+				gotA = append(gotA, line.text)
+			}
+			if line.op != '-' {
+				gotB = append(gotB, line.text)
+			}
+			if line.op != ' ' {
+				changes++
+			}
+		}
+		if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+			t.Fatalf("the diff of %q and %q does not give them back, got %q and %q", a, b, gotA, gotB)
+		}
+		if expected := len(a) + len(b) - 2*lcs(a, b); changes != expected {
+			t.Fatalf("expected %d changes between %q and %q, got %d", expected, a, b, changes)
+		}
+	}
+}
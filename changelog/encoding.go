@@ -0,0 +1,178 @@
+package changelog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// The input encodings for Options.InputEncoding, with their aliases
+var encodings = map[string]string{
+	"":             "auto",
+	"auto":         "auto",
+	"utf-8":        "utf-8",
+	"utf8":         "utf-8",
+	"latin1":       "latin1",
+	"latin-1":      "latin1",
+	"iso-8859-1":   "latin1",
+	"windows-1252": "windows-1252",
+	"cp1252":       "windows-1252",
+}
+
+// Find the canonical name of an input encoding, or return an error if it is unknown
+func CheckEncoding(name string) (string, error) {
+	encoding, ok := encodings[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("Unknown input encoding: %s (available: auto, utf-8, latin1, windows-1252)", name)
+	}
+	return encoding, nil
+}
+
+// The characters of windows-1252 for 0x80 to 0x9f, where latin-1 has
+// control characters. 0 is used where windows-1252 has no character.
+var cp1252 = [32]rune{
+	0x20ac, 0, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+	0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017d, 0,
+	0, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+	0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0, 0x017e, 0x0178,
+}
+
+// Decode a byte that is not ASCII, in latin-1 or windows-1252
+func decodeByte(b byte, encoding string) rune {
+	if b >= 0x80 && b < 0xa0 && encoding != "latin1" && cp1252[b-0x80] != 0 {
+		return cp1252[b-0x80]
+	}
+	return rune(b)
+}
+
+// Convert text in the given encoding to UTF-8. With "auto", valid UTF-8 is
+// kept as it is, and the other bytes are read as windows-1252, which is a
+// superset of latin-1. Unless final is true, an incomplete UTF-8 sequence
+// at the end is returned as the rest, to be converted with what follows.
+func toUTF8(in []byte, encoding string, final bool) (out, rest []byte) {
+	if encoding == "utf-8" {
+		return in, nil
+	}
+	out = make([]byte, 0, len(in))
+	for i := 0; i < len(in); {
+		b := in[i]
+		if b < utf8.RuneSelf {
+			out = append(out, b)
+			i++
+			continue
+		}
+		if encoding == "auto" {
+			if !final && !utf8.FullRune(in[i:]) {
+				return out, in[i:]
+			}
+			if r, size := utf8.DecodeRune(in[i:]); r != utf8.RuneError || size > 1 {
+				out = append(out, in[i:i+size]...)
+				i += size
+				continue
+			}
+		}
+		out = utf8.AppendRune(out, decodeByte(b, encoding))
+		i++
+	}
+	return out, nil
+}
+
+// A reader that converts the text it reads to UTF-8
+type utf8Reader struct {
+	r        io.Reader
+	encoding string
+	buf      []byte
+	in, out  []byte
+	err      error
+}
+
+func newUTF8Reader(r io.Reader, encoding string) io.Reader {
+	if encoding == "utf-8" {
+		return r
+	}
+	return &utf8Reader{r: r, encoding: encoding, buf: make([]byte, 32*1024)}
+}
+
+func (r *utf8Reader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		n, err := r.r.Read(r.buf)
+		r.in = append(r.in, r.buf[:n]...)
+		r.err = err
+		r.out, r.in = toUTF8(r.in, r.encoding, err != nil)
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// The line endings for Options.EOL
+const (
+	EOL_LF   = "lf"   // Unix line endings, which is the default
+	EOL_CRLF = "crlf" // Windows line endings
+)
+
+// The byte order mark for Options.BOM, which some Windows tools need to
+// tell that a file is UTF-8
+const UTF8_BOM = "\ufeff"
+
+// Check the line endings, where "" is EOL_LF
+func CheckEOL(eol string) error {
+	switch eol {
+	case "", EOL_LF, EOL_CRLF:
+		return nil
+	}
+	return fmt.Errorf("Unknown line endings: %s (available: %s, %s)", eol, EOL_LF, EOL_CRLF)
+}
+
+// Convert a ChangeLog with Unix line endings to Options.EOL, and add the
+// byte order mark with Options.BOM, unless it is empty
+func (opts *Options) LineEndings(contents string) string {
+	if opts.EOL == EOL_CRLF {
+		contents = strings.Replace(contents, "\n", "\r\n", -1)
+	}
+	if opts.BOM && contents != "" {
+		contents = UTF8_BOM + contents
+	}
+	return contents
+}
+
+// Convert a ChangeLog that was written with Options.EOL and Options.BOM
+// back to Unix line endings without a byte order mark, for reading it
+func UnixLineEndings(contents string) string {
+	return strings.Replace(strings.TrimPrefix(contents, UTF8_BOM), "\r\n", "\n", -1)
+}
+
+// A writer that converts what is written with Options.LineEndings. The
+// formatters only write "\n", and the byte order mark is only written
+// before the first write, so that an empty ChangeLog stays empty.
+type lineEndingWriter struct {
+	w       io.Writer
+	opts    *Options
+	started bool
+}
+
+func newLineEndingWriter(w io.Writer, opts *Options) io.Writer {
+	if opts.EOL != EOL_CRLF && !opts.BOM {
+		return w
+	}
+	return &lineEndingWriter{w: w, opts: opts}
+}
+
+func (w *lineEndingWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	contents := w.opts.LineEndings(string(p))
+	if w.started {
+		contents = strings.TrimPrefix(contents, UTF8_BOM)
+	}
+	w.started = true
+	if _, err := io.WriteString(w.w, contents); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,87 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestToUTF8(t *testing.T) {
+	// "Rødseth" in UTF-8, and "Rødseth – fix" in windows-1252
+	mixed := []byte("R\xc3\xb8dseth, R\xf8dseth \x96 fix")
+	if got, _ := toUTF8(mixed, "auto", true); string(got) != "Rødseth, Rødseth – fix" {
+		t.Fatalf("unexpected auto conversion: %q", got)
+	}
+	if got, _ := toUTF8([]byte("R\xf8dseth \x96"), "latin1", true); string(got) != "Rødseth \u0096" {
+		t.Fatalf("unexpected latin1 conversion: %q", got)
+	}
+	// A UTF-8 sequence that is split between reads is kept together
+	r := newUTF8Reader(iotest.OneByteReader(bytes.NewReader(mixed)), "auto")
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "Rødseth, Rødseth – fix" {
+		t.Fatalf("unexpected conversion when reading: %q", got)
+	}
+	if _, err := CheckEncoding("ebcdic"); err == nil {
+		t.Fatal("expected an error for an unknown encoding")
+	}
+	entries, err := ParseSvnLog([]byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<log><logentry revision=\"1\"><author>x</author><msg>R\xf8dseth</msg></logentry></log>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Message != "Rødseth" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	entries := []Entry{
+		{Revision: 2, Author: "alice", Date: time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC), Message: "Fix the build\nfor arm"},
+		{Revision: 1, Author: "alice", Date: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Message: "Initial import"},
+	}
+	for _, format := range []string{"plain", "markdown", "json", "html"} {
+		g := New(&Options{Format: format, EOL: EOL_CRLF, BOM: true})
+		g.Names.Resolver = AuthorsFile{}
+		var buf bytes.Buffer
+		if err := g.Write(context.Background(), &buf, entries); err != nil {
+			t.Fatal(err)
+		}
+		got := buf.String()
+		if !strings.HasPrefix(got, UTF8_BOM) || strings.Count(got, UTF8_BOM) != 1 {
+			t.Fatalf("expected one byte order mark at the start of the %s format: %q", format, got)
+		}
+		if strings.Count(got, "\n") == 0 || strings.Count(got, "\n") != strings.Count(got, "\r\n") {
+			t.Fatalf("expected only Windows line endings in the %s format: %q", format, got)
+		}
+		// Reading it back gives the same as without the options
+		g = New(&Options{Format: format})
+		g.Names.Resolver = AuthorsFile{}
+		var unix bytes.Buffer
+		if err := g.Write(context.Background(), &unix, entries); err != nil {
+			t.Fatal(err)
+		}
+		if UnixLineEndings(got) != unix.String() {
+			t.Fatalf("unexpected %s format with Unix line endings:\n%s", format, UnixLineEndings(got))
+		}
+		if format == "json" && !json.Valid([]byte(strings.TrimPrefix(got, UTF8_BOM))) {
+			t.Fatalf("expected valid JSON: %q", got)
+		}
+	}
+	opts := &Options{EOL: EOL_CRLF, BOM: true}
+	if got := opts.LineEndings(""); got != "" {
+		t.Fatalf("expected an empty ChangeLog to stay empty, got %q", got)
+	}
+	if got := opts.LineEndings("a\nb\n"); got != UTF8_BOM+"a\r\nb\r\n" {
+		t.Fatalf("unexpected line endings: %q", got)
+	}
+	if err := CheckEOL("cr"); err == nil {
+		t.Fatal("expected an error for unknown line endings")
+	}
+}
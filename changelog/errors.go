@@ -0,0 +1,67 @@
+package changelog
+
+import (
+	"fmt"
+)
+
+// No working copy was found
+type NoRepositoryError struct {
+	Dir string // The directory that was searched, or "" for the current directory
+	VCS string // The kind of repository that was searched for, or "" for "subversion"
+}
+
+func (e *NoRepositoryError) Error() string {
+	vcs := e.VCS
+	if vcs == "" {
+		vcs = "subversion"
+	}
+	if e.Dir == "" {
+		return "Could not find a " + vcs + " repository here"
+	}
+	return "Could not find a " + vcs + " repository in " + e.Dir
+}
+
+// svn or git could not be found, it failed or timed out
+type VCSError struct {
+	Err error
+}
+
+func (e *VCSError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *VCSError) Unwrap() error {
+	return e.Err
+}
+
+// Web lookups failed because of the network
+type NetworkError struct {
+	Failures int   // The number of failed lookups
+	Err      error // The last error
+}
+
+func (e *NetworkError) Error() string {
+	if e.Failures == 1 {
+		return fmt.Sprintf("A web lookup failed: %v", e.Err)
+	}
+	return fmt.Sprintf("%d web lookups failed, the last one with: %v", e.Failures, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// The certificate of a server could not be verified, so the web lookups
+// can not be trusted
+type CertificateError struct {
+	URL string
+	Err error
+}
+
+func (e *CertificateError) Error() string {
+	return fmt.Sprintf("Could not verify the certificate for %s, use -ca-file if it is signed by a private CA: %v", e.URL, e.Err)
+}
+
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,149 @@
+package changelog
+
+import (
+	"context"
+	"encoding/xml"
+	"iter"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// An svn:externals definition in the working copy
+type SvnExternal struct {
+	Dir string // Where the external is checked out, relative to the working copy, with forward slashes
+	URL string // The URL, as it is written in the definition, which may be relative, like "^/libs/foo"
+}
+
+// Parse the svn:externals property of the directory, which is relative to
+// the working copy. Both the format of svn 1.5 and later, like
+// "^/libs/foo@123 foo", and the older one, like "foo -r123 http://...",
+// are understood. Comments and empty lines are skipped.
+func ParseSvnExternals(dir, property string) []SvnExternal {
+	var externals []SvnExternal
+	for _, line := range strings.Split(property, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		// Leave out the operative revision, like "-r 123" or "-r123"
+		var rest []string
+		for i := 0; i < len(fields); i++ {
+			if fields[i] == "-r" {
+				i++
+				continue
+			}
+			if strings.HasPrefix(fields[i], "-r") {
+				continue
+			}
+			rest = append(rest, fields[i])
+		}
+		if len(rest) != 2 {
+			slog.Debug("Skipped an svn:externals definition that could not be parsed", "dir", dir, "line", line)
+			continue
+		}
+		local, address := rest[1], rest[0]
+		if isSvnURL(rest[1]) && !isSvnURL(rest[0]) {
+			// The format from before svn 1.5
+			local, address = rest[0], rest[1]
+		}
+		externals = append(externals, SvnExternal{Dir: path.Join(filepath.ToSlash(dir), local), URL: address})
+	}
+	return externals
+}
+
+// Check if a field in an svn:externals definition is a URL, either a full
+// one or one that is relative to the repository, the server or the directory
+func isSvnURL(field string) bool {
+	return strings.Contains(field, "://") || strings.HasPrefix(field, "^/") || strings.HasPrefix(field, "//") || strings.HasPrefix(field, "/") || strings.HasPrefix(field, "../")
+}
+
+// Used when parsing the output of "svn propget --xml"
+type svnProperties struct {
+	Targets []struct {
+		Path     string `xml:"path,attr"`
+		Property string `xml:"property"`
+	} `xml:"target"`
+}
+
+// Find the svn:externals in the working copy, with "svn propget -R"
+func SvnExternals(ctx context.Context, opts *Options) ([]SvnExternal, error) {
+	opts, err := withNetrcLogin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	output, err := runSvn(ctx, opts, "propget", "--xml", "-R", "svn:externals")
+	if err != nil {
+		return nil, err
+	}
+	var properties svnProperties
+	if err := xml.Unmarshal(output, &properties); err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	var externals []SvnExternal
+	for _, target := range properties.Targets {
+		externals = append(externals, ParseSvnExternals(target.Path, target.Property)...)
+	}
+	return externals, nil
+}
+
+// Fetch the entries of the working copy and of each of its svn:externals,
+// and merge them, ordered from the newest to the oldest by date. The
+// messages of the entries of an external start with where it is, like
+// "libs/foo: ", since the revisions are from another repository, or from
+// another part of the same one. The externals of the externals, and the
+// ones that are not checked out, are left out.
+func svnExternalEntries(ctx context.Context, opts *Options) (iter.Seq2[Entry, error], error) {
+	externals, err := SvnExternals(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	own := *opts
+	own.Externals = false
+	entries, err := collectEntries(svnSource{}.Entries(ctx, &own))
+	if err != nil {
+		return nil, err
+	}
+	for _, external := range externals {
+		dir := filepath.Join(opts.Repo, filepath.FromSlash(external.Dir))
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			slog.Warn("Skipped an svn:externals that is not a checked out directory", "dir", external.Dir, "url", external.URL)
+			continue
+		}
+		externalOpts := own
+		externalOpts.Repo, externalOpts.SvnPath, externalOpts.FromRevision = dir, "", 0
+		externalEntries, err := collectEntries(svnSource{}.Entries(ctx, &externalOpts))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range externalEntries {
+			entry.Message = external.Dir + ": " + entry.Message
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.After(entries[j].Date)
+	})
+	if opts.Entries != -1 && opts.Entries < len(entries) {
+		entries = entries[:opts.Entries]
+	}
+	return sliceEntries(entries), nil
+}
+
+// Collect the entries from a Source
+func collectEntries(seq iter.Seq2[Entry, error], err error) ([]Entry, error) {
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for entry, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
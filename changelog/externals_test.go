@@ -0,0 +1,63 @@
+package changelog
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSvnExternals(t *testing.T) {
+	property := "# The libraries\n^/libs/foo@123 foo\n-r 7 http://svn.example.org/bar bar\n\nbaz -r42 http://svn.example.org/baz\nnot an external\n"
+	externals := ParseSvnExternals("src", property)
+	expected := []SvnExternal{
+		{Dir: "src/foo", URL: "^/libs/foo@123"},
+		{Dir: "src/bar", URL: "http://svn.example.org/bar"},
+		{Dir: "src/baz", URL: "http://svn.example.org/baz"},
+	}
+	if !reflect.DeepEqual(externals, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, externals)
+	}
+	if externals := ParseSvnExternals(".", "^/libs/foo foo"); len(externals) != 1 || externals[0].Dir != "foo" {
+		t.Fatalf("unexpected externals at the top of the working copy: %+v", externals)
+	}
+}
+
+func TestExternals(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the fake svn with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "libs", "foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// An svn with one external, and one entry in the log of each working copy
+	svn := filepath.Join(dir, "svn")
+	script := `#!/bin/sh
+if [ "$1" = propget ]; then
+	echo '<?xml version="1.0"?><properties><target path="libs"><property name="svn:externals">^/libs/foo foo
+^/libs/missing missing</property></target></properties>'
+elif [ "$(basename "$PWD")" = foo ]; then
+	echo '<?xml version="1.0"?><log><logentry revision="10"><author>bob</author><date>2024-03-02T10:00:00.000000Z</date><msg>Fix the library</msg></logentry></log>'
+else
+	echo '<?xml version="1.0"?><log><logentry revision="3"><author>alice</author><date>2024-03-01T10:00:00.000000Z</date><msg>Use the library</msg></logentry></log>'
+fi
+`
+	if err := ioutil.WriteFile(svn, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := New(&Options{Repo: dir, SvnBin: svn, Entries: -1, Externals: true}).Entries(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Message != "libs/foo: Fix the library" || entries[1].Message != "Use the library" {
+		t.Fatalf("expected the entry of the external first, got %+v", entries)
+	}
+}
@@ -0,0 +1,109 @@
+package changelog
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Check if a line is a ChangeLog header, like "2014-03-17 Name <email>"
+func IsHeader(line string) bool {
+	if len(line) < 10 || (len(line) > 10 && line[10] != ' ') {
+		return false
+	}
+	for i, r := range line[:10] {
+		switch i {
+		case 4, 7:
+			if r != '-' {
+				return false
+			}
+		default:
+			if r < '0' || r > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Find the date of the newest entry in an existing ChangeLog, which may
+// also be written by hand in one of the common styles, like the one of RPM.
+// Returns "" if there are no entries.
+func NewestDate(contents string) string {
+	newest := ""
+	for _, line := range strings.Split(contents, "\n") {
+		if date, ok := headerDate(line); ok && date > newest {
+			newest = date
+		}
+	}
+	return newest
+}
+
+// Write to a file by first writing everything to a temporary file in the
+// same directory, then renaming it over the target. An existing file is
+// left untouched if anything fails, and its permissions are preserved.
+// A symlink is followed, so that the file it points to is replaced, and
+// something that is not a regular file, like /dev/null, is written to
+// directly.
+func WriteFileAtomic(filename string, write func(w io.Writer) error) error {
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	}
+	var mode os.FileMode = 0644
+	if fi, err := os.Stat(filename); err == nil {
+		if !fi.Mode().IsRegular() {
+			return writeFileDirectly(filename, write)
+		}
+		mode = fi.Mode().Perm()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpname := f.Name()
+	// Remove the temporary file, unless it has been renamed
+	defer os.Remove(tmpname)
+
+	bw := bufio.NewWriter(f)
+	if err := write(bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpname, filename)
+}
+
+// Write to a file that can not be replaced, like a device or a named pipe
+func writeFileDirectly(filename string, write func(w io.Writer) error) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	if err := write(bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,118 @@
+package changelog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewestDate(t *testing.T) {
+	contents := `2014-03-17 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-2
+
+2014-01-06 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-1
+      2014-12-24 is not a header
+
+`
+	if got := NewestDate(contents); got != "2014-03-17" {
+		t.Fatalf("unexpected newest date: %q", got)
+	}
+	if got := NewestDate("A hand-written ChangeLog\n"); got != "" {
+		t.Fatalf("expected no date, got %q", got)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "ChangeLog")
+	if err := ioutil.WriteFile(filename, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// A failing write must leave the existing file untouched
+	err := WriteFileAtomic(filename, func(w io.Writer) error {
+		fmt.Fprintln(w, "partial")
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if b, _ := ioutil.ReadFile(filename); string(b) != "old\n" {
+		t.Fatalf("the file was changed by a failed write: %q", b)
+	}
+	if err := WriteFileAtomic(filename, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, "new")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(filename); string(b) != "new\n" {
+		t.Fatalf("unexpected contents: %q", b)
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("the permissions were not preserved: %v", fi.Mode().Perm())
+	}
+	// No temporary files should be left behind
+	if names, _ := filepath.Glob(filepath.Join(dir, ".*")); len(names) != 0 {
+		t.Fatalf("temporary files were left behind: %v", names)
+	}
+}
+
+func TestWriteFileAtomicInPlace(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "ChangeLog")
+	if err := ioutil.WriteFile(filepath.Join(dir, "ChangeLog.real"), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("ChangeLog.real", filename); err != nil {
+		t.Skip("could not make a symlink:", err)
+	}
+	write := func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, "new")
+		return err
+	}
+	// The file that the symlink points to is replaced, not the symlink
+	if err := WriteFileAtomic(filename, write); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Lstat(filename); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("expected the symlink to be kept, got %v, %v", fi, err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "ChangeLog.real")); string(b) != "new\n" {
+		t.Fatalf("unexpected contents: %q", b)
+	}
+	// A named pipe is written to, not replaced
+	fifo := filepath.Join(dir, "pipe")
+	if err := exec.Command("mkfifo", fifo).Run(); err != nil {
+		t.Skip("could not make a named pipe:", err)
+	}
+	read := make(chan string, 1)
+	go func() {
+		b, _ := ioutil.ReadFile(fifo)
+		read <- string(b)
+	}()
+	if err := WriteFileAtomic(fifo, write); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case b := <-read:
+		if b != "new\n" {
+			t.Fatalf("unexpected contents from the named pipe: %q", b)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing was written to the named pipe")
+	}
+	if fi, err := os.Lstat(fifo); err != nil || fi.Mode()&os.ModeNamedPipe == 0 {
+		t.Fatalf("expected the named pipe to be kept, got %v, %v", fi, err)
+	}
+}
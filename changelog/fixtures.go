@@ -0,0 +1,99 @@
+package changelog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The error for a request that there is no recorded response for, when replaying
+var ErrNoFixture = errors.New("no recorded response")
+
+// Characters that are replaced in the names of the fixture files
+var fixtureUnsafe = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// Records the responses of the web lookups to files in Dir, or replays them
+// from there instead of sending the requests, so that the lookups can be
+// repeated against the same snapshot of the web pages. Use it as the
+// Transport of Names.Client. The files have the responses as they would be
+// sent over HTTP/1.1, one per file, and can be edited by hand.
+type Fixtures struct {
+	Dir    string
+	Replay bool              // Replay the responses, instead of recording them
+	Next   http.RoundTripper // Sends the requests when recording, or nil for http.DefaultTransport
+}
+
+// The name of the file for the response to a request, from the method and
+// the URL, like "GET_www.archlinux.org_people_developers_-1a2b3c4d.http"
+func FixtureName(req *http.Request) string {
+	key := req.Method + " " + req.URL.String()
+	sum := sha256.Sum256([]byte(key))
+	name := strings.Trim(fixtureUnsafe.ReplaceAllString(req.URL.Host+req.URL.Path, "_"), "_")
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return req.Method + "_" + name + "-" + hex.EncodeToString(sum[:4]) + ".http"
+}
+
+func (f *Fixtures) RoundTrip(req *http.Request) (*http.Response, error) {
+	filename := filepath.Join(f.Dir, FixtureName(req))
+	if f.Replay {
+		data, err := ioutil.ReadFile(filename)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w for %s in %s", ErrNoFixture, req.URL.Redacted(), f.Dir)
+		} else if err != nil {
+			return nil, err
+		}
+		return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	}
+	next := f.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	// Record the body as it was read, without any compression or chunking
+	header := resp.Header.Clone()
+	header.Del("Content-Encoding")
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	recorded := &http.Response{
+		Status:        resp.Status,
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		ContentLength: int64(len(body)),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+	}
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return nil, err
+	}
+	err = WriteFileAtomic(filename, func(w io.Writer) error {
+		return recorded.Write(w)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Could not record the response for %s: %w", req.URL.Redacted(), err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
@@ -0,0 +1,43 @@
+package changelog
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestFixtures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("<td>arodseth</td>"))
+	}))
+	a := &ArchWeb{Client: &http.Client{Transport: &Fixtures{Dir: dir}}}
+	ctx := context.Background()
+	recorded, err := a.get(ctx, server.URL+"/people/developers/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	// The server is gone, so the response must come from the fixture
+	a = &ArchWeb{Client: &http.Client{Transport: &Fixtures{Dir: dir, Replay: true}}}
+	replayed, err := a.get(ctx, server.URL+"/people/developers/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(replayed) != string(recorded) || string(replayed) != "<td>arodseth</td>" {
+		t.Fatalf("unexpected response: %q", replayed)
+	}
+	a.Retries = DEFAULT_RETRIES
+	if _, err := a.get(ctx, server.URL+"/people/trusted-users/"); !errors.Is(err, ErrNoFixture) {
+		t.Fatalf("expected ErrNoFixture, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package changelog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A footer at the end of a ChangeLog that tells what generated it and from
+// which revisions, so that it can be told apart from one that is written
+// by hand, for Options.Footer. The json format has Options.Metadata instead.
+type Footer struct {
+	Template  string    // Where {generator}, {from}, {to} and {date} are replaced, or "" for the one in the Language
+	Generator string    // Like "archlog 0.7"
+	Date      time.Time // The time for {date}, or zero to leave it out of the default template, for reproducible builds
+}
+
+// Keeps track of the oldest and the newest revision while a ChangeLog is
+// written, for the footer at the end
+type footerWriter struct {
+	footer   *Footer
+	catalog  *Catalog
+	from, to int
+}
+
+// A footerWriter for Options.Footer, or nil if there is no footer
+func (opts *Options) footer() *footerWriter {
+	if opts.Footer == nil {
+		return nil
+	}
+	return &footerWriter{footer: opts.Footer, catalog: opts.catalog()}
+}
+
+// Add the revisions of a section to the range
+func (f *footerWriter) add(section *Section) {
+	if f == nil {
+		return
+	}
+	for _, revision := range section.Revisions {
+		if f.from == 0 || revision < f.from {
+			f.from = revision
+		}
+		if revision > f.to {
+			f.to = revision
+		}
+	}
+}
+
+// The footer, like "Generated by archlog 0.7 from r1..r42 on 2024-03-01
+// 12:00:00 UTC", or "" if there is no footer or there were no entries.
+// The time is always in UTC, like for Options.GeneratedAt.
+func (f *footerWriter) String() string {
+	if f == nil || f.to == 0 {
+		return ""
+	}
+	template := f.footer.Template
+	if template == "" {
+		template = f.catalog.Footer
+		if f.footer.Date.IsZero() {
+			template = f.catalog.FooterWithoutDate
+		}
+	}
+	date := ""
+	if !f.footer.Date.IsZero() {
+		date = f.footer.Date.UTC().Format("2006-01-02 15:04:05")
+	}
+	r := strings.NewReplacer("{generator}", f.footer.Generator, "{from}", strconv.Itoa(f.from), "{to}", strconv.Itoa(f.to), "{date}", date)
+	return r.Replace(template)
+}
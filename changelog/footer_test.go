@@ -0,0 +1,57 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFooter(t *testing.T) {
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Revision: 42, Author: "alice", Date: day, Message: "Update"},
+		{Revision: 7, Author: "alice", Date: day.AddDate(0, 0, -1), Message: "Initial import"},
+	}
+	for _, tc := range []struct {
+		format, lang string
+		footer       Footer
+		expected     string
+	}{
+		{"plain", "", Footer{Generator: "archlog 0.7", Date: day}, "\nGenerated by archlog 0.7 from r7..r42 on 2024-03-01 12:00:00 UTC\n"},
+		{"plain", "", Footer{Generator: "archlog 0.7"}, "    * Initial import\n\nGenerated by archlog 0.7 from r7..r42\n"},
+		{"plain", "", Footer{Template: "{generator}: {from}-{to} {date}", Generator: "archlog 0.7"}, "\narchlog 0.7: 7-42 \n"},
+		{"plain", "de", Footer{Generator: "archlog 0.7"}, "\nErstellt von archlog 0.7 aus r7..r42\n"},
+		{"markdown", "", Footer{Generator: "archlog 0.7"}, "\n---\n\nGenerated by archlog 0.7 from r7..r42\n"},
+		{"html", "", Footer{Template: "<{generator}>", Generator: "archlog 0.7"}, "<footer>&lt;archlog 0.7&gt;</footer>\n</body>\n</html>\n"},
+	} {
+		footer := tc.footer
+		g := New(&Options{Format: tc.format, Language: tc.lang, Footer: &footer})
+		g.Names.Resolver = AuthorsFile{}
+		var buf bytes.Buffer
+		if err := g.Write(context.Background(), &buf, entries); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(buf.String(), tc.expected) {
+			t.Fatalf("expected the %s format to end with %q, got:\n%s", tc.format, tc.expected, buf.String())
+		}
+	}
+	// There is no footer without entries, or in the json format
+	for format, entries := range map[string][]Entry{"plain": nil, "json": entries} {
+		g := New(&Options{Format: format, Footer: &Footer{Generator: "archlog 0.7"}})
+		g.Names.Resolver = AuthorsFile{}
+		var buf bytes.Buffer
+		if err := g.Write(context.Background(), &buf, entries); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(buf.String(), "Generated by") {
+			t.Fatalf("expected no footer in the %s format, got:\n%s", format, buf.String())
+		}
+	}
+	for name, catalog := range catalogs {
+		if !strings.Contains(catalog.Footer, "{date}") || strings.Contains(catalog.FooterWithoutDate, "{date}") || !strings.Contains(catalog.FooterWithoutDate, "r{from}..r{to}") {
+			t.Fatalf("unexpected footers for %s: %q and %q", name, catalog.Footer, catalog.FooterWithoutDate)
+		}
+	}
+}
@@ -0,0 +1,509 @@
+package changelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The entries of one author on one day, which make up one entry in the ChangeLog
+type Section struct {
+	Date         string   `json:"date"`   // YYYY-MM-DD
+	Name         string   `json:"name"`   // The name and e-mail address, or the nick
+	Author       string   `json:"author"` // The nick
+	Messages     []string `json:"messages"`
+	Revisions    []int    `json:"revisions"`              // The revisions of the messages
+	Version      string   `json:"version,omitempty"`      // The version of the package, with Options.Versions
+	Avatar       string   `json:"avatar,omitempty"`       // The URL of the avatar of the author, with Options.Avatars
+	Repositories []string `json:"repositories,omitempty"` // The labels of the repositories of the entries, with REPOSITORY_LABELS_SECTIONS
+	ID           string   `json:"id,omitempty"`           // The id of the section, which stays the same when newer entries are added
+	IDs          []string `json:"ids,omitempty"`          // The ids of the messages, which are hashes of the revisions
+}
+
+// Writes the sections of a ChangeLog in a particular format.
+// Begin is called first, then Entry for each section, from the newest to
+// the oldest, and End at the end, also when there are no sections.
+// With Options.Versions, the sections of a version follow each other, and
+// a heading for the version can be written when it changes.
+type Formatter interface {
+	Begin(w io.Writer) error
+	Entry(w io.Writer, section *Section) error
+	End(w io.Writer) error
+}
+
+var (
+	formattersMutex sync.Mutex
+	formatters      = make(map[string]func(opts *Options) Formatter)
+)
+
+// Make a Formatter available by name, for Options.Format and the -format flag.
+// A new Formatter is created for each ChangeLog that is written.
+func RegisterFormatter(name string, newFormatter func(opts *Options) Formatter) {
+	formattersMutex.Lock()
+	defer formattersMutex.Unlock()
+	formatters[name] = newFormatter
+}
+
+// Create a registered Formatter by name
+func NewFormatter(name string, opts *Options) (Formatter, error) {
+	formattersMutex.Lock()
+	defer formattersMutex.Unlock()
+	newFormatter, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown output format: %s (available: %s)", name, strings.Join(formatterNames(), ", "))
+	}
+	return newFormatter(opts), nil
+}
+
+// The names of the registered formatters, sorted
+func FormatterNames() []string {
+	formattersMutex.Lock()
+	defer formattersMutex.Unlock()
+	return formatterNames()
+}
+
+func formatterNames() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterFormatter("plain", func(opts *Options) Formatter {
+		return &plainFormatter{color: opts.Color, catalog: opts.catalog(), obfuscate: opts.Obfuscate, generatedAt: opts.GeneratedAt, footer: opts.footer(), relativeTo: opts.RelativeTo, maintainers: opts.maintainers(), archives: opts.Archives}
+	})
+	RegisterFormatter("markdown", func(opts *Options) Formatter {
+		return &markdownFormatter{catalog: opts.catalog(), obfuscate: opts.Obfuscate, generatedAt: opts.GeneratedAt, footer: opts.footer(), relativeTo: opts.RelativeTo, maintainers: opts.maintainers(), archives: opts.Archives}
+	})
+	RegisterFormatter("json", func(opts *Options) Formatter {
+		return &jsonFormatter{obfuscate: opts.Obfuscate, metadata: opts.Metadata}
+	})
+	RegisterFormatter("html", func(opts *Options) Formatter {
+		return &htmlFormatter{catalog: opts.catalog(), obfuscate: opts.Obfuscate, generatedAt: opts.GeneratedAt, footer: opts.footer(), relativeTo: opts.RelativeTo, maintainers: opts.maintainers(), archives: opts.Archives, permalinks: opts.PermalinkTemplate, search: opts.Search}
+	})
+}
+
+// The start of each line with a maintainer at the top of a plain ChangeLog,
+// in English
+const MAINTAINER_PREFIX = "Maintainer: "
+
+// Split the lines with the maintainers and the blank lines after them
+// from the top of a plain ChangeLog, in any of the languages
+func SplitMaintainers(contents string) (string, string) {
+	rest := contents
+	for hasCatalogPrefix(rest, func(c *Catalog) string { return c.Maintainer }) {
+		if i := strings.Index(rest, "\n"); i >= 0 {
+			rest = rest[i+1:]
+		} else {
+			rest = ""
+		}
+	}
+	if len(rest) < len(contents) {
+		rest = strings.TrimLeft(rest, "\n")
+	}
+	return contents[:len(contents)-len(rest)], rest
+}
+
+// The footer for Options.GeneratedAt. The time is always in UTC, so that
+// the output does not depend on the time zone.
+func generatedFooter(catalog *Catalog, t time.Time) string {
+	return fmt.Sprintf(catalog.GeneratedBy, t.UTC().Format("2006-01-02 15:04:05"))
+}
+
+// The start of the line at the end of a ChangeLog that tells where the
+// older entries are, for Options.Archives, in English
+const ARCHIVES_PREFIX = "Older entries are in "
+
+// Format a message as an item in a plain ChangeLog, with the lead star
+// and with the lines after the first one indented
+func plainMessage(msg string) string {
+	return LEAD_STAR + strings.Replace(msg, "\n", "\n      ", -1)
+}
+
+// The heading of the sections of a version of the package
+func versionHeading(catalog *Catalog, version string) string {
+	return catalog.Version + version
+}
+
+// The classic ChangeLog format
+type plainFormatter struct {
+	color       bool // Color the output for terminals
+	catalog     *Catalog
+	obfuscate   string // The way of obfuscating the e-mail addresses, or ""
+	generatedAt time.Time
+	footer      *footerWriter // The footer for Options.Footer, or nil
+	relativeTo  time.Time
+	maintainers []string
+	archives    []string
+	first       bool
+	version     string // The version of the previous section
+}
+
+func (f *plainFormatter) Begin(w io.Writer) error {
+	f.first, f.version = true, ""
+	for _, maintainer := range f.maintainers {
+		if _, err := fmt.Fprintln(w, f.catalog.Maintainer+obfuscateEmails(maintainer, f.obfuscate)); err != nil {
+			return err
+		}
+		f.first = false
+	}
+	return nil
+}
+
+func (f *plainFormatter) Entry(w io.Writer, section *Section) error {
+	f.footer.add(section)
+	if section.Version != "" && section.Version != f.version {
+		heading := versionHeading(f.catalog, section.Version)
+		if !f.first {
+			heading = "\n" + heading
+		}
+		f.first = false
+		if _, err := fmt.Fprintln(w, heading); err != nil {
+			return err
+		}
+	}
+	f.version = section.Version
+	name := obfuscateEmails(section.Name, f.obfuscate)
+	header := section.Date + " " + name
+	if f.color {
+		header = colorHeader(section.Date, name, section.Author)
+	}
+	if label := repositoryLabel(section.Repositories); label != "" {
+		header += " " + label
+	}
+	if ago := relativeDate(f.catalog, section.Date, f.relativeTo); ago != "" {
+		header += " " + ago
+	}
+	if !f.first {
+		header = "\n" + header
+	}
+	f.first = false
+	if _, err := fmt.Fprintln(w, header); err != nil {
+		return err
+	}
+	for _, msg := range section.Messages {
+		msg = plainMessage(obfuscateEmails(msg, f.obfuscate))
+		if f.color {
+			msg = colorMessage(msg, LEAD_STAR)
+		}
+		if _, err := fmt.Fprintln(w, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *plainFormatter) End(w io.Writer) error {
+	if !f.first {
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	if len(f.archives) > 0 {
+		if _, err := fmt.Fprintf(w, "%s%s\n\n", f.catalog.Archives, strings.Join(f.archives, ", ")); err != nil {
+			return err
+		}
+	}
+	if !f.generatedAt.IsZero() {
+		if _, err := fmt.Fprintln(w, generatedFooter(f.catalog, f.generatedAt)); err != nil {
+			return err
+		}
+	}
+	if footer := f.footer.String(); footer != "" {
+		if _, err := fmt.Fprintln(w, footer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The characters that may have a meaning in Markdown anywhere in a line
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "&", `\&`, "#", `\#`, "|", `\|`, "~", `\~`,
+)
+
+// Escape text for Markdown, so that it is shown as it is. Lines that
+// would otherwise start a list or a heading are also escaped.
+func escapeMarkdown(text string) string {
+	lines := strings.Split(markdownEscaper.Replace(text), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		indent := line[:len(line)-len(trimmed)]
+		switch {
+		case strings.HasPrefix(trimmed, "-") || strings.HasPrefix(trimmed, "+") || strings.HasPrefix(trimmed, "="):
+			lines[i] = indent + `\` + trimmed
+		default:
+			// Numbered lists, like "1." or "1)"
+			digits := len(trimmed) - len(strings.TrimLeft(trimmed, "0123456789"))
+			if digits > 0 && digits < len(trimmed) && (trimmed[digits] == '.' || trimmed[digits] == ')') {
+				lines[i] = indent + trimmed[:digits] + `\` + trimmed[digits:]
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// A Markdown document with a heading for each section
+type markdownFormatter struct {
+	catalog     *Catalog
+	obfuscate   string // The way of obfuscating the e-mail addresses, or ""
+	generatedAt time.Time
+	footer      *footerWriter // The footer for Options.Footer, or nil
+	relativeTo  time.Time
+	maintainers []string
+	archives    []string
+	version     string // The version of the previous section
+}
+
+func (f *markdownFormatter) Begin(w io.Writer) error {
+	f.version = ""
+	if _, err := fmt.Fprintf(w, "# %s\n", escapeMarkdown(f.catalog.Title)); err != nil || len(f.maintainers) == 0 {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%s%s\n", escapeMarkdown(f.catalog.Maintainers), escapeEmails(strings.Join(f.maintainers, ", "), f.obfuscate, escapeMarkdown))
+	return err
+}
+
+func (f *markdownFormatter) Entry(w io.Writer, section *Section) error {
+	f.footer.add(section)
+	if section.Version != "" && section.Version != f.version {
+		if _, err := fmt.Fprintf(w, "\n## %s\n", escapeMarkdown(versionHeading(f.catalog, section.Version))); err != nil {
+			return err
+		}
+	}
+	f.version = section.Version
+	header := section.Date + " " + escapeEmails(section.Name, f.obfuscate, escapeMarkdown)
+	if label := repositoryLabel(section.Repositories); label != "" {
+		header += " " + escapeMarkdown(label)
+	}
+	if ago := relativeDate(f.catalog, section.Date, f.relativeTo); ago != "" {
+		header += " " + escapeMarkdown(ago)
+	}
+	if _, err := fmt.Fprintf(w, "\n## %s\n\n", header); err != nil {
+		return err
+	}
+	for _, msg := range section.Messages {
+		if _, err := fmt.Fprintln(w, "* "+strings.Replace(escapeEmails(msg, f.obfuscate, escapeMarkdown), "\n", "\n  ", -1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *markdownFormatter) End(w io.Writer) error {
+	if len(f.archives) > 0 {
+		links := make([]string, len(f.archives))
+		for i, archive := range f.archives {
+			links[i] = "[" + escapeMarkdown(archive) + "](" + url.PathEscape(archive) + ")"
+		}
+		if _, err := fmt.Fprintf(w, "\n%s%s\n", escapeMarkdown(f.catalog.Archives), strings.Join(links, ", ")); err != nil {
+			return err
+		}
+	}
+	if !f.generatedAt.IsZero() {
+		if _, err := fmt.Fprintf(w, "\n---\n\n%s\n", generatedFooter(f.catalog, f.generatedAt)); err != nil {
+			return err
+		}
+	}
+	if footer := f.footer.String(); footer != "" {
+		if _, err := fmt.Fprintf(w, "\n---\n\n%s\n", escapeMarkdown(footer)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A JSON object with the version of the JSON Schema and an array with an
+// object for each section
+type jsonFormatter struct {
+	obfuscate string    // The way of obfuscating the e-mail addresses, or ""
+	metadata  *Metadata // What generated the ChangeLog, or nil
+	first     bool
+}
+
+func (f *jsonFormatter) Begin(w io.Writer) error {
+	f.first = true
+	if _, err := fmt.Fprintf(w, "{\n  \"$schema\": %q,\n  \"schema_version\": %d,\n", JSON_SCHEMA_URL, JSON_SCHEMA_VERSION); err != nil {
+		return err
+	}
+	if f.metadata != nil {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("  ", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(f.metadata); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "  \"metadata\": %s,\n", strings.TrimSuffix(buf.String(), "\n")); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "  \"sections\": [")
+	return err
+}
+
+func (f *jsonFormatter) Entry(w io.Writer, section *Section) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("    ", "  ")
+	// Keep the e-mail addresses readable
+	enc.SetEscapeHTML(false)
+	if f.obfuscate != "" {
+		obfuscated := *section
+		obfuscated.Name = obfuscateEmails(section.Name, f.obfuscate)
+		obfuscated.Messages = make([]string, len(section.Messages))
+		for i, msg := range section.Messages {
+			obfuscated.Messages[i] = obfuscateEmails(msg, f.obfuscate)
+		}
+		section = &obfuscated
+	}
+	if err := enc.Encode(section); err != nil {
+		return err
+	}
+	sep := ",\n    "
+	if f.first {
+		sep = "\n    "
+	}
+	f.first = false
+	_, err := io.WriteString(w, sep+strings.TrimSuffix(buf.String(), "\n"))
+	return err
+}
+
+func (f *jsonFormatter) End(w io.Writer) error {
+	end := "\n  ]\n}\n"
+	if f.first {
+		end = "]\n}\n"
+	}
+	_, err := io.WriteString(w, end)
+	return err
+}
+
+// An HTML document with a heading and a list for each section
+type htmlFormatter struct {
+	catalog     *Catalog
+	obfuscate   string // The way of obfuscating the e-mail addresses, or ""
+	generatedAt time.Time
+	footer      *footerWriter // The footer for Options.Footer, or nil
+	relativeTo  time.Time
+	maintainers []string
+	archives    []string
+	permalinks  string // The template for the links to the sections and entries, or ""
+	search      bool   // Add the search by text, author and date
+	version     string // The version of the previous section
+}
+
+// The id attribute for an element, and the link to it with the template,
+// if there is an id
+func (f *htmlFormatter) anchor(id string, revision int, date string) (string, string) {
+	if id == "" {
+		return "", ""
+	}
+	attribute := " id=\"" + html.EscapeString(id) + "\""
+	if f.permalinks == "" {
+		return attribute, ""
+	}
+	return attribute, " <a class=\"permalink\" href=\"" + permalink(f.permalinks, id, revision, date) + "\">#</a>"
+}
+
+func (f *htmlFormatter) Begin(w io.Writer) error {
+	f.version = ""
+	_, err := fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n", html.EscapeString(f.catalog.Title))
+	if err != nil {
+		return err
+	}
+	if len(f.maintainers) > 0 {
+		if _, err := fmt.Fprintf(w, "<p>%s%s</p>\n", html.EscapeString(f.catalog.Maintainers), escapeEmails(strings.Join(f.maintainers, ", "), f.obfuscate, html.EscapeString)); err != nil {
+			return err
+		}
+	}
+	if f.search {
+		return writeSearchForm(w, f.catalog)
+	}
+	return nil
+}
+
+func (f *htmlFormatter) Entry(w io.Writer, section *Section) error {
+	f.footer.add(section)
+	if section.Version != "" && section.Version != f.version {
+		if _, err := fmt.Fprintf(w, "<h1>%s</h1>\n", html.EscapeString(versionHeading(f.catalog, section.Version))); err != nil {
+			return err
+		}
+	}
+	f.version = section.Version
+	avatar := ""
+	if section.Avatar != "" {
+		avatar = fmt.Sprintf("<img class=\"avatar\" src=\"%s\" alt=\"\" width=\"%d\" height=\"%d\"> ", html.EscapeString(section.Avatar), AVATAR_SIZE, AVATAR_SIZE)
+	}
+	ago := relativeDate(f.catalog, section.Date, f.relativeTo)
+	if ago != "" {
+		ago = " <small>" + html.EscapeString(ago) + "</small>"
+	}
+	label := repositoryLabel(section.Repositories)
+	if label != "" {
+		label = " " + html.EscapeString(label)
+	}
+	oldest := 0
+	if len(section.Revisions) > 0 {
+		oldest = section.Revisions[0]
+	}
+	attributes, link := f.anchor(section.ID, oldest, section.Date)
+	if f.search {
+		// What the search filters by, without the e-mail address
+		attributes += " data-date=\"" + html.EscapeString(section.Date) + "\" data-author=\"" + html.EscapeString(ParseIdentity(section.Name).Name+" "+section.Author) + "\""
+	}
+	if _, err := fmt.Fprintf(w, "<h2%s>%s%s %s%s%s%s</h2>\n<ul>\n", attributes, avatar, html.EscapeString(section.Date), escapeEmails(section.Name, f.obfuscate, html.EscapeString), label, ago, link); err != nil {
+		return err
+	}
+	for i, msg := range section.Messages {
+		msg = strings.Replace(escapeEmails(msg, f.obfuscate, html.EscapeString), "\n", "<br>\n", -1)
+		id, link := "", ""
+		if i < len(section.IDs) && i < len(section.Revisions) {
+			id, link = f.anchor(section.IDs[i], section.Revisions[i], section.Date)
+		}
+		if _, err := fmt.Fprintf(w, "<li%s>%s%s</li>\n", id, msg, link); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</ul>\n")
+	return err
+}
+
+func (f *htmlFormatter) End(w io.Writer) error {
+	if len(f.archives) > 0 {
+		links := make([]string, len(f.archives))
+		for i, archive := range f.archives {
+			links[i] = "<a href=\"" + html.EscapeString(url.PathEscape(archive)) + "\">" + html.EscapeString(archive) + "</a>"
+		}
+		if _, err := fmt.Fprintf(w, "<p>%s%s</p>\n", html.EscapeString(f.catalog.Archives), strings.Join(links, ", ")); err != nil {
+			return err
+		}
+	}
+	if !f.generatedAt.IsZero() {
+		if _, err := fmt.Fprintf(w, "<footer>%s</footer>\n", generatedFooter(f.catalog, f.generatedAt)); err != nil {
+			return err
+		}
+	}
+	if footer := f.footer.String(); footer != "" {
+		if _, err := fmt.Fprintf(w, "<footer>%s</footer>\n", html.EscapeString(footer)); err != nil {
+			return err
+		}
+	}
+	if f.search {
+		if err := writeSearchScript(w); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</body>\n</html>\n")
+	return err
+}
@@ -0,0 +1,179 @@
+package changelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Write the sections with the named formatter
+func formatSections(t *testing.T, name string, sections []*Section) string {
+	f, err := NewFormatter(name, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Begin(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, section := range sections {
+		if err := f.Entry(&buf, section); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.End(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFormatters(t *testing.T) {
+	sections := []*Section{
+		{Date: "2024-03-02", Name: "Bob <bob@example.org>", Author: "bob", Messages: []string{"Fix the build\nfor <arm>"}, Revisions: []int{3}},
+		{Date: "2024-03-01", Name: "alice", Author: "alice", Messages: []string{"Initial import", "Add feature"}, Revisions: []int{1, 2}},
+	}
+	plain := "2024-03-02 Bob <bob@example.org>\n    * Fix the build\n      for <arm>\n\n2024-03-01 alice\n    * Initial import\n    * Add feature\n\n"
+	if got := formatSections(t, "plain", sections); got != plain {
+		t.Fatalf("unexpected plain output:\n%s", got)
+	}
+	if got := formatSections(t, "plain", nil); got != "" {
+		t.Fatalf("expected no plain output for no sections, got %q", got)
+	}
+	if got := formatSections(t, "markdown", sections); !strings.Contains(got, "## 2024-03-01 alice\n\n* Initial import\n* Add feature\n") {
+		t.Fatalf("unexpected markdown output:\n%s", got)
+	}
+	if got := formatSections(t, "html", sections); !strings.Contains(got, "<li>Fix the build<br>\nfor &lt;arm&gt;</li>") {
+		t.Fatalf("unexpected html output:\n%s", got)
+	}
+	for _, input := range [][]*Section{sections, nil} {
+		var decoded struct {
+			SchemaVersion int       `json:"schema_version"`
+			Sections      []Section `json:"sections"`
+		}
+		output := formatSections(t, "json", input)
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.SchemaVersion != JSON_SCHEMA_VERSION || len(decoded.Sections) != len(input) {
+			t.Fatalf("expected %d sections in the json output, got %d", len(input), len(decoded.Sections))
+		}
+		if err := ValidateJSON([]byte(output)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestJSONMetadata(t *testing.T) {
+	sections := []*Section{{Date: "2024-03-01", Name: "alice", Author: "alice", Messages: []string{"Initial import"}, Revisions: []int{1}}}
+	if got := formatSections(t, "json", sections); strings.Contains(got, "metadata") {
+		t.Fatalf("expected no metadata by default, got:\n%s", got)
+	}
+	f, err := NewFormatter("json", &Options{Metadata: &Metadata{Generator: "archlog 0.7", Parameters: map[string]string{"format": "json"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	f.Begin(&buf)
+	f.Entry(&buf, sections[0])
+	f.End(&buf)
+	if !strings.Contains(buf.String(), "\"metadata\": {\n    \"generator\": \"archlog 0.7\",\n    \"parameters\": {\n      \"format\": \"json\"\n    }\n  },\n") {
+		t.Fatalf("expected the metadata in the json output:\n%s", buf.String())
+	}
+	if err := ValidateJSON(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeneratedFooter(t *testing.T) {
+	sections := []*Section{{Date: "2024-03-01", Name: "alice", Author: "alice", Messages: []string{"Initial import"}, Revisions: []int{1}}}
+	opts := &Options{GeneratedAt: time.Unix(1709287200, 0)}
+	for _, name := range []string{"plain", "markdown", "html"} {
+		f, err := NewFormatter(name, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var outputs [2]string
+		for i := range outputs {
+			var buf bytes.Buffer
+			f.Begin(&buf)
+			f.Entry(&buf, sections[0])
+			f.End(&buf)
+			outputs[i] = buf.String()
+		}
+		if !strings.Contains(outputs[0], "Generated by archlog on 2024-03-01 10:00:00 UTC") {
+			t.Fatalf("expected a footer in the %s output:\n%s", name, outputs[0])
+		}
+		if outputs[0] != outputs[1] {
+			t.Fatalf("expected the same %s output every time", name)
+		}
+	}
+	if got := formatSections(t, "plain", sections); strings.Contains(got, "Generated") {
+		t.Fatalf("expected no footer by default, got:\n%s", got)
+	}
+}
+
+func TestMaintainers(t *testing.T) {
+	sections := []*Section{{Date: "2024-03-01", Name: "alice", Author: "alice", Messages: []string{"Initial import"}, Revisions: []int{1}}}
+	opts := &Options{Maintainers: []string{"Alice A <alice@example.org>", "bob"}}
+	expected := map[string]string{
+		"plain":    "Maintainer: Alice A <alice@example.org>\nMaintainer: bob\n\n2024-03-01 alice\n",
+		"markdown": "# ChangeLog\n\nMaintainers: Alice A \\<alice@example.org\\>, bob\n",
+		"html":     "<p>Maintainers: Alice A &lt;alice@example.org&gt;, bob</p>\n",
+	}
+	for name, want := range expected {
+		f, err := NewFormatter(name, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		f.Begin(&buf)
+		f.Entry(&buf, sections[0])
+		f.End(&buf)
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %q in the %s output:\n%s", want, name, buf.String())
+		}
+	}
+	maintainers, rest := SplitMaintainers("Maintainer: bob\n\n2024-03-01 alice\n")
+	if maintainers != "Maintainer: bob\n\n" || rest != "2024-03-01 alice\n" {
+		t.Fatalf("unexpected split: %q and %q", maintainers, rest)
+	}
+	if maintainers, rest := SplitMaintainers("2024-03-01 alice\n"); maintainers != "" || rest != "2024-03-01 alice\n" {
+		t.Fatalf("expected nothing to split, got %q and %q", maintainers, rest)
+	}
+}
+
+func TestArchives(t *testing.T) {
+	sections := []*Section{{Date: "2024-03-01", Name: "alice", Author: "alice", Messages: []string{"Initial import"}, Revisions: []int{1}}}
+	opts := &Options{Archives: []string{"ChangeLog.2023", "ChangeLog.2022"}}
+	expected := map[string]string{
+		"plain":    "    * Initial import\n\nOlder entries are in ChangeLog.2023, ChangeLog.2022\n",
+		"markdown": "Older entries are in [ChangeLog.2023](ChangeLog.2023), [ChangeLog.2022](ChangeLog.2022)\n",
+		"html":     "<p>Older entries are in <a href=\"ChangeLog.2023\">ChangeLog.2023</a>, <a href=\"ChangeLog.2022\">ChangeLog.2022</a></p>\n",
+	}
+	for name, want := range expected {
+		f, err := NewFormatter(name, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		f.Begin(&buf)
+		f.Entry(&buf, sections[0])
+		f.End(&buf)
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %q in the %s output:\n%s", want, name, buf.String())
+		}
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	sections := []*Section{{Date: "2024-03-01", Name: "Bob <bob@example.org>", Author: "bob", Messages: []string{"Use *args & <T> in #12\n- not a list\n1. not a list either"}, Revisions: []int{1}}}
+	expected := "## 2024-03-01 Bob \\<bob@example.org\\>\n\n* Use \\*args \\& \\<T\\> in \\#12\n  \\- not a list\n  1\\. not a list either\n"
+	if got := formatSections(t, "markdown", sections); !strings.Contains(got, expected) {
+		t.Fatalf("unexpected markdown output:\n%s", got)
+	}
+	if got := formatSections(t, "html", sections); !strings.Contains(got, "<li>Use *args &amp; &lt;T&gt; in #12<br>\n") {
+		t.Fatalf("unexpected html output:\n%s", got)
+	}
+}
@@ -0,0 +1,603 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"iter"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The fields of each commit are separated by the unit separator,
+// and the commits by the record separator
+const gitLogFormat = "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%B%x1e"
+
+// Find the git executable, either the given one or "git" in the PATH
+func FindGit(gitBin string) (string, error) {
+	if gitBin == "" {
+		gitBin = "git"
+	}
+	path, err := exec.LookPath(gitBin)
+	if err != nil {
+		return "", &VCSError{Err: fmt.Errorf("Could not find git, install it or use -git-bin (%s)", err)}
+	}
+	return path, nil
+}
+
+// Fetches log entries with "git log"
+type gitSource struct{}
+
+// Run git with the given arguments in opts.Repo and return the output
+func runGit(ctx context.Context, opts *Options, args ...string) ([]byte, error) {
+	git, err := FindGit(opts.GitBin)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, git, args...)
+	cmd.Dir = opts.Repo
+	// Don't wait for long for any child processes that keep the output open, once canceled
+	cmd.WaitDelay = time.Second
+	slog.Debug("Running "+strings.Join(cmd.Args, " "), "dir", opts.Repo)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, ctx.Err()
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, &VCSError{Err: fmt.Errorf("Timed out after %s: %s", opts.Timeout, strings.Join(cmd.Args, " "))}
+		}
+		if strings.Contains(stderr.String(), "not a git repository") {
+			return nil, &NoRepositoryError{Dir: opts.Repo, VCS: "git"}
+		}
+		return nil, &VCSError{Err: fmt.Errorf("Error running: %s (%s): %s", strings.Join(cmd.Args, " "), err.Error(), strings.TrimSpace(stderr.String()))}
+	}
+	return stdout.Bytes(), nil
+}
+
+// The arguments for "git log" and "git rev-list" for the history of a ref:
+// only the first parents with opts.FirstParent, or else all of the commits
+// in topological order, so that both commands list them in the same order
+// and the revision numbers are the same
+func gitHistoryArgs(opts *Options) []string {
+	if opts.FirstParent {
+		return []string{"--first-parent"}
+	}
+	return []string{"--topo-order"}
+}
+
+// Run "git log" or "git rev-list" for the history of a ref, with the
+// arguments of gitHistoryArgs after the command
+func runGitHistory(ctx context.Context, opts *Options, command string, args ...string) ([]byte, error) {
+	return runGit(ctx, opts, append(append([]string{command}, gitHistoryArgs(opts)...), args...)...)
+}
+
+// Use the "git log" command to fetch log entries for the working copy in
+// opts.Repo. git has no revision numbers, so the commits in the history of
+// HEAD are numbered from 1 for the oldest one, which makes
+// opts.FromRevision and incremental mode work like for svn. With
+// opts.FirstParent, only the commits on the first-parent history are
+// numbered and fetched. With opts.Ref,
+// the history of that commit is fetched instead. With opts.Submodules, the
+// commits of the submodules that each commit updated are added to it.
+func (gitSource) Entries(ctx context.Context, opts *Options) (iter.Seq2[Entry, error], error) {
+	if len(opts.Branches) > 0 {
+		return gitBranchEntries(ctx, opts)
+	}
+	ref := opts.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	count, err := gitRevision(ctx, opts, ref)
+	if err != nil {
+		return nil, err
+	}
+	limit := count
+	if opts.FromRevision > 0 {
+		limit = count - opts.FromRevision + 1
+	}
+	if opts.Since != "" {
+		// Only fetch the commits from the date on. "git log --since" could
+		// leave out commits in the middle of the history, which would give
+		// the others the wrong revision numbers.
+		before, err := gitSource{}.RevisionBefore(ctx, opts, opts.Since)
+		if err != nil {
+			return nil, err
+		}
+		if count-before < limit {
+			limit = count - before
+		}
+	}
+	if opts.Entries != -1 && opts.Entries < limit {
+		limit = opts.Entries
+	}
+	if limit <= 0 {
+		return sliceEntries(nil), nil
+	}
+	stop := opts.Timings.Start("fetch")
+	output, err := runGitHistory(ctx, opts, "log", "-n", strconv.Itoa(limit), gitLogFormat, ref, "--")
+	stop()
+	if err != nil {
+		return nil, err
+	}
+	defer opts.Timings.Start("parse")()
+	encoding, _ := CheckEncoding(opts.InputEncoding)
+	output, _ = toUTF8(output, encoding, true)
+	entries, err := gitToEntries(output, count, opts.Parsing)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Submodules {
+		if err := addSubmoduleCommits(ctx, opts, ref, entries); err != nil {
+			return nil, err
+		}
+	}
+	return sliceEntries(entries), nil
+}
+
+// Fetch the entries of each of opts.Branches and merge them, ordered from
+// the newest to the oldest by date. The commits that are on several of the
+// branches are only included once, with all of the branches they are on.
+// Each commit has the revision number of its place in the history of its
+// branch, so commits on different branches can have the same number.
+func gitBranchEntries(ctx context.Context, opts *Options) (iter.Seq2[Entry, error], error) {
+	var entries []Entry
+	seen := make(map[string]int)
+	for _, branch := range opts.Branches {
+		branchOpts := *opts
+		branchOpts.Ref, branchOpts.Branches = branch, nil
+		seq, err := gitSource{}.Entries(ctx, &branchOpts)
+		if err != nil {
+			return nil, err
+		}
+		for entry, err := range seq {
+			if err != nil {
+				return nil, err
+			}
+			if i, ok := seen[entry.Commit]; ok {
+				entries[i].Branches = append(entries[i].Branches, branch)
+				continue
+			}
+			seen[entry.Commit] = len(entries)
+			entry.Branches = []string{branch}
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.After(entries[j].Date)
+	})
+	if opts.Entries != -1 && opts.Entries < len(entries) {
+		entries = entries[:opts.Entries]
+	}
+	return sliceEntries(entries), nil
+}
+
+// Start the message of each entry with the branches it is on, like
+// "[main, 1.x] Fix the build"
+func withBranchLabels(entries iter.Seq2[Entry, error]) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		for entry, err := range entries {
+			if err == nil && len(entry.Branches) > 0 {
+				entry.Message = "[" + strings.Join(entry.Branches, ", ") + "] " + entry.Message
+			}
+			if !yield(entry, err) {
+				return
+			}
+		}
+	}
+}
+
+// Update the working copy with "git pull", if it can be fast-forwarded
+func (gitSource) Update(ctx context.Context, opts *Options) error {
+	_, err := runGit(ctx, opts, "pull", "--ff-only", "--quiet")
+	return err
+}
+
+// Clone the git repository at address into dir, or update the clone if dir
+// is already there. Local changes in the clone are discarded, so dir should
+// only be used for reading the history. With a depth above 0, only that
+// many of the newest commits are fetched.
+func CloneGit(ctx context.Context, opts *Options, address, dir string, depth int) error {
+	var shallow []string
+	if depth > 0 {
+		shallow = []string{"--depth", strconv.Itoa(depth)}
+	}
+	clone := *opts
+	clone.Repo = dir
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		args := append(append([]string{"fetch", "--quiet"}, shallow...), "--", address)
+		if _, err := runGit(ctx, &clone, args...); err != nil {
+			return err
+		}
+		_, err := runGit(ctx, &clone, "reset", "--quiet", "--hard", "FETCH_HEAD")
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	clone.Repo = filepath.Dir(dir)
+	args := append(append([]string{"clone", "--quiet"}, shallow...), "--", address, dir)
+	_, err := runGit(ctx, &clone, args...)
+	return err
+}
+
+// Commit the file with "git commit" and push it, if it has changed
+func (gitSource) Commit(ctx context.Context, opts *Options, filename, message string) error {
+	if _, err := runGit(ctx, opts, "add", "--", filename); err != nil {
+		return err
+	}
+	status, err := runGit(ctx, opts, "status", "--porcelain", "--", filename)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(status)) == 0 {
+		return nil
+	}
+	if _, err := runGit(ctx, opts, "commit", "--quiet", "-m", message, "--", filename); err != nil {
+		return err
+	}
+	_, err = runGit(ctx, opts, "push", "--quiet")
+	return err
+}
+
+// git runs archlog in the post-commit hook of a working copy, or in the
+// post-receive hook of a repository that is pushed to
+func (gitSource) Hooks() []string {
+	return []string{"post-commit", "post-receive"}
+}
+
+// The hooks directory of the repository, which honors core.hooksPath
+func (gitSource) HooksDir(ctx context.Context, opts *Options) (string, error) {
+	output, err := runGit(ctx, opts, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(opts.Repo, dir)
+	}
+	return filepath.Abs(dir)
+}
+
+// The URL of the origin remote, without a password in it, or the directory
+// of the repository if there is no origin
+func (gitSource) RepositoryURL(ctx context.Context, opts *Options) (string, error) {
+	output, err := runGit(ctx, opts, "config", "--get", "remote.origin.url")
+	if remote := strings.TrimSpace(string(output)); err == nil && remote != "" {
+		if u, err := url.Parse(remote); err == nil && u.User != nil {
+			return u.Redacted(), nil
+		}
+		return remote, nil
+	}
+	output, err = runGit(ctx, opts, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// The number of commits in the history of ref, which is the revision
+// number of ref
+func gitRevision(ctx context.Context, opts *Options, ref string) (int, error) {
+	output, err := runGitHistory(ctx, opts, "rev-list", "--count", ref, "--")
+	if err != nil {
+		return 0, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, &VCSError{Err: fmt.Errorf("Could not count the git commits: %w", err)}
+	}
+	return count, nil
+}
+
+// The commits in the history of Options.Ref that changed the
+// file, numbered like the entries. The history is followed back through
+// the renames of the file, like with "git log --follow", with the path of
+// the file from the top of the repository at each commit.
+func (gitSource) FileHistory(ctx context.Context, opts *Options, filename string) ([]Entry, error) {
+	ref := opts.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	all, err := runGitHistory(ctx, opts, "rev-list", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	commits := strings.Fields(string(all))
+	numbers := make(map[string]int, len(commits))
+	for i, commit := range commits {
+		numbers[commit] = len(commits) - i
+	}
+	changed, err := runGitHistory(ctx, opts, "log", "--follow", "--diff-merges=first-parent", "--name-only", "--format=%x1e%H", ref, "--", filename)
+	if err != nil {
+		return nil, err
+	}
+	var history []Entry
+	// Each commit starts with a record separator, so the first record is empty
+	for _, record := range strings.Split(string(changed), "\x1e")[1:] {
+		var lines []string
+		for _, line := range strings.Split(record, "\n") {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		entry := Entry{Revision: numbers[lines[0]], Commit: lines[0]}
+		if len(lines) > 1 {
+			entry.File = lines[len(lines)-1]
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+// The contents of the file at the commit, with "git show"
+func (gitSource) FileAt(ctx context.Context, opts *Options, filename string, revision Entry) ([]byte, error) {
+	if revision.File != "" {
+		// The path from the top of the repository, which may be from before a rename
+		return runGit(ctx, opts, "show", revision.Commit+":"+revision.File)
+	}
+	// ./ makes the path relative to the working copy, instead of to the top of the repository
+	return runGit(ctx, opts, "show", revision.Commit+":./"+filepath.ToSlash(filename))
+}
+
+// The files that each commit in the history of Options.Ref changed, with
+// "git log --name-only", where a merge changed the files that differ from
+// its first parent
+func (gitSource) ChangedFiles(ctx context.Context, opts *Options) (map[int][]string, error) {
+	ref := opts.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	count, err := gitRevision(ctx, opts, ref)
+	if err != nil {
+		return nil, err
+	}
+	output, err := runGitHistory(ctx, opts, "log", "--diff-merges=first-parent", "--name-only", "--relative", "--format=%x1e", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[int][]string, count)
+	// Each commit starts with a record separator, so the first record is empty
+	for i, record := range strings.Split(string(output), "\x1e")[1:] {
+		var files []string
+		for _, line := range strings.Split(record, "\n") {
+			if line != "" {
+				files = append(files, line)
+			}
+		}
+		changed[count-i] = files
+	}
+	return changed, nil
+}
+
+// The files that were renamed by each commit in the history of Options.Ref, as git detects them with "git log -M". Copies are left
+// out, since a copy of a file from another package is not a move.
+func (gitSource) Renames(ctx context.Context, opts *Options) (map[int][]Rename, error) {
+	ref := opts.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	count, err := gitRevision(ctx, opts, ref)
+	if err != nil {
+		return nil, err
+	}
+	output, err := runGitHistory(ctx, opts, "log", "--diff-merges=first-parent", "-M", "--name-status", "--relative", "--format=%x1e", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	renames := make(map[int][]Rename)
+	// Each commit starts with a record separator, so the first record is empty
+	for i, record := range strings.Split(string(output), "\x1e")[1:] {
+		for _, line := range strings.Split(record, "\n") {
+			// Like "R100\told/PKGBUILD\tnew/PKGBUILD"
+			fields := strings.Split(line, "\t")
+			if len(fields) == 3 && strings.HasPrefix(fields[0], "R") {
+				renames[count-i] = append(renames[count-i], Rename{From: fields[1], To: fields[2]})
+			}
+		}
+	}
+	return renames, nil
+}
+
+// The commit before the oldest one in the history of Options.Ref that was
+// authored on or after the date, in UTC, like the dates of the entries.
+// The author dates are not always from the newest to the oldest, like
+// when a merge brings in older commits, so every commit that was authored
+// on or after the date is newer than the returned one.
+func (gitSource) RevisionBefore(ctx context.Context, opts *Options, date string) (int, error) {
+	ref := opts.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	output, err := runGitHistory(ctx, opts, "log", "--format=%aI", ref, "--")
+	if err != nil {
+		return 0, err
+	}
+	dates := strings.Fields(string(output))
+	before := len(dates)
+	for i, authored := range dates {
+		t, err := time.Parse(time.RFC3339, authored)
+		if err != nil {
+			return 0, &VCSError{Err: fmt.Errorf("Could not parse the date of a git commit: %w", err)}
+		}
+		if t.UTC().Format("2006-01-02") >= date {
+			before = len(dates) - i - 1
+		}
+	}
+	return before, nil
+}
+
+// The commit of the tag, or "" if there is no such tag
+func (gitSource) TagCommit(ctx context.Context, opts *Options, name string) (string, error) {
+	output, err := runGit(ctx, opts, "tag", "--list", name)
+	if err != nil || strings.TrimSpace(string(output)) == "" {
+		return "", err
+	}
+	output, err = runGit(ctx, opts, "rev-parse", "--verify", "refs/tags/"+name+"^{commit}")
+	return strings.TrimSpace(string(output)), err
+}
+
+// The newest tag in the history before ref, found with "git describe",
+// and its revision number
+func (gitSource) PreviousTag(ctx context.Context, opts *Options, ref string) (string, int, error) {
+	revision, err := gitRevision(ctx, opts, ref)
+	if err != nil || revision <= 1 {
+		// The first commit has no tags before it
+		return "", 0, err
+	}
+	args := []string{"describe", "--tags", "--abbrev=0"}
+	if opts.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	output, err := runGit(ctx, opts, append(args, ref+"^")...)
+	if err != nil {
+		if strings.Contains(err.Error(), "No names found") || strings.Contains(err.Error(), "No tags can describe") {
+			return "", 0, nil
+		}
+		return "", 0, err
+	}
+	tag := strings.TrimSpace(string(output))
+	revision, err = gitRevision(ctx, opts, tag)
+	return tag, revision, err
+}
+
+// Write the annotated tag with "git tag", replacing it if it is already
+// there. The message is passed in a file, since it can be long.
+func (gitSource) WriteTag(ctx context.Context, opts *Options, name, ref, message string, sign bool, key string) error {
+	f, err := ioutil.TempFile("", "archlog-tag")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = io.WriteString(f, message)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	args := []string{"tag", "--force", "--cleanup=verbatim", "--file", f.Name()}
+	switch {
+	case key != "":
+		args = append(args, "--local-user", key)
+	case sign:
+		args = append(args, "--sign")
+	default:
+		args = append(args, "--annotate")
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	_, err = runGit(ctx, opts, append(args, "--", name, ref)...)
+	return err
+}
+
+// Convert the "git log" output to entries, numbering them down from count.
+// In lenient mode, malformed commits are skipped, and in strict mode, an
+// invalid date is also an error.
+func gitToEntries(output []byte, count int, mode ParseMode) ([]Entry, error) {
+	var entries []Entry
+	for i, record := range strings.Split(string(output), "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		fields := strings.SplitN(record, "\x1f", 5)
+		if record == "" {
+			continue
+		} else if len(fields) != 5 && mode == PARSE_LENIENT {
+			slog.Warn("Skipped a malformed commit in the git log", "commit", i+1)
+			continue
+		} else if len(fields) != 5 {
+			return nil, &ParseError{Err: fmt.Errorf("Commit %d in the git log is malformed", i+1)}
+		}
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil && mode == PARSE_STRICT {
+			return nil, &ParseError{Err: fmt.Errorf("Invalid date of %s: %q", fields[0], fields[3])}
+		} else if err != nil {
+			slog.Debug("Could not parse the date of "+fields[0], "date", fields[3], "err", err)
+		}
+		entries = append(entries, Entry{
+			Revision: count - len(entries),
+			Author:   fields[1],
+			Name:     gitName(fields[1], fields[2]),
+			Date:     date.UTC(),
+			Message:  fields[4],
+			Commit:   fields[0],
+		})
+	}
+	return entries, nil
+}
+
+// The name and e-mail address of a git author, or "" if there is no name,
+// so that it is handled like a missing svn author
+func gitName(name, email string) string {
+	if strings.TrimSpace(name) == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s <%s>", name, email)
+}
+
+// The date format of the default "git log" output
+const gitDateFormat = "Mon Jan 2 15:04:05 2006 -0700"
+
+// Parse the default output of "git log", with "commit", "Author:" and
+// "Date:" lines followed by the indented message. The commits are
+// numbered down to 1 for the last one, which is the oldest.
+func ParseGitLog(output []byte) ([]Entry, error) {
+	output, _ = toUTF8(output, "auto", true)
+	var entries []Entry
+	var entry *Entry
+	for i, line := range strings.Split(strings.Replace(string(output), "\r\n", "\n", -1), "\n") {
+		switch {
+		case strings.HasPrefix(line, "commit "):
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: expected a commit line", i+1)
+			}
+			entries = append(entries, Entry{Commit: fields[1]})
+			entry = &entries[len(entries)-1]
+		case entry == nil:
+			if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("line %d: expected a commit line", i+1)
+			}
+		case strings.HasPrefix(line, "Author:"):
+			id := ParseIdentity(strings.TrimPrefix(line, "Author:"))
+			if id.Name != "" {
+				entry.Author, entry.Name = id.Name, id.String()
+			}
+		case strings.HasPrefix(line, "Date:"):
+			date, err := time.Parse(gitDateFormat, strings.TrimSpace(strings.TrimPrefix(line, "Date:")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			entry.Date = date.UTC()
+		case strings.HasPrefix(line, "    "):
+			entry.Message += strings.TrimPrefix(line, "    ") + "\n"
+		case line == "" && entry.Message != "":
+			// Keep the blank lines within the message
+			entry.Message += "\n"
+		}
+	}
+	for i := range entries {
+		entries[i].Revision = len(entries) - i
+		entries[i].Message = strings.TrimSpace(entries[i].Message)
+	}
+	return entries, nil
+}
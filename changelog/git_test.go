@@ -0,0 +1,331 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGitToEntries(t *testing.T) {
+	output := "abc\x1fBob B\x1fbob@example.org\x1f2024-03-02T11:00:00+01:00\x1fFix the build\n\x1e\n" +
+		"def\x1fAlice A\x1falice@example.org\x1f2024-03-01T09:00:00Z\x1fInitial import\n\x1e\n"
+	entries, err := gitToEntries([]byte(output), 7, PARSE_NORMAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Revision != 7 || entries[1].Revision != 6 {
+		t.Fatalf("unexpected revisions: %d, %d", entries[0].Revision, entries[1].Revision)
+	}
+	if entries[0].Name != "Bob B <bob@example.org>" || entries[0].Day() != "2024-03-02" || entries[0].Commit != "abc" {
+		t.Fatalf("unexpected entry: %+v", entries[0])
+	}
+	if _, err := LookupSource("git"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseGitLog(t *testing.T) {
+	output := `commit 0123456789abcdef
+Merge: 0123 4567
+Author: Bob B <bob@example.org>
+Date:   Sat Mar 2 11:00:00 2024 +0100
+
+    Fix the build
+
+    For arm too
+
+commit fedcba9876543210
+Author: Alice A <alice@example.org>
+Date:   Fri Mar 1 09:00:00 2024 +0000
+
+    Initial import
+`
+	entries, err := ParseGitLog([]byte(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Revision != 2 || entries[1].Revision != 1 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if entries[0].Name != "Bob B <bob@example.org>" || entries[0].Message != "Fix the build\n\nFor arm too" || entries[1].Commit != "fedcba9876543210" {
+		t.Fatalf("unexpected entry: %+v", entries[0])
+	}
+	if entries[0].Date.Hour() != 10 {
+		t.Fatalf("expected the date in UTC: %v", entries[0].Date)
+	}
+	// A commit line without the hash is an error, also when it is not the first
+	for _, dump := range []string{"commit \nAuthor: A <a@b>\n", output + "\ncommit  \nAuthor: A <a@b>\n"} {
+		if _, err := ParseLog([]byte(dump)); err == nil || !strings.Contains(err.Error(), "expected a commit line") {
+			t.Fatalf("expected an error for a commit line without a hash, got %v", err)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	opts := &Options{Repo: dir, VCS: "git", Entries: -1}
+	git := func(args ...string) {
+		if _, err := runGit(ctx, opts, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "--quiet")
+	git("config", "user.name", "Alice A")
+	git("config", "user.email", "alice@example.org")
+	git("config", "tag.gpgSign", "false")
+	git("commit", "--quiet", "--allow-empty", "-m", "Initial import")
+	git("tag", "-a", "-m", "v1", "v1")
+	git("commit", "--quiet", "--allow-empty", "-m", "Fix the build")
+	source := gitSource{}
+	if tag, revision, err := source.PreviousTag(ctx, opts, "HEAD"); err != nil || tag != "v1" || revision != 1 {
+		t.Fatalf("expected v1 at revision 1, got %q, %d, %v", tag, revision, err)
+	}
+	if tag, _, err := source.PreviousTag(ctx, opts, "v1"); err != nil || tag != "" {
+		t.Fatalf("expected no tag before the first commit, got %q, %v", tag, err)
+	}
+	if commit, err := source.TagCommit(ctx, opts, "v2"); err != nil || commit != "" {
+		t.Fatalf("expected no commit for a missing tag, got %q, %v", commit, err)
+	}
+	if err := source.WriteTag(ctx, &Options{Repo: dir, GitBin: "git"}, "v2", "", "v2\n\n# Not a comment\n", false, ""); err != nil {
+		t.Fatal(err)
+	}
+	output, err := runGit(ctx, opts, "tag", "--list", "--format=%(contents)", "v2")
+	if err != nil || string(output) != "v2\n\n# Not a comment\n\n" {
+		t.Fatalf("unexpected message: %q, %v", output, err)
+	}
+	// The history of a ref, like a tag, can be fetched instead of HEAD
+	opts.Ref = "v1"
+	entries, err := New(opts).Entries(ctx)
+	if err != nil || len(entries) != 1 || entries[0].Message != "Initial import\n" {
+		t.Fatalf("expected the entry of v1, got %+v, %v", entries, err)
+	}
+}
+
+func TestCloneGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	origin := &Options{Repo: filepath.Join(dir, "origin")}
+	if err := os.Mkdir(origin.Repo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	git := func(args ...string) {
+		if _, err := runGit(ctx, origin, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "--quiet")
+	git("config", "user.name", "Alice A")
+	git("config", "user.email", "alice@example.org")
+	git("commit", "--quiet", "--allow-empty", "-m", "Initial import")
+	clone := filepath.Join(dir, "clones", "archlog")
+	if err := CloneGit(ctx, &Options{}, origin.Repo, clone, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Cloning again updates the clone
+	git("commit", "--quiet", "--allow-empty", "-m", "Fix the build")
+	if err := CloneGit(ctx, &Options{}, origin.Repo, clone, 0); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := New(&Options{Repo: clone, VCS: "git", Entries: -1}).Entries(ctx)
+	if err != nil || len(entries) != 2 || entries[0].Message != "Fix the build\n" {
+		t.Fatalf("expected both commits in the clone, got %+v, %v", entries, err)
+	}
+}
+
+func TestFirstParent(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir := t.TempDir()
+	ctx := context.Background()
+	opts := &Options{Repo: dir, VCS: "git", Entries: -1}
+	git := func(args ...string) {
+		if _, err := runGit(ctx, opts, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "--quiet", "--initial-branch=main")
+	git("config", "user.name", "Alice A")
+	git("config", "user.email", "alice@example.org")
+	git("commit", "--quiet", "--allow-empty", "-m", "Initial import")
+	git("checkout", "--quiet", "-b", "feature")
+	if err := ioutil.WriteFile(filepath.Join(dir, "feature.txt"), []byte("work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "feature.txt")
+	git("commit", "--quiet", "-m", "Work in progress")
+	git("commit", "--quiet", "--allow-empty", "-m", "Fix the work in progress")
+	git("checkout", "--quiet", "main")
+	git("merge", "--quiet", "--no-ff", "-m", "Add the feature", "feature")
+	for _, tc := range []struct {
+		firstParent bool
+		messages    []string
+	}{
+		// All of the commits, numbered in topological order
+		{false, []string{"Add the feature\n", "Fix the work in progress\n", "Work in progress\n", "Initial import\n"}},
+		// The commits of the feature branch are summarized by the merge commit
+		{true, []string{"Add the feature\n", "Initial import\n"}},
+	} {
+		opts.FirstParent = tc.firstParent
+		entries, err := New(opts).Entries(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != len(tc.messages) {
+			t.Fatalf("expected %d entries with FirstParent %v, got %+v", len(tc.messages), tc.firstParent, entries)
+		}
+		for i, entry := range entries {
+			if entry.Message != tc.messages[i] || entry.Revision != len(entries)-i {
+				t.Fatalf("unexpected entries with FirstParent %v: %+v", tc.firstParent, entries)
+			}
+		}
+		// The other commands number the commits the same way
+		if count, err := gitRevision(ctx, opts, "HEAD"); err != nil || count != len(entries) {
+			t.Fatalf("expected the revision %d with FirstParent %v, got %d, %v", len(entries), tc.firstParent, count, err)
+		}
+		changed, err := gitSource{}.ChangedFiles(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changed) != len(entries) || !reflect.DeepEqual(changed[entries[0].Revision], []string{"feature.txt"}) {
+			t.Fatalf("unexpected changed files with FirstParent %v: %v", tc.firstParent, changed)
+		}
+		history, err := gitSource{}.FileHistory(ctx, opts, "feature.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		last := history[len(history)-1]
+		for _, entry := range entries {
+			if entry.Commit == last.Commit && entry.Revision != last.Revision {
+				t.Fatalf("expected the same revision for %s in the file history, got %d and %d", entry.Commit, entry.Revision, last.Revision)
+			}
+		}
+	}
+}
+
+func TestGitSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir := t.TempDir()
+	ctx := context.Background()
+	opts := &Options{Repo: dir, VCS: "git", Entries: -1}
+	git := func(args ...string) {
+		if _, err := runGit(ctx, opts, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "--quiet", "--initial-branch=main")
+	git("config", "user.name", "Alice A")
+	git("config", "user.email", "alice@example.org")
+	git("commit", "--quiet", "--allow-empty", "--date=2024-01-01T12:00:00Z", "-m", "Initial import")
+	git("commit", "--quiet", "--allow-empty", "--date=2024-02-10T12:00:00Z", "-m", "Fix the build")
+	git("commit", "--quiet", "--allow-empty", "--date=2024-03-01T12:00:00Z", "-m", "Update")
+	// Only the commits from the date on are fetched, with the same revisions
+	opts.Since = "2024-02-01"
+	entries, err := New(opts).Entries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Revision != 3 || entries[1].Revision != 2 || entries[1].Message != "Fix the build\n" {
+		t.Fatalf("unexpected entries since %s: %+v", opts.Since, entries)
+	}
+	opts.Since = "2024-04-01"
+	if entries, err := New(opts).Entries(ctx); err != nil || len(entries) != 0 {
+		t.Fatalf("expected no entries since %s, got %+v, %v", opts.Since, entries, err)
+	}
+	// A merge brings in a commit that was authored before the newest commit
+	// on main, and the commits after it in the history are still fetched
+	git("checkout", "--quiet", "-b", "feature", "HEAD~2")
+	git("commit", "--quiet", "--allow-empty", "--date=2024-01-02T12:00:00Z", "-m", "Old work")
+	git("checkout", "--quiet", "main")
+	git("commit", "--quiet", "--allow-empty", "--date=2024-03-04T12:00:00Z", "-m", "New fix")
+	git("merge", "--quiet", "--no-ff", "--no-commit", "feature")
+	git("commit", "--quiet", "--date=2024-03-05T12:00:00Z", "-m", "Merge the old work")
+	opts.Since = "2024-03-01"
+	g := New(opts)
+	g.Names.Resolver = AuthorsFile{}
+	entries, err = g.Entries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := g.Write(ctx, &buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range []string{"Merge the old work", "New fix", "Update"} {
+		if !strings.Contains(buf.String(), message) {
+			t.Fatalf("expected %q in the ChangeLog since %s, got:\n%s", message, opts.Since, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "Old work") {
+		t.Fatalf("expected no entries from before %s, got:\n%s", opts.Since, buf.String())
+	}
+}
+
+func TestBranches(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	opts := &Options{Repo: dir, VCS: "git", Entries: -1}
+	git := func(args ...string) {
+		if _, err := runGit(ctx, opts, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(message, date string) {
+		git("commit", "--quiet", "--allow-empty", "--date", date, "-m", message)
+	}
+	git("init", "--quiet", "--initial-branch=main")
+	git("config", "user.name", "Alice A")
+	git("config", "user.email", "alice@example.org")
+	commit("Initial import", "2024-03-01T10:00:00Z")
+	git("checkout", "--quiet", "-b", "1.x")
+	commit("Fix the stable version", "2024-03-03T10:00:00Z")
+	git("checkout", "--quiet", "main")
+	commit("Add a feature", "2024-03-02T10:00:00Z")
+
+	opts.Branches, opts.BranchLabels = []string{"main", "1.x"}, true
+	entries, err := New(opts).Entries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, entry := range entries {
+		messages = append(messages, strings.TrimSpace(entry.Message))
+	}
+	expected := []string{"[1.x] Fix the stable version", "[main] Add a feature", "[main, 1.x] Initial import"}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Fatalf("expected %q, got %q", expected, messages)
+	}
+}
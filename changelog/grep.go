@@ -0,0 +1,85 @@
+package changelog
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"regexp"
+	"strings"
+)
+
+// The fields that are searched by default
+var GREP_FIELDS = []string{"message", "author", "name", "date"}
+
+// Finds the entries that a regular expression matches, in the fields of
+// the entries, as named for Transform
+type Grep struct {
+	pattern *regexp.Regexp
+	fields  []string
+}
+
+// Create a Grep for the pattern, in the given fields, or in GREP_FIELDS if
+// there are none. With fixed, the pattern is a plain string.
+func NewGrep(pattern string, fields []string, ignoreCase, fixed bool) (*Grep, error) {
+	if len(fields) == 0 {
+		fields = GREP_FIELDS
+	}
+	for _, field := range fields {
+		if !transformFields[field] {
+			return nil, fmt.Errorf("Unknown field: %s (available: message, author, name, revision, date)", field)
+		}
+	}
+	if fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid pattern: %w", err)
+	}
+	return &Grep{pattern: re, fields: fields}, nil
+}
+
+// Check if the pattern matches any of the fields of the entry
+func (g *Grep) Matches(entry Entry) bool {
+	for _, field := range g.fields {
+		if g.pattern.MatchString(getField(&entry, field)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the name field is searched, so that the names must be resolved
+func (g *Grep) searchesNames() bool {
+	for _, field := range g.fields {
+		if field == "name" {
+			return true
+		}
+	}
+	return false
+}
+
+// Stream the entries that the Grep matches, like Stream. If the names are
+// searched, the name of each author is looked up first, so that the
+// pattern can match the name and e-mail address and not only the nick.
+func (g *Generator) GrepStream(ctx context.Context, grep *Grep) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		for entry, err := range g.Stream(ctx) {
+			if err != nil {
+				yield(entry, err)
+				return
+			}
+			if entry.Name == "" && strings.TrimSpace(entry.Author) != "" && grep.searchesNames() {
+				stop := g.Options.Timings.Start("resolve")
+				entry.Name = g.Names.Resolve(ctx, entry.Author)
+				stop()
+			}
+			if grep.Matches(entry) && !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
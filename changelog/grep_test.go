@@ -0,0 +1,48 @@
+package changelog
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGrepStream(t *testing.T) {
+	g := New(nil)
+	g.Names.Resolver = AuthorsFile{"arodseth": {Name: "Alexander Rødseth", Email: "rodseth@gmail.com"}}
+	day := time.Date(2014, 3, 17, 0, 0, 0, 0, time.UTC)
+	g.Source = testSource{entries: []Entry{
+		{Revision: 3, Author: "arodseth", Date: day, Message: "upgpkg: 4.3.3-1"},
+		{Revision: 2, Author: "bob", Date: day, Message: "Fix the build"},
+		{Revision: 1, Author: "bob", Date: day.AddDate(0, 0, -1), Message: "Initial import"},
+	}}
+	grep := func(pattern string, fields []string, ignoreCase bool) []int {
+		t.Helper()
+		p, err := NewGrep(pattern, fields, ignoreCase, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var revisions []int
+		for entry, err := range g.GrepStream(context.Background(), p) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			revisions = append(revisions, entry.Revision)
+		}
+		return revisions
+	}
+	if revisions := grep("rødseth", nil, true); len(revisions) != 1 || revisions[0] != 3 {
+		t.Fatalf("expected the name to be searched, got %v", revisions)
+	}
+	if revisions := grep("2014-03-17", nil, false); len(revisions) != 2 {
+		t.Fatalf("expected the date to be searched, got %v", revisions)
+	}
+	if revisions := grep("bob", []string{"message"}, false); len(revisions) != 0 {
+		t.Fatalf("expected only the messages to be searched, got %v", revisions)
+	}
+	if _, err := NewGrep("x", []string{"files"}, false, false); err == nil {
+		t.Fatal("expected an error for an unknown field")
+	}
+	if _, err := NewGrep("(", nil, false, false); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+}
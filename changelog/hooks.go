@@ -0,0 +1,78 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Run a hook command through the shell, in the working copy, with the
+// given input on stdin. Returns the output.
+func runHook(ctx context.Context, opts *Options, name, command string, input []byte) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = opts.Repo
+	cmd.Env = append(os.Environ(), "ARCHLOG_HOOK="+name)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	slog.Debug("Running the "+name+" hook", "command", command)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("The %s hook failed: %s (%w)", name, command, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Run the pre-entry hook for an entry. Returns the modified entry,
+// or false if the hook dropped it by not outputting anything.
+func runPreEntryHook(ctx context.Context, opts *Options, entry Entry) (Entry, bool, error) {
+	input, err := json.Marshal(entry)
+	if err != nil {
+		return entry, false, err
+	}
+	output, err := runHook(ctx, opts, "pre-entry", opts.PreEntryHook, input)
+	if err != nil {
+		return entry, false, err
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		return entry, false, nil
+	}
+	var modified Entry
+	if err := json.Unmarshal(output, &modified); err != nil {
+		return entry, false, fmt.Errorf("Invalid output from the pre-entry hook for r%d: %w", entry.Revision, err)
+	}
+	return modified, true, nil
+}
+
+// Run the post-generate hook for all the entries and return the modified entries
+func runPostGenerateHook(ctx context.Context, opts *Options, entries []Entry) ([]Entry, error) {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	input, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+	output, err := runHook(ctx, opts, "post-generate", opts.PostGenerateHook, input)
+	if err != nil {
+		return nil, err
+	}
+	var modified []Entry
+	if err := json.Unmarshal(output, &modified); err != nil {
+		return nil, fmt.Errorf("Invalid output from the post-generate hook: %w", err)
+	}
+	return modified, nil
+}
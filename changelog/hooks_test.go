@@ -0,0 +1,30 @@
+package changelog
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the hooks with")
+	}
+	g := New(&Options{
+		Entries:          -1,
+		PreEntryHook:     `grep -v '"revision":2' | sed 's/fixed/Fixed/'`,
+		PostGenerateHook: `cat`,
+	})
+	g.Source = testSource{entries: []Entry{{Revision: 3, Message: "fixed it"}, {Revision: 2}, {Revision: 1}}}
+	entries, err := g.Entries(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Message != "Fixed it" || entries[1].Revision != 1 {
+		t.Fatalf("unexpected entries after the hooks: %+v", entries)
+	}
+	g.Options.PostGenerateHook = "exit 1"
+	if _, err := g.Entries(context.Background()); err == nil {
+		t.Fatal("expected an error from a failing hook")
+	}
+}
@@ -0,0 +1,225 @@
+package changelog
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The words that archlog writes in a ChangeLog, in one language. The dates
+// are always written as YYYY-MM-DD, which is the same in every language.
+type Catalog struct {
+	Title         string // The title of the markdown and html formats
+	Version       string // The start of the headings of the versions
+	Maintainer    string // The start of each line with a maintainer in the plain format
+	Maintainers   string // The start of the line with all of the maintainers in the markdown and html formats
+	Archives      string // The start of the line that tells where the older entries are
+	GeneratedBy   string // The footer for Options.GeneratedAt, with %s for the time
+	UnknownAuthor string // The name for entries without an author
+	UpgradedTo    string // The start of the line for Options.Upgrades
+	Pseudonym     string // The names for Options.Anonymize, with %d for the number
+	// How long ago an entry was, for Options.RelativeTo. The ones with %d
+	// are only used for 2 or more days, weeks, months or years.
+	Today, Yesterday                       string
+	DaysAgo, WeeksAgo, MonthsAgo, YearsAgo string
+	// The labels of the fields of the search in the html format, for
+	// Options.Search: the text, the author and the first and last day
+	Search, SearchAuthor, SearchFrom, SearchTo string
+	// The footer for Options.Footer, where {generator}, {from}, {to} and
+	// {date} are replaced, and the one for when there is no date
+	Footer, FooterWithoutDate string
+}
+
+// The languages for Options.Language, by their ISO 639-1 code
+var catalogs = map[string]*Catalog{
+	"en": {
+		Title:         "ChangeLog",
+		Version:       "Version ",
+		Maintainer:    MAINTAINER_PREFIX,
+		Maintainers:   "Maintainers: ",
+		Archives:      ARCHIVES_PREFIX,
+		GeneratedBy:   "Generated by archlog on %s UTC",
+		UnknownAuthor: DEFAULT_UNKNOWN_AUTHOR,
+		UpgradedTo:    "Upgraded to ",
+		Pseudonym:     "Author %d",
+		Today:         "today",
+		Yesterday:     "yesterday",
+		DaysAgo:       "%d days ago",
+		WeeksAgo:      "%d weeks ago",
+		MonthsAgo:     "%d months ago",
+		YearsAgo:      "%d years ago",
+		Search:        "Search",
+		SearchAuthor:  "Author",
+		SearchFrom:    "From",
+		SearchTo:      "To",
+
+		Footer:            "Generated by {generator} from r{from}..r{to} on {date} UTC",
+		FooterWithoutDate: "Generated by {generator} from r{from}..r{to}",
+	},
+	"de": {
+		Title:         "Änderungsprotokoll",
+		Version:       "Version ",
+		Maintainer:    "Betreuer: ",
+		Maintainers:   "Betreuer: ",
+		Archives:      "Ältere Einträge sind in ",
+		GeneratedBy:   "Erstellt von archlog am %s UTC",
+		UnknownAuthor: "unbekannt",
+		UpgradedTo:    "Aktualisiert auf ",
+		Pseudonym:     "Autor %d",
+		Today:         "heute",
+		Yesterday:     "gestern",
+		DaysAgo:       "vor %d Tagen",
+		WeeksAgo:      "vor %d Wochen",
+		MonthsAgo:     "vor %d Monaten",
+		YearsAgo:      "vor %d Jahren",
+		Search:        "Suche",
+		SearchAuthor:  "Autor",
+		SearchFrom:    "Von",
+		SearchTo:      "Bis",
+
+		Footer:            "Erstellt von {generator} aus r{from}..r{to} am {date} UTC",
+		FooterWithoutDate: "Erstellt von {generator} aus r{from}..r{to}",
+	},
+	"es": {
+		Title:         "Registro de cambios",
+		Version:       "Versión ",
+		Maintainer:    "Mantenedor: ",
+		Maintainers:   "Mantenedores: ",
+		Archives:      "Las entradas anteriores están en ",
+		GeneratedBy:   "Generado por archlog el %s UTC",
+		UnknownAuthor: "desconocido",
+		UpgradedTo:    "Actualizado a ",
+		Pseudonym:     "Autor %d",
+		Today:         "hoy",
+		Yesterday:     "ayer",
+		DaysAgo:       "hace %d días",
+		WeeksAgo:      "hace %d semanas",
+		MonthsAgo:     "hace %d meses",
+		YearsAgo:      "hace %d años",
+		Search:        "Buscar",
+		SearchAuthor:  "Autor",
+		SearchFrom:    "Desde",
+		SearchTo:      "Hasta",
+
+		Footer:            "Generado por {generator} a partir de r{from}..r{to} el {date} UTC",
+		FooterWithoutDate: "Generado por {generator} a partir de r{from}..r{to}",
+	},
+	"fr": {
+		Title:         "Journal des modifications",
+		Version:       "Version ",
+		Maintainer:    "Mainteneur : ",
+		Maintainers:   "Mainteneurs : ",
+		Archives:      "Les entrées plus anciennes sont dans ",
+		GeneratedBy:   "Généré par archlog le %s UTC",
+		UnknownAuthor: "inconnu",
+		UpgradedTo:    "Mis à jour vers ",
+		Pseudonym:     "Auteur %d",
+		Today:         "aujourd'hui",
+		Yesterday:     "hier",
+		DaysAgo:       "il y a %d jours",
+		WeeksAgo:      "il y a %d semaines",
+		MonthsAgo:     "il y a %d mois",
+		YearsAgo:      "il y a %d ans",
+		Search:        "Rechercher",
+		SearchAuthor:  "Auteur",
+		SearchFrom:    "Du",
+		SearchTo:      "Au",
+
+		Footer:            "Généré par {generator} à partir de r{from}..r{to} le {date} UTC",
+		FooterWithoutDate: "Généré par {generator} à partir de r{from}..r{to}",
+	},
+	"nb": {
+		Title:         "Endringslogg",
+		Version:       "Versjon ",
+		Maintainer:    "Vedlikeholder: ",
+		Maintainers:   "Vedlikeholdere: ",
+		Archives:      "Eldre oppføringer er i ",
+		GeneratedBy:   "Laget av archlog %s UTC",
+		UnknownAuthor: "ukjent",
+		UpgradedTo:    "Oppgradert til ",
+		Pseudonym:     "Forfatter %d",
+		Today:         "i dag",
+		Yesterday:     "i går",
+		DaysAgo:       "for %d dager siden",
+		WeeksAgo:      "for %d uker siden",
+		MonthsAgo:     "for %d måneder siden",
+		YearsAgo:      "for %d år siden",
+		Search:        "Søk",
+		SearchAuthor:  "Forfatter",
+		SearchFrom:    "Fra",
+		SearchTo:      "Til",
+
+		Footer:            "Laget av {generator} fra r{from}..r{to} {date} UTC",
+		FooterWithoutDate: "Laget av {generator} fra r{from}..r{to}",
+	},
+}
+
+// The codes of the languages, sorted
+func LanguageNames() []string {
+	names := make([]string, 0, len(catalogs))
+	for name := range catalogs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Find the words for a language, like "de", or "" for English
+func LookupLanguage(lang string) (*Catalog, error) {
+	if lang == "" {
+		return catalogs["en"], nil
+	}
+	catalog, ok := catalogs[lang]
+	if !ok {
+		return nil, fmt.Errorf("Unknown language: %s (available: %s)", lang, strings.Join(LanguageNames(), ", "))
+	}
+	return catalog, nil
+}
+
+// The language of the locale in LC_ALL, LC_TIME or LANG, the first one that
+// is set, like "de" for "de_DE.UTF-8", or "en" if it is not one of the
+// languages of the catalog. "no" and "nn" are written in Norwegian Bokmål.
+func LanguageFromEnvironment() string {
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		locale := os.Getenv(name)
+		if locale == "" {
+			continue
+		}
+		lang := strings.ToLower(strings.FieldsFunc(locale, func(r rune) bool { return r == '_' || r == '.' || r == '@' || r == '-' })[0])
+		if lang == "no" || lang == "nn" {
+			lang = "nb"
+		}
+		if _, ok := catalogs[lang]; ok {
+			return lang
+		}
+		return "en"
+	}
+	return "en"
+}
+
+// Check if a line starts with the words of any of the languages, like the
+// "Version " of a version heading, and return the rest of the line
+func cutCatalogPrefix(line string, words func(c *Catalog) string) (string, bool) {
+	for _, name := range LanguageNames() {
+		if rest, ok := strings.CutPrefix(line, words(catalogs[name])); ok {
+			return rest, true
+		}
+	}
+	return line, false
+}
+
+// Check if a line starts with the words of any of the languages
+func hasCatalogPrefix(line string, words func(c *Catalog) string) bool {
+	_, ok := cutCatalogPrefix(line, words)
+	return ok
+}
+
+// The words for Options.Language, which is English if the language is
+// unknown. Check the language with LookupLanguage first.
+func (opts *Options) catalog() *Catalog {
+	if catalog, ok := catalogs[opts.Language]; ok {
+		return catalog
+	}
+	return catalogs["en"]
+}
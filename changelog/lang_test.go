@@ -0,0 +1,90 @@
+package changelog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLookupLanguage(t *testing.T) {
+	for _, name := range LanguageNames() {
+		catalog, err := LookupLanguage(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(catalog.GeneratedBy, "%s") {
+			t.Fatalf("expected %%s in the footer of %s, got %q", name, catalog.GeneratedBy)
+		}
+	}
+	if catalog, err := LookupLanguage(""); err != nil || catalog.Title != "ChangeLog" {
+		t.Fatalf("expected English for no language, got %v, %v", catalog, err)
+	}
+	if _, err := LookupLanguage("xx"); err == nil {
+		t.Fatal("expected an error for an unknown language")
+	}
+}
+
+func TestLanguageFromEnvironment(t *testing.T) {
+	for env, want := range map[[3]string]string{
+		{"", "", ""}:                       "en",
+		{"", "", "de_DE.UTF-8"}:            "de",
+		{"", "fr_FR.UTF-8", "de_DE.UTF-8"}: "fr",
+		{"C", "fr_FR.UTF-8", ""}:           "en",
+		{"nn_NO", "", ""}:                  "nb",
+		{"", "", "pt_BR.UTF-8"}:            "en",
+	} {
+		t.Setenv("LC_ALL", env[0])
+		t.Setenv("LC_TIME", env[1])
+		t.Setenv("LANG", env[2])
+		if got := LanguageFromEnvironment(); got != want {
+			t.Fatalf("expected %s for %v, got %s", want, env, got)
+		}
+	}
+}
+
+func TestLocalizedFormat(t *testing.T) {
+	sections := []*Section{{Date: "2024-03-01", Name: "alice", Author: "alice", Version: "1.0-1", Messages: []string{"Initial import"}, Revisions: []int{1}}}
+	opts := &Options{Language: "de", GeneratedAt: time.Unix(1709287200, 0), Maintainers: []string{"bob"}, Archives: []string{"ChangeLog.2023"}}
+	expected := map[string][]string{
+		"plain":    {"Betreuer: bob\n", "Version 1.0-1\n", "Ältere Einträge sind in ChangeLog.2023\n", "Erstellt von archlog am 2024-03-01 10:00:00 UTC\n"},
+		"markdown": {"# Änderungsprotokoll\n", "Betreuer: bob\n", "## 2024-03-01 alice\n"},
+		"html":     {"<title>Änderungsprotokoll</title>", "<p>Betreuer: bob</p>"},
+	}
+	for name, wants := range expected {
+		f, err := NewFormatter(name, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		f.Begin(&buf)
+		f.Entry(&buf, sections[0])
+		f.End(&buf)
+		for _, want := range wants {
+			if !strings.Contains(buf.String(), want) {
+				t.Fatalf("expected %q in the %s output:\n%s", want, name, buf.String())
+			}
+		}
+	}
+	if got := (&Options{Language: "nb"}).unknownAuthor(); got != "ukjent" {
+		t.Fatalf("expected the unknown author in Norwegian, got %s", got)
+	}
+	if got := (&Options{Language: "nb", UnknownAuthor: "nobody"}).unknownAuthor(); got != "nobody" {
+		t.Fatalf("expected the given unknown author, got %s", got)
+	}
+}
+
+func TestParseLocalized(t *testing.T) {
+	contents := "Mainteneur : bob\n\nVersion 1.0-1\n\n2024-03-01 alice\n    * Initial import\n\nLes entrées plus anciennes sont dans ChangeLog.2023\n\n"
+	maintainers, rest := SplitMaintainers(contents)
+	if maintainers != "Mainteneur : bob\n\n" {
+		t.Fatalf("unexpected maintainers: %q", maintainers)
+	}
+	sections := ParseChangeLog(rest)
+	if len(sections) != 1 || sections[0].Version != "1.0-1" || len(sections[0].Messages) != 1 {
+		t.Fatalf("unexpected sections: %+v", sections)
+	}
+	if live, _ := Rotate(contents, "2000-01-01"); strings.Contains(live, "Les entrées") {
+		t.Fatalf("expected the line with the archives to be removed:\n%s", live)
+	}
+}
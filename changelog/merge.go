@@ -0,0 +1,213 @@
+package changelog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Marker comments for hand-written sections that survive regeneration
+const (
+	BEGIN_MARKER = "# archlog:begin"
+	END_MARKER   = "# archlog:end"
+)
+
+// Part of a ChangeLog, either a generated entry or a hand-written section
+type section struct {
+	key    string // The header and the occurrence of it, empty for hand-written sections
+	text   string
+	manual bool
+}
+
+// The key of the generated text before the first entry, like the maintainers
+const preambleKey = "preamble"
+
+// Split a ChangeLog into generated entries and hand-written sections.
+// An entry is a header followed by the lines up to the next header. The
+// lines before the first entry are a generated section too.
+func parseSections(contents string) []section {
+	var (
+		sections []section
+		lines    []string
+		current  = &section{key: preambleKey}
+		seen     = make(map[string]int)
+	)
+	flush := func() {
+		if current != nil {
+			current.text = strings.TrimRight(strings.Join(lines, "\n"), "\n ")
+			if current.key != preambleKey || strings.TrimSpace(current.text) != "" {
+				sections = append(sections, *current)
+			}
+		}
+		current, lines = nil, nil
+	}
+	inManual := false
+	for _, line := range strings.Split(contents, "\n") {
+		switch {
+		case inManual:
+			lines = append(lines, line)
+			if strings.TrimSpace(line) == END_MARKER {
+				flush()
+				inManual = false
+			}
+		case strings.TrimSpace(line) == BEGIN_MARKER:
+			flush()
+			current = &section{manual: true}
+			lines = []string{line}
+			inManual = true
+		case IsHeader(line):
+			flush()
+			seen[line]++
+			current = &section{key: fmt.Sprintf("%s#%d", line, seen[line])}
+			lines = []string{line}
+		case current != nil:
+			lines = append(lines, line)
+		}
+	}
+	if inManual {
+		// Keep an unterminated hand-written section as it is
+		lines = append(lines, END_MARKER)
+	}
+	flush()
+	return sections
+}
+
+// Join sections the same way as the ChangeLog is generated, with a
+// blank line after each section
+func joinSections(sections []section) string {
+	var sb strings.Builder
+	for _, s := range sections {
+		sb.WriteString(s.text)
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
+
+// Index the generated entries by key
+func sectionMap(sections []section) map[string]string {
+	m := make(map[string]string)
+	for _, s := range sections {
+		if !s.manual {
+			m[s.key] = s.text
+		}
+	}
+	return m
+}
+
+// Merge a newly generated ChangeLog (theirs) into the existing file (ours),
+// given the previously generated ChangeLog (base). Hand-written sections
+// are kept in place, and entries that have been manually reworded or
+// removed since the last generation are kept the way they are in the file.
+// The returned conflicts are the headers of entries that were changed both
+// manually and by the regeneration, where the manual edit wins.
+func Merge(base, ours, theirs string) (string, []string) {
+	return merge(sectionMap(parseSections(base)), ours, theirs)
+}
+
+// Merge like Merge, with the previously generated entries by key, or nil if
+// the previously generated ChangeLog is not known. Then the entries that are
+// in the existing file are kept the way they are in it, since there is no
+// telling if they were edited by hand, and the ones that differ from the
+// newly generated ones are returned as the conflicts.
+func merge(baseMap map[string]string, ours, theirs string) (string, []string) {
+	oursSections := parseSections(ours)
+	oursMap := sectionMap(oursSections)
+
+	var (
+		merged    []section
+		conflicts []string
+		included  = make(map[string]bool)
+	)
+	for _, s := range parseSections(theirs) {
+		baseText, inBase := baseMap[s.key]
+		oursText, inOurs := oursMap[s.key]
+		switch {
+		case baseMap == nil && inOurs:
+			if oursText != s.text {
+				conflicts = append(conflicts, strings.SplitN(s.key, "#", 2)[0])
+				s.text = oursText
+			}
+		case !inBase:
+			// Not generated before, so use the new entry
+		case !inOurs:
+			if s.text == baseText {
+				// Removed by hand and unchanged since, so leave it out
+				continue
+			}
+		case oursText == baseText:
+			// Not edited by hand, so use the new entry
+		case s.text == baseText:
+			// Edited by hand, and unchanged since
+			s.text = oursText
+		default:
+			conflicts = append(conflicts, strings.SplitN(s.key, "#", 2)[0])
+			s.text = oursText
+		}
+		merged = append(merged, s)
+		included[s.key] = true
+	}
+
+	// Place each hand-written section before the first of the entries that
+	// followed it in the existing file that is still present
+	for i, s := range oursSections {
+		if !s.manual {
+			continue
+		}
+		anchor := ""
+		for _, next := range oursSections[i+1:] {
+			if !next.manual && included[next.key] {
+				anchor = next.key
+				break
+			}
+		}
+		pos := len(merged)
+		if anchor != "" {
+			for j, m := range merged {
+				if m.key == anchor {
+					pos = j
+					break
+				}
+			}
+		}
+		merged = append(merged[:pos], append([]section{s}, merged[pos:]...)...)
+	}
+	return joinSections(merged), conflicts
+}
+
+// The file where the last generated version of a ChangeLog is kept,
+// used as the base when merging in manual edits
+func BaseFilename(filename string) string {
+	return filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".archlog-base")
+}
+
+// Merge a newly generated ChangeLog with the manual edits in the
+// existing file, if there is one. Without the base file, like the first
+// time, the entries in the existing file are kept the way they are, with a
+// warning for the ones that differ from the log.
+func MergeWithExisting(filename, generated string) (string, error) {
+	ours, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return generated, nil
+	} else if err != nil {
+		return "", err
+	}
+	base, err := ioutil.ReadFile(BaseFilename(filename))
+	if os.IsNotExist(err) {
+		merged, differing := merge(nil, UnixLineEndings(string(ours)), generated)
+		for _, header := range differing {
+			slog.Warn(header + " in " + filename + " differs from the log, and there is no " + filepath.Base(BaseFilename(filename)) + " to tell if it was edited by hand, keeping it")
+		}
+		return merged, nil
+	} else if err != nil {
+		return "", err
+	}
+	// The manual edits may have been made with other line endings
+	merged, conflicts := Merge(UnixLineEndings(string(base)), UnixLineEndings(string(ours)), generated)
+	for _, header := range conflicts {
+		slog.Warn(header + " was changed both in " + filename + " and in the log, keeping the manual edit")
+	}
+	return merged, nil
+}
@@ -0,0 +1,120 @@
+package changelog
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeChangeLog(t *testing.T) {
+	base := `2014-03-17 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-2
+
+2014-01-06 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-1
+
+`
+	ours := `2014-03-17 arodseth
+    * Upgraded to 4.3.2, with a fix for the build
+
+# archlog:begin
+Everything below was imported from the old tracker.
+# archlog:end
+
+2014-01-06 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-1
+
+`
+	theirs := `2014-04-01 arodseth
+    * upgpkg: python-cx_freeze 4.3.3-1
+
+2014-03-17 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-2
+
+2014-01-06 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-1
+
+`
+	expected := `2014-04-01 arodseth
+    * upgpkg: python-cx_freeze 4.3.3-1
+
+2014-03-17 arodseth
+    * Upgraded to 4.3.2, with a fix for the build
+
+# archlog:begin
+Everything below was imported from the old tracker.
+# archlog:end
+
+2014-01-06 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-1
+
+`
+	merged, conflicts := Merge(base, ours, theirs)
+	if merged != expected {
+		t.Fatalf("unexpected merge:\n%s", merged)
+	}
+	if len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %v", conflicts)
+	}
+	// Without a base, the generated entries win, but the hand-written section survives
+	merged, _ = Merge("", ours, theirs)
+	if merged == theirs {
+		t.Fatal("the hand-written section was lost")
+	}
+}
+
+func TestMergePreamble(t *testing.T) {
+	entries := `2014-03-17 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-2
+
+`
+	ours := "Maintainer: arodseth\n\n" + entries
+	theirs := "Maintainer: Alexander F. Rødseth <xyproto@archlinux.org>\n\n" + entries
+	if merged, _ := Merge(ours, ours, theirs); merged != theirs {
+		t.Fatalf("expected the new maintainers, got:\n%s", merged)
+	}
+	// Edited by hand, and unchanged since
+	if merged, _ := Merge(theirs, ours, theirs); merged != ours {
+		t.Fatalf("expected the edited maintainers to be kept, got:\n%s", merged)
+	}
+}
+
+func TestMergeWithoutBase(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "ChangeLog")
+	// Written before there was a base file, and reworded by hand since
+	ours := `2014-03-17 arodseth
+    * Upgraded to 4.3.2-2, with a fix for the build
+
+2014-01-06 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-1
+
+`
+	if err := ioutil.WriteFile(filename, []byte(ours), 0644); err != nil {
+		t.Fatal(err)
+	}
+	theirs := `2014-04-01 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-3
+
+2014-03-17 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-2
+
+2014-01-06 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-1
+
+`
+	merged, err := MergeWithExisting(filename, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `2014-04-01 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-3
+
+` + ours
+	if merged != expected {
+		t.Fatalf("expected the reworded entry to be kept and the new one to be added, got:\n%s", merged)
+	}
+	if _, differing := merge(nil, ours, theirs); len(differing) != 1 || differing[0] != "2014-03-17 arodseth" {
+		t.Fatalf("unexpected differing entries: %v", differing)
+	}
+}
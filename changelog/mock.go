@@ -0,0 +1,88 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"iter"
+	"sort"
+	"time"
+)
+
+// A Source with the entries from the file in Options.MockData instead of
+// from a repository, for trying out the formats and options, and for
+// golden-file tests that are the same on every run
+type mockSource struct{}
+
+// An entry in the mock data, where the date can also be just YYYY-MM-DD
+type mockEntry struct {
+	Entry
+	Date string `json:"date"`
+}
+
+// Parse the entries for the mock Source: a JSON array of entries, with the
+// fields of the json format, or a dump of "svn log --xml" or "git log".
+// The entries are sorted from the newest to the oldest, and the ones
+// without a revision are numbered like the commits of git.
+func ParseMockData(data []byte) ([]Entry, error) {
+	var entries []Entry
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("[")) {
+		var mock []mockEntry
+		if err := json.Unmarshal(trimmed, &mock); err != nil {
+			return nil, &ParseError{Err: err}
+		}
+		for i, m := range mock {
+			entry := m.Entry
+			date, err := time.Parse(time.RFC3339, m.Date)
+			if err != nil {
+				if date, err = time.Parse("2006-01-02", m.Date); err != nil {
+					return nil, &ParseError{Err: fmt.Errorf("Invalid date of entry %d: %q", i+1, m.Date)}
+				}
+			}
+			entry.Date = date.UTC()
+			entries = append(entries, entry)
+		}
+	} else {
+		var err error
+		if entries, err = ParseLog(trimmed); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.After(entries[j].Date)
+	})
+	for i := range entries {
+		if entries[i].Revision == 0 {
+			entries[i].Revision = len(entries) - i
+		}
+	}
+	return entries, nil
+}
+
+func (mockSource) Entries(ctx context.Context, opts *Options) (iter.Seq2[Entry, error], error) {
+	if opts.MockData == "" {
+		return nil, errors.New("Please provide the file with the entries for -vcs mock with -mock-data")
+	}
+	data, err := ioutil.ReadFile(opts.MockData)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ParseMockData(data)
+	if err != nil {
+		return nil, err
+	}
+	var kept []Entry
+	for _, entry := range entries {
+		if opts.FromRevision > 0 && entry.Revision < opts.FromRevision {
+			continue
+		}
+		if opts.Entries >= 0 && len(kept) == opts.Entries {
+			break
+		}
+		kept = append(kept, entry)
+	}
+	return sliceEntries(kept), nil
+}
@@ -0,0 +1,51 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMockSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "entries.json")
+	data := `[
+  {"author": "arodseth", "date": "2014-01-06", "message": "Initial import"},
+  {"author": "arodseth", "date": "2014-03-17T12:00:00Z", "message": "Update to 4.3.2-1"},
+  {"author": "felixonmars", "name": "Felix Yan <felixonmars@archlinux.org>", "date": "2014-03-17T09:00:00Z", "message": "Fix the build"}
+]`
+	if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g := New(&Options{VCS: "mock", MockData: filename, Entries: -1})
+	g.Names.Resolver = AuthorsFile{"arodseth": {Name: "Alexander Rødseth", Email: "rodseth@gmail.com"}}
+	entries, err := g.Entries(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Revision != 3 || entries[0].Message != "Update to 4.3.2-1" || entries[2].Revision != 1 {
+		t.Fatalf("expected the entries from the newest to the oldest, got %+v", entries)
+	}
+	var buf bytes.Buffer
+	if err := g.Write(context.Background(), &buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	expected := "2014-03-17 Alexander Rødseth <rodseth@gmail.com>\n    * Update to 4.3.2-1\n\n2014-03-17 Felix Yan <felixonmars@archlinux.org>\n    * Fix the build\n\n2014-01-06 Alexander Rødseth <rodseth@gmail.com>\n    * Initial import\n\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected ChangeLog:\n%s", buf.String())
+	}
+	g.Options.Entries = 1
+	if entries, err := g.Entries(context.Background()); err != nil || len(entries) != 1 {
+		t.Fatalf("expected one entry, got %d: %v", len(entries), err)
+	}
+	if _, err := ParseMockData([]byte(`[{"date": "yesterday"}]`)); err == nil {
+		t.Fatal("expected an error for an invalid date")
+	}
+}
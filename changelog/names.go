@@ -0,0 +1,294 @@
+package changelog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Finds names and e-mail addresses for nicks with a Resolver, which looks
+// them up on the Arch Linux web pages by default. The results are cached.
+type Names struct {
+	Resolver Resolver     // Used for looking up the nicks that are not cached
+	Client   *http.Client // Used for all web lookups by the default Resolver
+	Workers  int          // The number of concurrent lookups in ResolveAll, 0 for DEFAULT_LOOKUPS
+
+	mu          sync.Mutex
+	cache       map[string]string
+	inflight    map[string]*lookup
+	failures    int
+	lastFailure error
+	certErr     error // The first lookup that failed because of a certificate
+	hits        int   // The number of nicks that were found in the cache, or that were already being looked up
+	lookups     int   // The number of nicks that were looked up with the Resolver
+}
+
+// The default number of concurrent lookups in ResolveAll
+const DEFAULT_LOOKUPS = 8
+
+// A lookup of a nick that is in progress, which other callers can wait for
+type lookup struct {
+	done  chan struct{}
+	value string
+}
+
+// Create a Names resolver with an empty cache, that looks up nicks on
+// the Arch Linux web pages
+func NewNames() *Names {
+	// Keep enough connections open for the concurrent lookups to reuse
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = DEFAULT_LOOKUPS
+	client := &http.Client{Transport: transport, CheckRedirect: noDowngrade}
+	web := &ArchWeb{Client: client, Rate: DEFAULT_RATE, Retries: DEFAULT_RETRIES, Robots: true}
+	return &Names{Resolver: web, Client: client, cache: make(map[string]string)}
+}
+
+// Send the web lookups through a proxy, like "http://proxy:3128" or
+// "socks5://localhost:1080", instead of the one in $HTTPS_PROXY
+func (n *Names) SetProxy(proxy string) error {
+	u, err := url.Parse(proxy)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// Don't show the URL, which may contain a password
+		return fmt.Errorf("Invalid proxy: %w", urlErr.Err)
+	} else if err != nil {
+		return fmt.Errorf("Invalid proxy: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("Invalid proxy: %s (use http://, https:// or socks5://)", u.Redacted())
+	}
+	if u.Host == "" {
+		return fmt.Errorf("Invalid proxy: %s has no host", u.Redacted())
+	}
+	transport, ok := n.Client.Transport.(*http.Transport)
+	if !ok {
+		return errors.New("Can not use a proxy with this HTTP client")
+	}
+	transport.Proxy = http.ProxyURL(u)
+	return nil
+}
+
+// Return the cached name and e-mail address for a nick, if it has been looked up.
+// For nicks that could not be found, the nick itself is cached.
+func (n *Names) Cached(nick string) (string, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	value, ok := n.cache[nick]
+	return value, ok
+}
+
+// Check if a nick has been looked up, but could not be found
+func (n *Names) Unresolved(nick string) bool {
+	value, ok := n.Cached(nick)
+	return ok && value == nick
+}
+
+// Record an error from a web lookup, if it was caused by the network
+// and not just by the nick not being found
+func (n *Names) recordLookupError(err error) {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && !errors.Is(err, context.Canceled) {
+		n.mu.Lock()
+		n.failures++
+		n.lastFailure = err
+		n.mu.Unlock()
+	}
+}
+
+// The number of nicks that were resolved from the cache, looked up, and
+// that could not be looked up because of the network
+type LookupStats struct {
+	Hits     int
+	Lookups  int
+	Failures int
+}
+
+// Count the lookups so far
+func (n *Names) Stats() LookupStats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return LookupStats{Hits: n.hits, Lookups: n.lookups, Failures: n.failures}
+}
+
+// Return an error if any web lookups failed because of the network
+func (n *Names) NetworkError() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch n.failures {
+	case 0:
+		return nil
+	case 1:
+		return &NetworkError{Failures: 1, Err: n.lastFailure}
+	}
+	return &NetworkError{Failures: n.failures, Err: n.lastFailure}
+}
+
+// Read cached nicks from a file with one "nick<TAB>Name <email>" per line.
+// A missing file is an empty cache.
+func (n *Names) Load(filename string) error {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(fields) == 2 && fields[0] != "" {
+			n.cache[fields[0]] = fields[1]
+		}
+	}
+	return scanner.Err()
+}
+
+// Write the resolved nicks to a cache file, sorted by nick.
+// Nicks that could not be resolved are not saved, so they are looked up again.
+func (n *Names) Save(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	n.mu.Lock()
+	nicks := make([]string, 0, len(n.cache))
+	lines := make(map[string]string, len(n.cache))
+	for nick, nameEmail := range n.cache {
+		if nameEmail != nick {
+			nicks = append(nicks, nick)
+			lines[nick] = nameEmail
+		}
+	}
+	n.mu.Unlock()
+	sort.Strings(nicks)
+	return WriteFileAtomic(filename, func(w io.Writer) error {
+		for _, nick := range nicks {
+			if _, err := fmt.Fprintf(w, "%s\t%s\n", nick, lines[nick]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Find the name and e-mail address for a nick, formatted as "Name <email>".
+// Returns the nick itself if it could not be found, and also if ctx is
+// canceled, but then the nick is not cached. If the nick is already being
+// looked up by another goroutine, the result of that lookup is used.
+func (n *Names) Resolve(ctx context.Context, nick string) string {
+	n.mu.Lock()
+	if value, ok := n.cache[nick]; ok {
+		n.hits++
+		n.mu.Unlock()
+		return value
+	}
+	if l, ok := n.inflight[nick]; ok {
+		n.hits++
+		n.mu.Unlock()
+		select {
+		case <-l.done:
+			return l.value
+		case <-ctx.Done():
+			return nick
+		}
+	}
+	l := &lookup{done: make(chan struct{}), value: nick}
+	if n.inflight == nil {
+		n.inflight = make(map[string]*lookup)
+	}
+	n.inflight[nick] = l
+	n.lookups++
+	n.mu.Unlock()
+
+	id, err := n.Resolver.Resolve(ctx, nick)
+	// Lookups that failed because of a certificate are not cached, and
+	// make ResolveAll fail instead of leaving the nick unresolved
+	var certErr *CertificateError
+	untrusted := errors.As(err, &certErr)
+	n.mu.Lock()
+	delete(n.inflight, nick)
+	if untrusted && n.certErr == nil {
+		n.certErr = certErr
+	}
+	if ctx.Err() == nil && !untrusted {
+		if err == nil {
+			l.value = id.String()
+		}
+		n.cache[nick] = l.value
+	}
+	n.mu.Unlock()
+	close(l.done)
+	if ctx.Err() != nil {
+		return nick
+	}
+	if err != nil {
+		n.recordLookupError(err)
+		slog.Info("Could not find the name and e-mail address for " + nick)
+	}
+	return l.value
+}
+
+// Look up the nicks concurrently, with up to n.Workers lookups at a time,
+// so that they are cached when they are needed. progress is called with
+// the number of looked up nicks and the total, and can be nil.
+// Returns the error from ctx if it is canceled, or a *CertificateError if
+// the certificate of a server could not be verified.
+func (n *Names) ResolveAll(ctx context.Context, nicks []string, progress func(done, total int)) error {
+	workers := n.Workers
+	if workers < 1 {
+		workers = DEFAULT_LOOKUPS
+	}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+		next = make(chan string)
+	)
+	for worker := 0; worker < workers && worker < len(nicks); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for nick := range next {
+				n.Resolve(ctx, nick)
+				mu.Lock()
+				done++
+				if progress != nil {
+					progress(done, len(nicks))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, nick := range nicks {
+		if ctx.Err() != nil || n.certificateError() != nil {
+			break
+		}
+		next <- nick
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return n.certificateError()
+}
+
+// The first certificate error of the lookups, if any
+func (n *Names) certificateError() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.certErr
+}
@@ -0,0 +1,176 @@
+package changelog
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// A login from a .netrc file
+type NetrcLogin struct {
+	Login    string
+	Password string
+}
+
+// The logins of a .netrc file, by machine. The "default" login is stored
+// with "" as the machine.
+type Netrc map[string]NetrcLogin
+
+// The .netrc file to use by default, which is $NETRC, or .netrc in the
+// home directory (_netrc on Windows)
+func DefaultNetrc() string {
+	if filename := os.Getenv("NETRC"); filename != "" {
+		return filename
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// Parse the contents of a .netrc file. Macro definitions are skipped.
+func ParseNetrc(data string) Netrc {
+	netrc := make(Netrc)
+	var (
+		machine string
+		current NetrcLogin
+		found   bool
+	)
+	save := func() {
+		if found {
+			if _, ok := netrc[machine]; !ok {
+				// The first entry for a machine is used, like ftp and curl do
+				netrc[machine] = current
+			}
+		}
+	}
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			value := ""
+			if j+1 < len(fields) {
+				value = fields[j+1]
+			}
+			switch fields[j] {
+			case "machine":
+				save()
+				machine, current, found = value, NetrcLogin{}, true
+				j++
+			case "default":
+				save()
+				machine, current, found = "", NetrcLogin{}, true
+			case "login":
+				current.Login = value
+				j++
+			case "password":
+				current.Password = value
+				j++
+			case "account":
+				j++
+			case "macdef":
+				// A macro lasts until the next empty line
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+	save()
+	return netrc
+}
+
+// Read a .netrc file. A file that does not exist has no logins.
+func LoadNetrc(filename string) (Netrc, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return Netrc{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(filename); err == nil && runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		slog.Warn("The .netrc file can be read by other users, change its permissions with chmod 600", "file", filename)
+	}
+	return ParseNetrc(string(data)), nil
+}
+
+// Find the login for a host, or the default login
+func (n Netrc) Lookup(host string) (NetrcLogin, bool) {
+	if login, ok := n[host]; ok {
+		return login, true
+	}
+	login, ok := n[""]
+	return login, ok
+}
+
+// Used when parsing svn info xml for the URL of the working copy and the
+// root of the repository
+type svnURLInfo struct {
+	Entry struct {
+		URL  string `xml:"url"`
+		Root string `xml:"repository>root"`
+	} `xml:"entry"`
+}
+
+// Find the URL of the working copy and the root of the repository with
+// "svn info", which does not contact the server
+func svnURLs(ctx context.Context, opts *Options) (*url.URL, *url.URL, error) {
+	data, err := runSvn(ctx, opts, "info", "--xml")
+	if err != nil {
+		return nil, nil, err
+	}
+	var info svnURLInfo
+	if err := xml.Unmarshal(data, &info); err != nil {
+		return nil, nil, &VCSError{Err: fmt.Errorf("Could not parse the svn info: %w", err)}
+	}
+	wc, err := url.Parse(info.Entry.URL)
+	if err != nil {
+		return nil, nil, &VCSError{Err: fmt.Errorf("Could not parse the repository URL: %w", err)}
+	}
+	root, err := url.Parse(info.Entry.Root)
+	if err != nil {
+		return nil, nil, &VCSError{Err: fmt.Errorf("Could not parse the repository URL: %w", err)}
+	}
+	return wc, root, nil
+}
+
+// Use the login in the opts.Netrc file for the host of the repository, if
+// no svn username or password has been given. Returns the options to use.
+func withNetrcLogin(ctx context.Context, opts *Options) (*Options, error) {
+	if opts.Netrc == "" || opts.SvnUsername != "" || opts.SvnPassword != "" {
+		return opts, nil
+	}
+	netrc, err := LoadNetrc(opts.Netrc)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the .netrc file: %w", err)
+	}
+	if len(netrc) == 0 {
+		return opts, nil
+	}
+	wc, _, err := svnURLs(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	host := wc.Hostname()
+	login, ok := netrc.Lookup(host)
+	if !ok {
+		return opts, nil
+	}
+	// The password is never logged
+	slog.Debug("Using the login in "+opts.Netrc, "host", host, "login", login.Login)
+	withLogin := *opts
+	withLogin.SvnUsername, withLogin.SvnPassword = login.Login, login.Password
+	return &withLogin, nil
+}
@@ -0,0 +1,31 @@
+package changelog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	netrc := ParseNetrc(`machine svn.example.org
+	login bob password hunter2
+
+macdef init
+machine evil.example.org login mallory password secret
+
+machine svn.example.org login alice password other
+default login anonymous password guest
+`)
+	expected := Netrc{
+		"svn.example.org": {Login: "bob", Password: "hunter2"},
+		"":                {Login: "anonymous", Password: "guest"},
+	}
+	if !reflect.DeepEqual(netrc, expected) {
+		t.Fatalf("expected %v, got %v", expected, netrc)
+	}
+	if login, ok := netrc.Lookup("svn.example.org"); !ok || login.Login != "bob" {
+		t.Fatalf("expected the login of bob, got %v", login)
+	}
+	if login, ok := netrc.Lookup("other.example.org"); !ok || login.Login != "anonymous" {
+		t.Fatalf("expected the default login, got %v", login)
+	}
+}
@@ -0,0 +1,123 @@
+package changelog
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Settings for the optional commit message normalization stage
+type Normalization struct {
+	Capitalize    bool     // Capitalize the first letter of the message
+	CollapseSpace bool     // Collapse runs of spaces and tabs into one space
+	StripPeriod   bool     // Remove a single trailing period
+	Prefixes      []string // Redundant prefixes, like "pkgname:", to strip
+}
+
+// Check if any of the normalization rules are enabled
+func (n *Normalization) Enabled() bool {
+	return n != nil && (n.Capitalize || n.CollapseSpace || n.StripPeriod || len(n.Prefixes) > 0)
+}
+
+// Split a comma separated list of prefixes, skipping blanks
+func SplitPrefixes(s string) []string {
+	var prefixes []string
+	for _, prefix := range strings.Split(s, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// Guess the package name from the directory of the working copy, or from
+// the current directory if it is empty.
+// Arch Linux package repositories are laid out as pkgname/trunk.
+func GuessPackageName(repo string) string {
+	if repo == "" {
+		repo = "."
+	}
+	dir, err := filepath.Abs(repo)
+	if err != nil {
+		return ""
+	}
+	base := filepath.Base(dir)
+	if base == "trunk" {
+		base = filepath.Base(filepath.Dir(dir))
+	}
+	if base == "." || base == string(filepath.Separator) {
+		return ""
+	}
+	return base
+}
+
+// Remove a prefix like "pkgname:" or "[pkgname]" from the start of the message,
+// in any case. The case is compared rune by rune, since the other case of a
+// rune may have another length, like for "Ⱥ" and the Kelvin sign.
+func stripPrefix(msg, prefix string) string {
+	for _, candidate := range []string{prefix + ":", "[" + prefix + "]", "(" + prefix + ")"} {
+		if rest, ok := cutPrefixFold(msg, candidate); ok {
+			return strings.TrimLeft(rest, " \t")
+		}
+	}
+	return msg
+}
+
+// Remove the prefix from s if s starts with it in any case, and report if it did
+func cutPrefixFold(s, prefix string) (string, bool) {
+	for _, p := range prefix {
+		r, size := utf8.DecodeRuneInString(s)
+		if size == 0 || !equalFoldRune(r, p) {
+			return s, false
+		}
+		s = s[size:]
+	}
+	return s, true
+}
+
+// Check if two runes are the same in any case, by going through the
+// orbit of the simple case folding of one of them
+func equalFoldRune(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Collapse runs of spaces and tabs within each line, but keep the newlines
+func collapseSpace(msg string) string {
+	lines := strings.Split(msg, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Apply the enabled normalization rules to a commit message
+func (n *Normalization) Apply(msg string) string {
+	if !n.Enabled() {
+		return msg
+	}
+	for _, prefix := range n.Prefixes {
+		msg = stripPrefix(msg, prefix)
+	}
+	if n.CollapseSpace {
+		msg = collapseSpace(msg)
+	}
+	msg = strings.TrimSpace(msg)
+	if n.StripPeriod && strings.HasSuffix(msg, ".") && !strings.HasSuffix(msg, "..") {
+		msg = strings.TrimSuffix(msg, ".")
+	}
+	if n.Capitalize && msg != "" {
+		r, size := utf8.DecodeRuneInString(msg)
+		msg = string(unicode.ToUpper(r)) + msg[size:]
+	}
+	return msg
+}
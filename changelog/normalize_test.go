@@ -0,0 +1,44 @@
+package changelog
+
+import (
+	"testing"
+)
+
+func TestNormalization(t *testing.T) {
+	norm := &Normalization{
+		Capitalize:    true,
+		CollapseSpace: true,
+		StripPeriod:   true,
+		Prefixes:      []string{"python-cx_freeze"},
+	}
+	got := norm.Apply("python-cx_freeze:  fixed   the build.")
+	if got != "Fixed the build" {
+		t.Fatalf("unexpected normalization: %q", got)
+	}
+	if got := norm.Apply("wait for it..."); got != "Wait for it..." {
+		t.Fatalf("an ellipsis should be kept: %q", got)
+	}
+	var disabled *Normalization
+	if got := disabled.Apply("as  is."); got != "as  is." {
+		t.Fatalf("a disabled normalization should not change the message: %q", got)
+	}
+}
+
+func TestStripPrefix(t *testing.T) {
+	for _, tc := range []struct{ msg, prefix, expected string }{
+		{"[Foo] Fix the build", "foo", "Fix the build"},
+		{"(FOO) Fix the build", "foo", "Fix the build"},
+		{"Ⱥ:xyz", "Ⱥ", "xyz"},
+		{"Ⱥ:", "Ⱥ", ""},
+		{"ⱥ:xyz", "Ⱥ", "xyz"},
+		{"Øl: Fix", "øl", "Fix"},
+		// The Kelvin sign folds to "k", and is longer
+		{"K: Fix", "k", "Fix"},
+		{"K", "k", "K"},
+		{"Rødseth", "røds", "Rødseth"},
+	} {
+		if got := stripPrefix(tc.msg, tc.prefix); got != tc.expected {
+			t.Errorf("expected %q for %q without %q, got %q", tc.expected, tc.msg, tc.prefix, got)
+		}
+	}
+}
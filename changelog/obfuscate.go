@@ -0,0 +1,68 @@
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The ways of obfuscating the e-mail addresses, for Options.Obfuscate
+const (
+	OBFUSCATE_AT       = "at"       // Like "alice at example dot org"
+	OBFUSCATE_ENTITIES = "entities" // HTML character references in the html and markdown formats, and like OBFUSCATE_AT in the others
+	OBFUSCATE_DROP     = "drop"     // Leave out the addresses, and keep the part before the @ of the ones that are not in <>
+)
+
+// An e-mail address, with the space and the <> around it, if there are any
+var emailAddress = regexp.MustCompile(`(\s*<)?([A-Za-z0-9._%+-]+)@([A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+)(>)?`)
+
+// Check the way of obfuscating the e-mail addresses, where "" is not obfuscating them
+func CheckObfuscateEmail(mode string) error {
+	switch mode {
+	case "", OBFUSCATE_AT, OBFUSCATE_ENTITIES, OBFUSCATE_DROP:
+		return nil
+	}
+	return fmt.Errorf("Unknown way of obfuscating the e-mail addresses: %s (available: %s, %s, %s)", mode, OBFUSCATE_AT, OBFUSCATE_ENTITIES, OBFUSCATE_DROP)
+}
+
+// Obfuscate the e-mail addresses in a name or a message, for the formats
+// that are not HTML
+func obfuscateEmails(text, mode string) string {
+	if mode == "" {
+		return text
+	}
+	return emailAddress.ReplaceAllStringFunc(text, func(match string) string {
+		m := emailAddress.FindStringSubmatch(match)
+		bracketed := m[1] != "" && m[4] != ""
+		switch {
+		case mode == OBFUSCATE_DROP && bracketed:
+			return ""
+		case mode == OBFUSCATE_DROP:
+			return m[1] + m[2] + m[4]
+		}
+		return m[1] + m[2] + " at " + strings.Replace(m[3], ".", " dot ", -1) + m[4]
+	})
+}
+
+// Escape a name or a message with the escape function of a format, and
+// obfuscate the e-mail addresses in it. With OBFUSCATE_ENTITIES, the
+// addresses are written as HTML character references, which are shown as
+// the addresses, but are not escaped.
+func escapeEmails(text, mode string, escape func(string) string) string {
+	if mode != OBFUSCATE_ENTITIES {
+		return escape(obfuscateEmails(text, mode))
+	}
+	var sb strings.Builder
+	prev := 0
+	for _, loc := range emailAddress.FindAllStringSubmatchIndex(text, -1) {
+		// Only the address itself, and not the <> around it
+		start, end := loc[4], loc[7]
+		sb.WriteString(escape(text[prev:start]))
+		for _, r := range text[start:end] {
+			fmt.Fprintf(&sb, "&#%d;", r)
+		}
+		prev = end
+	}
+	sb.WriteString(escape(text[prev:]))
+	return sb.String()
+}
@@ -0,0 +1,55 @@
+package changelog
+
+import (
+	"html"
+	"strings"
+	"testing"
+)
+
+func TestObfuscateEmails(t *testing.T) {
+	text := "Alice A <alice.a@example.org>, thanks to bob@mail.example.com"
+	for mode, want := range map[string]string{
+		"":                 text,
+		OBFUSCATE_AT:       "Alice A <alice.a at example dot org>, thanks to bob at mail dot example dot com",
+		OBFUSCATE_ENTITIES: "Alice A <alice.a at example dot org>, thanks to bob at mail dot example dot com",
+		OBFUSCATE_DROP:     "Alice A, thanks to bob",
+	} {
+		if got := obfuscateEmails(text, mode); got != want {
+			t.Fatalf("expected %q with %q, got %q", want, mode, got)
+		}
+	}
+	if got := escapeEmails("Bob <b@x.org>", OBFUSCATE_ENTITIES, html.EscapeString); got != "Bob &lt;&#98;&#64;&#120;&#46;&#111;&#114;&#103;&gt;" {
+		t.Fatalf("unexpected character references: %q", got)
+	}
+	if got := escapeEmails("first_last@x.org", OBFUSCATE_ENTITIES, escapeMarkdown); strings.Contains(got, `\`) || html.UnescapeString(got) != "first_last@x.org" {
+		t.Fatalf("expected the address to be written as character references only, got %q", got)
+	}
+	if err := CheckObfuscateEmail("rot13"); err == nil {
+		t.Fatal("expected an error for an unknown way of obfuscating")
+	}
+}
+
+func TestObfuscatedFormats(t *testing.T) {
+	sections := []*Section{{Date: "2024-03-01", Name: "Alice <alice@example.org>", Author: "alice", Messages: []string{"Reported by bob@example.org"}, Revisions: []int{1}}}
+	for _, name := range []string{"plain", "markdown", "html", "json"} {
+		output := formatObfuscated(t, name, sections)
+		if strings.Contains(output, "@") || strings.Contains(output, "&#64;") {
+			t.Fatalf("expected no e-mail addresses in the %s output:\n%s", name, output)
+		}
+	}
+}
+
+// Write the sections with the named formatter, with the e-mail addresses dropped
+func formatObfuscated(t *testing.T, name string, sections []*Section) string {
+	f, err := NewFormatter(name, &Options{Obfuscate: OBFUSCATE_DROP, Maintainers: []string{"Carol <carol@example.org>"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	f.Begin(&sb)
+	for _, section := range sections {
+		f.Entry(&sb, section)
+	}
+	f.End(&sb)
+	return sb.String()
+}
@@ -0,0 +1,133 @@
+package changelog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// The package search of the Arch Linux web site, which answers with JSON
+const PACKAGES_API_URL = "https://archlinux.org/packages/search/json/"
+
+// The info endpoint of the AUR RPC
+const AUR_RPC_URL = "https://aur.archlinux.org/rpc/v5/info"
+
+// Returned by FindPackage and FindAURPackage when there is no package with the name
+var ErrUnknownPackage = errors.New("Could not find the package")
+
+// A package in the official repositories, as found with the package search
+type Package struct {
+	Name        string   `json:"pkgname"`
+	Base        string   `json:"pkgbase"`
+	Repo        string   `json:"repo"`
+	Arch        string   `json:"arch"`
+	Version     string   `json:"pkgver"`
+	Release     string   `json:"pkgrel"`
+	Epoch       int      `json:"epoch"`
+	Maintainers []string `json:"maintainers"` // The nicks of the current maintainers
+	Packager    string   `json:"packager"`    // The name and e-mail address of the last packager
+}
+
+// The answer of the package search
+type packageSearch struct {
+	Results []Package `json:"results"`
+}
+
+// The address of the package search, from PackagesURL
+func (a *ArchWeb) packagesURL() string {
+	if a.PackagesURL == "" {
+		return PACKAGES_API_URL
+	}
+	return a.PackagesURL
+}
+
+// Find a package by its exact name with the package search. A package
+// that is in several repositories or for several architectures is only
+// returned once. Returns ErrUnknownPackage if there is no such package.
+func (a *ArchWeb) FindPackage(ctx context.Context, name string) (*Package, error) {
+	body, err := a.get(ctx, a.packagesURL()+"?name="+url.QueryEscape(name))
+	if err != nil {
+		return nil, err
+	}
+	var search packageSearch
+	if err := json.Unmarshal(body, &search); err != nil {
+		return nil, fmt.Errorf("Could not read the package search: %w", err)
+	}
+	for _, pkg := range search.Results {
+		if pkg.Name == name {
+			return &pkg, nil
+		}
+	}
+	return nil, ErrUnknownPackage
+}
+
+// A package in the AUR, as found with the AUR RPC
+type AURPackage struct {
+	Name          string   `json:"Name"`
+	Base          string   `json:"PackageBase"`
+	Version       string   `json:"Version"`
+	Maintainer    string   `json:"Maintainer"`    // The AUR username, or "" for an orphan
+	CoMaintainers []string `json:"CoMaintainers"` // The AUR usernames
+	Submitter     string   `json:"Submitter"`     // The AUR username of the one who first submitted it
+}
+
+// The AUR users of the package: the maintainer, the co-maintainers and the submitter
+func (p *AURPackage) Users() []string {
+	var users []string
+	for _, user := range append(append([]string{p.Maintainer}, p.CoMaintainers...), p.Submitter) {
+		if user != "" && !containsFold(users, user) {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// Check if the list has the string, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// The answer of the AUR RPC
+type aurInfo struct {
+	Type    string       `json:"type"`
+	Error   string       `json:"error"`
+	Results []AURPackage `json:"results"`
+}
+
+// The address of the AUR RPC, from AURURL
+func (a *ArchWeb) aurURL() string {
+	if a.AURURL == "" {
+		return AUR_RPC_URL
+	}
+	return a.AURURL
+}
+
+// Find a package in the AUR by its name with the AUR RPC. Returns
+// ErrUnknownPackage if there is no such package.
+func (a *ArchWeb) FindAURPackage(ctx context.Context, name string) (*AURPackage, error) {
+	body, err := a.get(ctx, a.aurURL()+"?arg[]="+url.QueryEscape(name))
+	if err != nil {
+		return nil, err
+	}
+	var info aurInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("Could not read the answer of the AUR RPC: %w", err)
+	}
+	if info.Type == "error" {
+		return nil, fmt.Errorf("The AUR RPC failed: %s", info.Error)
+	}
+	for _, pkg := range info.Results {
+		if pkg.Name == name {
+			return &pkg, nil
+		}
+	}
+	return nil, ErrUnknownPackage
+}
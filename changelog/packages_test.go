@@ -0,0 +1,61 @@
+package changelog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFindPackage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") != "python-foo" {
+			w.Write([]byte(`{"results": []}`))
+			return
+		}
+		w.Write([]byte(`{"results": [{"pkgname": "python-foo-docs", "pkgbase": "python-foo"}, {"pkgname": "python-foo", "pkgbase": "python-foo", "repo": "extra", "pkgver": "1.2", "pkgrel": "3", "epoch": 0, "maintainers": ["alice", "bob"]}]}`))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	web := &ArchWeb{Client: server.Client(), PackagesURL: server.URL}
+	pkg, err := web.FindPackage(ctx, "python-foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Base != "python-foo" || pkg.Repo != "extra" || pkg.Version != "1.2" || len(pkg.Maintainers) != 2 {
+		t.Fatalf("unexpected package: %+v", pkg)
+	}
+	if _, err := web.FindPackage(ctx, "missing"); !errors.Is(err, ErrUnknownPackage) {
+		t.Fatalf("expected ErrUnknownPackage, got %v", err)
+	}
+}
+
+func TestFindAURPackage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("arg[]") {
+		case "yay":
+			w.Write([]byte(`{"resultcount": 1, "results": [{"Name": "yay", "PackageBase": "yay", "Maintainer": "alice", "CoMaintainers": ["bob", "Alice"], "Submitter": "carol"}], "type": "multiple", "version": 5}`))
+		case "":
+			w.Write([]byte(`{"error": "Incorrect request type specified.", "resultcount": 0, "results": [], "type": "error", "version": 5}`))
+		default:
+			w.Write([]byte(`{"resultcount": 0, "results": [], "type": "multiple", "version": 5}`))
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	web := &ArchWeb{Client: server.Client(), AURURL: server.URL}
+	pkg, err := web.FindAURPackage(ctx, "yay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users := pkg.Users(); len(users) != 3 || users[0] != "alice" || users[1] != "bob" || users[2] != "carol" {
+		t.Fatalf("unexpected users: %q", users)
+	}
+	if _, err := web.FindAURPackage(ctx, "missing"); !errors.Is(err, ErrUnknownPackage) {
+		t.Fatalf("expected ErrUnknownPackage, got %v", err)
+	}
+	if _, err := web.FindAURPackage(ctx, ""); err == nil || errors.Is(err, ErrUnknownPackage) {
+		t.Fatalf("expected the error of the RPC, got %v", err)
+	}
+}
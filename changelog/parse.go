@@ -0,0 +1,79 @@
+package changelog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// How to handle a log that can not be fully parsed
+type ParseMode int
+
+const (
+	PARSE_NORMAL  ParseMode = iota // Fail if the log is malformed
+	PARSE_STRICT                   // Also fail on entries with an invalid revision or date
+	PARSE_LENIENT                  // Skip the malformed parts of the log, with a warning, and keep the rest
+)
+
+// The log from svn or git could not be parsed
+type ParseError struct {
+	Line int // The line in the log where the problem was found, or 0 if unknown
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("Could not parse the log: %v", e.Err)
+	}
+	return fmt.Sprintf("Could not parse the log at line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// A reader that keeps track of the line number, for diagnostics. The xml
+// decoder reads one byte at a time from it, so that nothing is read ahead,
+// and the rest of the log can be read from it after a syntax error.
+type lineReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func (r *lineReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if b == '\n' && err == nil {
+		r.line++
+	}
+	return b, err
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.line += bytes.Count(p[:n], []byte{'\n'})
+	return n, err
+}
+
+// Skip ahead to the next occurrence of s, without reading it.
+// Returns false if the end is reached first.
+func (r *lineReader) skipTo(s string) (bool, error) {
+	for {
+		next, err := r.r.Peek(len(s))
+		if string(next) == s {
+			return true, nil
+		}
+		if err != nil {
+			// Too little is left for s to be found
+			return false, nil
+		}
+		if _, err := r.ReadByte(); err != nil {
+			return false, err
+		}
+	}
+}
+
+// Check if an error is only about the end of the log, after skipping to an entry
+func strayLogEnd(err error) bool {
+	return strings.Contains(err.Error(), "unexpected end element </log>")
+}
@@ -0,0 +1,45 @@
+package changelog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// The number of hex digits of the hash in the ids of the entries and the
+// sections, which is enough to tell apart the commits of large repositories
+const ID_LENGTH = 12
+
+// The id of an entry, for the anchors in the html format, like
+// "e-1f0a9c3b7d2e". It is a hash of the commit hash, or of the revision
+// for svn, and of the repository with Options.Repositories, so it stays
+// the same when the ChangeLog is generated again with newer entries.
+func entryID(entry Entry) string {
+	key := "r" + strconv.Itoa(entry.Revision)
+	if entry.Commit != "" {
+		key = entry.Commit
+	}
+	if len(entry.Repositories) > 0 {
+		key = entry.Repositories[0] + "\x00" + key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "e-" + hex.EncodeToString(sum[:])[:ID_LENGTH]
+}
+
+// The id of a section, which is the id of its oldest entry with another
+// prefix, like "s-1f0a9c3b7d2e", since newer entries are added at the end
+func sectionID(section *Section) string {
+	if len(section.IDs) == 0 {
+		return ""
+	}
+	return "s-" + strings.TrimPrefix(section.IDs[0], "e-")
+}
+
+// The link to an entry or a section from Options.PermalinkTemplate, where
+// {id}, {revision} and {date} are replaced, escaped for an HTML attribute
+func permalink(template, id string, revision int, date string) string {
+	r := strings.NewReplacer("{id}", id, "{revision}", strconv.Itoa(revision), "{date}", date)
+	return html.EscapeString(r.Replace(template))
+}
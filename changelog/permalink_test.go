@@ -0,0 +1,60 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntryID(t *testing.T) {
+	svn := Entry{Revision: 1234}
+	if id := entryID(svn); len(id) != len("e-")+ID_LENGTH || id != entryID(Entry{Revision: 1234, Message: "Changed"}) {
+		t.Fatalf("expected the id to only depend on the revision, got %s", id)
+	}
+	if entryID(svn) == entryID(Entry{Revision: 1235}) {
+		t.Fatal("expected different ids for different revisions")
+	}
+	git := Entry{Revision: 3, Commit: "1f0a9c3b7d2e4f5a6b7c8d9e0f1a2b3c4d5e6f7a"}
+	if entryID(git) != entryID(Entry{Revision: 4, Commit: git.Commit}) {
+		t.Fatal("expected the id of a git commit to only depend on the commit hash")
+	}
+	if entryID(svn) == entryID(Entry{Revision: 1234, Repositories: []string{"frontend"}}) {
+		t.Fatal("expected different ids for the same revision in another repository")
+	}
+}
+
+func TestPermalinks(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	older := []Entry{{Revision: 1, Author: "alice", Date: day, Message: "Initial import"}}
+	newer := append([]Entry{{Revision: 2, Author: "alice", Date: day.Add(time.Hour), Message: "Fix the build"}}, older...)
+	write := func(opts *Options, entries []Entry) string {
+		g := New(opts)
+		g.Names.Resolver = AuthorsFile{}
+		var buf bytes.Buffer
+		if err := g.Write(context.Background(), &buf, entries); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	section, first, second := sectionID(&Section{IDs: []string{entryID(older[0])}}), entryID(older[0]), entryID(newer[0])
+	for _, entries := range [][]Entry{older, newer} {
+		got := write(&Options{Format: "html"}, entries)
+		if !strings.Contains(got, `<h2 id="`+section+`">`) || !strings.Contains(got, `<li id="`+first+`">Initial import</li>`) {
+			t.Fatalf("expected the same ids when newer entries are added:\n%s", got)
+		}
+	}
+	got := write(&Options{Format: "html", PermalinkTemplate: "https://example.org/log?r={revision}&day={date}#{id}"}, newer)
+	for _, want := range []string{
+		`alice <a class="permalink" href="https://example.org/log?r=1&amp;day=2024-03-01#` + section + `">#</a></h2>`,
+		`<li id="` + second + `">Fix the build <a class="permalink" href="https://example.org/log?r=2&amp;day=2024-03-01#` + second + `">#</a></li>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in:\n%s", want, got)
+		}
+	}
+	if err := ValidateJSON([]byte(write(&Options{Format: "json"}, newer))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,263 @@
+package changelog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The version of a package, as set by a commit that changed it in the PKGBUILD or .SRCINFO
+type PackageVersion struct {
+	Version  string // [epoch:]pkgver-pkgrel
+	Revision int    // The revision that changed the version
+	Commit   string // The commit hash, for git
+}
+
+// The value of a variable that is set on a line of its own at the top level
+// of a PKGBUILD, like pkgname=archlog, without the quotes. For arrays, like
+// pkgname=(a b), the first element is returned.
+func PkgbuildField(pkgbuild, name string) (string, bool) {
+	for _, line := range strings.Split(pkgbuild, "\n") {
+		if !strings.HasPrefix(line, name+"=") {
+			continue
+		}
+		value := strings.TrimSpace(strings.TrimPrefix(line, name+"="))
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		if strings.HasPrefix(value, "(") {
+			fields := strings.Fields(strings.Trim(value, "()"))
+			if len(fields) == 0 {
+				return "", true
+			}
+			value = fields[0]
+		}
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return value, true
+	}
+	return "", false
+}
+
+// Join the parts of a version the way pacman shows it
+func packageVersion(epoch, pkgver, pkgrel string) string {
+	if pkgver == "" || pkgrel == "" {
+		return ""
+	}
+	if epoch != "" && epoch != "0" {
+		return epoch + ":" + pkgver + "-" + pkgrel
+	}
+	return pkgver + "-" + pkgrel
+}
+
+// The version in a PKGBUILD, or "" if it is not set, or set with variables
+// or in a pkgver() function, which only makepkg can tell the value of
+func PkgbuildVersion(pkgbuild string) string {
+	var values [3]string
+	for i, name := range []string{"epoch", "pkgver", "pkgrel"} {
+		values[i], _ = PkgbuildField(pkgbuild, name)
+		if strings.ContainsAny(values[i], "$`(") {
+			return ""
+		}
+	}
+	if strings.Contains(pkgbuild, "\npkgver()") || strings.HasPrefix(pkgbuild, "pkgver()") {
+		return ""
+	}
+	return packageVersion(values[0], values[1], values[2])
+}
+
+// The version in a .SRCINFO, from the pkgbase section, or "" if it is not set
+func SrcinfoVersion(srcinfo string) string {
+	var epoch, pkgver, pkgrel string
+	for _, line := range strings.Split(srcinfo, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " = ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "pkgname":
+			// The split packages have the same version as pkgbase
+			return packageVersion(epoch, pkgver, pkgrel)
+		case "epoch":
+			epoch = value
+		case "pkgver":
+			pkgver = value
+		case "pkgrel":
+			pkgrel = value
+		}
+	}
+	return packageVersion(epoch, pkgver, pkgrel)
+}
+
+// The PKGBUILD of the package, relative to the working copy
+func (opts *Options) pkgbuild() string {
+	if opts.Pkgbuild == "" {
+		return "PKGBUILD"
+	}
+	return opts.Pkgbuild
+}
+
+// The revision in history that is the newest one at or before the revision
+// of entry, from history that is ordered from the newest to the oldest
+func fileRevision(history []Entry, entry Entry) (Entry, bool) {
+	for _, e := range history {
+		if e.Revision <= entry.Revision {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Find the versions of the package from the history of the PKGBUILD in
+// Options.Pkgbuild and the .SRCINFO next to it, from the newest to the
+// oldest. The PKGBUILD is used, unless the version in it is set with
+// variables or in a pkgver() function, then the .SRCINFO is used. A
+// version that is older than the newest one so far, according to Vercmp, is
+// not a release, and neither is a version before it was reverted.
+func (g *Generator) Versions(ctx context.Context) ([]PackageVersion, error) {
+	source, err := g.source()
+	if err != nil {
+		return nil, err
+	}
+	files, ok := source.(FileSource)
+	if !ok {
+		return nil, fmt.Errorf("The versions of the package can not be found with -vcs %s", g.Options.VCS)
+	}
+	defer g.Options.Timings.Start("versions")()
+	pkgbuild := g.Options.pkgbuild()
+	srcinfo := filepath.Join(filepath.Dir(pkgbuild), ".SRCINFO")
+	pkgbuildHistory, err := files.FileHistory(ctx, g.Options, pkgbuild)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgbuildHistory) == 0 {
+		return nil, fmt.Errorf("There is no history for %s", pkgbuild)
+	}
+	srcinfoHistory, err := files.FileHistory(ctx, g.Options, srcinfo)
+	if err != nil {
+		return nil, err
+	}
+	// All the revisions that changed any of the files, from the oldest to the newest
+	changes := make(map[int]Entry)
+	for _, e := range append(pkgbuildHistory, srcinfoHistory...) {
+		changes[e.Revision] = e
+	}
+	revisions := make([]Entry, 0, len(changes))
+	for _, e := range changes {
+		revisions = append(revisions, e)
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+	var (
+		versions []PackageVersion
+		previous string // The version of the previous revision
+	)
+	for i, revision := range revisions {
+		g.Options.progress("Finding the versions", i+1, len(revisions))
+		version := ""
+		if e, ok := fileRevision(pkgbuildHistory, revision); ok {
+			data, err := files.FileAt(ctx, g.Options, pkgbuild, e)
+			if err != nil {
+				return nil, err
+			}
+			version = PkgbuildVersion(string(data))
+		}
+		if e, ok := fileRevision(srcinfoHistory, revision); ok && version == "" {
+			data, err := files.FileAt(ctx, g.Options, srcinfo, e)
+			if err != nil {
+				return nil, err
+			}
+			version = SrcinfoVersion(string(data))
+		}
+		if version == "" || version == previous {
+			continue
+		}
+		previous = version
+		if len(versions) == 0 {
+			versions = append(versions, PackageVersion{Version: version, Revision: revision.Revision, Commit: revision.Commit})
+			continue
+		}
+		// Versions are ordered like pacman does it, so that an upgrade from
+		// 1.9 to 1.10 is an upgrade, and an older version is not a release
+		newest := &versions[len(versions)-1]
+		switch Vercmp(version, newest.Version) {
+		case 1:
+			versions = append(versions, PackageVersion{Version: version, Revision: revision.Revision, Commit: revision.Commit})
+		case 0:
+			// Back at the newest version after a downgrade or a revert,
+			// which is where the version was released after all
+			*newest = PackageVersion{Version: version, Revision: revision.Revision, Commit: revision.Commit}
+		}
+	}
+	if len(versions) == 0 {
+		return nil, errors.New("Could not find the version of the package in the history of " + pkgbuild)
+	}
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	return versions, nil
+}
+
+// Set the Version of the entries while they are passed on, to the oldest
+// version that was set at or after the revision of the entry. Entries after
+// the newest version have no version, since they have not been released yet.
+func withVersions(entries iter.Seq2[Entry, error], versions []PackageVersion) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		// The versions up to and including i are not older than the entry
+		i := -1
+		for entry, err := range entries {
+			if err == nil {
+				for i+1 < len(versions) && versions[i+1].Revision >= entry.Revision {
+					i++
+				}
+				if i >= 0 {
+					entry.Version = versions[i].Version
+				}
+			}
+			if !yield(entry, err) {
+				return
+			}
+		}
+	}
+}
+
+// Check if a commit message already tells which version it upgraded to,
+// like "upgpkg: 2.4.1-2", or "Update to 2.4.1" for the first release of 2.4.1
+func mentionsVersion(message, version string) bool {
+	if strings.Contains(message, version) {
+		return true
+	}
+	_, pkgver, pkgrel := splitVersion(version)
+	return pkgrel == "1" && strings.Contains(message, pkgver)
+}
+
+// Add an "Upgraded to" line to the message of the entries that changed the
+// version of the package, unless the message already says so. The line
+// starts with the prefix, which is "Upgraded to " in English. The oldest
+// version is where the package was added, which is not an upgrade.
+func withUpgrades(entries iter.Seq2[Entry, error], versions []PackageVersion, prefix string) iter.Seq2[Entry, error] {
+	upgrades := make(map[int]string, len(versions))
+	for i, v := range versions {
+		if i < len(versions)-1 {
+			upgrades[v.Revision] = v.Version
+		}
+	}
+	return func(yield func(Entry, error) bool) {
+		for entry, err := range entries {
+			if version, ok := upgrades[entry.Revision]; ok && err == nil && !mentionsVersion(entry.Message, version) {
+				upgraded := prefix + version
+				if message := strings.TrimRight(entry.Message, "\n "); message != "" {
+					upgraded = message + "\n" + upgraded
+				}
+				entry.Message = upgraded
+			}
+			if !yield(entry, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPkgbuildField(t *testing.T) {
+	const pkgbuild = "pkgname=archlog\npkgver=1.0\n"
+	for _, test := range []struct {
+		pkgbuild, name, value string
+		ok                    bool
+	}{
+		{pkgbuild, "pkgname", "archlog", true},
+		{"pkgname=('a' 'b')\n", "pkgname", "a", true},
+		{"changelog='ChangeLog' # the ChangeLog\n", "changelog", "ChangeLog", true},
+		{pkgbuild, "changelog", "", false},
+	} {
+		if value, ok := PkgbuildField(test.pkgbuild, test.name); value != test.value || ok != test.ok {
+			t.Fatalf("expected %q and %v for %s, got %q and %v", test.value, test.ok, test.name, value, ok)
+		}
+	}
+}
+
+func TestPackageVersions(t *testing.T) {
+	for pkgbuild, expected := range map[string]string{
+		"pkgver=1.2\npkgrel=1\n":                   "1.2-1",
+		"epoch=1\npkgver='1.2'\npkgrel=3\n":        "1:1.2-3",
+		"pkgver=${_ver//-/.}\npkgrel=1\n":          "",
+		"pkgver=r1.abc\npkgrel=1\npkgver() {\n}\n": "",
+		"pkgname=archlog\n":                        "",
+	} {
+		if version := PkgbuildVersion(pkgbuild); version != expected {
+			t.Fatalf("expected %q for %q, got %q", expected, pkgbuild, version)
+		}
+	}
+	srcinfo := "pkgbase = archlog\n\tpkgver = 2.0\n\tpkgrel = 1\n\tepoch = 0\n\npkgname = archlog\n\tpkgver = 3.0\n"
+	if version := SrcinfoVersion(srcinfo); version != "2.0-1" {
+		t.Fatalf("expected 2.0-1, got %q", version)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	opts := &Options{Repo: dir, VCS: "git", Entries: -1, Versions: true}
+	git := func(args ...string) {
+		if _, err := runGit(ctx, opts, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(message, filename, contents string) {
+		if filename != "" {
+			if err := ioutil.WriteFile(filepath.Join(dir, filename), []byte(contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+			git("add", filename)
+		}
+		git("commit", "--quiet", "--allow-empty", "-m", message)
+	}
+	git("init", "--quiet")
+	git("config", "user.name", "Alice A")
+	git("config", "user.email", "alice@example.org")
+	commit("Add the package", "PKGBUILD", "pkgname=archlog\npkgver=1.0\npkgrel=1\n")
+	commit("Fix the build", "", "")
+	commit("upgpkg: 1.1-1", "PKGBUILD", "pkgname=archlog\npkgver=1.1\npkgrel=1\n")
+	commit("Add a comment", "PKGBUILD", "# A comment\npkgname=archlog\npkgver=1.1\npkgrel=1\n")
+	// From here on, the version is only in the .SRCINFO
+	commit("Use pkgver()", "PKGBUILD", "pkgname=archlog\npkgver=1.1\npkgrel=1\npkgver() {\n  git describe\n}\n")
+	commit("upgpkg: 1.2-1", ".SRCINFO", "pkgbase = archlog\n\tpkgver = 1.2\n\tpkgrel = 1\n")
+	commit("Not released yet", "", "")
+
+	versions, err := New(opts).Versions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shown []string
+	for _, v := range versions {
+		shown = append(shown, v.Version)
+	}
+	if strings.Join(shown, " ") != "1.2-1 1.1-1 1.0-1" || versions[0].Revision != 6 || versions[1].Revision != 3 || versions[2].Revision != 1 {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+	g := New(opts)
+	g.Names.Resolver = AuthorsFile{}
+	entries, err := g.Entries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shown = nil
+	for _, entry := range entries {
+		shown = append(shown, entry.Version)
+	}
+	if strings.Join(shown, ",") != ",1.2-1,1.2-1,1.2-1,1.1-1,1.1-1,1.0-1" {
+		t.Fatalf("unexpected versions of the entries: %q", shown)
+	}
+	// A heading is written where the version changes
+	var buf bytes.Buffer
+	if err := g.Write(ctx, &buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "    * Not released yet\n\nVersion 1.2-1\n\n") || strings.Count(buf.String(), "Version ") != 3 {
+		t.Fatalf("unexpected ChangeLog:\n%s", buf.String())
+	}
+}
+
+func TestVersionsOrder(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	opts := &Options{Repo: dir, VCS: "git", Entries: -1}
+	git := func(args ...string) {
+		if _, err := runGit(ctx, opts, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "--quiet")
+	git("config", "user.name", "Alice A")
+	git("config", "user.email", "alice@example.org")
+	for _, version := range []string{"1.9", "1.10", "1.9", "1.10", "1.10-2"} {
+		pkgver, pkgrel, ok := strings.Cut(version, "-")
+		if !ok {
+			pkgrel = "1"
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "PKGBUILD"), []byte("pkgver="+pkgver+"\npkgrel="+pkgrel+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "PKGBUILD")
+		message := "upgpkg: " + version
+		if version == "1.10-2" {
+			message = "Rebuild"
+		}
+		git("commit", "--quiet", "-m", message)
+	}
+	versions, err := New(opts).Versions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1.10 was reverted to 1.9, and released when it came back
+	if len(versions) != 3 || versions[0].Version != "1.10-2" || versions[1].Version != "1.10-1" || versions[1].Revision != 4 || versions[2].Version != "1.9-1" {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+	// Only the rebuild does not say which version it is
+	opts.Upgrades = true
+	g := New(opts)
+	g.Names.Resolver = AuthorsFile{}
+	entries, err := g.Entries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Message != "Rebuild\nUpgraded to 1.10-2" || strings.TrimSpace(entries[1].Message) != "upgpkg: 1.10" {
+		t.Fatalf("unexpected messages: %+v", entries)
+	}
+}
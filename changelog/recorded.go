@@ -0,0 +1,108 @@
+package changelog
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The newest entry that an existing ChangeLog has recorded, for bootstrapping
+// incremental mode from the ChangeLog itself
+type Recorded struct {
+	Date     string // The date of the newest entry (YYYY-MM-DD), or "" if there are none
+	Revision int    // The newest revision, or 0 if the ChangeLog does not tell
+}
+
+var (
+	// The revision at the start of a message or line, like "r1234:", "[r1234]" or "* (r1234)"
+	leadingRevision = regexp.MustCompile(`^\s*(?:[*-]\s+)?(?:\[r([0-9]+)\]|\(r([0-9]+)\)|r([0-9]+):)`)
+	// The header of an entry in the output of "svn log", like "r1234 | arodseth | 2014-03-17 10:00:00 +0100 (Mon, 17 Mar 2014) | 1 line"
+	svnLogHeader = regexp.MustCompile(`^r([0-9]+) \| [^|]* \| ([0-9]{4}-[0-9]{2}-[0-9]{2}) `)
+)
+
+// The date in the header of an entry, in the styles that ChangeLogs are
+// commonly written in: "2014-03-17 Name" from archlog and GNU, the
+// "* Mon Mar 17 2014 Name" of RPM, the " -- Name  Mon, 17 Mar 2014 10:00:00 +0100"
+// trailer of Debian and the headers of "svn log"
+func headerDate(line string) (string, bool) {
+	if IsHeader(line) {
+		return line[:10], true
+	}
+	if m := svnLogHeader.FindStringSubmatch(line); m != nil {
+		return m[2], true
+	}
+	if strings.HasPrefix(line, "* ") {
+		fields := strings.Fields(line)
+		if len(fields) >= 5 {
+			if t, err := time.Parse("Mon Jan 2 2006", strings.Join(fields[1:5], " ")); err == nil {
+				return t.Format("2006-01-02"), true
+			}
+		}
+	}
+	if strings.HasPrefix(line, " -- ") {
+		if i := strings.Index(line, ">  "); i >= 0 {
+			if t, err := time.Parse(time.RFC1123Z, strings.TrimSpace(line[i+3:])); err == nil {
+				return t.UTC().Format("2006-01-02"), true
+			}
+		}
+	}
+	return "", false
+}
+
+// Find the newest entry that is recorded in a ChangeLog, in the plain or
+// json format of archlog, or written by hand in one of the styles of
+// headerDate. The revision is taken from the json format, from the headers
+// of "svn log", or from messages that start with one, like "r1234: Fix the build"
+// or "[r1234] Fix the build".
+func ParseRecorded(contents string) Recorded {
+	var recorded Recorded
+	if strings.HasPrefix(strings.TrimSpace(contents), "[") {
+		var sections []Section
+		if err := json.Unmarshal([]byte(contents), &sections); err == nil {
+			for _, section := range sections {
+				if section.Date > recorded.Date {
+					recorded.Date = section.Date
+				}
+				for _, revision := range section.Revisions {
+					recorded.Revision = max(recorded.Revision, revision)
+				}
+			}
+			return recorded
+		}
+	}
+	for _, line := range strings.Split(contents, "\n") {
+		if date, ok := headerDate(line); ok && date > recorded.Date {
+			recorded.Date = date
+		}
+		m := svnLogHeader.FindStringSubmatch(line)
+		if m == nil {
+			m = leadingRevision.FindStringSubmatch(line)
+		}
+		if m == nil {
+			continue
+		}
+		for _, group := range m[1:] {
+			if revision, err := strconv.Atoi(group); err == nil {
+				recorded.Revision = max(recorded.Revision, revision)
+			}
+		}
+	}
+	return recorded
+}
+
+// Find the newest revision before the date (YYYY-MM-DD), for fetching only
+// the revisions from that date on. Returns 0 if the Source can not tell.
+func (g *Generator) RevisionBefore(ctx context.Context, date string) (int, error) {
+	source, err := g.source()
+	if err != nil {
+		return 0, err
+	}
+	dates, ok := source.(DateSource)
+	if !ok {
+		return 0, nil
+	}
+	return dates.RevisionBefore(ctx, g.Options, date)
+}
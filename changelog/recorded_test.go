@@ -0,0 +1,59 @@
+package changelog
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestParseRecorded(t *testing.T) {
+	for contents, expected := range map[string]Recorded{
+		"2014-03-17 arodseth\n    * upgpkg: 4.3.2-2\n\n2014-01-06 arodseth\n    * upgpkg: 4.3.2-1\n":                                                                             {Date: "2014-03-17"},
+		"2014-03-17  Alexander Rødseth  <rodseth@gmail.com>\n\n\t* r1234: Fix the build\n":                                                                                       {Date: "2014-03-17", Revision: 1234},
+		"* Mon Mar 17 2014 Alexander Rødseth <rodseth@gmail.com> - 4.3.2-2\n- [r12] Rebuild\n":                                                                                   {Date: "2014-03-17", Revision: 12},
+		"archlog (1.0-1) unstable; urgency=low\n\n  * Initial release\n\n -- Alexander Rødseth <rodseth@gmail.com>  Mon, 17 Mar 2014 23:30:00 -0100\n":                           {Date: "2014-03-18"},
+		"------------------------------------------------------------------------\nr210 | arodseth | 2014-03-17 10:00:00 +0100 (Mon, 17 Mar 2014) | 1 line\n\nupgpkg: 4.3.2-2\n": {Date: "2014-03-17", Revision: 210},
+		`[{"date": "2014-03-17", "name": "arodseth", "author": "arodseth", "messages": ["a", "b"], "revisions": [209, 210]}]`:                                                    {Date: "2014-03-17", Revision: 210},
+		"    * r8169: not a header\n": {Revision: 8169},
+		"":                            {},
+	} {
+		if recorded := ParseRecorded(contents); recorded != expected {
+			t.Fatalf("expected %+v for %q, got %+v", expected, contents, recorded)
+		}
+	}
+	if date := NewestDate("* Mon Mar 17 2014 Alexander Rødseth <rodseth@gmail.com> - 4.3.2-2\n- Rebuild\n"); date != "2014-03-17" {
+		t.Fatalf("expected the date of the RPM style header, got %q", date)
+	}
+}
+
+func TestRevisionBefore(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	git := func(date string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Alice A", "GIT_AUTHOR_EMAIL=alice@example.org", "GIT_COMMITTER_NAME=Alice A", "GIT_COMMITTER_EMAIL=alice@example.org", "GIT_AUTHOR_DATE="+date)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, output)
+		}
+	}
+	git("", "init", "--quiet")
+	for _, date := range []string{"2024-03-01T10:00:00Z", "2024-03-02T10:00:00Z", "2024-03-02T12:00:00Z", "2024-03-04T10:00:00Z"} {
+		git(date, "commit", "--quiet", "--allow-empty", "-m", "Change on "+date)
+	}
+	g := New(&Options{Repo: dir, VCS: "git"})
+	for date, expected := range map[string]int{"2024-03-01": 0, "2024-03-02": 1, "2024-03-03": 3, "2024-03-05": 4} {
+		if revision, err := g.RevisionBefore(ctx, date); err != nil || revision != expected {
+			t.Fatalf("expected revision %d before %s, got %d (%v)", expected, date, revision, err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package changelog
+
+import (
+	"fmt"
+	"time"
+)
+
+// How long before the time the date (YYYY-MM-DD) was, in days, like
+// "(3 days ago)", for Options.RelativeTo. Returns "" for dates that are
+// not valid or that are after the time.
+func relativeDate(catalog *Catalog, date string, now time.Time) string {
+	if now.IsZero() {
+		return ""
+	}
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return ""
+	}
+	today, _ := time.Parse("2006-01-02", now.UTC().Format("2006-01-02"))
+	days := int(today.Sub(t).Hours() / 24)
+	var ago string
+	switch {
+	case days < 0:
+		return ""
+	case days == 0:
+		ago = catalog.Today
+	case days == 1:
+		ago = catalog.Yesterday
+	case days < 14:
+		ago = fmt.Sprintf(catalog.DaysAgo, days)
+	case days < 60:
+		ago = fmt.Sprintf(catalog.WeeksAgo, days/7)
+	case days < 730:
+		ago = fmt.Sprintf(catalog.MonthsAgo, days/30)
+	default:
+		ago = fmt.Sprintf(catalog.YearsAgo, days/365)
+	}
+	return "(" + ago + ")"
+}
@@ -0,0 +1,55 @@
+package changelog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRelativeDate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	en := catalogs["en"]
+	for date, want := range map[string]string{
+		"2024-03-01": "(today)",
+		"2024-02-29": "(yesterday)",
+		"2024-02-26": "(4 days ago)",
+		"2024-02-01": "(4 weeks ago)",
+		"2023-12-01": "(3 months ago)",
+		"2021-03-01": "(3 years ago)",
+		"2024-03-02": "",
+		"latest":     "",
+	} {
+		if got := relativeDate(en, date, now); got != want {
+			t.Fatalf("expected %q for %s, got %q", want, date, got)
+		}
+	}
+	if got := relativeDate(en, "2024-03-01", time.Time{}); got != "" {
+		t.Fatalf("expected nothing without a time, got %q", got)
+	}
+	if got := relativeDate(catalogs["de"], "2024-02-26", now); got != "(vor 4 Tagen)" {
+		t.Fatalf("unexpected German relative date: %q", got)
+	}
+}
+
+func TestRelativeHeaders(t *testing.T) {
+	section := &Section{Date: "2024-02-26", Name: "alice", Author: "alice", Messages: []string{"Initial import"}, Revisions: []int{1}}
+	opts := &Options{RelativeTo: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+	for name, want := range map[string]string{
+		"plain":    "2024-02-26 alice (4 days ago)\n",
+		"markdown": "## 2024-02-26 alice (4 days ago)\n",
+		"html":     "<h2>2024-02-26 alice <small>(4 days ago)</small></h2>\n",
+	} {
+		f, err := NewFormatter(name, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		f.Begin(&buf)
+		f.Entry(&buf, section)
+		f.End(&buf)
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %q in the %s output:\n%s", want, name, buf.String())
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package changelog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+)
+
+// Returned by a Resolver when the nick is unknown to it
+var ErrNotFound = errors.New("Could not find the nick")
+
+// A name and an e-mail address
+type Identity struct {
+	Name  string
+	Email string // Can be empty
+}
+
+// Format the identity as "Name <email>", or just the name if there is no e-mail address
+func (id Identity) String() string {
+	if id.Email == "" {
+		return id.Name
+	}
+	return id.Name + " <" + id.Email + ">"
+}
+
+// Parse "Name <email>", or just a name
+func ParseIdentity(s string) Identity {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "<"); i >= 0 && strings.HasSuffix(s, ">") {
+		return Identity{Name: strings.TrimSpace(s[:i]), Email: s[i+1 : len(s)-1]}
+	}
+	return Identity{Name: s}
+}
+
+// Finds the identity behind a nick
+type Resolver interface {
+	// Returns ErrNotFound if the nick is unknown, or another error if
+	// the lookup failed
+	Resolve(ctx context.Context, nick string) (Identity, error)
+}
+
+// A function that can be used as a Resolver
+type ResolverFunc func(ctx context.Context, nick string) (Identity, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, nick string) (Identity, error) {
+	return f(ctx, nick)
+}
+
+// Tries the resolvers in order, until one of them finds the nick
+type Chain []Resolver
+
+// Resolve the nick with the first resolver that knows it. If none of them
+// do, the first error that is not ErrNotFound is returned, if any.
+func (c Chain) Resolve(ctx context.Context, nick string) (Identity, error) {
+	var failure error
+	for _, resolver := range c {
+		id, err := resolver.Resolve(ctx, nick)
+		if err == nil {
+			return id, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return Identity{}, ctxErr
+		}
+		if failure == nil && !errors.Is(err, ErrNotFound) {
+			failure = err
+		}
+	}
+	if failure != nil {
+		return Identity{}, failure
+	}
+	return Identity{}, ErrNotFound
+}
+
+// Identities for nicks, read from an authors file
+type AuthorsFile map[string]Identity
+
+// Read an authors file in the format used by "git svn", with one
+// "nick = Name <email>" per line. Empty lines and lines starting with "#"
+// are ignored.
+func LoadAuthorsFile(filename string) (AuthorsFile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	authors := make(AuthorsFile)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, "=", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		if nick := strings.TrimSpace(fields[0]); nick != "" {
+			authors[nick] = ParseIdentity(fields[1])
+		}
+	}
+	return authors, scanner.Err()
+}
+
+func (a AuthorsFile) Resolve(ctx context.Context, nick string) (Identity, error) {
+	if id, ok := a[nick]; ok {
+		return id, nil
+	}
+	return Identity{}, ErrNotFound
+}
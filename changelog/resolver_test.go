@@ -0,0 +1,49 @@
+package changelog
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "authors")
+	if err := ioutil.WriteFile(filename, []byte("# comment\nalice = Alice A <alice@example.org>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	authors, err := LoadAuthorsFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing := ResolverFunc(func(ctx context.Context, nick string) (Identity, error) {
+		return Identity{}, errors.New("network is down")
+	})
+	bob := ResolverFunc(func(ctx context.Context, nick string) (Identity, error) {
+		if nick == "bob" {
+			return Identity{Name: "Bob"}, nil
+		}
+		return Identity{}, ErrNotFound
+	})
+	chain := Chain{authors, failing, bob}
+	ctx := context.Background()
+	if id, err := chain.Resolve(ctx, "alice"); err != nil || id.String() != "Alice A <alice@example.org>" {
+		t.Fatalf("unexpected identity for alice: %v, %v", id, err)
+	}
+	if id, err := chain.Resolve(ctx, "bob"); err != nil || id.String() != "Bob" {
+		t.Fatalf("a failing resolver should not stop the chain: %v, %v", id, err)
+	}
+	if _, err := chain.Resolve(ctx, "carol"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the failure to be returned, got %v", err)
+	}
+	if _, err := (Chain{authors, bob}).Resolve(ctx, "carol"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package changelog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// The default maximum number of requests per second to the Arch Linux web pages
+	DEFAULT_RATE = 4.0
+	// The default number of times to retry a failed request
+	DEFAULT_RETRIES = 3
+	// The delay before the first retry, which is doubled for each retry
+	RETRY_BACKOFF = 500 * time.Millisecond
+	// The longest delay before a retry, also when the server asks for more
+	MAX_RETRY_DELAY = time.Minute
+)
+
+// An unexpected HTTP status from a web server
+type statusError struct {
+	Code       int
+	Status     string
+	RetryAfter time.Duration // How long the server asked to wait, if it did
+}
+
+func (e *statusError) Error() string {
+	return e.Status
+}
+
+// Create a statusError from a response, with the Retry-After header, which
+// is either a number of seconds or a date
+func newStatusError(resp *http.Response) *statusError {
+	e := &statusError{Code: resp.StatusCode, Status: resp.Status}
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			e.RetryAfter = time.Duration(seconds) * time.Second
+		} else if date, err := http.ParseTime(value); err == nil {
+			e.RetryAfter = time.Until(date)
+		}
+	}
+	return e
+}
+
+// Check if a failed request is worth retrying. Network errors, timeouts,
+// "429 Too Many Requests" and server errors are, unless ctx is done. A
+// missing response when replaying fixtures is not.
+func temporary(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var (
+		statusErr *statusError
+		certErr   *CertificateError
+	)
+	if errors.As(err, &statusErr) {
+		return statusErr.Code == http.StatusTooManyRequests || statusErr.Code >= 500
+	}
+	return !errors.As(err, &certErr) && !errors.Is(err, ErrNoFixture)
+}
+
+// How long to wait before retrying a failed request, for the given attempt,
+// starting at 0
+func retryDelay(err error, attempt int) time.Duration {
+	delay := RETRY_BACKOFF << attempt
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		delay = statusErr.RetryAfter
+	}
+	if delay > MAX_RETRY_DELAY {
+		delay = MAX_RETRY_DELAY
+	}
+	return delay
+}
+
+// Wait for the duration, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Spaces out requests evenly, so that there are at most a given number of
+// requests per second
+type rateLimiter struct {
+	mu   sync.Mutex
+	next time.Time // When the next request may be sent
+}
+
+// Wait until the next request may be sent, with the given number of
+// requests per second, or 0 for no limit
+func (l *rateLimiter) wait(ctx context.Context, rate float64) error {
+	if rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(time.Duration(float64(time.Second) / rate))
+	l.mu.Unlock()
+	return sleep(ctx, at.Sub(now))
+}
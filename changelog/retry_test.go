@@ -0,0 +1,59 @@
+package changelog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if requests == 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("people"))
+	}))
+	defer server.Close()
+
+	web := &ArchWeb{Client: server.Client(), Retries: 3}
+	if _, err := web.download(context.Background(), server.URL); err == nil {
+		t.Fatal("expected the 404 to not be retried")
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", requests)
+	}
+	body, err := web.download(context.Background(), server.URL)
+	if err != nil || string(body) != "people" {
+		t.Fatalf("expected people, got %q (%v)", body, err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	var limiter rateLimiter
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.wait(context.Background(), 50); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first request is sent at once, then one every 20ms
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("expected the requests to be spaced out, they took %s", elapsed)
+	}
+	if retryDelay(&statusError{RetryAfter: time.Hour}, 0) != MAX_RETRY_DELAY {
+		t.Fatal("expected the delay to be capped")
+	}
+	if retryDelay(nil, 2) != 4*RETRY_BACKOFF {
+		t.Fatal("expected the delay to double for each attempt")
+	}
+}
@@ -0,0 +1,126 @@
+package changelog
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Where archlog can be found, for the User-Agent
+const ARCHLOG_URL = "https://github.com/xyproto/archlog"
+
+// The User-Agent that is sent by default
+const DEFAULT_USER_AGENT = "archlog (+" + ARCHLOG_URL + ")"
+
+// The name that archlog looks for in the User-agent lines of robots.txt
+const robotsName = "archlog"
+
+// The Crawl-delay of a host, which is fetched once
+type robotsRules struct {
+	once  sync.Once
+	delay time.Duration
+}
+
+// Parse the Crawl-delay for archlog in a robots.txt file. The delay for a
+// User-agent group that names archlog is used before the one for "*".
+func parseCrawlDelay(robots string) time.Duration {
+	var (
+		agents        []string
+		inRules       bool
+		named, common time.Duration
+		foundNamed    bool
+	)
+	scanner := bufio.NewScanner(strings.NewReader(robots))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if inRules {
+				// A new group starts
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "crawl-delay":
+			inRules = true
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds <= 0 {
+				continue
+			}
+			delay := time.Duration(seconds * float64(time.Second))
+			for _, agent := range agents {
+				if strings.HasPrefix(agent, robotsName) {
+					named, foundNamed = delay, true
+				} else if agent == "*" {
+					common = delay
+				}
+			}
+		default:
+			inRules = true
+		}
+	}
+	if foundNamed {
+		return named
+	}
+	return common
+}
+
+// The Crawl-delay in the robots.txt of the host of the given URL, which is
+// only fetched once. If it can not be fetched, there is no delay.
+func (a *ArchWeb) crawlDelay(ctx context.Context, address string) time.Duration {
+	u, err := url.Parse(address)
+	if err != nil {
+		return 0
+	}
+	a.mu.Lock()
+	if a.robots == nil {
+		a.robots = make(map[string]*robotsRules)
+	}
+	rules, ok := a.robots[u.Host]
+	if !ok {
+		rules = &robotsRules{}
+		a.robots[u.Host] = rules
+	}
+	a.mu.Unlock()
+	rules.once.Do(func() {
+		robotsURL := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+		body, err := a.request(ctx, robotsURL)
+		if err != nil {
+			slog.Debug("Could not retrieve "+robotsURL, "err", err)
+			return
+		}
+		rules.delay = parseCrawlDelay(string(body))
+		if rules.delay > 0 {
+			slog.Debug("Using the Crawl-delay in "+robotsURL, "delay", rules.delay)
+		}
+	})
+	return rules.delay
+}
+
+// The number of requests per second for a URL, which is at most Rate,
+// and also honors the Crawl-delay of the host if Robots is set
+func (a *ArchWeb) rate(ctx context.Context, address string) float64 {
+	if !a.Robots {
+		return a.Rate
+	}
+	delay := a.crawlDelay(ctx, address)
+	if delay <= 0 {
+		return a.Rate
+	}
+	if robotsRate := float64(time.Second) / float64(delay); a.Rate <= 0 || robotsRate < a.Rate {
+		return robotsRate
+	}
+	return a.Rate
+}
@@ -0,0 +1,62 @@
+package changelog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseCrawlDelay(t *testing.T) {
+	const robots = `# Go easy
+User-agent: *
+Crawl-delay: 2
+Disallow: /admin/
+
+User-agent: googlebot
+User-agent: archlog
+Crawl-delay: 0.5
+`
+	if delay := parseCrawlDelay(robots); delay != 500*time.Millisecond {
+		t.Fatalf("expected the delay for archlog, got %s", delay)
+	}
+	if delay := parseCrawlDelay(robots[:40]); delay != 2*time.Second {
+		t.Fatalf("expected the delay for all robots, got %s", delay)
+	}
+	if delay := parseCrawlDelay("User-agent: *\nDisallow:\n"); delay != 0 {
+		t.Fatalf("expected no delay, got %s", delay)
+	}
+}
+
+func TestArchWebRobots(t *testing.T) {
+	robots, agents := 0, map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents[r.Header.Get("User-Agent")] = true
+		if r.URL.Path == "/robots.txt" {
+			robots++
+			w.Write([]byte("User-agent: *\nCrawl-delay: 4\n"))
+			return
+		}
+		w.Write([]byte("people"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	web := &ArchWeb{Client: server.Client(), Rate: 10, Robots: true, UserAgent: "archlog/1.0"}
+	for i := 0; i < 2; i++ {
+		if rate := web.rate(ctx, server.URL+"/people/"); rate != 0.25 {
+			t.Fatalf("expected 0.25 requests per second, got %v", rate)
+		}
+	}
+	if robots != 1 {
+		t.Fatalf("expected robots.txt to be fetched once, got %d", robots)
+	}
+	if !agents["archlog/1.0"] || len(agents) != 1 {
+		t.Fatalf("expected the User-Agent to be archlog/1.0, got %v", agents)
+	}
+	web.Robots = false
+	if rate := web.rate(ctx, server.URL+"/people/"); rate != 10 {
+		t.Fatalf("expected the Rate without Robots, got %v", rate)
+	}
+}
@@ -0,0 +1,56 @@
+package changelog
+
+import (
+	"sort"
+	"strings"
+)
+
+// Move the entries that are older than the date (YYYY-MM-DD) out of a
+// plain ChangeLog. Returns the ChangeLog with the newer entries, and the
+// older entries by year, like "2023", ordered from the newest to the oldest,
+// as they were in the ChangeLog. A hand-written section goes along with the
+// entry above it, and the text before the first entry, like the maintainers,
+// is kept. The lines that tell where the older entries are, starting with
+// ARCHIVES_PREFIX or the same words in another language, are removed.
+func Rotate(contents, before string) (string, map[string]string) {
+	var lines []string
+	for _, line := range strings.Split(contents, "\n") {
+		if !hasCatalogPrefix(line, func(c *Catalog) string { return c.Archives }) {
+			lines = append(lines, line)
+		}
+	}
+	var (
+		live    []section
+		byYear  = make(map[string][]section)
+		current = "" // The year of the section above, or "" if it is kept
+	)
+	for _, s := range parseSections(strings.Join(lines, "\n")) {
+		switch {
+		case s.manual || s.key == preambleKey:
+		case s.text[:10] < before:
+			current = s.text[:4]
+		default:
+			current = ""
+		}
+		if current == "" {
+			live = append(live, s)
+		} else {
+			byYear[current] = append(byYear[current], s)
+		}
+	}
+	archives := make(map[string]string, len(byYear))
+	for year, sections := range byYear {
+		archives[year] = joinSections(sections)
+	}
+	return joinSections(live), archives
+}
+
+// The years of the archives from Rotate, from the newest to the oldest
+func ArchiveYears(archives map[string]string) []string {
+	years := make([]string, 0, len(archives))
+	for year := range archives {
+		years = append(years, year)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(years)))
+	return years
+}
@@ -0,0 +1,41 @@
+package changelog
+
+import "testing"
+
+func TestRotate(t *testing.T) {
+	contents := `Maintainer: arodseth
+
+2024-03-17 arodseth
+    * upgpkg: python-cx_freeze 4.3.3-1
+
+2023-12-01 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-2
+
+# archlog:begin
+Imported from the old tracker.
+# archlog:end
+
+2022-01-06 arodseth
+    * upgpkg: python-cx_freeze 4.3.2-1
+
+Older entries are in ChangeLog.2021
+
+`
+	live, archives := Rotate(contents, "2024-01-01")
+	if live != "Maintainer: arodseth\n\n2024-03-17 arodseth\n    * upgpkg: python-cx_freeze 4.3.3-1\n\n" {
+		t.Fatalf("unexpected ChangeLog:\n%s", live)
+	}
+	if len(archives) != 2 || archives["2022"] != "2022-01-06 arodseth\n    * upgpkg: python-cx_freeze 4.3.2-1\n\n" {
+		t.Fatalf("unexpected archives: %q", archives)
+	}
+	// The hand-written section goes along with the entry above it
+	if archives["2023"] != "2023-12-01 arodseth\n    * upgpkg: python-cx_freeze 4.3.2-2\n\n# archlog:begin\nImported from the old tracker.\n# archlog:end\n\n" {
+		t.Fatalf("unexpected archive for 2023:\n%s", archives["2023"])
+	}
+	if years := ArchiveYears(archives); len(years) != 2 || years[0] != "2023" {
+		t.Fatalf("unexpected years: %v", years)
+	}
+	if live, archives := Rotate(contents, "2020-01-01"); len(archives) != 0 || CountEntries(live) != 3 {
+		t.Fatalf("expected nothing to be moved, got:\n%s", live)
+	}
+}
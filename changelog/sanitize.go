@@ -0,0 +1,99 @@
+package changelog
+
+import (
+	"strings"
+)
+
+// Remove the ANSI escape sequences and control characters from text, so
+// that it can not corrupt the ChangeLog or a terminal. Carriage returns
+// become newlines, and unless multiline is true, newlines and tabs become
+// spaces. Unicode characters that change the direction of the text are
+// also removed.
+func Sanitize(text string, multiline bool) string {
+	if isClean(text, multiline) {
+		return text
+	}
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	var sb strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\x1b' || r == '\u009b':
+			i = skipEscape(runes, i)
+		case r == '\r' || r == '\n':
+			if multiline {
+				sb.WriteRune('\n')
+			} else {
+				sb.WriteRune(' ')
+			}
+		case r == '\t':
+			if multiline {
+				sb.WriteRune('\t')
+			} else {
+				sb.WriteRune(' ')
+			}
+		case r < 0x20 || (r >= 0x7f && r < 0xa0):
+			// Other control characters
+		case (r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069'):
+			// Direction overrides and isolates
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// Check if there is nothing to sanitize, which is the common case
+func isClean(text string, multiline bool) bool {
+	for _, r := range text {
+		switch {
+		case r == '\n' || r == '\t':
+			if !multiline {
+				return false
+			}
+		case r < 0x20 || (r >= 0x7f && r < 0xa0):
+			return false
+		case (r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069'):
+			return false
+		}
+	}
+	return true
+}
+
+// Skip an escape sequence that starts at runes[i], and return the index
+// of its last rune
+func skipEscape(runes []rune, i int) int {
+	csi := runes[i] == '\u009b'
+	if !csi {
+		if i+1 >= len(runes) {
+			return i
+		}
+		i++
+		switch runes[i] {
+		case '[':
+			csi = true
+		case ']', 'P', '_', '^':
+			// OSC, DCS and the like, which end with BEL or ESC \
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\a' {
+					return i
+				}
+				if runes[i] == '\x1b' && i+1 < len(runes) && runes[i+1] == '\\' {
+					return i + 1
+				}
+			}
+			return len(runes) - 1
+		default:
+			// A two character sequence
+			return i
+		}
+	}
+	// CSI, which ends with a character from @ to ~
+	for i++; i < len(runes); i++ {
+		if runes[i] >= '@' && runes[i] <= '~' {
+			return i
+		}
+	}
+	return len(runes) - 1
+}
@@ -0,0 +1,25 @@
+package changelog
+
+import (
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	for _, test := range []struct {
+		text      string
+		multiline bool
+		expected  string
+	}{
+		{"Fix the build", true, "Fix the build"},
+		{"\x1b[31mRed\x1b[0m and \x1b]0;title\a plain", true, "Red and  plain"},
+		{"Line one\r\nLine two\rover\x00\x08", true, "Line one\nLine two\nover"},
+		{"bob\n\tthe builder\x1b", false, "bob  the builder"},
+		{"abc\u202edcba\u2066x", true, "abcdcbax"},
+		{"tab\tkept", true, "tab\tkept"},
+		{"\u009b1mC1\x7f", true, "C1"},
+	} {
+		if got := Sanitize(test.text, test.multiline); got != test.expected {
+			t.Errorf("Sanitize(%q): expected %q, got %q", test.text, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package changelog
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The version of the JSON Schema of the json format, which is only
+// increased when a field is removed or changes its meaning
+const JSON_SCHEMA_VERSION = 1
+
+// Where the JSON Schema of the json format is published, in the "$schema"
+// field of each document
+const JSON_SCHEMA_URL = "https://raw.githubusercontent.com/xyproto/archlog/main/changelog/schema/changelog-1.schema.json"
+
+//go:embed schema/changelog-1.schema.json
+var jsonSchema []byte
+
+// The JSON Schema of the json format
+func JSONSchema() []byte {
+	return bytes.Clone(jsonSchema)
+}
+
+// What generated a document in the json format, for Options.Metadata. It
+// changes with each version of archlog, so it is left out by default.
+type Metadata struct {
+	Generator  string            `json:"generator"`            // archlog and its version, like "archlog 0.7"
+	Parameters map[string]string `json:"parameters,omitempty"` // The flags that were given, by name, without the passwords and tokens
+}
+
+// A document in the json format that does not follow the JSON Schema
+type SchemaError struct {
+	Problems []string // Like "sections[2].date: does not match ^[0-9]{4}-[0-9]{2}-[0-9]{2}$"
+}
+
+func (e *SchemaError) Error() string {
+	if len(e.Problems) == 1 {
+		return "The document does not follow the schema: " + e.Problems[0]
+	}
+	return fmt.Sprintf("The document does not follow the schema, there are %d problems:\n%s", len(e.Problems), strings.Join(e.Problems, "\n"))
+}
+
+// The part of JSON Schema that the schema of the json format uses
+type schemaNode struct {
+	Type       string                 `json:"type"`
+	Const      any                    `json:"const"`
+	Required   []string               `json:"required"`
+	Properties map[string]*schemaNode `json:"properties"`
+	Items      *schemaNode            `json:"items"`
+	Pattern    string                 `json:"pattern"`
+	Minimum    *float64               `json:"minimum"`
+}
+
+// Check that a document in the json format follows the JSON Schema.
+// Returns a ParseError if it is not JSON, and a SchemaError with all of
+// the problems if it does not follow the schema.
+func ValidateJSON(data []byte) error {
+	var schema schemaNode
+	if err := json.Unmarshal(jsonSchema, &schema); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var document any
+	if err := dec.Decode(&document); err != nil {
+		return &ParseError{Err: err}
+	}
+	var problems []string
+	schema.validate("", document, &problems)
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+// The name of a JSON value for the problems, like "object" or "string"
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "null"
+}
+
+// Check a value against the schema, and add the problems, with the path
+// to the value, like "sections[2].date"
+func (node *schemaNode) validate(path string, value any, problems *[]string) {
+	where := path
+	if where == "" {
+		where = "the document"
+	}
+	problem := func(format string, args ...any) {
+		*problems = append(*problems, where+": "+fmt.Sprintf(format, args...))
+	}
+	if node.Const != nil && fmt.Sprint(node.Const) != fmt.Sprint(value) {
+		problem("expected %v, got %v", node.Const, value)
+		return
+	}
+	if actual := jsonType(value); node.Type != "" && actual != node.Type && !(node.Type == "number" && actual == "integer") {
+		problem("expected %s, got %s", node.Type, actual)
+		return
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range node.Required {
+			if _, ok := v[name]; !ok {
+				problem("the field %q is missing", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := node.Properties[name]; ok {
+				property.validate(strings.TrimPrefix(path+"."+name, "."), v[name], problems)
+			}
+		}
+	case []any:
+		if node.Items != nil {
+			for i, item := range v {
+				node.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, problems)
+			}
+		}
+	case string:
+		if node.Pattern != "" {
+			if re, err := regexp.Compile(node.Pattern); err == nil && !re.MatchString(v) {
+				problem("%q does not match %s", v, node.Pattern)
+			}
+		}
+	case json.Number:
+		if f, err := v.Float64(); err == nil && node.Minimum != nil && f < *node.Minimum {
+			problem("%s is less than %v", v, *node.Minimum)
+		}
+	}
+}
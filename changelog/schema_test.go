@@ -0,0 +1,55 @@
+package changelog
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	var schema struct {
+		ID         string `json:"$id"`
+		Properties struct {
+			SchemaVersion struct {
+				Const int `json:"const"`
+			} `json:"schema_version"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(JSONSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.ID != JSON_SCHEMA_URL || schema.Properties.SchemaVersion.Const != JSON_SCHEMA_VERSION {
+		t.Fatalf("the schema does not match JSON_SCHEMA_URL and JSON_SCHEMA_VERSION: %+v", schema)
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	valid := `{"schema_version": 1, "sections": [{"date": "2024-03-01", "name": "alice", "author": "alice", "messages": ["Initial import"], "revisions": [1], "future": true}]}`
+	if err := ValidateJSON([]byte(valid)); err != nil {
+		t.Fatalf("expected a valid document, got %v", err)
+	}
+	var parseErr *ParseError
+	if err := ValidateJSON([]byte(`{"sections": [`)); !errors.As(err, &parseErr) {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+	invalid := `{"schema_version": 2, "sections": [{"date": "March 1", "name": "alice", "messages": "Initial import", "revisions": [-1]}]}`
+	var schemaErr *SchemaError
+	if err := ValidateJSON([]byte(invalid)); !errors.As(err, &schemaErr) {
+		t.Fatalf("expected a schema error, got %v", err)
+	}
+	expected := []string{
+		"schema_version: expected 1, got 2",
+		`sections[0]: the field "author" is missing`,
+		`sections[0].date: "March 1" does not match ^[0-9]{4}-[0-9]{2}-[0-9]{2}$`,
+		"sections[0].messages: expected array, got string",
+		"sections[0].revisions[0]: -1 is less than 0",
+	}
+	if len(schemaErr.Problems) != len(expected) {
+		t.Fatalf("unexpected problems: %q", schemaErr.Problems)
+	}
+	for i := range expected {
+		if schemaErr.Problems[i] != expected[i] {
+			t.Fatalf("expected %q, got %q", expected[i], schemaErr.Problems[i])
+		}
+	}
+}
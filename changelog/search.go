@@ -0,0 +1,27 @@
+package changelog
+
+import (
+	_ "embed"
+	"fmt"
+	"html"
+	"io"
+)
+
+// The script that filters the sections and entries of the html format
+//
+//go:embed search.js
+var searchScript string
+
+// Write the form of the search, for Options.Search. It is hidden until the
+// script shows it.
+func writeSearchForm(w io.Writer, catalog *Catalog) error {
+	_, err := fmt.Fprintf(w, "<form class=\"search\" hidden>\n<input type=\"search\" name=\"text\" placeholder=\"%[1]s\" aria-label=\"%[1]s\">\n<input type=\"search\" name=\"author\" placeholder=\"%[2]s\" aria-label=\"%[2]s\">\n<label>%[3]s <input type=\"date\" name=\"from\"></label>\n<label>%[4]s <input type=\"date\" name=\"to\"></label>\n</form>\n",
+		html.EscapeString(catalog.Search), html.EscapeString(catalog.SearchAuthor), html.EscapeString(catalog.SearchFrom), html.EscapeString(catalog.SearchTo))
+	return err
+}
+
+// Write the script of the search, at the end of the body
+func writeSearchScript(w io.Writer) error {
+	_, err := io.WriteString(w, "<script>\n"+searchScript+"</script>\n")
+	return err
+}
@@ -0,0 +1,34 @@
+// Filters the sections and entries of a ChangeLog in the html format by
+// text, author and date, for Options.Search. The form is hidden until this
+// runs, so the page works the same without JavaScript.
+(function () {
+  var form = document.querySelector("form.search");
+  var headers = document.querySelectorAll("h2[data-date]");
+  function filter() {
+    var text = form.elements.text.value.toLowerCase();
+    var author = form.elements.author.value.toLowerCase();
+    var from = form.elements.from.value;
+    var to = form.elements.to.value;
+    for (var i = 0; i < headers.length; i++) {
+      var header = headers[i];
+      var list = header.nextElementSibling;
+      var date = header.getAttribute("data-date");
+      var show = (!from || date >= from) && (!to || date <= to) &&
+        header.getAttribute("data-author").toLowerCase().indexOf(author) >= 0;
+      var shown = 0;
+      for (var j = 0; j < list.children.length; j++) {
+        var item = list.children[j];
+        item.hidden = !(show && item.textContent.toLowerCase().indexOf(text) >= 0);
+        if (!item.hidden) {
+          shown++;
+        }
+      }
+      header.hidden = list.hidden = shown === 0;
+    }
+  }
+  form.addEventListener("input", filter);
+  form.addEventListener("submit", function (event) {
+    event.preventDefault();
+  });
+  form.hidden = false;
+})();
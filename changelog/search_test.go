@@ -0,0 +1,36 @@
+package changelog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	sections := []*Section{{Date: "2024-03-01", Name: "Alice A <alice@example.org>", Author: "alice", Messages: []string{"Initial import"}, Revisions: []int{1}}}
+	if got := formatSections(t, "html", sections); strings.Contains(got, "<script>") || strings.Contains(got, "data-date") {
+		t.Fatalf("expected no search by default, got:\n%s", got)
+	}
+	for _, lang := range LanguageNames() {
+		f, err := NewFormatter("html", &Options{Search: true, Language: lang})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		f.Begin(&buf)
+		f.Entry(&buf, sections[0])
+		f.End(&buf)
+		got := buf.String()
+		catalog, _ := LookupLanguage(lang)
+		for _, want := range []string{
+			`<form class="search" hidden>` + "\n" + `<input type="search" name="text" placeholder="` + catalog.Search + `"`,
+			`<label>` + catalog.SearchTo + ` <input type="date" name="to"></label>`,
+			`<h2 data-date="2024-03-01" data-author="Alice A alice">`,
+			"<script>\n" + searchScript + "</script>\n</body>",
+		} {
+			if !strings.Contains(got, want) {
+				t.Fatalf("expected %q in the %s output:\n%s", want, lang, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A version control system that log entries can be fetched from
+type Source interface {
+	// Fetch the log entries for the working copy in opts.Repo, ordered from
+	// the newest to the oldest. Errors that happen while iterating are
+	// yielded together with an empty Entry.
+	Entries(ctx context.Context, opts *Options) (iter.Seq2[Entry, error], error)
+}
+
+// A Source with a working copy that can be updated and committed to, for
+// keeping a ChangeLog in the repository up to date
+type WorkingCopy interface {
+	// Update the working copy in opts.Repo to the newest revision
+	Update(ctx context.Context, opts *Options) error
+	// Commit the file in the working copy, and push it if needed. Nothing is
+	// committed if the file has not changed.
+	Commit(ctx context.Context, opts *Options, filename, message string) error
+}
+
+// A Source with hooks that can run archlog after each commit
+type HookSource interface {
+	// The names of the hooks that run after a commit, the default one first
+	Hooks() []string
+	// The directory of the hooks for the repository of the working copy in opts.Repo
+	HooksDir(ctx context.Context, opts *Options) (string, error)
+}
+
+// A Source with tags, for keeping the release notes of each release in an
+// annotated tag
+type TagSource interface {
+	// The commit of the tag, or "" if there is no such tag
+	TagCommit(ctx context.Context, opts *Options, name string) (string, error)
+	// The newest tag before ref and its revision number, or "" and 0 if there is none
+	PreviousTag(ctx context.Context, opts *Options, ref string) (string, int, error)
+	// Create the annotated tag for ref, or replace it if it is already there.
+	// The tag is signed with the key, if given, or with the default key if sign is set.
+	WriteTag(ctx context.Context, opts *Options, name, ref, message string, sign bool, key string) error
+}
+
+// A Source that can tell where the repository of a working copy is, for
+// recording which history a ChangeLog was generated from
+type LocatedSource interface {
+	// The URL of the repository of the working copy in opts.Repo
+	RepositoryURL(ctx context.Context, opts *Options) (string, error)
+}
+
+// A Source that can read the history of the files in the working copy, for
+// finding the version of a package at each revision, or which packages
+// each revision changed
+type FileSource interface {
+	// The revisions that changed the file, from the newest to the oldest,
+	// also from before the file was renamed, with only the Revision, the
+	// Commit and the File set
+	FileHistory(ctx context.Context, opts *Options, filename string) ([]Entry, error)
+	// The contents of the file at one of the revisions from FileHistory
+	FileAt(ctx context.Context, opts *Options, filename string, revision Entry) ([]byte, error)
+	// The files that each revision changed, relative to the working copy, by
+	// revision. Files outside of the working copy are left out.
+	ChangedFiles(ctx context.Context, opts *Options) (map[int][]string, error)
+}
+
+// A file or directory that was renamed or copied in a revision, with the
+// paths relative to the working copy
+type Rename struct {
+	From string
+	To   string
+	Copy bool // The From is still there after the revision
+}
+
+// A Source that can tell which files and directories were renamed, for
+// following the history of a package that was moved
+type RenameSource interface {
+	// The renames and copies, by revision. The ones from outside of the
+	// working copy are left out.
+	Renames(ctx context.Context, opts *Options) (map[int][]Rename, error)
+}
+
+// A Source that can find the revision at a date, for fetching only the
+// revisions since the newest entry in an existing ChangeLog
+type DateSource interface {
+	// A revision that all of the revisions from the date (YYYY-MM-DD) on
+	// are newer than, ideally the newest one before it, or 0 if there is none
+	RevisionBefore(ctx context.Context, opts *Options, date string) (int, error)
+}
+
+var (
+	sourcesMutex sync.Mutex
+	sources      = make(map[string]Source)
+)
+
+// Make a Source available by name, for Options.VCS and the -vcs flag.
+// Registering a name again replaces the previous Source.
+func RegisterSource(name string, source Source) {
+	sourcesMutex.Lock()
+	defer sourcesMutex.Unlock()
+	sources[name] = source
+}
+
+// Find a registered Source by name
+func LookupSource(name string) (Source, error) {
+	sourcesMutex.Lock()
+	defer sourcesMutex.Unlock()
+	source, ok := sources[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown version control system: %s (available: %s)", name, strings.Join(sourceNames(), ", "))
+	}
+	return source, nil
+}
+
+// The names of the registered sources, sorted
+func SourceNames() []string {
+	sourcesMutex.Lock()
+	defer sourcesMutex.Unlock()
+	return sourceNames()
+}
+
+func sourceNames() []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterSource("svn", svnSource{})
+	RegisterSource("git", gitSource{})
+	RegisterSource("mock", mockSource{})
+}
+
+// Yield all the entries in a slice
+func sliceEntries(entries []Entry) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		for _, entry := range entries {
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Parse a dump of either "svn log --xml" or the default "git log" output
+func ParseLog(data []byte) ([]Entry, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return ParseSvnLog(trimmed)
+	}
+	return ParseGitLog(trimmed)
+}
@@ -0,0 +1,125 @@
+package changelog
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Split the entries by the directories in the working copy that they
+// changed files in, for one ChangeLog per package in a repository with
+// many packages. The directories are relative to the working copy, with
+// forward slashes. An entry that changed several of the directories is in
+// each of them, and an entry that changed none of them is left out. If the
+// Source can tell, the directories are followed back through their renames,
+// so that the entries from before a package was moved are not left out.
+func (g *Generator) SplitEntries(ctx context.Context, entries []Entry, dirs []string) (map[string][]Entry, error) {
+	source, err := g.source()
+	if err != nil {
+		return nil, err
+	}
+	files, ok := source.(FileSource)
+	if !ok {
+		return nil, fmt.Errorf("The changed files can not be found with -vcs %s", g.Options.VCS)
+	}
+	stop := g.Options.Timings.Start("fetch")
+	changed, err := files.ChangedFiles(ctx, g.Options)
+	stop()
+	if err != nil {
+		return nil, err
+	}
+	var renames map[int][]Rename
+	if renamed, ok := source.(RenameSource); ok {
+		stop := g.Options.Timings.Start("fetch")
+		renames, err = renamed.Renames(ctx, g.Options)
+		stop()
+		if err != nil {
+			return nil, err
+		}
+	}
+	split := make(map[string][]Entry, len(dirs))
+	for _, dir := range dirs {
+		names := dirNames(dir, renames)
+		for _, entry := range entries {
+			for _, name := range names {
+				if entry.Revision > name.after && entry.Revision < name.before && changesDir(changed[entry.Revision], name.dir) {
+					split[dir] = append(split[dir], entry)
+					break
+				}
+			}
+		}
+	}
+	return split, nil
+}
+
+// A name that a directory had, in the revisions between the one that
+// moved another directory away from the name and the one that renamed it
+type dirName struct {
+	dir    string
+	after  int
+	before int
+}
+
+// The names of a directory, from the current one to the oldest one, by
+// following the renames back from the newest revision. Renames of the
+// files in it, like "old/PKGBUILD" to "new/PKGBUILD", also count as renames
+// of the directory, since git only tracks files. A name that was moved
+// away and then used again, like for a new package with the old name of
+// another one, only has the revisions since it was used again.
+func dirNames(dir string, renames map[int][]Rename) []dirName {
+	dir = path.Clean(dir)
+	names := []dirName{{dir, 0, math.MaxInt}}
+	if dir == "." {
+		return names
+	}
+	revisions := make([]int, 0, len(renames))
+	for revision := range renames {
+		revisions = append(revisions, revision)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(revisions)))
+	known := map[string]bool{dir: true}
+	for _, revision := range revisions {
+		for i := range names {
+			if revision >= names[i].before || revision <= names[i].after {
+				continue
+			}
+			for _, rename := range renames[revision] {
+				if from := renamedFrom(rename, names[i].dir); from != "" && !known[from] {
+					known[from] = true
+					names = append(names, dirName{from, 0, revision})
+				} else if to := renamedFrom(Rename{From: rename.To, To: rename.From}, names[i].dir); to != "" && to != names[i].dir && !rename.Copy {
+					// The history of the name before this belongs to where it was moved to
+					names[i].after = revision
+				}
+			}
+		}
+	}
+	return names
+}
+
+// The name the directory had before the rename, or "" if the rename was not of the directory
+func renamedFrom(rename Rename, dir string) string {
+	from, to := path.Clean(rename.From), path.Clean(rename.To)
+	if to == dir {
+		return from
+	}
+	suffix, ok := strings.CutPrefix(to, dir+"/")
+	if !ok || !strings.HasSuffix(from, "/"+suffix) {
+		return ""
+	}
+	return strings.TrimSuffix(from, "/"+suffix)
+}
+
+// Check if any of the files are in the directory, or are the directory
+func changesDir(files []string, dir string) bool {
+	dir = path.Clean(dir)
+	for _, file := range files {
+		if file == dir || strings.HasPrefix(file, dir+"/") || dir == "." {
+			return true
+		}
+	}
+	return false
+}
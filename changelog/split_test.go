@@ -0,0 +1,163 @@
+package changelog
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitEntries(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	opts := &Options{Repo: dir, VCS: "git", Entries: -1}
+	git := func(args ...string) {
+		if _, err := runGit(ctx, opts, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(message string, filenames ...string) {
+		for _, filename := range filenames {
+			filename = filepath.Join(dir, filepath.FromSlash(filename))
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filename, []byte(message), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", "-A")
+		git("commit", "--quiet", "-m", message)
+	}
+	git("init", "--quiet")
+	git("config", "user.name", "Alice A")
+	git("config", "user.email", "alice@example.org")
+	commit("Add foo", "foo/PKGBUILD")
+	commit("Add foobar", "foobar/PKGBUILD")
+	commit("Upgrade both", "foo/PKGBUILD", "foobar/PKGBUILD")
+	commit("Add a README", "README")
+
+	g := New(opts)
+	g.Names.Resolver = AuthorsFile{}
+	entries, err := g.Entries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	split, err := g.SplitEntries(ctx, entries, []string{"foo", "foobar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(split["foo"]) != 2 || split["foo"][0].Revision != 3 || split["foo"][1].Revision != 1 {
+		t.Fatalf("unexpected entries for foo: %+v", split["foo"])
+	}
+	if len(split["foobar"]) != 2 || split["foobar"][1].Revision != 2 {
+		t.Fatalf("unexpected entries for foobar: %+v", split["foobar"])
+	}
+}
+
+func TestSplitEntriesRenamed(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	opts := &Options{Repo: dir, VCS: "git", Entries: -1}
+	git := func(args ...string) {
+		if _, err := runGit(ctx, opts, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(message string, filenames ...string) {
+		for _, filename := range filenames {
+			filename = filepath.Join(dir, filepath.FromSlash(filename))
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filename, []byte("pkgname="+message+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", "-A")
+		git("commit", "--quiet", "-m", message)
+	}
+	git("init", "--quiet")
+	git("config", "user.name", "Alice A")
+	git("config", "user.email", "alice@example.org")
+	commit("Add foo", "foo/PKGBUILD")
+	commit("Upgrade foo", "foo/PKGBUILD")
+	git("mv", "foo", "bar")
+	git("commit", "--quiet", "-m", "Rename foo to bar")
+	commit("Upgrade bar", "bar/PKGBUILD")
+	commit("Add a new foo", "foo/PKGBUILD")
+
+	g := New(opts)
+	g.Names.Resolver = AuthorsFile{}
+	entries, err := g.Entries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	split, err := g.SplitEntries(ctx, entries, []string{"foo", "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The history of bar goes back through the rename, to when it was foo
+	if len(split["bar"]) != 4 || split["bar"][0].Revision != 4 || split["bar"][3].Revision != 1 {
+		t.Fatalf("unexpected entries for bar: %+v", split["bar"])
+	}
+	// The new foo does not get the entries of the old one
+	if len(split["foo"]) != 1 || split["foo"][0].Revision != 5 {
+		t.Fatalf("unexpected entries for foo: %+v", split["foo"])
+	}
+
+	history, err := gitSource{}.FileHistory(ctx, opts, "bar/PKGBUILD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 4 || history[3].File != "foo/PKGBUILD" {
+		t.Fatalf("expected the history to follow the rename, got %+v", history)
+	}
+	contents, err := gitSource{}.FileAt(ctx, opts, "bar/PKGBUILD", history[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "pkgname=Add foo\n" {
+		t.Fatalf("unexpected contents from before the rename: %q", contents)
+	}
+}
+
+func TestDirNames(t *testing.T) {
+	renames := map[int][]Rename{
+		3: {{From: "old/PKGBUILD", To: "new/PKGBUILD"}},
+		2: {{From: "older", To: "old"}},
+		1: {{From: "other/PKGBUILD", To: "unrelated/PKGBUILD"}},
+	}
+	names := dirNames("new", renames)
+	if len(names) != 3 || names[1] != (dirName{"old", 0, 3}) || names[2] != (dirName{"older", 0, 2}) {
+		t.Fatalf("unexpected names: %+v", names)
+	}
+	// A directory with the name that another one was moved away from
+	names = dirNames("old", renames)
+	if len(names) != 1 || names[0] != (dirName{"old", 3, math.MaxInt}) {
+		t.Fatalf("unexpected names for a reused name: %+v", names)
+	}
+	// A copy does not move the history away
+	renames[3][0].Copy = true
+	names = dirNames("old", renames)
+	if len(names) != 2 || names[0] != (dirName{"old", 0, math.MaxInt}) {
+		t.Fatalf("unexpected names for a copied directory: %+v", names)
+	}
+}
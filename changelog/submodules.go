@@ -0,0 +1,115 @@
+package changelog
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A change of the commit of a submodule, in a commit of the superproject
+type submoduleUpdate struct {
+	path string // The path of the submodule in the superproject
+	from string // The commit of the submodule before
+	to   string // The commit of the submodule after
+}
+
+// The paths of the submodules in .gitmodules, or none if there is no .gitmodules
+func gitSubmodulePaths(ctx context.Context, opts *Options) (map[string]bool, error) {
+	if _, err := os.Stat(filepath.Join(opts.Repo, ".gitmodules")); err != nil {
+		return nil, nil
+	}
+	output, err := runGit(ctx, opts, "config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.path$`)
+	if err != nil && strings.Contains(err.Error(), "exit status 1") {
+		// There are no submodules in it
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		// Like "submodule.libfoo.path libs/foo"
+		if fields := strings.SplitN(line, " ", 2); len(fields) == 2 {
+			paths[strings.TrimSpace(fields[1])] = true
+		}
+	}
+	return paths, nil
+}
+
+// The submodule updates of the n newest commits in the history of ref, by
+// commit, found with "git log --raw", where the submodules have the mode
+// 160000
+func gitSubmoduleUpdates(ctx context.Context, opts *Options, ref string, n int) (map[string][]submoduleUpdate, error) {
+	output, err := runGitHistory(ctx, opts, "log", "--diff-merges=first-parent", "-n", strconv.Itoa(n), "--raw", "--no-abbrev", "--format=%x1e%H", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	updates := make(map[string][]submoduleUpdate)
+	// Each commit starts with a record separator, so the first record is empty
+	for _, record := range strings.Split(string(output), "\x1e")[1:] {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		commit := lines[0]
+		for _, line := range lines[1:] {
+			// Like ":160000 160000 <from> <to> M\tlibs/foo"
+			meta, path, ok := strings.Cut(line, "\t")
+			fields := strings.Fields(meta)
+			if !ok || len(fields) != 5 || fields[0] != ":160000" || fields[1] != "160000" {
+				continue
+			}
+			updates[commit] = append(updates[commit], submoduleUpdate{path: path, from: fields[2], to: fields[3]})
+		}
+	}
+	return updates, nil
+}
+
+// Add the commits of the submodules that each of the entries updated to
+// its message, one line for each, like "libs/foo: Fix the build", from
+// the oldest to the newest. Only the submodules in .gitmodules that are
+// checked out have the commits, so the other ones are left out.
+func addSubmoduleCommits(ctx context.Context, opts *Options, ref string, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	// The paths are from the top of the repository
+	top, err := runGit(ctx, opts, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	topOpts := *opts
+	topOpts.Repo = strings.TrimSpace(string(top))
+	opts = &topOpts
+	paths, err := gitSubmodulePaths(ctx, opts)
+	if err != nil || len(paths) == 0 {
+		return err
+	}
+	updates, err := gitSubmoduleUpdates(ctx, opts, ref, len(entries))
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		var lines []string
+		for _, update := range updates[entry.Commit] {
+			if !paths[update.path] {
+				continue
+			}
+			submodule := *opts
+			submodule.Repo = filepath.Join(opts.Repo, filepath.FromSlash(update.path))
+			output, err := runGitHistory(ctx, &submodule, "log", "--reverse", "--format=%s", update.from+".."+update.to, "--")
+			if err != nil {
+				slog.Warn("Could not find the commits of a submodule, it may not be checked out", "path", update.path, "err", err)
+				continue
+			}
+			for _, subject := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+				if subject != "" {
+					lines = append(lines, update.path+": "+subject)
+				}
+			}
+		}
+		if len(lines) > 0 {
+			entries[i].Message = strings.TrimRight(entry.Message, "\n") + "\n" + strings.Join(lines, "\n") + "\n"
+		}
+	}
+	return nil
+}
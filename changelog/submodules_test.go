@@ -0,0 +1,54 @@
+package changelog
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubmodules(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	t.Setenv("GIT_AUTHOR_NAME", "Alice A")
+	t.Setenv("GIT_AUTHOR_EMAIL", "alice@example.org")
+	t.Setenv("GIT_COMMITTER_NAME", "Alice A")
+	t.Setenv("GIT_COMMITTER_EMAIL", "alice@example.org")
+	ctx := context.Background()
+	git := func(repo string, args ...string) {
+		if _, err := runGit(ctx, &Options{Repo: repo}, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lib, super := filepath.Join(dir, "lib"), filepath.Join(dir, "super")
+	for _, repo := range []string{lib, super} {
+		if err := os.Mkdir(repo, 0755); err != nil {
+			t.Fatal(err)
+		}
+		git(repo, "init", "--quiet")
+	}
+	git(lib, "commit", "--quiet", "--allow-empty", "-m", "Initial import of the library")
+	git(super, "-c", "protocol.file.allow=always", "submodule", "--quiet", "add", lib, "libs/foo")
+	git(super, "commit", "--quiet", "-m", "Add the library")
+	checkout := filepath.Join(super, "libs", "foo")
+	git(checkout, "commit", "--quiet", "--allow-empty", "-m", "Fix the build")
+	git(checkout, "commit", "--quiet", "--allow-empty", "-m", "Add a feature")
+	git(super, "commit", "--quiet", "-a", "-m", "Update the library")
+
+	entries, err := New(&Options{Repo: super, VCS: "git", Entries: -1, Submodules: true}).Entries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Update the library\nlibs/foo: Fix the build\nlibs/foo: Add a feature\n"
+	if len(entries) != 2 || entries[0].Message != expected || entries[1].Message != "Add the library\n" {
+		t.Fatalf("expected the commits of the submodule in the message, got %+v", entries)
+	}
+}
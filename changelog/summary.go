@@ -0,0 +1,133 @@
+package changelog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The non-fatal issues of a run, for showing them together at the end
+type Summary struct {
+	Entries       int      `json:"entries"`                // The number of fetched log entries
+	Unresolved    []string `json:"unresolved"`             // The nicks that could not be resolved, sorted
+	EmptyMessages []int    `json:"empty_messages"`         // The revisions that were skipped because of empty messages
+	FailedLookups int      `json:"failed_lookups"`         // The number of web lookups that failed because of the network
+	LookupError   string   `json:"lookup_error,omitempty"` // The last error from a failed web lookup
+
+	Metadata *Metadata `json:"metadata,omitempty"` // What generated the ChangeLog, with Options.Metadata
+}
+
+// Summarize the issues with the given entries, after they have been written
+func (g *Generator) Summary(entries []Entry) *Summary {
+	s := g.Summarizer()
+	s.Add(entries...)
+	return s.Summary()
+}
+
+// Collects what the Summary needs from the entries while they are written
+// with WriteStream, so that they do not have to be kept in memory. It can
+// be used by several goroutines at once.
+type Summarizer struct {
+	g       *Generator
+	mu      sync.Mutex
+	entries int
+	seen    map[string]bool
+	authors []string
+	empty   []int
+}
+
+// A Summarizer for the entries that are written with this Generator
+func (g *Generator) Summarizer() *Summarizer {
+	return &Summarizer{g: g, seen: make(map[string]bool)}
+}
+
+// Add entries to the summary
+func (s *Summarizer) Add(entries ...Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range entries {
+		s.entries++
+		if !s.seen[entry.Author] {
+			s.seen[entry.Author] = true
+			s.authors = append(s.authors, entry.Author)
+		}
+		if s.g.Options.Normalization.Apply(strings.TrimSpace(Sanitize(entry.Message, true))) == "" {
+			s.empty = append(s.empty, entry.Revision)
+		}
+	}
+}
+
+// Pass the entries on, while adding them to the summary
+func (s *Summarizer) Stream(entries iter.Seq2[Entry, error]) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		for entry, err := range entries {
+			if err == nil {
+				s.Add(entry)
+			}
+			if !yield(entry, err) {
+				return
+			}
+		}
+	}
+}
+
+// The Summary of the entries so far, after they have been written, when
+// the names of the authors have been looked up
+func (s *Summarizer) Summary() *Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.g
+	summary := &Summary{Entries: s.entries, Unresolved: []string{}, EmptyMessages: append([]int{}, s.empty...), Metadata: g.Options.Metadata}
+	for _, author := range s.authors {
+		if g.Names.Unresolved(author) {
+			summary.Unresolved = append(summary.Unresolved, Sanitize(author, false))
+		}
+	}
+	sort.Strings(summary.Unresolved)
+	var networkErr *NetworkError
+	if errors.As(g.Names.NetworkError(), &networkErr) {
+		summary.FailedLookups = networkErr.Failures
+		summary.LookupError = networkErr.Err.Error()
+	}
+	return summary
+}
+
+// Check if there is anything to warn about
+func (s *Summary) Empty() bool {
+	return len(s.Unresolved) == 0 && len(s.EmptyMessages) == 0 && s.FailedLookups == 0
+}
+
+// Choose between the singular and the plural form
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// Write the summary as text, with one line per kind of issue
+func (s *Summary) WriteText(w io.Writer) error {
+	if s.Empty() {
+		return nil
+	}
+	lines := []string{"Summary:"}
+	if n := len(s.Unresolved); n > 0 {
+		lines = append(lines, fmt.Sprintf("  %s could not be resolved: %s", plural(n, "nick", "nicks"), strings.Join(s.Unresolved, ", ")))
+	}
+	if n := len(s.EmptyMessages); n > 0 {
+		revisions := make([]string, n)
+		for i, revision := range s.EmptyMessages {
+			revisions[i] = fmt.Sprintf("r%d", revision)
+		}
+		lines = append(lines, fmt.Sprintf("  %s skipped because of empty messages: %s", plural(n, "entry was", "entries were"), strings.Join(revisions, ", ")))
+	}
+	if s.FailedLookups > 0 {
+		lines = append(lines, fmt.Sprintf("  %s failed, the last one with: %s", plural(s.FailedLookups, "web lookup", "web lookups"), s.LookupError))
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
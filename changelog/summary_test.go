@@ -0,0 +1,28 @@
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	g := New(nil)
+	g.Names.Resolver = AuthorsFile{"alice": {Name: "Alice A", Email: "alice@example.org"}}
+	entries := []Entry{{Revision: 3, Author: "bob", Message: "Fix"}, {Revision: 2, Author: "alice", Message: " "}, {Revision: 1, Author: "alice", Message: "Import"}}
+	if err := g.Write(context.Background(), &bytes.Buffer{}, entries); err != nil {
+		t.Fatal(err)
+	}
+	s := g.Summary(entries)
+	if len(s.Unresolved) != 1 || s.Unresolved[0] != "bob" || len(s.EmptyMessages) != 1 || s.EmptyMessages[0] != 2 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	var buf bytes.Buffer
+	if err := s.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "1 nick could not be resolved: bob") || !strings.Contains(buf.String(), "1 entry was skipped because of empty messages: r2") {
+		t.Fatalf("unexpected summary text:\n%s", buf.String())
+	}
+}
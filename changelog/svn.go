@@ -0,0 +1,723 @@
+package changelog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Used when parsing svn log xml
+type svnLogEntry struct {
+	Revision string `xml:"revision,attr"`
+	Author   string `xml:"author"`
+	Date     string `xml:"date"`
+	Msg      string `xml:"msg"`
+}
+
+// Find the svn executable, either the given one or "svn" in the PATH.
+// On Windows, LookPath also finds "svn.exe".
+func FindSvn(svnBin string) (string, error) {
+	if svnBin == "" {
+		svnBin = "svn"
+	}
+	path, err := exec.LookPath(svnBin)
+	if err != nil {
+		return "", &VCSError{Err: fmt.Errorf("Could not find svn, install Subversion or use -svn-bin (%s)", err)}
+	}
+	return path, nil
+}
+
+// svn could not find the given revision
+var errNoSuchRevision = errors.New("No such revision")
+
+// The svn arguments for the authentication options. The password is
+// written to the standard input of svn, so that it is not shown in the
+// process list.
+func svnAuthArgs(opts *Options) []string {
+	var args []string
+	if opts.SvnUsername != "" {
+		args = append(args, "--username", opts.SvnUsername)
+	}
+	if opts.SvnPassword != "" {
+		// Don't let svn store the password
+		args = append(args, "--password-from-stdin", "--no-auth-cache")
+	}
+	if opts.NonInteractive || opts.TrustServerCert {
+		args = append(args, "--non-interactive")
+	}
+	if opts.TrustServerCert {
+		args = append(args, "--trust-server-cert-failures=unknown-ca,cn-mismatch,expired,not-yet-valid,other")
+	}
+	return args
+}
+
+// Start svn with the given arguments in the working copy. Returns the
+// output, and a function that waits for svn to finish, once all of the
+// output has been read.
+func startSvn(ctx context.Context, opts *Options, args ...string) (io.Reader, func() error, error) {
+	svn, err := FindSvn(opts.SvnBin)
+	if err != nil {
+		return nil, nil, err
+	}
+	cancel := func() {}
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+	cmd := exec.CommandContext(ctx, svn, append(svnAuthArgs(opts), args...)...)
+	if opts.SvnPassword != "" {
+		cmd.Stdin = strings.NewReader(opts.SvnPassword + "\n")
+	}
+	shown := strings.Join(cmd.Args, " ")
+	// Run svn in the working copy, or in the current directory if it is empty
+	cmd.Dir = opts.Repo
+	// Don't wait for long for any child processes that keep the output open, once canceled
+	cmd.WaitDelay = time.Second
+	slog.Debug("Running "+shown, "dir", opts.Repo)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		cancel()
+		return nil, nil, &VCSError{Err: fmt.Errorf("Error running: %s (%s)", shown, err.Error())}
+	}
+	wait := func() error {
+		defer cancel()
+		err := cmd.Wait()
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(*exec.ExitError); ok && strings.Contains(stderr.String(), "E160006") {
+			return errNoSuchRevision
+		}
+		if ctx.Err() == context.Canceled {
+			return ctx.Err()
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return &VCSError{Err: fmt.Errorf("Timed out after %s: %s", opts.Timeout, shown)}
+		}
+		if strings.Contains(stderr.String(), "155007") {
+			// E155007 or W155007: not a working copy
+			return &NoRepositoryError{Dir: opts.Repo}
+		}
+		// Return an error
+		return &VCSError{Err: fmt.Errorf("Error running: %s (%s): %s", shown, err.Error(), strings.TrimSpace(stderr.String()))}
+	}
+	return stdout, wait, nil
+}
+
+// Run svn with the given arguments in the working copy, and return the output
+func runSvn(ctx context.Context, opts *Options, args ...string) ([]byte, error) {
+	stdout, wait, err := startSvn(ctx, opts, args...)
+	if err != nil {
+		return nil, err
+	}
+	output, readErr := io.ReadAll(stdout)
+	if err := wait(); err != nil {
+		return nil, err
+	} else if readErr != nil {
+		return nil, &VCSError{Err: readErr}
+	}
+	return output, nil
+}
+
+// Decode the svn log xml while it is being read, and pass each entry to
+// yield, until it returns false. In lenient mode, the malformed parts of
+// the log are skipped, and decoding goes on from the next entry.
+func decodeSvnLog(r io.Reader, mode ParseMode, yield func(Entry) bool) error {
+	lr := &lineReader{r: bufio.NewReader(r), line: 1}
+	foundLog, skipped := false, false
+	for {
+		decoder := xml.NewDecoder(lr)
+		// The log has already been converted to UTF-8, whatever it says its encoding is
+		decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+		stopped, err := decodeSvnEntries(decoder, mode, &foundLog, yield)
+		if stopped {
+			return nil
+		}
+		if err == nil {
+			if !foundLog {
+				return &ParseError{Err: errors.New("This is not an svn log")}
+			}
+			return nil
+		}
+		// The line numbers of the decoder start over after skipping ahead,
+		// so only the line number of lr is used
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			err = errors.New(syntaxErr.Msg)
+		}
+		parseErr := &ParseError{Line: lr.line, Err: err}
+		if mode != PARSE_LENIENT {
+			return parseErr
+		}
+		found, readErr := lr.skipTo("<logentry")
+		if readErr != nil {
+			return &ParseError{Line: lr.line, Err: readErr}
+		}
+		if !found {
+			if !skipped || !strayLogEnd(err) {
+				slog.Warn("Skipped the malformed end of the svn log", "err", parseErr)
+			}
+			return nil
+		}
+		slog.Warn("Skipped a malformed part of the svn log", "err", parseErr)
+		foundLog, skipped = true, true
+	}
+}
+
+// Decode svn log entries with the decoder until the end, an error or until
+// yield returns false, which returns true. foundLog is set when the start
+// of the log is found.
+func decodeSvnEntries(decoder *xml.Decoder, mode ParseMode, foundLog *bool, yield func(Entry) bool) (bool, error) {
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		start, ok := token.(xml.StartElement)
+		if ok && start.Name.Local == "log" {
+			*foundLog = true
+		}
+		if !ok || start.Name.Local != "logentry" {
+			continue
+		}
+		*foundLog = true
+		var logentry svnLogEntry
+		if err := decoder.DecodeElement(&logentry, &start); err != nil {
+			return false, err
+		}
+		entry, err := svnToEntry(logentry, mode)
+		if err != nil {
+			return false, err
+		}
+		if !yield(entry) {
+			return true, nil
+		}
+	}
+}
+
+// Stream the entries from "svn log --xml" for the given revision range,
+// like "HEAD:1", as svn outputs them. limit is the maximum number of
+// entries, or -1 for all. The entries are counted in count, for the progress.
+func streamSvnLog(ctx context.Context, opts *Options, revisions string, limit int, count *atomic.Int64) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		args := []string{"log", "--xml", "-r", revisions}
+		if limit != -1 {
+			args = append(args, "--limit", strconv.Itoa(limit))
+		}
+		if opts.StopOnCopy {
+			args = append(args, "--stop-on-copy")
+		}
+		target, err := svnTargetArgs(ctx, opts, "")
+		if err != nil {
+			yield(Entry{}, err)
+			return
+		}
+		args = append(args, target...)
+		stdout, wait, err := startSvn(ctx, opts, args...)
+		if err != nil {
+			yield(Entry{}, err)
+			return
+		}
+		// The time spent waiting for svn is "fetch", and the rest of the
+		// time spent decoding is "parse"
+		var (
+			stopped  bool
+			consumer time.Duration
+			reader   = &timedReader{r: stdout}
+			start    = time.Now()
+		)
+		encoding, _ := CheckEncoding(opts.InputEncoding)
+		decodeErr := decodeSvnLog(newUTF8Reader(reader, encoding), opts.Parsing, func(entry Entry) bool {
+			opts.progress("Fetching the log", int(count.Add(1)), 0)
+			yielded := time.Now()
+			stopped = !yield(entry, nil)
+			consumer += time.Since(yielded)
+			return !stopped
+		})
+		opts.Timings.Add("fetch", reader.waiting)
+		opts.Timings.Add("parse", time.Since(start)-reader.waiting-consumer)
+		if stopped {
+			// No more entries are wanted, so stop svn
+			cancel()
+			wait()
+			return
+		}
+		// Read the rest of the output, if it could not be parsed, so that svn can finish
+		io.Copy(io.Discard, stdout)
+		err = wait()
+		if err == errNoSuchRevision && opts.FromRevision > 0 {
+			// There are no revisions newer than the given one
+			return
+		} else if err == errNoSuchRevision {
+			err = &VCSError{Err: err}
+		}
+		if err == nil {
+			err = decodeErr
+		}
+		if err != nil {
+			yield(Entry{}, err)
+		}
+	}
+}
+
+// Used when parsing svn info xml
+type svnInfo struct {
+	Entry struct {
+		Revision string `xml:"revision,attr"`
+	} `xml:"entry"`
+}
+
+// Find the newest revision in the repository
+func svnHead(ctx context.Context, opts *Options) (int, error) {
+	stdout, wait, err := startSvn(ctx, opts, "info", "--xml", "-r", "HEAD")
+	if err != nil {
+		return 0, err
+	}
+	data, readErr := io.ReadAll(stdout)
+	if err := wait(); err != nil {
+		return 0, err
+	} else if readErr != nil {
+		return 0, &VCSError{Err: readErr}
+	}
+	var info svnInfo
+	if err := xml.Unmarshal(data, &info); err != nil {
+		return 0, &VCSError{Err: fmt.Errorf("Could not parse the svn info: %w", err)}
+	}
+	head, err := strconv.Atoi(info.Entry.Revision)
+	if err != nil {
+		return 0, &VCSError{Err: fmt.Errorf("Could not find the HEAD revision: %w", err)}
+	}
+	return head, nil
+}
+
+// The number of revisions that each concurrent svn log invocation fetches
+const SVN_CHUNK_SIZE = 1000
+
+// Split the revisions from "from" up to "head" into ranges for svn log,
+// ordered from the newest to the oldest, like "2000:1001" and "1000:1"
+func svnChunks(from, head, size int) []string {
+	var chunks []string
+	for high := head; high >= from; high -= size {
+		low := high - size + 1
+		if low < from {
+			low = from
+		}
+		chunks = append(chunks, fmt.Sprintf("%d:%d", high, low))
+	}
+	return chunks
+}
+
+// Fetch the log in chunks with several concurrent svn log invocations,
+// and put the entries together in order
+func fetchSvnChunks(ctx context.Context, opts *Options) ([]Entry, error) {
+	head := svnPeg(opts)
+	if head == 0 {
+		var err error
+		if head, err = svnHead(ctx, opts); err != nil {
+			return nil, err
+		}
+	}
+	from := opts.FromRevision
+	if from < 1 {
+		from = 1
+	}
+	chunks := svnChunks(from, head, SVN_CHUNK_SIZE)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		count   atomic.Int64
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failure error
+		results = make([][]Entry, len(chunks))
+		next    = make(chan int)
+	)
+	for worker := 0; worker < opts.Jobs; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				for entry, err := range streamSvnLog(ctx, opts, chunks[i], -1, &count) {
+					if err != nil {
+						// Keep the first error, and stop the other invocations
+						mu.Lock()
+						if failure == nil {
+							failure = err
+						}
+						mu.Unlock()
+						cancel()
+						break
+					}
+					results[i] = append(results[i], entry)
+				}
+			}
+		}()
+	}
+	for i := range chunks {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if failure != nil {
+		return nil, failure
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, chunk := range results {
+		entries = append(entries, chunk...)
+	}
+	return entries, nil
+}
+
+// Convert a parsed svn log entry. In strict mode, an invalid revision or
+// date is an error.
+func svnToEntry(logentry svnLogEntry, mode ParseMode) (Entry, error) {
+	revision, err := strconv.Atoi(logentry.Revision)
+	if err != nil && mode == PARSE_STRICT {
+		return Entry{}, fmt.Errorf("Invalid revision: %q", logentry.Revision)
+	}
+	date, err := time.Parse(time.RFC3339Nano, logentry.Date)
+	if err != nil && mode == PARSE_STRICT {
+		return Entry{}, fmt.Errorf("Invalid date of r%s: %q", logentry.Revision, logentry.Date)
+	} else if err != nil {
+		slog.Debug("Could not parse the date of r"+logentry.Revision, "date", logentry.Date, "err", err)
+	}
+	return Entry{
+		Revision: revision,
+		Author:   logentry.Author,
+		Date:     date.UTC(),
+		Message:  logentry.Msg,
+	}, nil
+}
+
+// Fetches log entries with "svn log --xml"
+type svnSource struct{}
+
+// Use the "svn log --xml" command to fetch log entries for the working copy
+// in opts.Repo, or for opts.SvnPath, from opts.FromRevision and up to HEAD,
+// or up to the peg revision of opts.SvnPath. The entries are
+// passed on while svn outputs them. With opts.Jobs > 1, all the entries
+// are fetched in chunks concurrently instead, unless opts.StopOnCopy is
+// set, since only svn knows where the copy was. With opts.Externals, the
+// entries of the svn:externals are included too.
+func (svnSource) Entries(ctx context.Context, opts *Options) (iter.Seq2[Entry, error], error) {
+	if _, err := FindSvn(opts.SvnBin); err != nil {
+		return nil, err
+	}
+	opts, err := withNetrcLogin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Externals {
+		return svnExternalEntries(ctx, opts)
+	}
+	if opts.Since != "" {
+		// Only fetch the revisions from the date on, instead of the whole log
+		before, err := svnSource{}.RevisionBefore(ctx, opts, opts.Since)
+		if err != nil {
+			return nil, err
+		}
+		if before >= opts.FromRevision {
+			since := *opts
+			since.FromRevision = before + 1
+			opts = &since
+		}
+	}
+	if opts.Jobs > 1 && opts.Entries == -1 && !opts.StopOnCopy {
+		entries, err := fetchSvnChunks(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return sliceEntries(entries), nil
+	}
+	// Get the entries in reverse order by asking for revisions from HEAD, or
+	// the peg revision, to the first one
+	head := "HEAD"
+	if peg := svnPeg(opts); peg > 0 {
+		head = strconv.Itoa(peg)
+	}
+	var count atomic.Int64
+	return streamSvnLog(ctx, opts, fmt.Sprintf("%s:%d", head, opts.FromRevision), opts.Entries, &count), nil
+}
+
+// Update the working copy with "svn update"
+func (svnSource) Update(ctx context.Context, opts *Options) error {
+	opts, err := withNetrcLogin(ctx, opts)
+	if err != nil {
+		return err
+	}
+	_, err = runSvn(ctx, opts, "update", "--quiet")
+	return err
+}
+
+// Commit the file with "svn commit", after adding it if it is not
+// versioned yet. svn does not commit a file that has not changed.
+func (svnSource) Commit(ctx context.Context, opts *Options, filename, message string) error {
+	opts, err := withNetrcLogin(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if _, err := runSvn(ctx, opts, "add", "--force", "--quiet", "--", filename); err != nil {
+		return err
+	}
+	_, err = runSvn(ctx, opts, "commit", "--quiet", "-m", message, "--", filename)
+	return err
+}
+
+// svn runs archlog in the post-commit hook
+func (svnSource) Hooks() []string {
+	return []string{"post-commit"}
+}
+
+// The hooks directory of the repository, which is only found if the
+// repository is on this computer, with a file:// URL
+func (svnSource) HooksDir(ctx context.Context, opts *Options) (string, error) {
+	_, root, err := svnURLs(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	if root.Scheme != "file" {
+		return "", fmt.Errorf("The repository is at %s, install the hook on that server, in the hooks directory of the repository", root.Redacted())
+	}
+	path := root.Path
+	if runtime.GOOS == "windows" {
+		// file:///C:/repos has the path /C:/repos
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.Join(filepath.FromSlash(path), "hooks"), nil
+}
+
+// The URL of the working copy, without a password in it
+func (svnSource) RepositoryURL(ctx context.Context, opts *Options) (string, error) {
+	wc, _, err := svnURLs(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	return wc.Redacted(), nil
+}
+
+// The revisions that changed the file, with "svn log --quiet"
+func (svnSource) FileHistory(ctx context.Context, opts *Options, filename string) ([]Entry, error) {
+	opts, err := withNetrcLogin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	target, err := svnTargetArgs(ctx, opts, filename)
+	if err != nil {
+		return nil, err
+	}
+	output, err := runSvn(ctx, opts, append([]string{"log", "--xml", "--quiet", "--"}, target...)...)
+	if err != nil {
+		if strings.Contains(err.Error(), "E155010") || strings.Contains(err.Error(), "W155010") {
+			// The file is not in the working copy
+			return nil, nil
+		}
+		return nil, err
+	}
+	entries, err := ParseSvnLog(output)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i] = Entry{Revision: entries[i].Revision}
+	}
+	return entries, nil
+}
+
+// The contents of the file at the revision, with "svn cat"
+func (svnSource) FileAt(ctx context.Context, opts *Options, filename string, revision Entry) ([]byte, error) {
+	opts, err := withNetrcLogin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	target, err := svnTargetArgs(ctx, opts, filename)
+	if err != nil {
+		return nil, err
+	}
+	return runSvn(ctx, opts, append([]string{"cat", "-r", strconv.Itoa(revision.Revision), "--"}, target...)...)
+}
+
+// The newest revision before the date, which is the revision that svn
+// finds for {date}
+func (svnSource) RevisionBefore(ctx context.Context, opts *Options, date string) (int, error) {
+	opts, err := withNetrcLogin(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+	target, err := svnTargetArgs(ctx, opts, "")
+	if err != nil {
+		return 0, err
+	}
+	output, err := runSvn(ctx, opts, append([]string{"log", "--xml", "--quiet", "--limit", "1", "-r", "{" + date + "T00:00:00Z}:0"}, target...)...)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := ParseSvnLog(output)
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	return entries[0].Revision, nil
+}
+
+// The changed paths of each revision, as listed by "svn log --xml --verbose"
+type svnChangedPaths struct {
+	Entries []struct {
+		Revision int      `xml:"revision,attr"`
+		Paths    []string `xml:"paths>path"`
+	} `xml:"logentry"`
+}
+
+// The path of the working copy in the repository, like "/trunk/", that the
+// paths that svn lists start with, or the path of Options.SvnPath
+func svnPathPrefix(ctx context.Context, opts *Options) (string, error) {
+	wc, root, err := svnURLs(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	if target, _, err := svnTarget(ctx, opts); err != nil {
+		return "", err
+	} else if target != nil {
+		wc = target
+	}
+	prefix := strings.TrimSuffix(strings.TrimPrefix(wc.Path, root.Path), "/") + "/"
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix, nil
+}
+
+// The files that each revision changed, with "svn log --verbose --quiet".
+// svn lists the paths from the root of the repository, so the path of the
+// working copy is removed from them.
+func (svnSource) ChangedFiles(ctx context.Context, opts *Options) (map[int][]string, error) {
+	opts, err := withNetrcLogin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := svnPathPrefix(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	target, err := svnTargetArgs(ctx, opts, "")
+	if err != nil {
+		return nil, err
+	}
+	output, err := runSvn(ctx, opts, append([]string{"log", "--xml", "--verbose", "--quiet"}, target...)...)
+	if err != nil {
+		return nil, err
+	}
+	var log svnChangedPaths
+	if err := xml.Unmarshal(output, &log); err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	changed := make(map[int][]string, len(log.Entries))
+	for _, entry := range log.Entries {
+		var files []string
+		for _, path := range entry.Paths {
+			if strings.HasPrefix(path, prefix) {
+				files = append(files, strings.TrimPrefix(path, prefix))
+			}
+		}
+		changed[entry.Revision] = files
+	}
+	return changed, nil
+}
+
+// The changed paths of each revision, with the paths they were copied from
+type svnCopiedPaths struct {
+	Entries []struct {
+		Revision int `xml:"revision,attr"`
+		Paths    []struct {
+			Path     string `xml:",chardata"`
+			Action   string `xml:"action,attr"`
+			CopyFrom string `xml:"copyfrom-path,attr"`
+		} `xml:"paths>path"`
+	} `xml:"logentry"`
+}
+
+// The files and directories that each revision copied, with "svn log
+// --verbose --quiet". svn has no renames, so a rename is a copy, and the
+// old path is deleted in the same revision.
+func (svnSource) Renames(ctx context.Context, opts *Options) (map[int][]Rename, error) {
+	opts, err := withNetrcLogin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := svnPathPrefix(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	target, err := svnTargetArgs(ctx, opts, "")
+	if err != nil {
+		return nil, err
+	}
+	output, err := runSvn(ctx, opts, append([]string{"log", "--xml", "--verbose", "--quiet"}, target...)...)
+	if err != nil {
+		return nil, err
+	}
+	var log svnCopiedPaths
+	if err := xml.Unmarshal(output, &log); err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	renames := make(map[int][]Rename)
+	for _, entry := range log.Entries {
+		deleted := make(map[string]bool)
+		for _, path := range entry.Paths {
+			if path.Action == "D" {
+				deleted[path.Path] = true
+			}
+		}
+		for _, path := range entry.Paths {
+			if strings.HasPrefix(path.Path, prefix) && strings.HasPrefix(path.CopyFrom, prefix) {
+				renames[entry.Revision] = append(renames[entry.Revision], Rename{
+					From: strings.TrimPrefix(path.CopyFrom, prefix),
+					To:   strings.TrimPrefix(path.Path, prefix),
+					Copy: !deleted[path.CopyFrom],
+				})
+			}
+		}
+	}
+	return renames, nil
+}
+
+// Parse the output of "svn log --xml"
+func ParseSvnLog(xmlbytes []byte) ([]Entry, error) {
+	var entries []Entry
+	err := decodeSvnLog(newUTF8Reader(bytes.NewReader(xmlbytes), "auto"), PARSE_NORMAL, func(entry Entry) bool {
+		entries = append(entries, entry)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
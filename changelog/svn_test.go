@@ -0,0 +1,148 @@
+package changelog
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSvnChunks(t *testing.T) {
+	got := svnChunks(1, 2500, 1000)
+	expected := []string{"2500:1501", "1500:501", "500:1"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	if got := svnChunks(10, 9, 1000); len(got) != 0 {
+		t.Fatalf("expected no chunks when there are no new revisions, got %v", got)
+	}
+}
+
+func TestDecodeSvnLog(t *testing.T) {
+	const log = `<?xml version="1.0"?>
+<log>
+<logentry revision="2"><author>bob</author><date>2024-03-02T10:00:00.000000Z</date><msg>Fix the build</msg></logentry>
+<logentry revision="1"><author>alice</author><date>2024-03-01T09:00:00.000000Z</date><msg>Initial import</msg></logentry>
+</log>
+`
+	var revisions []int
+	err := decodeSvnLog(strings.NewReader(log), PARSE_NORMAL, func(entry Entry) bool {
+		revisions = append(revisions, entry.Revision)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(revisions, []int{2, 1}) {
+		t.Fatalf("expected revisions [2 1], got %v", revisions)
+	}
+	// Stop after the first entry, before the broken end is read
+	revisions = nil
+	err = decodeSvnLog(strings.NewReader(log[:strings.Index(log, "</log>")-20]), PARSE_NORMAL, func(entry Entry) bool {
+		revisions = append(revisions, entry.Revision)
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(revisions, []int{2}) {
+		t.Fatalf("expected revision [2], got %v", revisions)
+	}
+}
+
+func TestDecodeSvnLogModes(t *testing.T) {
+	const log = `<?xml version="1.0"?>
+<log>
+<logentry revision="3"><author>bob</author><date>2024-03-02T10:00:00.000000Z</date><msg>Fix the build</msg></logentry>
+<logentry revision="2"><author>alice</auth><date>2024-03-01T10:00:00.000000Z</date></logentry>
+<logentry revision="1"><author>alice</author><date>yesterday</date><msg>Initial import</msg></logentry>
+</log>
+`
+	decode := func(log string, mode ParseMode) ([]int, error) {
+		var revisions []int
+		err := decodeSvnLog(strings.NewReader(log), mode, func(entry Entry) bool {
+			revisions = append(revisions, entry.Revision)
+			return true
+		})
+		return revisions, err
+	}
+	// The malformed entry is an error, instead of an empty log
+	revisions, err := decode(log, PARSE_NORMAL)
+	parseErr, ok := err.(*ParseError)
+	if !ok || parseErr.Line != 4 {
+		t.Fatalf("expected a parse error at line 4, got %v", err)
+	}
+	if !reflect.DeepEqual(revisions, []int{3}) {
+		t.Fatalf("expected revision [3] before the error, got %v", revisions)
+	}
+	// The entries around the malformed one are kept in lenient mode
+	revisions, err = decode(log, PARSE_LENIENT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(revisions, []int{3, 1}) {
+		t.Fatalf("expected revisions [3 1], got %v", revisions)
+	}
+	// The invalid date is only an error in strict mode
+	valid := strings.Replace(log, "</auth>", "</author>", 1)
+	if _, err := decode(valid, PARSE_NORMAL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decode(valid, PARSE_STRICT); err == nil || !strings.Contains(err.Error(), "line 5") {
+		t.Fatalf("expected an error about the date at line 5, got %v", err)
+	}
+	if _, err := decode("<html></html>", PARSE_LENIENT); err == nil {
+		t.Fatal("expected an error for something that is not an svn log")
+	}
+}
+
+func TestSvnAuthArgs(t *testing.T) {
+	opts := &Options{SvnUsername: "bob", SvnPassword: "hunter2", TrustServerCert: true}
+	args := append(svnAuthArgs(opts), "log", "--xml")
+	expected := []string{"--username", "bob", "--password-from-stdin", "--no-auth-cache", "--non-interactive", "--trust-server-cert-failures=unknown-ca,cn-mismatch,expired,not-yet-valid,other", "log", "--xml"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected %v, got %v", expected, args)
+	}
+	if args := svnAuthArgs(&Options{}); len(args) != 0 {
+		t.Fatalf("expected no arguments without credentials, got %v", args)
+	}
+}
+
+func TestStopOnCopy(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the fake svn with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// An svn that writes its arguments to a file, and outputs one entry
+	svn := filepath.Join(dir, "svn")
+	script := "#!/bin/sh\necho \"$*\" > \"$(dirname \"$0\")/args\"\necho '<?xml version=\"1.0\"?><log><logentry revision=\"3\"><author>alice</author><date>2024-03-01T10:00:00.000000Z</date><msg>Branch</msg></logentry></log>'\n"
+	if err := ioutil.WriteFile(svn, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, stopOnCopy := range []bool{false, true} {
+		// The log is not fetched in chunks with StopOnCopy, since only svn knows where the copy was
+		opts := &Options{Repo: dir, SvnBin: svn, Entries: -1, StopOnCopy: stopOnCopy}
+		if stopOnCopy {
+			opts.Jobs = 4
+		}
+		entries, err := New(opts).Entries(context.Background())
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("expected one entry, got %+v, %v", entries, err)
+		}
+		args, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(args), "--stop-on-copy") != stopOnCopy {
+			t.Fatalf("unexpected arguments with StopOnCopy %v: %s", stopOnCopy, args)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package changelog
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The directories of the standard svn layout of a project
+var svnLayoutDirs = []string{"trunk", "branches", "tags"}
+
+// Split a path with an optional peg revision, like "branches/1.x@1234",
+// into the path and the revision, which is 0 for HEAD or no revision
+func SplitPegRevision(svnPath string) (string, int, error) {
+	i := strings.LastIndex(svnPath, "@")
+	if i < 0 || strings.Contains(svnPath[i:], "/") {
+		return svnPath, 0, nil
+	}
+	peg := svnPath[i+1:]
+	if peg == "" || strings.EqualFold(peg, "HEAD") {
+		return svnPath[:i], 0, nil
+	}
+	revision, err := strconv.Atoi(strings.TrimPrefix(peg, "r"))
+	if err != nil || revision < 1 {
+		return "", 0, fmt.Errorf("Invalid peg revision in %s, expected a revision number like @1234 or @HEAD", svnPath)
+	}
+	return svnPath[:i], revision, nil
+}
+
+// The URL of the project that the working copy is in, which is the
+// directory above trunk, branches or tags. A working copy that is not in
+// any of them is the project itself.
+func svnProjectURL(wc *url.URL) *url.URL {
+	project := *wc
+	segments := strings.Split(strings.TrimSuffix(wc.Path, "/"), "/")
+	for i := len(segments) - 1; i > 0; i-- {
+		for _, dir := range svnLayoutDirs {
+			if segments[i] == dir {
+				project.Path = strings.Join(segments[:i], "/")
+				return &project
+			}
+		}
+	}
+	return &project
+}
+
+// The URL of Options.SvnPath and its peg revision, which is 0 for HEAD.
+// The path is relative to the project of the working copy, like
+// "branches/1.x", or a URL, and "@1234" alone is the working copy at
+// that revision. The URL is nil without SvnPath.
+func svnTarget(ctx context.Context, opts *Options) (*url.URL, int, error) {
+	if opts.SvnPath == "" {
+		return nil, 0, nil
+	}
+	svnPath, peg, err := SplitPegRevision(opts.SvnPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	if strings.Contains(svnPath, "://") {
+		target, err := url.Parse(svnPath)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Invalid svn path: %w", err)
+		}
+		return target, peg, nil
+	}
+	wc, _, err := svnURLs(ctx, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if svnPath == "" {
+		return wc, peg, nil
+	}
+	target := svnProjectURL(wc)
+	target.Path = path.Join(target.Path, svnPath)
+	return target, peg, nil
+}
+
+// The argument for running svn on Options.SvnPath, or on a file in it,
+// instead of on the working copy, like "https://svn.example.org/repo/
+// archlog/branches/1.x/PKGBUILD@1234". The "@" is always there, so that
+// an "@" in the path is not taken for a peg revision. Without SvnPath,
+// it is the filename, or nothing.
+func svnTargetArgs(ctx context.Context, opts *Options, filename string) ([]string, error) {
+	target, peg, err := svnTarget(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		if filename == "" {
+			return nil, nil
+		}
+		return []string{filename}, nil
+	}
+	if filename != "" {
+		target.Path = path.Join(target.Path, filepath.ToSlash(filename))
+	}
+	arg := target.String() + "@"
+	if peg > 0 {
+		arg += strconv.Itoa(peg)
+	}
+	return []string{arg}, nil
+}
+
+// The peg revision of Options.SvnPath, which is the newest revision to
+// fetch the log from, or 0 for HEAD
+func svnPeg(opts *Options) int {
+	_, peg, err := SplitPegRevision(opts.SvnPath)
+	if err != nil {
+		return 0
+	}
+	return peg
+}
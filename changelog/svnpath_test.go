@@ -0,0 +1,91 @@
+package changelog
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitPegRevision(t *testing.T) {
+	for _, test := range []struct {
+		svnPath string
+		path    string
+		peg     int
+	}{
+		{"branches/1.x", "branches/1.x", 0},
+		{"branches/1.x@1234", "branches/1.x", 1234},
+		{"trunk@r42", "trunk", 42},
+		{"trunk@HEAD", "trunk", 0},
+		{"@7", "", 7},
+		{"tags/user@host/1.0", "tags/user@host/1.0", 0},
+	} {
+		path, peg, err := SplitPegRevision(test.svnPath)
+		if err != nil || path != test.path || peg != test.peg {
+			t.Fatalf("expected %q and %d for %q, got %q, %d and %v", test.path, test.peg, test.svnPath, path, peg, err)
+		}
+	}
+	if _, _, err := SplitPegRevision("trunk@yesterday"); err == nil {
+		t.Fatal("expected an error for an invalid peg revision")
+	}
+}
+
+func TestSvnProjectURL(t *testing.T) {
+	for wc, project := range map[string]string{
+		"https://svn.example.org/repos/archlog/trunk":         "https://svn.example.org/repos/archlog",
+		"https://svn.example.org/repos/archlog/trunk/src":     "https://svn.example.org/repos/archlog",
+		"https://svn.example.org/repos/archlog/branches/1.x":  "https://svn.example.org/repos/archlog",
+		"https://svn.example.org/repos/archlog/tags/1.0/":     "https://svn.example.org/repos/archlog",
+		"https://svn.example.org/repos/archlog":               "https://svn.example.org/repos/archlog",
+		"file:///srv/svn/packages/archlog/repos/extra-x86_64": "file:///srv/svn/packages/archlog/repos/extra-x86_64",
+	} {
+		u, err := url.Parse(wc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found := svnProjectURL(u).String(); found != project {
+			t.Fatalf("expected %s for %s, got %s", project, wc, found)
+		}
+	}
+}
+
+func TestSvnPath(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the fake svn with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// An svn that writes the arguments of svn log to a file, and outputs one entry
+	svn := filepath.Join(dir, "svn")
+	script := `#!/bin/sh
+if [ "$1" = info ]; then
+	echo '<?xml version="1.0"?><info><entry><url>https://svn.example.org/repos/archlog/trunk</url><repository><root>https://svn.example.org/repos</root></repository></entry></info>'
+	exit 0
+fi
+echo "$*" > "$(dirname "$0")/args"
+echo '<?xml version="1.0"?><log><logentry revision="3"><author>alice</author><date>2024-03-01T10:00:00.000000Z</date><msg>Branch</msg></logentry></log>'
+`
+	if err := ioutil.WriteFile(svn, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	opts := &Options{Repo: dir, SvnBin: svn, Entries: -1, SvnPath: "branches/1.x@1234"}
+	entries, err := New(opts).Entries(context.Background())
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one entry, got %+v, %v", entries, err)
+	}
+	args, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "log --xml -r 1234:0 https://svn.example.org/repos/archlog/branches/1.x@1234"
+	if strings.TrimSpace(string(args)) != expected {
+		t.Fatalf("expected %q, got %q", expected, args)
+	}
+}
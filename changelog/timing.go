@@ -0,0 +1,97 @@
+package changelog
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+	"time"
+)
+
+// The time spent in each phase of generating a ChangeLog, like "fetch",
+// "parse", "resolve", "hooks" and "format". For work that is done
+// concurrently, the time of each goroutine is added up.
+// A nil *Timings records nothing.
+type Timings struct {
+	mu        sync.Mutex
+	phases    []string // In the order they were first added
+	durations map[string]time.Duration
+}
+
+// Add time spent in a phase
+func (t *Timings) Add(phase string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.durations == nil {
+		t.durations = make(map[string]time.Duration)
+	}
+	if _, ok := t.durations[phase]; !ok {
+		t.phases = append(t.phases, phase)
+	}
+	t.durations[phase] += d
+}
+
+// Start timing a phase. The returned function adds the time since then.
+func (t *Timings) Start(phase string) func() {
+	start := time.Now()
+	return func() {
+		t.Add(phase, time.Since(start))
+	}
+}
+
+// The time spent in a phase
+func (t *Timings) Get(phase string) time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.durations[phase]
+}
+
+// Write the time spent in each phase, with one line per phase
+func (t *Timings) WriteText(w io.Writer) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := "Timing:\n"
+	for _, phase := range t.phases {
+		lines += fmt.Sprintf("  %-10s %s\n", phase, t.durations[phase].Round(time.Millisecond))
+	}
+	_, err := io.WriteString(w, lines)
+	return err
+}
+
+// Measure the time spent producing the entries of seq, not counting the
+// time spent by the consumer, and pass it to done when seq is finished
+func producerTime(seq iter.Seq2[Entry, error], done func(time.Duration)) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		start := time.Now()
+		var consumer time.Duration
+		seq(func(entry Entry, err error) bool {
+			yielded := time.Now()
+			ok := yield(entry, err)
+			consumer += time.Since(yielded)
+			return ok
+		})
+		done(time.Since(start) - consumer)
+	}
+}
+
+// A reader that adds up the time spent waiting for Read
+type timedReader struct {
+	r       io.Reader
+	waiting time.Duration
+}
+
+func (r *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := r.r.Read(p)
+	r.waiting += time.Since(start)
+	return n, err
+}
@@ -0,0 +1,37 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimings(t *testing.T) {
+	timings := &Timings{}
+	var producer time.Duration
+	seq := producerTime(sliceEntries([]Entry{{Revision: 2}, {Revision: 1}}), func(d time.Duration) { producer = d })
+	for range seq {
+		// A slow consumer does not count as time spent producing the entries
+		time.Sleep(20 * time.Millisecond)
+	}
+	if producer >= 20*time.Millisecond {
+		t.Fatalf("expected the time of the consumer to be left out, got %s", producer)
+	}
+	timings.Add("fetch", time.Second)
+	timings.Add("resolve", time.Second)
+	timings.Add("fetch", time.Second)
+	var buf strings.Builder
+	if err := timings.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Timing:\n  fetch      2s\n  resolve    1s\n"
+	if buf.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buf.String())
+	}
+	// A nil *Timings records nothing
+	var none *Timings
+	none.Start("fetch")()
+	if none.Get("fetch") != 0 {
+		t.Fatal("expected nothing to be recorded")
+	}
+}
@@ -0,0 +1,68 @@
+package changelog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+)
+
+// Check if a request failed because the certificate of the server could
+// not be verified
+func isCertificateError(err error) bool {
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &verifyErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// Refuse to follow a redirect from https to plain http
+func noDowngrade(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Scheme == "http" && via[len(via)-1].URL.Scheme == "https" {
+		return fmt.Errorf("refusing to follow a redirect from https to %s", req.URL.Redacted())
+	}
+	return nil
+}
+
+// Trust the certificates in a PEM file for the web lookups, in addition to
+// the ones of the system, or skip verifying the certificates if insecure
+// is true. caFile can be "".
+func (n *Names) SetTLS(caFile string, insecure bool) error {
+	transport, ok := n.Client.Transport.(*http.Transport)
+	if !ok {
+		return errors.New("Can not change the TLS settings of this HTTP client")
+	}
+	config := &tls.Config{}
+	if transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig.Clone()
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("Could not read the CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("Could not find any PEM certificates in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	if insecure {
+		slog.Warn("The certificates of the servers are not verified, so the names and e-mail addresses may come from anyone")
+		config.InsecureSkipVerify = true
+	}
+	transport.TLSClientConfig = config
+	return nil
+}
@@ -0,0 +1,92 @@
+package changelog
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("people"))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(block), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// An unknown certificate is an error that is not retried
+	n := NewNames()
+	web := &ArchWeb{Client: n.Client, Retries: 3}
+	_, err = web.get(ctx, server.URL)
+	var certErr *CertificateError
+	if !errors.As(err, &certErr) {
+		t.Fatalf("expected a certificate error, got %v", err)
+	}
+	// The certificate is trusted with the CA file
+	n = NewNames()
+	if err := n.SetTLS(caFile, false); err != nil {
+		t.Fatal(err)
+	}
+	if body, err := (&ArchWeb{Client: n.Client}).get(ctx, server.URL); err != nil || string(body) != "people" {
+		t.Fatalf("expected the page with the CA file, got %q, %v", body, err)
+	}
+	// Or by not verifying it at all
+	n = NewNames()
+	if err := n.SetTLS("", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&ArchWeb{Client: n.Client}).get(ctx, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewNames().SetTLS(filepath.Join(dir, "missing.pem"), false); err == nil {
+		t.Fatal("expected an error for a missing CA file")
+	}
+}
+
+func TestResolveAllCertificateError(t *testing.T) {
+	n := NewNames()
+	n.Resolver = ResolverFunc(func(ctx context.Context, nick string) (Identity, error) {
+		return Identity{}, &CertificateError{URL: TU_URL, Err: errors.New("x509: certificate signed by unknown authority")}
+	})
+	err := n.ResolveAll(context.Background(), []string{"alice", "bob"}, nil)
+	var certErr *CertificateError
+	if !errors.As(err, &certErr) {
+		t.Fatalf("expected a certificate error, got %v", err)
+	}
+	if _, ok := n.Cached("alice"); ok {
+		t.Fatal("expected the nick to not be cached after a certificate error")
+	}
+}
+
+func TestNoDowngrade(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("spoofed"))
+	}))
+	defer plain.Close()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, plain.URL, http.StatusFound)
+	}))
+	defer server.Close()
+	n := NewNames()
+	if err := n.SetTLS("", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&ArchWeb{Client: n.Client}).get(context.Background(), server.URL); err == nil {
+		t.Fatal("expected the redirect to plain http to be refused")
+	}
+}
@@ -0,0 +1,267 @@
+package changelog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rewrites, drops or retags entries with the rules of a small transform language,
+// with one rule per line:
+//
+//	drop if message ~ "^Merge"
+//	set author "arodseth" if author == "alexander"
+//	replace message "(?i)^fixed" "Fix"
+//	replace message "FS#([0-9]+)" "https://bugs.archlinux.org/task/$1" if date != "2009-01-01"
+//
+// The fields are message, author, name, revision and date (YYYY-MM-DD).
+// A condition compares a field with == or !=, or matches it against a
+// regular expression with ~ or !~. The rules are applied in order, and
+// lines that are empty or start with # are ignored.
+type Transform struct {
+	rules []transformRule
+}
+
+type transformRule struct {
+	line      int
+	action    string // drop, set or replace
+	field     string
+	value     string         // The value for set, or the replacement for replace
+	re        *regexp.Regexp // The regular expression for replace
+	condition *transformCondition
+}
+
+type transformCondition struct {
+	field string
+	op    string // ==, !=, ~ or !~
+	value string
+	re    *regexp.Regexp
+}
+
+var transformFields = map[string]bool{"message": true, "author": true, "name": true, "revision": true, "date": true}
+
+// Split a line into words and quoted strings. Quoted strings are unquoted.
+func transformTokens(line string) ([]string, error) {
+	var tokens []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" || line[0] == '#' {
+			return tokens, nil
+		}
+		if line[0] == '"' || line[0] == '`' {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, fmt.Errorf("unterminated string: %s", line)
+			}
+			s, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, s)
+			line = line[len(quoted):]
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+		tokens = append(tokens, line[:end])
+		line = line[end:]
+	}
+}
+
+// Parse the field name at the start of the tokens
+func transformField(tokens []string) (string, error) {
+	if len(tokens) == 0 {
+		return "", fmt.Errorf("missing a field")
+	}
+	if !transformFields[tokens[0]] {
+		return "", fmt.Errorf("unknown field: %s", tokens[0])
+	}
+	return tokens[0], nil
+}
+
+// Parse one rule
+func parseTransformRule(tokens []string) (transformRule, error) {
+	var rule transformRule
+	// Split off the condition
+	for i, token := range tokens {
+		if token != "if" {
+			continue
+		}
+		cond := tokens[i+1:]
+		if len(cond) != 3 {
+			return rule, fmt.Errorf("a condition must be: if field op \"value\"")
+		}
+		field, err := transformField(cond)
+		if err != nil {
+			return rule, err
+		}
+		c := &transformCondition{field: field, op: cond[1], value: cond[2]}
+		switch c.op {
+		case "==", "!=":
+		case "~", "!~":
+			if c.re, err = regexp.Compile(c.value); err != nil {
+				return rule, err
+			}
+		default:
+			return rule, fmt.Errorf("unknown operator: %s", c.op)
+		}
+		rule.condition = c
+		tokens = tokens[:i]
+		break
+	}
+	if len(tokens) == 0 {
+		return rule, fmt.Errorf("missing an action")
+	}
+	rule.action = tokens[0]
+	args := tokens[1:]
+	var err error
+	switch rule.action {
+	case "drop":
+		if len(args) != 0 {
+			return rule, fmt.Errorf("drop takes no arguments")
+		}
+	case "set":
+		if len(args) != 2 {
+			return rule, fmt.Errorf("set must be: set field \"value\"")
+		}
+		if rule.field, err = transformField(args); err != nil {
+			return rule, err
+		}
+		rule.value = args[1]
+	case "replace":
+		if len(args) != 3 {
+			return rule, fmt.Errorf("replace must be: replace field \"regexp\" \"replacement\"")
+		}
+		if rule.field, err = transformField(args); err != nil {
+			return rule, err
+		}
+		if rule.re, err = regexp.Compile(args[1]); err != nil {
+			return rule, err
+		}
+		rule.value = args[2]
+	default:
+		return rule, fmt.Errorf("unknown action: %s", rule.action)
+	}
+	return rule, nil
+}
+
+// Parse transform rules
+func ParseTransform(r io.Reader) (*Transform, error) {
+	t := &Transform{}
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		tokens, err := transformTokens(scanner.Text())
+		if err == nil && len(tokens) == 0 {
+			continue
+		}
+		var rule transformRule
+		if err == nil {
+			rule, err = parseTransformRule(tokens)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		rule.line = lineNumber
+		t.rules = append(t.rules, rule)
+	}
+	return t, scanner.Err()
+}
+
+// Read transform rules from a file
+func LoadTransform(filename string) (*Transform, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := ParseTransform(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return t, nil
+}
+
+// Get a field of an entry as a string
+func getField(entry *Entry, field string) string {
+	switch field {
+	case "message":
+		return entry.Message
+	case "author":
+		return entry.Author
+	case "name":
+		return entry.Name
+	case "revision":
+		return strconv.Itoa(entry.Revision)
+	case "date":
+		return entry.Day()
+	}
+	return ""
+}
+
+// Set a field of an entry from a string
+func setField(entry *Entry, field, value string) error {
+	switch field {
+	case "message":
+		entry.Message = value
+	case "author":
+		entry.Author = value
+	case "name":
+		entry.Name = value
+	case "revision":
+		revision, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		entry.Revision = revision
+	case "date":
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return err
+		}
+		entry.Date = date
+	}
+	return nil
+}
+
+// Check if the condition holds for the entry
+func (c *transformCondition) matches(entry *Entry) bool {
+	value := getField(entry, c.field)
+	switch c.op {
+	case "==":
+		return value == c.value
+	case "!=":
+		return value != c.value
+	case "~":
+		return c.re.MatchString(value)
+	}
+	return !c.re.MatchString(value)
+}
+
+// Apply the rules to an entry. Returns false if the entry is dropped.
+func (t *Transform) Apply(entry Entry) (Entry, bool, error) {
+	for _, rule := range t.rules {
+		if rule.condition != nil && !rule.condition.matches(&entry) {
+			continue
+		}
+		var err error
+		switch rule.action {
+		case "drop":
+			return entry, false, nil
+		case "set":
+			err = setField(&entry, rule.field, rule.value)
+		case "replace":
+			err = setField(&entry, rule.field, rule.re.ReplaceAllString(getField(&entry, rule.field), rule.value))
+		}
+		if err != nil {
+			return entry, false, fmt.Errorf("line %d: r%d: %w", rule.line, entry.Revision, err)
+		}
+	}
+	return entry, true, nil
+}
@@ -0,0 +1,37 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	rules := `# Clean up the log
+drop if message ~ "^Merge"
+set author "arodseth" if author == "alexander"
+replace message "(?i)^fixed" "Fix"
+replace message "FS#([0-9]+)" "https://bugs.archlinux.org/task/$1" if revision != "1"
+`
+	transform, err := ParseTransform(strings.NewReader(rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, keep, _ := transform.Apply(Entry{Revision: 4, Message: "Merge branch"}); keep {
+		t.Fatal("expected the merge to be dropped")
+	}
+	entry, keep, err := transform.Apply(Entry{Revision: 3, Author: "alexander", Message: "fixed FS#42"})
+	if err != nil || !keep {
+		t.Fatalf("expected the entry to be kept: %v", err)
+	}
+	if entry.Author != "arodseth" || entry.Message != "Fix https://bugs.archlinux.org/task/42" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+	if entry, _, _ := transform.Apply(Entry{Revision: 1, Message: "FS#1"}); entry.Message != "FS#1" {
+		t.Fatalf("the condition should have skipped the rule: %q", entry.Message)
+	}
+	for _, invalid := range []string{"explode", "set colour \"red\"", "drop if message ~ \"(\"", "replace message \"a\""} {
+		if _, err := ParseTransform(strings.NewReader(invalid)); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
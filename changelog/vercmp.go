@@ -0,0 +1,122 @@
+package changelog
+
+import "strings"
+
+// Split a version like 1:2.0-3 into the epoch, pkgver and pkgrel, the same
+// way as pacman. The epoch is "0" if it is not set, and the pkgrel is "" if
+// there is none.
+func splitVersion(version string) (string, string, string) {
+	epoch := "0"
+	i := 0
+	for i < len(version) && isDigit(version[i]) {
+		i++
+	}
+	if i < len(version) && version[i] == ':' {
+		if i > 0 {
+			epoch = version[:i]
+		}
+		version = version[i+1:]
+	}
+	if j := strings.LastIndexByte(version, '-'); j >= 0 {
+		return epoch, version[:j], version[j+1:]
+	}
+	return epoch, version, ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Compare two parts of a version segment by segment, like rpmvercmp in
+// pacman. Numbers are compared as numbers, letters as strings, a number is
+// newer than letters, and a trailing segment of letters, like the "beta"
+// in 1.0beta, is older than nothing at all.
+func compareSegments(a, b string) int {
+	if a == b {
+		return 0
+	}
+	one, two := 0, 0
+	for one < len(a) && two < len(b) {
+		start1, start2 := one, two
+		for one < len(a) && !isDigit(a[one]) && !isAlpha(a[one]) {
+			one++
+		}
+		for two < len(b) && !isDigit(b[two]) && !isAlpha(b[two]) {
+			two++
+		}
+		if one == len(a) || two == len(b) {
+			break
+		}
+		// The one with the longer separator is newer
+		if one-start1 != two-start2 {
+			if one-start1 < two-start2 {
+				return -1
+			}
+			return 1
+		}
+		end1, end2 := one, two
+		numeric := isDigit(a[one])
+		same := isAlpha
+		if numeric {
+			same = isDigit
+		}
+		for end1 < len(a) && same(a[end1]) {
+			end1++
+		}
+		for end2 < len(b) && same(b[end2]) {
+			end2++
+		}
+		if end2 == two {
+			// A number against letters, where the number is newer
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		seg1, seg2 := a[one:end1], b[two:end2]
+		if numeric {
+			seg1, seg2 = strings.TrimLeft(seg1, "0"), strings.TrimLeft(seg2, "0")
+			if len(seg1) != len(seg2) {
+				if len(seg1) < len(seg2) {
+					return -1
+				}
+				return 1
+			}
+		}
+		if c := strings.Compare(seg1, seg2); c != 0 {
+			return c
+		}
+		one, two = end1, end2
+	}
+	if one == len(a) && two == len(b) {
+		return 0
+	}
+	// Letters that remain never win over nothing, but numbers do
+	if one == len(a) && !isAlpha(b[two]) || one < len(a) && isAlpha(a[one]) {
+		return -1
+	}
+	return 1
+}
+
+// Compare two package versions the same way as vercmp from pacman, by the
+// epoch, then pkgver and then pkgrel. Returns -1 if a is older than b, 1 if
+// a is newer than b and 0 if they are the same version. The pkgrel is only
+// compared if both versions have one.
+func Vercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	epoch1, pkgver1, pkgrel1 := splitVersion(a)
+	epoch2, pkgver2, pkgrel2 := splitVersion(b)
+	if c := compareSegments(epoch1, epoch2); c != 0 {
+		return c
+	}
+	if c := compareSegments(pkgver1, pkgver2); c != 0 || pkgrel1 == "" || pkgrel2 == "" {
+		return c
+	}
+	return compareSegments(pkgrel1, pkgrel2)
+}
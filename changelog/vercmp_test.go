@@ -0,0 +1,35 @@
+package changelog
+
+import "testing"
+
+func TestVercmp(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.9", "1.10", -1},
+		{"1.0-1", "1.0-2", -1},
+		{"1.0-10", "1.0-9", 1},
+		{"1.0", "1.0-1", 0},
+		{"1:1.0-1", "2.0-1", 1},
+		{"0:1.0", "1.0", 0},
+		{"1.0alpha", "1.0beta", -1},
+		{"1.0beta", "1.0", -1},
+		{"1.0rc1", "1.0", -1},
+		{"1.0", "1.0.1", -1},
+		{"1.0a", "1.0.1", -1},
+		{"1.0.a", "1.0.1", -1},
+		{"1.001", "1.1", 0},
+		{"1.0..0", "1.0.0", 1},
+		{"1.0", "1.0a", 1},
+		{"r100.abc", "r99.def", 1},
+	} {
+		if c := Vercmp(test.a, test.b); c != test.expected {
+			t.Fatalf("expected %d for %s and %s, got %d", test.expected, test.a, test.b, c)
+		}
+		if c := Vercmp(test.b, test.a); c != -test.expected {
+			t.Fatalf("expected %d for %s and %s, got %d", -test.expected, test.b, test.a, c)
+		}
+	}
+}
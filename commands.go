@@ -0,0 +1,1190 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// A subcommand, like "archlog generate"
+type command struct {
+	name        string
+	syntax      string
+	description string
+	examples    []string
+	run         func(ctx context.Context, cmd *command, args []string) error
+}
+
+// All available subcommands, "generate" is the default
+var commands = []*command{
+	{
+		name:        "generate",
+		syntax:      "[flags] [n]",
+		description: "Generates a ChangeLog based on \"svn log\", where n is the number of entries to fetch from the log.\nTries to find names and e-mail addresses for Arch Linux related usernames.",
+		examples: []string{
+			"archlog",
+			"archlog 10",
+			"archlog generate -normalize 10",
+			"archlog generate -o ChangeLog",
+			"archlog generate -incremental -prepend ChangeLog",
+			"archlog generate -check ChangeLog",
+			"archlog generate -diff -o ChangeLog",
+			"archlog generate -repo ~/abs/archlog/trunk -o ~/abs/archlog/trunk/ChangeLog",
+		},
+		run: runGenerate,
+	},
+	{
+		name:        "resolve",
+		syntax:      "[flags] [nick...]",
+		description: "Finds the names and e-mail addresses for Arch Linux related usernames, and writes one \"nick<TAB>Name <email>\" per line.\nWithout nicks, or with \"-\", the nicks are read from stdin, one per line.",
+		examples:    []string{"archlog resolve arodseth", "cut -d' ' -f2 nicks.txt | archlog resolve"},
+		run:         runResolve,
+	},
+	{
+		name:        "cache",
+		syntax:      "[flags] [list|clear|path]",
+		description: "Lists or clears the cached names and e-mail addresses, and clears the cached web pages.",
+		examples:    []string{"archlog cache", "archlog cache clear"},
+		run:         runCache,
+	},
+	{
+		name:        "stats",
+		syntax:      "[flags] [n]",
+		description: "Shows statistics about the n last entries in \"svn log\", like the number of commits per author.",
+		examples:    []string{"archlog stats", "archlog stats -resolve 100"},
+		run:         runStats,
+	},
+	{
+		name:        "serve",
+		syntax:      "[flags]",
+		description: "Serves the ChangeLog over HTTP on /changelog, generated when it is requested.\nUse ?format=md for another format and ?n=50 for the 50 last entries.",
+		examples:    []string{"archlog serve", "archlog serve -repo ~/abs/archlog/trunk -listen :8080"},
+		run:         runServe,
+	},
+	{
+		name:        "install-hook",
+		syntax:      "[flags]",
+		description: "Installs a post-commit hook that adds the new entries to the ChangeLog after each commit, or removes it with -uninstall.\nInstalling it again replaces it, and the rest of an existing hook is kept.",
+		examples:    []string{"archlog install-hook -vcs git", "archlog install-hook -repo ~/abs/archlog/trunk", "archlog install-hook -uninstall"},
+		run:         runInstallHook,
+	},
+	{
+		name:        "publish",
+		syntax:      "[flags] [n]",
+		description: "Publishes the ChangeLog as the release notes of a GitLab release, which is created or updated.\nIn a GitLab CI job for a tag, the instance, project, tag and token are found in the environment.",
+		examples:    []string{"archlog publish -vcs git -since 2024-03-01", "archlog publish -gitlab-url https://gitlab.archlinux.org -gitlab-project archlinux/archlog -tag v1.0 -notes ChangeLog"},
+		run:         runPublish,
+	},
+	{
+		name:        "tag",
+		syntax:      "[flags] name",
+		description: "Creates an annotated git tag, with the ChangeLog since the previous tag as its message.\nAn existing tag is replaced with a new message, for the same commit.",
+		examples:    []string{"archlog tag v1.2.3", "archlog tag -sign -ref main v1.2.3", "archlog tag -dry-run v1.2.3"},
+		run:         runTag,
+	},
+	{
+		name:        "pkgbuild",
+		syntax:      "[flags]",
+		description: "Adds the new entries to the ChangeLog of a package, sets the changelog= field in the PKGBUILD,\nand checks that makepkg --printsrcinfo can still read the PKGBUILD.",
+		examples:    []string{"archlog pkgbuild", "archlog pkgbuild -repo ~/abs/archlog/trunk -changelog '$pkgname.changelog'"},
+		run:         runPkgbuild,
+	},
+	{
+		name:        "pkg",
+		syntax:      "[flags] pkgname [n]",
+		description: "Generates the ChangeLog of an official package, from its packaging repository on gitlab.archlinux.org,\nwhich is cloned to the cache directory, or updated if it has been cloned before.",
+		examples:    []string{"archlog pkg archlog", "archlog pkg -versions -o archlog.changelog archlog 20"},
+		run:         runPkg,
+	},
+	{
+		name:        "aur",
+		syntax:      "[flags] pkgname [n]",
+		description: "Generates the ChangeLog of an AUR package, from its git repository, which is cloned to the cache directory.\nThe commits of the maintainers, co-maintainers and the submitter get their AUR usernames.",
+		examples:    []string{"archlog aur yay", "archlog aur -depth 50 -o ChangeLog yay 20"},
+		run:         runAur,
+	},
+	{
+		name:        "rotate",
+		syntax:      "[flags] [file]",
+		description: "Moves the entries that are older than -keep from the ChangeLog to an archive for each year, like ChangeLog.2023,\nand ends the ChangeLog with where the older entries are. The file is ChangeLog by default.",
+		examples:    []string{"archlog rotate", "archlog rotate -keep 6m archlog.changelog", "archlog rotate -dry-run -keep 2024-01-01"},
+		run:         runRotate,
+	},
+	{
+		name:        "diff",
+		syntax:      "[flags] old new",
+		description: "Shows the entries that were added, removed or modified between two ChangeLogs, and not how the lines changed,\nwhich leaves out changes to how the messages are wrapped and indented.",
+		examples:    []string{"archlog diff ChangeLog.old ChangeLog", "archlog diff -exit-code ChangeLog.old ChangeLog"},
+		run:         runDiff,
+	},
+	{
+		name:        "validate",
+		syntax:      "[flags] file...",
+		description: "Checks that ChangeLogs in the json format follow its JSON Schema, which -schema writes to stdout.\nExits with 5 if any of them do not, and shows the problems.",
+		examples:    []string{"archlog validate ChangeLog.json", "archlog generate -format json | archlog validate -", "archlog validate -schema"},
+		run:         runValidate,
+	},
+	{
+		name:        "authors",
+		syntax:      "[flags] [n]",
+		description: "Lists the contributors in the n last entries in \"svn log\", with their names and e-mail addresses, the number of commits\nand the dates of their first and latest commit. Nicks with the same e-mail address are listed once.",
+		examples:    []string{"archlog authors", "archlog authors -vcs git -o AUTHORS", "archlog authors -json"},
+		run:         runAuthors,
+	},
+	{
+		name:        "whois",
+		syntax:      "[flags] nick",
+		description: "Looks up the name and e-mail address for one nick, like for the ChangeLog, and shows where it was found:\nin the nick cache, in the authors file, or on which web page. Exits with 7 if it could not be found.",
+		examples:    []string{"archlog whois arodseth", "archlog whois -no-cache -resolvers authors,web -authors authors.txt arodseth"},
+		run:         runWhois,
+	},
+	{
+		name:        "grep",
+		syntax:      "[flags] pattern [n]",
+		description: "Shows the entries in the n last entries in \"svn log\" where the regular expression matches the message, the author,\nthe name and e-mail address or the date, as a ChangeLog. The names are looked up before they are searched.",
+		examples:    []string{"archlog grep -i 'fs#[0-9]+'", "archlog grep -fields author arodseth", "archlog grep -F -vcs git 2014-03 100"},
+		run:         runGrep,
+	},
+	{
+		name:        "doctor",
+		syntax:      "[flags]",
+		description: "Checks that svn and git can be found, that the directory is a working copy, that the cache can be used,\nthat the environment variables are valid and that archlinux.org can be reached, and shows how to fix the problems.",
+		examples:    []string{"archlog doctor", "archlog doctor -vcs git -repo ~/src/archlog", "archlog doctor -offline"},
+		run:         runDoctor,
+	},
+	{
+		name:        "selftest",
+		syntax:      "[flags]",
+		description: "Creates throwaway svn and git repositories with known commits, generates their ChangeLogs offline,\nand checks that they are as expected, for after archlog or svn has been packaged or updated.",
+		examples:    []string{"archlog selftest", "archlog selftest -keep -svn-bin /usr/local/bin/svn"},
+		run:         runSelftest,
+	},
+}
+
+// Find a subcommand by name
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Output the list of subcommands
+func usage() {
+	fmt.Println()
+	fmt.Println("Generates a ChangeLog based on \"svn log\".")
+	fmt.Println("Tries to find names and e-mail addresses for Arch Linux related usernames")
+	fmt.Println()
+	fmt.Println("Syntax:")
+	fmt.Println("\tarchlog [command] [flags] [arguments]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, cmd := range commands {
+		fmt.Printf("\t%s %s\n", cmd.name, cmd.syntax)
+	}
+	fmt.Println()
+	fmt.Println("The default command is \"generate\".")
+	fmt.Println("Use \"archlog help [command]\" for more information about a command.")
+	fmt.Println()
+}
+
+// Create a flag set for a subcommand, with a usage text that lists the flags
+func (cmd *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("archlog "+cmd.name, flag.ExitOnError)
+	addLoggingFlags(fs)
+	addProfilingFlags(fs)
+	fs.Usage = func() {
+		fmt.Println()
+		fmt.Println(cmd.description)
+		fmt.Println()
+		fmt.Println("Syntax:")
+		fmt.Printf("\tarchlog %s %s\n", cmd.name, cmd.syntax)
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.SetOutput(os.Stdout)
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Every flag can also be set with an environment variable, like " + envName("cache-dir") + ".")
+		for name := range secretFlags {
+			if fs.Lookup(name) != nil {
+				fmt.Println("Prefer them for the passwords, tokens and webhooks, which are then not shown in the process list.")
+				break
+			}
+		}
+		fmt.Println()
+		fmt.Println("Examples:")
+		for _, example := range cmd.examples {
+			fmt.Println("\t" + example)
+		}
+		fmt.Println()
+	}
+	return fs
+}
+
+// The environment variable that mirrors a flag, like ARCHLOG_CACHE_DIR for -cache-dir
+func envName(flagName string) string {
+	return "ARCHLOG_" + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// Parse the flags, after first setting them from the ARCHLOG_* environment
+// variables. Flags given on the command line take precedence.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if value, ok := os.LookupEnv(envName(f.Name)); ok && err == nil {
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = withCode(EXIT_USAGE, fmt.Errorf("Invalid value %q for %s: %v", value, envName(f.Name), setErr))
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := fs.Parse(args); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	setupLogging(fs)
+	return setupProfiling(fs)
+}
+
+// The time to use for "generated at" timestamps, which is
+// $SOURCE_DATE_EPOCH if it is set, for reproducible builds
+func sourceDate() (time.Time, error) {
+	epoch, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || epoch == "" {
+		return time.Now().UTC(), nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid SOURCE_DATE_EPOCH: %q", epoch)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// The flags with passwords, tokens and webhook URLs, which are left out of
+// the -metadata
+var secretFlags = map[string]bool{
+	"svn-password":    true,
+	"gitlab-token":    true,
+	"matrix-token":    true,
+	"slack-webhook":   true,
+	"discord-webhook": true,
+	"hook-secret":     true,
+}
+
+// The metadata for -metadata: the version of archlog and the flags that
+// were given, also the ones from the ARCHLOG_* environment variables. The
+// passwords, tokens and webhooks are left out, and so are the logins in URLs.
+func flagMetadata(fs *flag.FlagSet) *changelog.Metadata {
+	parameters := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if secretFlags[f.Name] {
+			return
+		}
+		value := f.Value.String()
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			u.User = nil
+			value = u.String()
+		}
+		parameters[f.Name] = value
+	})
+	if args := fs.Args(); len(args) > 0 {
+		parameters["args"] = strings.Join(args, " ")
+	}
+	return &changelog.Metadata{Generator: "archlog " + VERSION, Parameters: parameters}
+}
+
+// Parse an optional argument with the number of log entries, -1 for all
+func parseEntries(args []string) (int, error) {
+	if len(args) == 0 {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if len(args) > 1 || err != nil || n <= 0 {
+		return 0, withCode(EXIT_USAGE, errors.New("Please provide an int that represents the number of svn log entries to recall.\nUse --help for more info."))
+	}
+	return n, nil
+}
+
+// The default -timeout for web lookups and svn
+const DEFAULT_TIMEOUT = time.Minute
+
+// The default number of concurrent svn log invocations, with -jobs. The
+// log is only fetched in chunks of changelog.SVN_CHUNK_SIZE revisions when
+// more are given, since the chunks span the whole repository, which may be
+// shared by many packages, and all of the entries are kept in memory.
+const DEFAULT_JOBS = 1
+
+// Add the -timeout flag
+func addTimeoutFlag(fs *flag.FlagSet) *time.Duration {
+	return fs.Duration("timeout", DEFAULT_TIMEOUT, "the `duration` before giving up on svn or a web lookup, 0 for no timeout")
+}
+
+// Add the -lang flag, for the language of the headings of the ChangeLog.
+// It is English by default, and not taken from the locale unless asked
+// for, so that the ChangeLog is the same wherever it is generated.
+func addLanguageFlag(fs *flag.FlagSet) *string {
+	return fs.String("lang", "en", "the `language` of the headings: "+strings.Join(changelog.LanguageNames(), ", ")+", or auto for the one of LC_ALL, LC_TIME or LANG")
+}
+
+// Check the language from the -lang flag, and find it in the environment for auto
+func checkLanguage(lang string) (string, error) {
+	if lang == "auto" {
+		return changelog.LanguageFromEnvironment(), nil
+	}
+	if _, err := changelog.LookupLanguage(lang); err != nil {
+		return "", withCode(EXIT_USAGE, err)
+	}
+	return lang, nil
+}
+
+// The flags for finding names and e-mail addresses
+type resolverFlags struct {
+	resolvers *string
+	authors   *string
+	lookups   *int
+	rate      *float64
+	retries   *int
+	proxy     *string
+	userAgent *string
+	caFile    *string
+	insecure  *bool
+	record    *string
+	replay    *string
+}
+
+// Add the -resolvers, -authors, -lookups, -rate, -retries, -proxy,
+// -user-agent, -ca-file, -insecure, -record and -replay flags
+func addResolverFlags(fs *flag.FlagSet) *resolverFlags {
+	return &resolverFlags{
+		resolvers: fs.String("resolvers", "authors,web", "comma separated `names` of the ways to find names and e-mail addresses, tried in order: authors, web"),
+		authors:   fs.String("authors", "", "an authors `file` with one \"nick = Name <email>\" per line, as used by git svn"),
+		lookups:   fs.Int("lookups", changelog.DEFAULT_LOOKUPS, "the `number` of nicks to look up concurrently"),
+		rate:      fs.Float64("rate", changelog.DEFAULT_RATE, "the maximum `number` of requests per second to archlinux.org, 0 for no limit"),
+		retries:   fs.Int("retries", changelog.DEFAULT_RETRIES, "the `number` of times to retry a failed request to archlinux.org"),
+		proxy:     fs.String("proxy", "", "the `URL` of an HTTP or SOCKS5 proxy for the requests to archlinux.org, like socks5://localhost:1080, instead of $HTTPS_PROXY"),
+		userAgent: fs.String("user-agent", "archlog/"+VERSION+" (+"+changelog.ARCHLOG_URL+")", "the User-Agent `header` for the requests to archlinux.org"),
+		caFile:    fs.String("ca-file", "", "a PEM `file` with CA certificates to trust for the web lookups, in addition to the ones of the system"),
+		insecure:  fs.Bool("insecure", false, "don't verify the certificates for the web lookups, which makes it possible for others to change the names and e-mail addresses"),
+		record:    fs.String("record", "", "record the responses of the web lookups as files in this `directory`, for -replay"),
+		replay:    fs.String("replay", "", "replay the responses of the web lookups from this `directory`, as recorded with -record, instead of sending the requests"),
+	}
+}
+
+// The flags for authenticating with svn
+type svnAuthFlags struct {
+	username        *string
+	password        *string
+	netrc           *string
+	nonInteractive  *bool
+	trustServerCert *bool
+}
+
+// Add the -svn-username, -svn-password, -netrc, -non-interactive and -trust-server-cert flags
+func addSvnAuthFlags(fs *flag.FlagSet) *svnAuthFlags {
+	return &svnAuthFlags{
+		username:        fs.String("svn-username", "", "the `username` for svn"),
+		password:        fs.String("svn-password", "", "the `password` for svn, which is not stored by svn"),
+		netrc:           fs.String("netrc", changelog.DefaultNetrc(), "the .netrc `file` with the svn login for the host of the repository, used if no username or password is given, or \"\" for none"),
+		nonInteractive:  fs.Bool("non-interactive", false, "make svn fail instead of prompting for credentials, for automation"),
+		trustServerCert: fs.Bool("trust-server-cert", false, "make svn trust the server certificate, also if it is self-signed or has expired"),
+	}
+}
+
+// Use the svn authentication flags
+func (flags *svnAuthFlags) apply(opts *changelog.Options) {
+	opts.SvnUsername = *flags.username
+	opts.SvnPassword = *flags.password
+	opts.Netrc = *flags.netrc
+	opts.NonInteractive = *flags.nonInteractive
+	opts.TrustServerCert = *flags.trustServerCert
+}
+
+// Add the -repo, -vcs, -svn-bin and -git-bin flags, for where the log is read from
+func addSourceFlags(fs *flag.FlagSet) (*string, *string, *string, *string) {
+	repo := fs.String("repo", "", "the `directory` of the working copy, instead of the current directory")
+	vcs := fs.String("vcs", "svn", "the `name` of the version control system: "+strings.Join(changelog.SourceNames(), ", "))
+	svnBin := fs.String("svn-bin", "svn", "the svn `executable`, either a path or a name to look for in the PATH")
+	gitBin := addGitBinFlag(fs)
+	return repo, vcs, svnBin, gitBin
+}
+
+// Add the -git-bin flag
+func addGitBinFlag(fs *flag.FlagSet) *string {
+	return fs.String("git-bin", "git", "the git `executable`, either a path or a name to look for in the PATH")
+}
+
+// Add the -jobs and -stop-on-copy flags, for how the svn log is fetched
+func addFetchFlags(fs *flag.FlagSet) (*int, *bool) {
+	jobs := fs.Int("jobs", DEFAULT_JOBS, "the `number` of concurrent svn log invocations, for large repositories")
+	stopOnCopy := fs.Bool("stop-on-copy", false, "only fetch the svn log back to where the branch or tag was copied from, like svn log --stop-on-copy")
+	return jobs, stopOnCopy
+}
+
+// Add the -mock-data, -svn-path and -externals flags, for which log is read
+func addLogFlags(fs *flag.FlagSet) (*string, *string, *bool) {
+	mockData := fs.String("mock-data", "", "the JSON `file` with the entries for -vcs mock, or a dump of \"svn log --xml\" or \"git log\"")
+	svnPath := fs.String("svn-path", "", "fetch the svn log of this `path` in the project instead of the working copy, like branches/1.x, or trunk@1234 for trunk as it was in r1234")
+	externals := fs.Bool("externals", false, "include the svn log of the svn:externals in the working copy, with the path of each external before its messages")
+	return mockData, svnPath, externals
+}
+
+// Add the -submodules flag
+func addSubmodulesFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("submodules", false, "add the commits of the git submodules that each commit updated to its message, like \"libs/foo: Fix the build\"")
+}
+
+// Add the -cache-dir and -no-cache flags
+func addCacheFlags(fs *flag.FlagSet) (*string, *bool) {
+	cacheDir := addCacheDirFlag(fs)
+	noCache := fs.Bool("no-cache", false, "do not use the cache of resolved names and e-mail addresses")
+	return cacheDir, noCache
+}
+
+// Add the -cache-dir flag
+func addCacheDirFlag(fs *flag.FlagSet) *string {
+	return fs.String("cache-dir", defaultCacheDir(), "the `directory` where resolved names and e-mail addresses are cached")
+}
+
+// Add the -unknown-author flag
+func addUnknownAuthorFlag(fs *flag.FlagSet) *string {
+	return fs.String("unknown-author", changelog.DEFAULT_UNKNOWN_AUTHOR, "the `name` to show for entries without an author, like property edits and anonymous commits")
+}
+
+// Add the -strict, -lenient and -input-encoding flags
+func addParseFlags(fs *flag.FlagSet) (*bool, *bool, *string) {
+	strict := fs.Bool("strict", false, "fail on log entries with an invalid revision or date, and not only on a malformed log")
+	lenient := fs.Bool("lenient", false, "skip the malformed parts of the log with a warning, and keep the entries that can be parsed")
+	inputEncoding := fs.String("input-encoding", "auto", "the `encoding` of the log: auto (UTF-8, and windows-1252 where it is not valid UTF-8), utf-8, latin1 or windows-1252")
+	return strict, lenient, inputEncoding
+}
+
+// Find the parse mode for the -strict and -lenient flags
+func parseMode(strict, lenient bool) (changelog.ParseMode, error) {
+	switch {
+	case strict && lenient:
+		return changelog.PARSE_NORMAL, withCode(EXIT_USAGE, errors.New("-strict and -lenient can not be used together"))
+	case strict:
+		return changelog.PARSE_STRICT, nil
+	case lenient:
+		return changelog.PARSE_LENIENT, nil
+	}
+	return changelog.PARSE_NORMAL, nil
+}
+
+// Check the -svn-path and -externals flags
+func checkSvnOptions(opts *changelog.Options) error {
+	if opts.Externals && opts.VCS != "svn" {
+		return withCode(EXIT_USAGE, errors.New("-externals only works with -vcs svn"))
+	}
+	if opts.SvnPath == "" {
+		return nil
+	}
+	if opts.VCS != "svn" {
+		return withCode(EXIT_USAGE, errors.New("-svn-path only works with -vcs svn"))
+	}
+	if _, _, err := changelog.SplitPegRevision(opts.SvnPath); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	return nil
+}
+
+// Set up the resolvers given with -resolvers, in order, and the number of concurrent lookups.
+// The authors resolver is skipped if there is no authors file.
+func setupResolvers(names *changelog.Names, flags *resolverFlags) error {
+	names.Workers = *flags.lookups
+	if *flags.proxy != "" {
+		if err := names.SetProxy(*flags.proxy); err != nil {
+			return withCode(EXIT_USAGE, err)
+		}
+	}
+	if *flags.caFile != "" || *flags.insecure {
+		if err := names.SetTLS(*flags.caFile, *flags.insecure); err != nil {
+			return withCode(EXIT_USAGE, err)
+		}
+	}
+	if *flags.record != "" && *flags.replay != "" {
+		return withCode(EXIT_USAGE, errors.New("-record and -replay can not be used together"))
+	}
+	// After the proxy and TLS settings, which are for the transport
+	if *flags.record != "" {
+		names.Client.Transport = &changelog.Fixtures{Dir: *flags.record, Next: names.Client.Transport}
+	} else if *flags.replay != "" {
+		names.Client.Transport = &changelog.Fixtures{Dir: *flags.replay, Replay: true}
+	}
+	order, authorsFile := *flags.resolvers, *flags.authors
+	var chain changelog.Chain
+	for _, name := range strings.Split(order, ",") {
+		switch strings.TrimSpace(name) {
+		case "authors":
+			if authorsFile == "" {
+				continue
+			}
+			authors, err := changelog.LoadAuthorsFile(authorsFile)
+			if err != nil {
+				return fmt.Errorf("Could not read the authors file: %w", err)
+			}
+			chain = append(chain, authors)
+		case "web":
+			chain = append(chain, &changelog.ArchWeb{Client: names.Client, Rate: *flags.rate, Retries: *flags.retries, UserAgent: *flags.userAgent, Robots: true})
+		default:
+			return withCode(EXIT_USAGE, fmt.Errorf("Unknown resolver: %s (available: authors, web)", name))
+		}
+	}
+	names.Resolver = chain
+	return nil
+}
+
+// Load the nick cache, unless caching is disabled
+func setupNickCache(names *changelog.Names, cacheDir string, noCache bool) error {
+	if noCache || cacheDir == "" {
+		return nil
+	}
+	if err := names.Load(nickCacheFilename(cacheDir)); err != nil {
+		return fmt.Errorf("Could not read the nick cache: %w", err)
+	}
+	return nil
+}
+
+// Cache the fetched web pages between runs, unless caching is disabled
+func setupPageCache(names *changelog.Names, cacheDir string, noCache bool) {
+	if noCache || cacheDir == "" {
+		return
+	}
+	if _, ok := names.Client.Transport.(*changelog.Fixtures); ok {
+		// The pages come from the fixtures, and are all recorded there
+		return
+	}
+	if chain, ok := names.Resolver.(changelog.Chain); ok {
+		for _, resolver := range chain {
+			if web, ok := resolver.(*changelog.ArchWeb); ok {
+				web.CacheDir = pageCacheDir(cacheDir)
+			}
+		}
+	}
+}
+
+// Save the nick cache, unless caching is disabled
+func storeNickCache(names *changelog.Names, cacheDir string, noCache bool) error {
+	if noCache || cacheDir == "" {
+		return nil
+	}
+	if err := names.Save(nickCacheFilename(cacheDir)); err != nil {
+		return fmt.Errorf("Could not write the nick cache: %w", err)
+	}
+	return nil
+}
+
+// archlog generate
+func runGenerate(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	repo, vcs, svn_bin, git_bin := addSourceFlags(fs)
+	mock_data, svn_path, externals := addLogFlags(fs)
+	svn_auth := addSvnAuthFlags(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	jobs, stop_on_copy := addFetchFlags(fs)
+	branch_flags := addBranchFlags(fs)
+	aggregate_flags := addAggregateFlags(fs)
+	each_flags := addEachFlags(fs)
+	var submodules *bool = addSubmodulesFlag(fs)
+	var output *string = fs.String("o", "", "write the ChangeLog to this `file` (atomically replaced) instead of stdout")
+	var prepend *string = fs.String("prepend", "", "add only the entries newer than the ones in this `file` to the top of it")
+	var check *string = fs.String("check", "", "exit with an error and a diff if this `file` is missing entries for recent revisions")
+	var diff *bool = fs.Bool("diff", false, "only show a diff of what would be written with -o or -prepend")
+	last_flags := addLastFlags(fs)
+	var incremental *bool = fs.Bool("incremental", false, "only fetch revisions newer than the last run, as recorded in "+STATE_FILE)
+	var format *string = fs.String("format", "plain", "the output `format`: "+strings.Join(changelog.FormatterNames(), ", "))
+	var normalize *bool = fs.Bool("normalize", false, "enable all of the message normalization rules below")
+	var capitalize *bool = fs.Bool("capitalize", false, "capitalize the first letter of each message")
+	var collapse_space *bool = fs.Bool("collapse-space", false, "collapse repeated spaces and tabs")
+	var strip_period *bool = fs.Bool("strip-period", false, "remove a trailing period from each message")
+	var strip_prefix *string = fs.String("strip-prefix", "", "comma separated `prefixes` to remove from messages, like \"pkgname:\"")
+	cache_dir, no_cache := addCacheFlags(fs)
+	resolver_flags := addResolverFlags(fs)
+	var color *string = fs.String("color", "auto", "color the output: `auto`, always or never")
+	var no_pager *bool = fs.Bool("no-pager", false, "do not pipe the output through $PAGER")
+	var no_progress *bool = fs.Bool("no-progress", false, "do not show the progress on stderr")
+	var require_names *bool = fs.Bool("require-names", false, "exit with an error if any nick could not be resolved")
+	var report *string = fs.String("report", "", "also write the summary of the issues at the end of the run to this JSON `file`")
+	var dry_run *bool = fs.Bool("dry-run", false, "fetch the log and resolve the names, but only report what would be written")
+	var transform *string = fs.String("transform", "", "a `file` with rules for rewriting, dropping or retagging entries")
+	var pre_entry_hook *string = fs.String("pre-entry-hook", "", "a shell `command` that gets each entry as JSON on stdin and outputs the modified entry, or nothing to drop it")
+	var post_generate_hook *string = fs.String("post-generate-hook", "", "a shell `command` that gets all the entries as a JSON array on stdin and outputs the modified array")
+	var timing *bool = fs.Bool("timing", false, "show the time spent in each phase, like fetching the log and resolving names, on stderr")
+	var unknown_author *string = addUnknownAuthorFlag(fs)
+	var lang *string = addLanguageFlag(fs)
+	anonymize := addAnonymizeFlag(fs)
+	var obfuscate_email *string = fs.String("obfuscate-email", "", "obfuscate the e-mail addresses: `at` for \"alice at example dot org\", entities for HTML character references in the html and markdown formats, or drop")
+	var avatars *string = fs.String("avatars", "", "add the avatars of the authors to the html and json formats, from `gravatar` or libravatar")
+	var search *bool = fs.Bool("search", false, "add a search by text, author and date to the html format, with a small script in the page")
+	var permalink_template *string = fs.String("permalink-template", "", "add a link to each section and entry in the html format, to this `URL`, where {id}, {revision} and {date} are replaced, like https://example.org/ChangeLog.html#{id}")
+	var eol *string = fs.String("eol", changelog.EOL_LF, "the line endings of the ChangeLog: lf, or crlf for Windows")
+	var ascii *bool = fs.Bool("ascii", false, "transliterate the names of the authors to ASCII, like Rodseth for Rødseth, for systems that can not show UTF-8")
+	var bom *bool = fs.Bool("bom", false, "start the ChangeLog with a UTF-8 byte order mark, for editors on Windows that need it")
+	var generated_at *bool = fs.Bool("generated-at", false, "add a footer with the time the ChangeLog was generated, which is $SOURCE_DATE_EPOCH if it is set")
+	var footer *bool = fs.Bool("footer", false, "add a footer with the version of archlog and the revisions the ChangeLog was generated from, and the time, unless $SOURCE_DATE_EPOCH is set")
+	var footer_template *string = fs.String("footer-template", "", "the `text` of the -footer, where {generator}, {from}, {to} and {date} are replaced, like \"Generated by {generator} from r{from}..r{to}\"")
+	var metadata *bool = fs.Bool("metadata", false, "add the version of archlog and the flags to the json format and the -report file, which makes them differ between versions")
+	var relative_dates *bool = fs.Bool("relative-dates", false, "add how long ago each entry was to the headers, like \"(3 days ago)\", counted from $SOURCE_DATE_EPOCH if it is set")
+	var versions *bool = fs.Bool("versions", false, "group the entries by the version of the package they were released in, from the history of the PKGBUILD and .SRCINFO")
+	var upgrades *bool = fs.Bool("upgrades", false, "add an \"Upgraded to\" line to the commits that changed the version of the package")
+	var pkgbuild_file *string = fs.String("pkgbuild", "PKGBUILD", "the PKGBUILD `file` for -versions, -upgrades and -maintainers, relative to the working copy")
+	var maintainers *bool = fs.Bool("maintainers", false, "write the current maintainers of the package at the top of the ChangeLog, from the package search on archlinux.org")
+	var split_by *string = fs.String("split-by", "", "write one ChangeLog per `package` (directory with a PKGBUILD) or top level directory to -out-dir, with the commits that changed it, or one per year next to the -o file, like ChangeLog.2023")
+	var out_dir *string = fs.String("out-dir", "changelogs", "the `directory` for the ChangeLogs of -split-by and -each, named like archlog"+SPLIT_EXTENSION)
+	sign_flags := addSignFlags(fs)
+	provenance_flags := addProvenanceFlags(fs)
+	notify_flags := addNotifyFlags(fs)
+	strict, lenient, input_encoding := addParseFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	language, err := checkLanguage(*lang)
+	if err != nil {
+		return err
+	}
+	if err := changelog.CheckObfuscateEmail(*obfuscate_email); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if err := changelog.CheckAvatars(*avatars); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	parsing, err := parseMode(*strict, *lenient)
+	if err != nil {
+		return err
+	}
+	encoding, err := changelog.CheckEncoding(*input_encoding)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if err := changelog.CheckEOL(*eol); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	notifiers, err := notify_flags.notifiers()
+	if err != nil {
+		return err
+	}
+
+	n, err := parseEntries(fs.Args())
+	if err != nil {
+		return err
+	}
+	if _, err := changelog.LookupSource(*vcs); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	var generatedAt time.Time
+	if *generated_at {
+		if *prepend != "" || *check != "" {
+			return withCode(EXIT_USAGE, errors.New("-generated-at can not be used with -prepend or -check"))
+		}
+		if generatedAt, err = sourceDate(); err != nil {
+			return withCode(EXIT_USAGE, err)
+		}
+	}
+	if *footer {
+		if *prepend != "" || *check != "" || *generated_at {
+			return withCode(EXIT_USAGE, errors.New("-footer can not be used with -prepend, -check or -generated-at"))
+		}
+	} else if *footer_template != "" {
+		return withCode(EXIT_USAGE, errors.New("-footer-template only works with -footer"))
+	}
+	since, err := last_flags.since()
+	if err != nil {
+		return err
+	}
+	if since != "" && (*prepend != "" || *check != "" || *incremental) {
+		return withCode(EXIT_USAGE, errors.New("-last-days, -last-weeks and -last-months can not be used with -prepend, -check or -incremental"))
+	}
+	// Only the newest entries would be fetched, and the ones before them would never be written
+	if n != -1 && *incremental {
+		return withCode(EXIT_USAGE, errors.New("-incremental can not be used with a number of entries"))
+	}
+	var relativeTo time.Time
+	if *relative_dates {
+		if *prepend != "" || *check != "" {
+			return withCode(EXIT_USAGE, errors.New("-relative-dates can not be used with -prepend or -check"))
+		}
+		if relativeTo, err = sourceDate(); err != nil {
+			return withCode(EXIT_USAGE, err)
+		}
+	}
+	norm := &changelog.Normalization{
+		Capitalize:    *normalize || *capitalize,
+		CollapseSpace: *normalize || *collapse_space,
+		StripPeriod:   *normalize || *strip_period,
+		Prefixes:      changelog.SplitPrefixes(*strip_prefix),
+	}
+	// With -each, the name of each package is guessed from its own directory
+	guessPrefixes := *normalize && len(norm.Prefixes) == 0
+	if guessPrefixes {
+		if pkgname := changelog.GuessPackageName(*repo); pkgname != "" {
+			norm.Prefixes = []string{pkgname}
+		}
+	}
+	g := changelog.New(&changelog.Options{
+		Repo:          *repo,
+		VCS:           *vcs,
+		SvnBin:        *svn_bin,
+		GitBin:        *git_bin,
+		MockData:      *mock_data,
+		Timeout:       *timeout,
+		Jobs:          *jobs,
+		StopOnCopy:    *stop_on_copy,
+		SvnPath:       *svn_path,
+		Externals:     *externals,
+		Submodules:    *submodules,
+		Entries:       n,
+		Normalization: norm,
+		Since:         since,
+		Format:        *format,
+		Parsing:       parsing,
+		InputEncoding: encoding,
+		EOL:           *eol,
+		BOM:           *bom,
+		ASCII:         *ascii,
+		GeneratedAt:   generatedAt,
+		RelativeTo:    relativeTo,
+		UnknownAuthor: *unknown_author,
+		Language:      language,
+		Anonymize:     anonymize.style,
+		Obfuscate:     *obfuscate_email,
+		Avatars:       *avatars,
+		Search:        *search,
+		Versions:      *versions,
+		Upgrades:      *upgrades,
+		Pkgbuild:      *pkgbuild_file,
+		Progress:      status.Report,
+
+		PreEntryHook:     *pre_entry_hook,
+		PostGenerateHook: *post_generate_hook,
+
+		PermalinkTemplate: *permalink_template,
+	})
+	svn_auth.apply(g.Options)
+	if *metadata {
+		g.Options.Metadata = flagMetadata(fs)
+	}
+	if *footer {
+		g.Options.Footer = &changelog.Footer{Template: *footer_template, Generator: "archlog " + VERSION}
+		// The time is left out for reproducible builds
+		if os.Getenv("SOURCE_DATE_EPOCH") == "" {
+			g.Options.Footer.Date = time.Now()
+		}
+	}
+	if err := branch_flags.apply(g.Options); err != nil {
+		return err
+	}
+	if err := checkSvnOptions(g.Options); err != nil {
+		return err
+	}
+	if *submodules && *vcs != "git" {
+		return withCode(EXIT_USAGE, errors.New("-submodules only works with -vcs git"))
+	}
+	if err := aggregate_flags.apply(g.Options); err != nil {
+		return err
+	}
+	eachRepositories, err := each_flags.expand()
+	if err != nil {
+		return err
+	}
+	if len(eachRepositories) > 0 && (*repo != "" || *output != "" || *prepend != "" || *check != "" || *diff || *maintainers || *split_by != "" || len(g.Options.Repositories) > 0 || len(notifiers) > 0) {
+		return withCode(EXIT_USAGE, errors.New("-each can not be used with -repo, -o, -prepend, -check, -diff, -maintainers, -split-by, -aggregate or the notifications"))
+	}
+	if len(eachRepositories) > 0 && *out_dir == "" {
+		return withCode(EXIT_USAGE, errors.New("Please provide a directory for the ChangeLogs with -out-dir"))
+	}
+	// The revision numbers of the repositories overlap, and the package is not known
+	if len(g.Options.Repositories) > 0 && (*incremental || *versions || *upgrades || *maintainers || *split_by == SPLIT_PACKAGE || *split_by == SPLIT_DIRECTORY) {
+		return withCode(EXIT_USAGE, errors.New("-aggregate can not be used with -incremental, -versions, -upgrades, -maintainers or -split-by package or directory"))
+	}
+	// The revision numbers of several branches overlap
+	if len(g.Options.Branches) > 1 && (*incremental || *versions || *upgrades || *split_by == SPLIT_PACKAGE || *split_by == SPLIT_DIRECTORY) {
+		return withCode(EXIT_USAGE, errors.New("Several -branch can not be used with -incremental, -versions, -upgrades or -split-by package or directory"))
+	}
+	// The revision numbers of the externals are from other repositories
+	if *externals && (*incremental || *versions || *upgrades || *split_by == SPLIT_PACKAGE || *split_by == SPLIT_DIRECTORY) {
+		return withCode(EXIT_USAGE, errors.New("-externals can not be used with -incremental, -versions, -upgrades or -split-by package or directory"))
+	}
+	g.Names.Client.Timeout = *timeout
+	if err := setupResolvers(g.Names, resolver_flags); err != nil {
+		return err
+	}
+	if *transform != "" {
+		if g.Options.Transform, err = changelog.LoadTransform(*transform); err != nil {
+			return withCode(EXIT_PARSE, err)
+		}
+	}
+	if _, err := changelog.NewFormatter(*format, g.Options); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if *format != "plain" && (*prepend != "" || *check != "" || *diff) {
+		return withCode(EXIT_USAGE, errors.New("-prepend, -check and -diff only work with the plain format"))
+	}
+	if *format != "html" && (*permalink_template != "" || *search) {
+		return withCode(EXIT_USAGE, errors.New("-permalink-template and -search only work with the html format"))
+	}
+	switch *split_by {
+	case "":
+	case SPLIT_PACKAGE, SPLIT_DIRECTORY:
+		if *output != "" || *prepend != "" || *check != "" || *diff || *incremental || *versions || *upgrades || *maintainers {
+			return withCode(EXIT_USAGE, errors.New("-split-by package or directory can not be used with -o, -prepend, -check, -diff, -incremental, -versions, -upgrades or -maintainers"))
+		}
+		if *out_dir == "" {
+			return withCode(EXIT_USAGE, errors.New("Please provide a directory for the ChangeLogs with -out-dir"))
+		}
+	case SPLIT_YEAR:
+		if *prepend != "" || *check != "" || *diff || *incremental {
+			return withCode(EXIT_USAGE, errors.New("-split-by year can not be used with -prepend, -check, -diff or -incremental"))
+		}
+		if *output == "" || *output == "-" {
+			*output = "ChangeLog"
+		}
+	default:
+		return withCode(EXIT_USAGE, fmt.Errorf("Invalid -split-by, expected package, directory or year: %s", *split_by))
+	}
+	dest := &Destination{
+		Filename:     *output,
+		Prepend:      *prepend,
+		Check:        *check,
+		Incremental:  *incremental,
+		Diff:         *diff,
+		NoPager:      *no_pager,
+		RequireNames: *require_names,
+		DryRun:       *dry_run,
+	}
+	if err := sign_flags.check(dest); err != nil {
+		return err
+	}
+	if err := provenance_flags.check(dest); err != nil {
+		return err
+	}
+	if dest.writesToStdout() && *split_by == "" && len(eachRepositories) == 0 {
+		if g.Options.Color, err = useColor(*color, os.Stdout); err != nil {
+			return withCode(EXIT_USAGE, err)
+		}
+	}
+	if err := setupNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	// Nothing is written to the cache directory with -dry-run
+	setupPageCache(g.Names, *cache_dir, *no_cache || *dry_run)
+	if *maintainers {
+		if g.Options.Maintainers, err = packageMaintainers(ctx, g.Names, changelog.PACKAGES_API_URL, packageName(*repo, *pkgbuild_file)); err != nil {
+			return err
+		}
+	}
+	if *timing {
+		g.Options.Timings = &changelog.Timings{}
+	}
+	// Nothing is posted when the ChangeLog is not written
+	if dest.DryRun || dest.Check != "" || dest.Diff {
+		notifiers = nil
+	}
+	// The notifications and the provenance need the entries after they are written
+	dest.KeepEntries = len(notifiers) > 0 || *provenance_flags.provenance
+	summary := g.Summarizer()
+	dest.Summary = summary
+	var previous string
+	if len(notifiers) > 0 && *format == "plain" {
+		if previous, err = previousChangeLog(dest); err != nil {
+			return err
+		}
+	}
+	status.Enable(!*no_progress)
+	defer writeTimings(g.Options.Timings, time.Now())
+	var (
+		entries []changelog.Entry
+		genErr  error
+	)
+	switch {
+	case len(eachRepositories) > 0:
+		entries, genErr = eachChangeLogs(ctx, dest, g, eachRepositories, *out_dir, *each_flags.parallel, guessPrefixes)
+	case *split_by == SPLIT_YEAR:
+		entries, genErr = splitChangeLogByYear(ctx, dest, g)
+		summary.Add(entries...)
+	case *split_by == SPLIT_PACKAGE || *split_by == SPLIT_DIRECTORY:
+		entries, genErr = splitChangeLogs(ctx, dest, g, *split_by, *out_dir)
+		summary.Add(entries...)
+	default:
+		entries, genErr = generate(ctx, dest, g)
+	}
+	if err := writeSummary(dest, summary.Summary(), *report); err != nil {
+		return err
+	}
+	if dest.DryRun && !*no_cache && *cache_dir != "" {
+		fmt.Fprintf(dest.report(), "Would update the nick cache in %s\n", nickCacheFilename(*cache_dir))
+	} else if err := storeNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	if genErr != nil {
+		return genErr
+	}
+	if dest.RequireNames && dest.Check == "" && !dest.Diff {
+		if err := unresolvedError(summary.Summary()); err != nil {
+			return err
+		}
+	}
+	signature, err := sign_flags.sign(ctx, dest)
+	if err != nil {
+		return err
+	}
+	if err := provenance_flags.write(ctx, dest, g, entries, signature); err != nil {
+		return err
+	}
+	if err := notifyAll(ctx, &http.Client{Timeout: *timeout}, notifiers, g, entries, previous); err != nil {
+		return err
+	}
+	return g.Names.NetworkError()
+}
+
+// Read the nicks to resolve, one per line, leaving out empty lines
+func readNicks(r io.Reader) ([]string, error) {
+	var nicks []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if nick := strings.TrimSpace(scanner.Text()); nick != "" {
+			nicks = append(nicks, nick)
+		}
+	}
+	return nicks, scanner.Err()
+}
+
+// archlog resolve
+func runResolve(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	cache_dir, no_cache := addCacheFlags(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	resolver_flags := addResolverFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	nicks := fs.Args()
+	if len(nicks) == 0 || (len(nicks) == 1 && nicks[0] == "-") {
+		if len(nicks) == 0 && isTerminal(os.Stdin) {
+			return withCode(EXIT_USAGE, errors.New("Please provide one or more nicks to resolve, or one per line on stdin.\nUse --help for more info."))
+		}
+		var err error
+		if nicks, err = readNicks(os.Stdin); err != nil {
+			return err
+		}
+	}
+	names := changelog.NewNames()
+	names.Client.Timeout = *timeout
+	if err := setupResolvers(names, resolver_flags); err != nil {
+		return err
+	}
+	if err := setupNickCache(names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	setupPageCache(names, *cache_dir, *no_cache)
+	if err := names.ResolveAll(ctx, nicks, nil); err != nil {
+		return err
+	}
+	for _, nick := range nicks {
+		fmt.Printf("%s\t%s\n", nick, names.Resolve(ctx, nick))
+	}
+	if err := storeNickCache(names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	return names.NetworkError()
+}
+
+// archlog cache
+func runCache(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var cache_dir *string = addCacheDirFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *cache_dir == "" {
+		return errors.New("Could not find a cache directory, please provide one with -cache-dir")
+	}
+	filename := nickCacheFilename(*cache_dir)
+	action := "list"
+	if fs.NArg() > 0 {
+		action = fs.Arg(0)
+	}
+	switch action {
+	case "list":
+		data, err := ioutil.ReadFile(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// The cache file is already sorted by nick
+		os.Stdout.Write(data)
+	case "clear":
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.RemoveAll(pageCacheDir(*cache_dir)); err != nil {
+			return err
+		}
+	case "path":
+		fmt.Println(filename)
+	default:
+		return withCode(EXIT_USAGE, fmt.Errorf("Unknown cache action: %s\nUse --help for more info.", action))
+	}
+	return nil
+}
+
+// archlog stats
+func runStats(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	repo, vcs, svn_bin, git_bin := addSourceFlags(fs)
+	mock_data, svn_path, externals := addLogFlags(fs)
+	svn_auth := addSvnAuthFlags(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	jobs, stop_on_copy := addFetchFlags(fs)
+	branch_flags := addBranchFlags(fs)
+	var resolve *bool = fs.Bool("resolve", false, "show names and e-mail addresses instead of nicks")
+	var no_progress *bool = fs.Bool("no-progress", false, "do not show the progress on stderr")
+	cache_dir, no_cache := addCacheFlags(fs)
+	resolver_flags := addResolverFlags(fs)
+	var unknown_author *string = addUnknownAuthorFlag(fs)
+	var timing *bool = fs.Bool("timing", false, "show the time spent in each phase, like fetching the log and resolving names, on stderr")
+	strict, lenient, input_encoding := addParseFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	parsing, err := parseMode(*strict, *lenient)
+	if err != nil {
+		return err
+	}
+	encoding, err := changelog.CheckEncoding(*input_encoding)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+
+	n, err := parseEntries(fs.Args())
+	if err != nil {
+		return err
+	}
+	if _, err := changelog.LookupSource(*vcs); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	status.Enable(!*no_progress)
+	g := changelog.New(&changelog.Options{Repo: *repo, VCS: *vcs, SvnBin: *svn_bin, GitBin: *git_bin, MockData: *mock_data, Timeout: *timeout, Jobs: *jobs, StopOnCopy: *stop_on_copy, SvnPath: *svn_path, Externals: *externals, Entries: n, Parsing: parsing, InputEncoding: encoding, Progress: status.Report})
+	svn_auth.apply(g.Options)
+	if err := branch_flags.apply(g.Options); err != nil {
+		return err
+	}
+	if err := checkSvnOptions(g.Options); err != nil {
+		return err
+	}
+	g.Names.Client.Timeout = *timeout
+	if *timing {
+		g.Options.Timings = &changelog.Timings{}
+		defer writeTimings(g.Options.Timings, time.Now())
+	}
+	if *resolve {
+		if err := setupResolvers(g.Names, resolver_flags); err != nil {
+			return err
+		}
+		if err := setupNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+			return err
+		}
+		setupPageCache(g.Names, *cache_dir, *no_cache)
+	}
+	var (
+		counts         = make(map[string]int)
+		anonymous      int // Entries without an author
+		revisions      int
+		empty          int
+		first, last    string
+		firstRevision  int
+		latestRevision int
+	)
+	// Go through the entries as they arrive, without keeping them around
+	for entry, err := range g.Stream(ctx) {
+		if err != nil {
+			status.Done()
+			return err
+		}
+		revisions++
+		if strings.TrimSpace(entry.Author) == "" {
+			anonymous++
+		} else {
+			counts[entry.Author]++
+		}
+		if strings.TrimSpace(entry.Message) == "" {
+			empty++
+		}
+		// The entries are ordered from the newest to the oldest
+		if last == "" {
+			last, latestRevision = entry.Day(), entry.Revision
+		}
+		first, firstRevision = entry.Day(), entry.Revision
+	}
+	if *resolve {
+		// Look up the names of all the authors at once, and count the commits by name
+		nicks := make([]string, 0, len(counts))
+		for nick := range counts {
+			nicks = append(nicks, nick)
+		}
+		sort.Strings(nicks)
+		stop := g.Options.Timings.Start("resolve")
+		err := g.Names.ResolveAll(ctx, nicks, func(done, total int) {
+			status.Report("Resolving names", done, total)
+		})
+		stop()
+		if err != nil {
+			status.Done()
+			return err
+		}
+		byName := make(map[string]int, len(counts))
+		for _, nick := range nicks {
+			byName[g.Names.Resolve(ctx, nick)] += counts[nick]
+		}
+		counts = byName
+	}
+	if anonymous > 0 {
+		// The entries without an author are not looked up
+		counts[*unknown_author] += anonymous
+	}
+	status.Done()
+	authors := make([]string, 0, len(counts))
+	for author := range counts {
+		authors = append(authors, author)
+	}
+	// Sort by the number of commits, then by name
+	sort.Slice(authors, func(i, j int) bool {
+		if counts[authors[i]] != counts[authors[j]] {
+			return counts[authors[i]] > counts[authors[j]]
+		}
+		return authors[i] < authors[j]
+	})
+	fmt.Printf("Revisions: %d\n", revisions)
+	fmt.Printf("Empty messages: %d\n", empty)
+	if revisions > 0 {
+		fmt.Printf("First: %s (r%d)\n", first, firstRevision)
+		fmt.Printf("Last: %s (r%d)\n", last, latestRevision)
+	}
+	fmt.Printf("Authors: %d\n", len(authors))
+	for _, author := range authors {
+		fmt.Printf("%8d %s\n", counts[author], changelog.Sanitize(author, false))
+	}
+	if *resolve {
+		if err := storeNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+			return err
+		}
+		return g.Names.NetworkError()
+	}
+	return nil
+}
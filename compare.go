@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The labels of the kinds of changes to an entry
+var changeLabels = map[string]string{"added": "Added", "modified": "Modified", "removed": "Removed"}
+
+// Write the changes between two ChangeLogs, one entry at a time, with the
+// messages that were added or removed below each header
+func writeEntryChanges(w io.Writer, changes []changelog.EntryChange) error {
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Kind]++
+		if _, err := fmt.Fprintf(w, "%s %s %s\n", changeLabels[change.Kind], change.Date, change.Name); err != nil {
+			return err
+		}
+		for _, messages := range []struct {
+			op   string
+			list []string
+		}{{"-", change.Removed}, {"+", change.Added}} {
+			for _, msg := range messages.list {
+				if _, err := fmt.Fprintf(w, "    %s %s\n", messages.op, strings.Replace(msg, "\n", "\n      ", -1)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d added, %d modified and %d removed entries\n", counts["added"], counts["modified"], counts["removed"])
+	return err
+}
+
+// archlog diff
+func runDiff(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var exit_code *bool = fs.Bool("exit-code", false, "exit with 8 if the ChangeLogs have different entries, like -check does when entries are missing")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return withCode(EXIT_USAGE, errors.New("Please provide the old and the new ChangeLog.\nUse --help for more info."))
+	}
+	var contents [2]string
+	for i, filename := range fs.Args() {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		contents[i] = string(data)
+	}
+	changes := changelog.CompareChangeLogs(changelog.ParseChangeLog(contents[0]), changelog.ParseChangeLog(contents[1]))
+	if err := writeEntryChanges(os.Stdout, changes); err != nil {
+		return err
+	}
+	if *exit_code && len(changes) > 0 {
+		return withCode(EXIT_OUTDATED, fmt.Errorf("%s and %s have different entries", fs.Arg(0), fs.Arg(1)))
+	}
+	return nil
+}
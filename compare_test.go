@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestWriteEntryChanges(t *testing.T) {
+	var buf bytes.Buffer
+	changes := []changelog.EntryChange{
+		{Kind: "added", Date: "2014-04-01", Name: "arodseth", Added: []string{"upgpkg: 4.3.3-1"}},
+		{Kind: "modified", Date: "2014-03-17", Name: "arodseth", Added: []string{"New\nlines"}, Removed: []string{"Old"}},
+	}
+	if err := writeEntryChanges(&buf, changes); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Added 2014-04-01 arodseth\n    + upgpkg: 4.3.3-1\nModified 2014-03-17 arodseth\n    - Old\n    + New\n      lines\n1 added, 1 modified and 0 removed entries\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The result of one of the checks of archlog doctor
+type diagnosis struct {
+	check   string // What was checked, like "svn"
+	ok      bool
+	details string // What was found, or what is wrong
+	fix     string // How to fix it, if it is not ok
+}
+
+// Write the results of the checks, one per line, with how to fix the problems
+func writeDiagnoses(w io.Writer, diagnoses []diagnosis) error {
+	for _, d := range diagnoses {
+		result := "ok  "
+		if !d.ok {
+			result = "FAIL"
+		}
+		if _, err := fmt.Fprintf(w, "%s  %s: %s\n", result, d.check, d.details); err != nil {
+			return err
+		}
+		if !d.ok && d.fix != "" {
+			if _, err := fmt.Fprintf(w, "      Fix: %s\n", d.fix); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Check that an executable can be found and run, and find its version. It
+// is only a problem if it can not be found when it is required.
+func checkExecutable(ctx context.Context, name, bin, flag string, required bool, args ...string) diagnosis {
+	path, err := exec.LookPath(bin)
+	if err != nil && !required {
+		return diagnosis{check: name, ok: true, details: bin + " could not be found in the PATH, which is only needed with -vcs " + name}
+	}
+	if err != nil {
+		return diagnosis{check: name, details: bin + " could not be found in the PATH", fix: "Install " + name + ", or give the path to it with -" + flag}
+	}
+	output, err := exec.CommandContext(ctx, path, args...).Output()
+	if err != nil {
+		return diagnosis{check: name, details: path + " could not be run: " + err.Error(), fix: "Check that " + path + " works, or give another one with -" + flag}
+	}
+	version := strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+	return diagnosis{check: name, ok: true, details: version + " (" + path + ")"}
+}
+
+// Check that the directory is a working copy of the version control system,
+// and suggest another -vcs if it is a working copy of another one
+func checkRepository(ctx context.Context, opts *changelog.Options) diagnosis {
+	dir := opts.Repo
+	if dir == "" {
+		dir = "the current directory"
+	}
+	found := make(map[string]string)
+	for _, name := range changelog.SourceNames() {
+		source, err := changelog.LookupSource(name)
+		if err != nil {
+			continue
+		}
+		located, ok := source.(changelog.LocatedSource)
+		if !ok {
+			continue
+		}
+		vcsOpts := *opts
+		vcsOpts.VCS = name
+		if url, err := located.RepositoryURL(ctx, &vcsOpts); err == nil {
+			found[name] = url
+		}
+	}
+	if url, ok := found[opts.VCS]; ok {
+		return diagnosis{check: "repository", ok: true, details: fmt.Sprintf("%s is a %s working copy of %s", dir, opts.VCS, url)}
+	}
+	for _, name := range changelog.SourceNames() {
+		url, ok := found[name]
+		if !ok {
+			continue
+		}
+		return diagnosis{check: "repository", details: fmt.Sprintf("%s is not a %s working copy, but a %s working copy of %s", dir, opts.VCS, name, url), fix: "Use -vcs " + name}
+	}
+	return diagnosis{check: "repository", details: dir + " is not a working copy", fix: "Run archlog in a working copy, or give its directory with -repo"}
+}
+
+// Check that a server that archlog uses can be reached
+func checkServer(ctx context.Context, client *http.Client, url, userAgent string) diagnosis {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return diagnosis{check: url, details: err.Error()}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		var (
+			verifyErr    *tls.CertificateVerificationError
+			authorityErr x509.UnknownAuthorityError
+		)
+		if errors.As(err, &verifyErr) || errors.As(err, &authorityErr) {
+			return diagnosis{check: url, details: "the certificate could not be verified: " + err.Error(), fix: "Behind a proxy with its own CA, trust it with -ca-file"}
+		}
+		return diagnosis{check: url, details: "could not be reached: " + err.Error(), fix: "Check the network, or use a proxy with -proxy or HTTPS_PROXY"}
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return diagnosis{check: url, details: resp.Status, fix: "The server has problems, try again later"}
+	}
+	return diagnosis{check: url, ok: true, details: fmt.Sprintf("%s in %s", resp.Status, time.Since(start).Round(time.Millisecond))}
+}
+
+// Check that the cache directory can be written to, and that the nick cache
+// can be read
+func checkCache(cacheDir string) []diagnosis {
+	if cacheDir == "" {
+		return []diagnosis{{check: "cache", details: "there is no cache directory", fix: "Give one with -cache-dir, or set XDG_CACHE_HOME"}}
+	}
+	info, err := os.Stat(cacheDir)
+	switch {
+	case os.IsNotExist(err):
+		return []diagnosis{{check: "cache", ok: true, details: cacheDir + " will be created when it is needed"}}
+	case err != nil:
+		return []diagnosis{{check: "cache", details: err.Error(), fix: "Give another directory with -cache-dir"}}
+	case !info.IsDir():
+		return []diagnosis{{check: "cache", details: cacheDir + " is not a directory", fix: "Remove it, or give another directory with -cache-dir"}}
+	}
+	f, err := os.CreateTemp(cacheDir, ".doctor-*")
+	if err != nil {
+		return []diagnosis{{check: "cache", details: cacheDir + " can not be written to: " + err.Error(), fix: "Fix the permissions, or give another directory with -cache-dir"}}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	diagnoses := []diagnosis{{check: "cache", ok: true, details: cacheDir + " can be written to"}}
+	filename := nickCacheFilename(cacheDir)
+	if _, err := os.Stat(filename); err == nil {
+		names := changelog.NewNames()
+		if err := names.Load(filename); err != nil {
+			diagnoses = append(diagnoses, diagnosis{check: "nick cache", details: err.Error(), fix: "Clear it with archlog cache clear"})
+		} else {
+			diagnoses = append(diagnoses, diagnosis{check: "nick cache", ok: true, details: filename + " can be read"})
+		}
+	}
+	return diagnoses
+}
+
+// Check the settings from the environment that are not flags, and list the
+// ARCHLOG_* variables that are set
+func checkEnvironment() []diagnosis {
+	var diagnoses []diagnosis
+	if _, err := sourceDate(); err != nil {
+		diagnoses = append(diagnoses, diagnosis{check: "SOURCE_DATE_EPOCH", details: err.Error(), fix: "Set it to the number of seconds since 1970-01-01, or unset it"})
+	}
+	if fields := pagerCommand(); fields != nil {
+		if _, err := exec.LookPath(fields[0]); err != nil {
+			diagnoses = append(diagnoses, diagnosis{check: "PAGER", details: fields[0] + " could not be found in the PATH, so the output is not paged", fix: "Install it, set PAGER to another pager, or use -no-pager"})
+		}
+	}
+	if proxy := os.Getenv("HTTPS_PROXY"); proxy != "" {
+		if err := changelog.NewNames().SetProxy(proxy); err != nil {
+			diagnoses = append(diagnoses, diagnosis{check: "HTTPS_PROXY", details: err.Error(), fix: "Set it to a proxy like http://proxy:3128, or use -proxy"})
+		}
+	}
+	var variables []string
+	for _, env := range os.Environ() {
+		if name, _, _ := strings.Cut(env, "="); strings.HasPrefix(name, "ARCHLOG_") && name != "ARCHLOG_HOOK" {
+			variables = append(variables, name)
+		}
+	}
+	sort.Strings(variables)
+	if len(variables) > 0 {
+		diagnoses = append(diagnoses, diagnosis{check: "environment", ok: true, details: "the flags are also set by " + strings.Join(variables, ", ")})
+	}
+	return diagnoses
+}
+
+// archlog doctor
+func runDoctor(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	repo, vcs, svn_bin, git_bin := addSourceFlags(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	var cache_dir *string = addCacheDirFlag(fs)
+	resolver_flags := addResolverFlags(fs)
+	var offline *bool = fs.Bool("offline", false, "do not check if the servers can be reached")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if _, err := changelog.LookupSource(*vcs); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+
+	opts := &changelog.Options{Repo: *repo, VCS: *vcs, SvnBin: *svn_bin, GitBin: *git_bin, Timeout: *timeout}
+	diagnoses := []diagnosis{
+		checkExecutable(ctx, "svn", *svn_bin, "svn-bin", *vcs == "svn", "--version", "--quiet"),
+		checkExecutable(ctx, "git", *git_bin, "git-bin", *vcs == "git", "--version"),
+		checkRepository(ctx, opts),
+	}
+	names := changelog.NewNames()
+	names.Client.Timeout = *timeout
+	if err := setupResolvers(names, resolver_flags); err != nil {
+		diagnoses = append(diagnoses, diagnosis{check: "resolvers", details: err.Error(), fix: "Check the -resolvers, -authors, -proxy and -ca-file flags"})
+	} else if *resolver_flags.authors != "" {
+		diagnoses = append(diagnoses, diagnosis{check: "authors file", ok: true, details: *resolver_flags.authors + " can be read"})
+	}
+	diagnoses = append(diagnoses, checkEnvironment()...)
+	diagnoses = append(diagnoses, checkCache(*cache_dir)...)
+	if !*offline {
+		for _, url := range []string{changelog.TU_URL, changelog.PACKAGES_API_URL, changelog.AUR_RPC_URL, ARCH_GITLAB_URL} {
+			diagnoses = append(diagnoses, checkServer(ctx, names.Client, url, *resolver_flags.userAgent))
+		}
+	}
+	if err := writeDiagnoses(os.Stdout, diagnoses); err != nil {
+		return err
+	}
+	problems := 0
+	for _, d := range diagnoses {
+		if !d.ok {
+			problems++
+		}
+	}
+	switch {
+	case problems == 1:
+		return errors.New("Found 1 problem")
+	case problems > 1:
+		return fmt.Errorf("Found %d problems", problems)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteDiagnoses(t *testing.T) {
+	var buf bytes.Buffer
+	diagnoses := []diagnosis{
+		{check: "svn", ok: true, details: "1.14.3 (/usr/bin/svn)"},
+		{check: "repository", details: ". is not a working copy", fix: "Use -repo"},
+	}
+	if err := writeDiagnoses(&buf, diagnoses); err != nil {
+		t.Fatal(err)
+	}
+	expected := "ok    svn: 1.14.3 (/usr/bin/svn)\nFAIL  repository: . is not a working copy\n      Fix: Use -repo\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if d := checkCache(filepath.Join(dir, "missing")); len(d) != 1 || !d[0].ok {
+		t.Fatalf("a missing cache directory should be fine: %+v", d)
+	}
+	if err := ioutil.WriteFile(nickCacheFilename(dir), []byte("arodseth\tAlexander Rødseth <rodseth@gmail.com>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if d := checkCache(dir); len(d) != 2 || !d[0].ok || !d[1].ok {
+		t.Fatalf("expected a working cache: %+v", d)
+	}
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if d := checkCache(file); len(d) != 1 || d[0].ok {
+		t.Fatalf("a file should not work as a cache directory: %+v", d)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The default number of working copies that ChangeLogs are generated for
+// at the same time, with -each
+const DEFAULT_PARALLEL = 4
+
+// The flags for generating a ChangeLog for each of several working copies
+type eachFlags struct {
+	repositories *repositoryList
+	parallel     *int
+}
+
+// Add the -each and -parallel flags
+func addEachFlags(fs *flag.FlagSet) *eachFlags {
+	flags := &eachFlags{repositories: &repositoryList{}}
+	fs.Var(flags.repositories, "each", "write a ChangeLog for this working copy to -out-dir, as `label=directory` or only the directory, which may be a pattern like pkgs/*, and can be given several times")
+	flags.parallel = fs.Int("parallel", DEFAULT_PARALLEL, "the `number` of working copies to generate the ChangeLogs for at the same time, with -each")
+	return flags
+}
+
+// The working copies of -each, with the patterns expanded to the
+// directories they match, and a label for each, which is used for the
+// name of its ChangeLog
+func (flags *eachFlags) expand() ([]changelog.Repository, error) {
+	var repositories []changelog.Repository
+	labels := make(map[string]string)
+	add := func(repository changelog.Repository) error {
+		if repository.Label == "" {
+			repository.Label = changelog.GuessPackageName(repository.Dir)
+		}
+		if other, ok := labels[repository.Label]; ok {
+			return withCode(EXIT_USAGE, fmt.Errorf("Both %s and %s would be written to %s%s, please give one of them a label with -each label=directory", other, repository.Dir, repository.Label, SPLIT_EXTENSION))
+		}
+		labels[repository.Label] = repository.Dir
+		repositories = append(repositories, repository)
+		return nil
+	}
+	for _, repository := range *flags.repositories {
+		if !strings.ContainsAny(repository.Dir, "*?[") {
+			if err := add(repository); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if repository.Label != "" {
+			return nil, withCode(EXIT_USAGE, fmt.Errorf("A pattern can not have a label: %s=%s", repository.Label, repository.Dir))
+		}
+		matches, err := filepath.Glob(repository.Dir)
+		if err != nil {
+			return nil, withCode(EXIT_USAGE, fmt.Errorf("Invalid pattern for -each: %s", repository.Dir))
+		}
+		for _, match := range matches {
+			if fi, err := os.Stat(match); err != nil || !fi.IsDir() {
+				continue
+			}
+			if err := add(changelog.Repository{Dir: match}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(*flags.repositories) > 0 && len(repositories) == 0 {
+		return nil, withCode(EXIT_NO_REPO, errors.New("There are no directories that match -each"))
+	}
+	if *flags.parallel < 1 {
+		return nil, withCode(EXIT_USAGE, errors.New("-parallel must be 1 or more"))
+	}
+	return repositories, nil
+}
+
+// Write a ChangeLog for each of the working copies to the files in outDir,
+// named after the labels, with up to parallel of them being fetched and
+// written at the same time. The names that are resolved are shared, so
+// each nick is only looked up once. A working copy that fails does not
+// stop the others, but the first error is returned at the end. The
+// entries are added to dest.Summary, and are only returned with
+// dest.KeepEntries.
+func eachChangeLogs(ctx context.Context, dest *Destination, g *changelog.Generator, repositories []changelog.Repository, outDir string, parallel int, guessPrefixes bool) ([]changelog.Entry, error) {
+	if !dest.DryRun {
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		entries  []changelog.Entry
+		failures []string
+		failure  error
+		next     = make(chan changelog.Repository)
+	)
+	for worker := 0; worker < parallel; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repository := range next {
+				opts := *g.Options
+				opts.Repo = repository.Dir
+				if repository.VCS != "" {
+					opts.VCS = repository.VCS
+				}
+				if guessPrefixes && opts.Normalization != nil {
+					// Strip the name of each package from its own messages
+					norm := *opts.Normalization
+					norm.Prefixes = nil
+					if pkgname := changelog.GuessPackageName(repository.Dir); pkgname != "" {
+						norm.Prefixes = []string{pkgname}
+					}
+					opts.Normalization = &norm
+				}
+				single := *g
+				single.Options = &opts
+				d := *dest
+				d.Filename = filepath.Join(outDir, repository.Label+SPLIT_EXTENSION)
+				repositoryEntries, err := generate(ctx, &d, &single)
+				mu.Lock()
+				entries = append(entries, repositoryEntries...)
+				if err != nil {
+					slog.Error("Could not write the ChangeLog for "+repository.Dir, "err", err)
+					failures = append(failures, repository.Label)
+					if failure == nil {
+						failure = err
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, repository := range repositories {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- repository
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return entries, err
+	}
+	if failure != nil {
+		return entries, fmt.Errorf("Could not write the ChangeLogs for %d of the %d working copies (%s): %w", len(failures), len(repositories), strings.Join(failures, ", "), failure)
+	}
+	return entries, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestEachExpand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"foo", "bar", "other/foo"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	parallel := 1
+	flags := &eachFlags{repositories: &repositoryList{}, parallel: &parallel}
+	flags.repositories.Set(filepath.Join(dir, "*"))
+	repositories, err := flags.expand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repositories) != 3 || repositories[0].Label != "bar" || repositories[1].Label != "foo" || repositories[2].Label != "other" {
+		t.Fatalf("unexpected working copies: %+v", repositories)
+	}
+	flags.repositories.Set(filepath.Join(dir, "other", "foo"))
+	if _, err := flags.expand(); exitCode(err) != EXIT_USAGE {
+		t.Fatalf("expected a usage error for two working copies named foo, got %v", err)
+	}
+}
+
+func TestEachChangeLogs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var repositories []changelog.Repository
+	for _, name := range []string{"foo", "bar", "baz"} {
+		repo := filepath.Join(dir, name)
+		if err := os.Mkdir(repo, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"init", "--quiet"}, {"commit", "--quiet", "--allow-empty", "-m", "Change in " + name}} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = repo
+			cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Alice A", "GIT_AUTHOR_EMAIL=alice@example.org", "GIT_COMMITTER_NAME=Alice A", "GIT_COMMITTER_EMAIL=alice@example.org", "GIT_AUTHOR_DATE=2024-05-01T10:00:00Z")
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%v: %s", err, output)
+			}
+		}
+		repositories = append(repositories, changelog.Repository{Dir: repo, Label: name})
+	}
+	// A working copy that fails does not stop the others
+	repositories = append(repositories, changelog.Repository{Dir: filepath.Join(dir, "missing"), Label: "missing"})
+	g := changelog.New(&changelog.Options{VCS: "git", Entries: -1})
+	g.Names.Resolver = changelog.AuthorsFile{}
+	outDir := filepath.Join(dir, "changelogs")
+	dest := &Destination{Summary: g.Summarizer()}
+	entries, err := eachChangeLogs(context.Background(), dest, g, repositories, outDir, 2, false)
+	if err == nil {
+		t.Fatal("expected an error for the missing working copy")
+	}
+	// The entries are written while they are fetched, and only summarized
+	if n := dest.Summary.Summary().Entries; n != 3 || len(entries) != 0 {
+		t.Fatalf("expected the entries of all three working copies to be summarized, got %d and %+v", n, entries)
+	}
+	for _, name := range []string{"foo", "bar", "baz"} {
+		data, err := ioutil.ReadFile(filepath.Join(outDir, name+SPLIT_EXTENSION))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "2024-05-01 Alice A <alice@example.org>\n    * Change in " + name + "\n\n"; string(data) != expected {
+			t.Fatalf("unexpected ChangeLog for %s:\n%s", name, data)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// Exit codes, so that scripts can tell what went wrong
+const (
+	EXIT_OK          = 0
+	EXIT_ERROR       = 1   // Any other error
+	EXIT_USAGE       = 2   // Invalid flags or arguments
+	EXIT_NO_REPO     = 3   // No working copy found
+	EXIT_VCS         = 4   // svn could not be found, or it failed
+	EXIT_PARSE       = 5   // The log or a state file could not be parsed
+	EXIT_NETWORK     = 6   // Web lookups failed because of the network or a certificate
+	EXIT_UNRESOLVED  = 7   // Some nicks could not be resolved, with -require-names
+	EXIT_OUTDATED    = 8   // The ChangeLog is missing entries, with -check
+	EXIT_INTERRUPTED = 130 // Interrupted with Ctrl-C (SIGINT) or SIGTERM, like for shells
+)
+
+// An error with the exit code that it should result in
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap an error together with an exit code
+func withCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Find the exit code for an error, including the errors from the changelog package
+func exitCode(err error) int {
+	if err == nil {
+		return EXIT_OK
+	}
+	var (
+		e          *Error
+		noRepoErr  *changelog.NoRepositoryError
+		vcsErr     *changelog.VCSError
+		networkErr *changelog.NetworkError
+		certErr    *changelog.CertificateError
+		parseErr   *changelog.ParseError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return EXIT_INTERRUPTED
+	case errors.As(err, &e):
+		return e.Code
+	case errors.As(err, &noRepoErr):
+		return EXIT_NO_REPO
+	case errors.As(err, &vcsErr):
+		return EXIT_VCS
+	case errors.As(err, &networkErr), errors.As(err, &certErr):
+		return EXIT_NETWORK
+	case errors.As(err, &parseErr):
+		return EXIT_PARSE
+	}
+	return EXIT_ERROR
+}
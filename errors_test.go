@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestExitCode(t *testing.T) {
+	if code := exitCode(nil); code != EXIT_OK {
+		t.Fatalf("expected %d, got %d", EXIT_OK, code)
+	}
+	if code := exitCode(errors.New("failed")); code != EXIT_ERROR {
+		t.Fatalf("expected %d, got %d", EXIT_ERROR, code)
+	}
+	err := fmt.Errorf("while generating: %w", withCode(EXIT_NO_REPO, errors.New("no repository")))
+	if code := exitCode(err); code != EXIT_NO_REPO {
+		t.Fatalf("expected %d for a wrapped error, got %d", EXIT_NO_REPO, code)
+	}
+	if code := exitCode(fmt.Errorf("interrupted: %w", context.Canceled)); code != EXIT_INTERRUPTED {
+		t.Fatalf("expected %d, got %d", EXIT_INTERRUPTED, code)
+	}
+	if code := exitCode(&changelog.ParseError{Line: 3, Err: errors.New("malformed")}); code != EXIT_PARSE {
+		t.Fatalf("expected %d, got %d", EXIT_PARSE, code)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/xyproto/archlog
+
+go 1.23
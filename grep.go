@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// archlog grep
+func runGrep(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	repo, vcs, svn_bin, git_bin := addSourceFlags(fs)
+	mock_data, svn_path, externals := addLogFlags(fs)
+	svn_auth := addSvnAuthFlags(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	jobs, stop_on_copy := addFetchFlags(fs)
+	branch_flags := addBranchFlags(fs)
+	var submodules *bool = addSubmodulesFlag(fs)
+	var fields *string = fs.String("fields", strings.Join(changelog.GREP_FIELDS, ","), "comma separated `names` of the fields to search: message, author, name, revision and date")
+	var ignore_case *bool = fs.Bool("i", false, "ignore the case of the letters")
+	var fixed *bool = fs.Bool("F", false, "search for the pattern as a plain string, not a regular expression")
+	var format *string = fs.String("format", "plain", "the output `format`: "+strings.Join(changelog.FormatterNames(), ", "))
+	cache_dir, no_cache := addCacheFlags(fs)
+	resolver_flags := addResolverFlags(fs)
+	var color *string = fs.String("color", "auto", "color the output: `auto`, always or never")
+	var no_pager *bool = fs.Bool("no-pager", false, "do not pipe the output through $PAGER")
+	var no_progress *bool = fs.Bool("no-progress", false, "do not show the progress on stderr")
+	var unknown_author *string = addUnknownAuthorFlag(fs)
+	var lang *string = addLanguageFlag(fs)
+	strict, lenient, input_encoding := addParseFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	language, err := checkLanguage(*lang)
+	if err != nil {
+		return err
+	}
+	parsing, err := parseMode(*strict, *lenient)
+	if err != nil {
+		return err
+	}
+	encoding, err := changelog.CheckEncoding(*input_encoding)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+
+	if fs.NArg() == 0 {
+		return withCode(EXIT_USAGE, errors.New("Please provide a pattern to search for.\nUse --help for more info."))
+	}
+	n, err := parseEntries(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, field := range strings.Split(*fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			names = append(names, field)
+		}
+	}
+	grep, err := changelog.NewGrep(fs.Arg(0), names, *ignore_case, *fixed)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if _, err := changelog.LookupSource(*vcs); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	status.Enable(!*no_progress)
+	g := changelog.New(&changelog.Options{Repo: *repo, VCS: *vcs, SvnBin: *svn_bin, GitBin: *git_bin, MockData: *mock_data, Timeout: *timeout, Jobs: *jobs, StopOnCopy: *stop_on_copy, SvnPath: *svn_path, Externals: *externals, Submodules: *submodules, Entries: n, Format: *format, Parsing: parsing, InputEncoding: encoding, UnknownAuthor: *unknown_author, Language: language, Progress: status.Report})
+	svn_auth.apply(g.Options)
+	if err := branch_flags.apply(g.Options); err != nil {
+		return err
+	}
+	if err := checkSvnOptions(g.Options); err != nil {
+		return err
+	}
+	if *submodules && *vcs != "git" {
+		return withCode(EXIT_USAGE, errors.New("-submodules only works with -vcs git"))
+	}
+	g.Names.Client.Timeout = *timeout
+	if err := setupResolvers(g.Names, resolver_flags); err != nil {
+		return err
+	}
+	if _, err := changelog.NewFormatter(*format, g.Options); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if g.Options.Color, err = useColor(*color, os.Stdout); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if err := setupNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	setupPageCache(g.Names, *cache_dir, *no_cache)
+	// The matching entries are written as they are found
+	out, closePager := startPager(!*no_pager)
+	err = g.WriteStream(ctx, out, g.GrepStream(ctx, grep))
+	status.Done()
+	if err != nil {
+		closePager()
+		return err
+	}
+	if err := closePager(); err != nil {
+		return err
+	}
+	if err := storeNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	return g.Names.NetworkError()
+}
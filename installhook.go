@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The lines around the part of a hook that archlog manages, so that it
+// can be replaced or removed without touching the rest of the hook
+const (
+	HOOK_BEGIN = "# BEGIN archlog, managed by archlog install-hook"
+	HOOK_END   = "# END archlog"
+)
+
+// Quote a string for sh
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// The part of a hook that runs archlog to prepend the new entries to the
+// ChangeLog. A failure is reported, but does not make the hook fail.
+func hookBlock(archlog, vcs, repo, changeLog string) string {
+	args := []string{shellQuote(archlog), "generate", "-vcs", vcs, "-repo", shellQuote(repo), "-incremental"}
+	if vcs == "git" {
+		// -incremental needs the numbers of the mainline history
+		args = append(args, "-first-parent")
+	}
+	args = append(args, "-prepend", shellQuote(changeLog), "-no-progress", "-no-pager")
+	return HOOK_BEGIN + "\n" + strings.Join(args, " ") + " || echo 'archlog could not update the ChangeLog' >&2\n" + HOOK_END + "\n"
+}
+
+// Remove the part of the hook that archlog manages, if any
+func removeHookBlock(hook string) (string, bool) {
+	begin := strings.Index(hook, HOOK_BEGIN+"\n")
+	if begin < 0 {
+		return hook, false
+	}
+	end := strings.Index(hook[begin:], HOOK_END+"\n")
+	if end < 0 {
+		return hook, false
+	}
+	return hook[:begin] + hook[begin+end+len(HOOK_END)+1:], true
+}
+
+// Add the block to the hook, or replace the one that is already there
+func installHookBlock(hook, block string) string {
+	hook, _ = removeHookBlock(hook)
+	if strings.TrimSpace(hook) == "" {
+		hook = "#!/bin/sh\n"
+	}
+	if !strings.HasSuffix(hook, "\n") {
+		hook += "\n"
+	}
+	return hook + block
+}
+
+// Install or uninstall the archlog part of a hook file. A hook that only
+// has the archlog part is removed when uninstalling. Returns a message
+// about what was done.
+func updateHook(filename, block string, uninstall bool) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	existing := string(data)
+	if uninstall {
+		hook, found := removeHookBlock(existing)
+		switch {
+		case !found:
+			return "archlog is not in " + filename, nil
+		case strings.TrimSpace(strings.TrimPrefix(hook, "#!/bin/sh\n")) == "":
+			return "Removed " + filename, os.Remove(filename)
+		}
+		return "Removed archlog from " + filename, changelog.WriteFileAtomic(filename, func(w io.Writer) error {
+			_, err := io.WriteString(w, hook)
+			return err
+		})
+	}
+	hook := installHookBlock(existing, block)
+	if hook == existing {
+		return "archlog is already in " + filename, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", err
+	}
+	err = changelog.WriteFileAtomic(filename, func(w io.Writer) error {
+		_, err := io.WriteString(w, hook)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	// Hooks must be executable to be run
+	if err := os.Chmod(filename, 0755); err != nil {
+		return "", err
+	}
+	return "Installed archlog in " + filename, nil
+}
+
+// archlog install-hook
+func runInstallHook(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	repo, vcs, svn_bin, git_bin := addSourceFlags(fs)
+	var hook *string = fs.String("hook", "", "the `name` of the hook, like post-commit, or post-receive for a git repository that is pushed to")
+	var hooks_dir *string = fs.String("hooks-dir", "", "the `directory` of the hooks, instead of the one of the repository")
+	var changelog_file *string = fs.String("changelog", "ChangeLog", "the ChangeLog `file`, relative to the working copy, where the new entries are added")
+	var uninstall *bool = fs.Bool("uninstall", false, "remove archlog from the hook")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return withCode(EXIT_USAGE, fmt.Errorf("Unexpected argument: %s", fs.Arg(0)))
+	}
+	source, err := changelog.LookupSource(*vcs)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	hookSource, ok := source.(changelog.HookSource)
+	if !ok {
+		return withCode(EXIT_USAGE, fmt.Errorf("Hooks can not be installed for -vcs %s", *vcs))
+	}
+	name := *hook
+	if name == "" {
+		name = hookSource.Hooks()[0]
+	} else if !slices.Contains(hookSource.Hooks(), name) {
+		return withCode(EXIT_USAGE, fmt.Errorf("Unknown hook for %s: %s (available: %s)", *vcs, name, strings.Join(hookSource.Hooks(), ", ")))
+	}
+	dir, err := filepath.Abs(*repo)
+	if err != nil {
+		return err
+	}
+	opts := &changelog.Options{Repo: *repo, VCS: *vcs, SvnBin: *svn_bin, GitBin: *git_bin, Timeout: DEFAULT_TIMEOUT}
+	hooksDir := *hooks_dir
+	if hooksDir == "" {
+		if hooksDir, err = hookSource.HooksDir(ctx, opts); err != nil {
+			return fmt.Errorf("%w, or use -hooks-dir", err)
+		}
+	}
+	changeLog := *changelog_file
+	if !filepath.IsAbs(changeLog) {
+		changeLog = filepath.Join(dir, changeLog)
+	}
+	archlog, err := os.Executable()
+	if err != nil {
+		return errors.New("Could not find the archlog executable: " + err.Error())
+	}
+	message, err := updateHook(filepath.Join(hooksDir, name), hookBlock(archlog, *vcs, dir, changeLog), *uninstall)
+	if err != nil {
+		return err
+	}
+	fmt.Println(message)
+	return nil
+}
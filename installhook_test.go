@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "post-commit")
+	const existing = "#!/bin/sh\nmake test\n"
+	if err := ioutil.WriteFile(filename, []byte(existing), 0755); err != nil {
+		t.Fatal(err)
+	}
+	block := hookBlock("/usr/bin/archlog", "git", "/src/it's here", "/src/it's here/ChangeLog")
+	// Installing twice is the same as installing once
+	for i := 0; i < 2; i++ {
+		if _, err := updateHook(filename, block, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := ioutil.ReadFile(filename)
+	if string(data) != existing+block || strings.Count(string(data), HOOK_BEGIN) != 1 {
+		t.Fatalf("expected the block to be added once, got %q", data)
+	}
+	if !strings.Contains(block, `-repo '/src/it'\''s here'`) {
+		t.Fatalf("expected the paths to be quoted, got %q", block)
+	}
+	if !strings.Contains(block, "-incremental -first-parent -prepend") {
+		t.Fatalf("expected -first-parent for git, got %q", block)
+	}
+	// Uninstalling keeps the rest of the hook
+	if _, err := updateHook(filename, block, true); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = ioutil.ReadFile(filename)
+	if string(data) != existing {
+		t.Fatalf("expected the hook to be restored, got %q", data)
+	}
+	// A hook with only archlog in it is removed
+	os.Remove(filename)
+	if _, err := updateHook(filename, block, false); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(filename); err != nil || fi.Mode().Perm()&0100 == 0 {
+		t.Fatalf("expected an executable hook, got %v", err)
+	}
+	if _, err := updateHook(filename, block, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Fatalf("expected the hook to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"flag"
+)
+
+// The flags for only writing the entries of the last days, weeks or months
+type lastFlags struct {
+	days, weeks, months *int
+}
+
+// Add the -last-days, -last-weeks and -last-months flags
+func addLastFlags(fs *flag.FlagSet) *lastFlags {
+	return &lastFlags{
+		days:   fs.Int("last-days", 0, "only the entries of the last `number` of days, counted from today, or $SOURCE_DATE_EPOCH if it is set"),
+		weeks:  fs.Int("last-weeks", 0, "only the entries of the last `number` of weeks, like -last-days"),
+		months: fs.Int("last-months", 0, "only the entries of the last `number` of months, like -last-days"),
+	}
+}
+
+// The oldest day (YYYY-MM-DD) of the entries to write, counted back from
+// today, or from $SOURCE_DATE_EPOCH if it is set, or "" if none of the
+// flags are given
+func (f *lastFlags) since() (string, error) {
+	given := 0
+	for _, n := range []int{*f.days, *f.weeks, *f.months} {
+		if n < 0 {
+			return "", withCode(EXIT_USAGE, errors.New("-last-days, -last-weeks and -last-months must be 1 or more"))
+		}
+		if n > 0 {
+			given++
+		}
+	}
+	switch {
+	case given == 0:
+		return "", nil
+	case given > 1:
+		return "", withCode(EXIT_USAGE, errors.New("Only one of -last-days, -last-weeks and -last-months can be given"))
+	}
+	now, err := sourceDate()
+	if err != nil {
+		return "", withCode(EXIT_USAGE, err)
+	}
+	switch {
+	case *f.days > 0:
+		now = now.AddDate(0, 0, -*f.days)
+	case *f.weeks > 0:
+		now = now.AddDate(0, 0, -7**f.weeks)
+	default:
+		now = now.AddDate(0, -*f.months, 0)
+	}
+	return now.Format("2006-01-02"), nil
+}
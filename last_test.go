@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestLastFlags(t *testing.T) {
+	// 2024-03-01 10:00 UTC
+	t.Setenv("SOURCE_DATE_EPOCH", "1709287200")
+	for args, expected := range map[string]string{
+		"":                "",
+		"-last-days=30":   "2024-01-31",
+		"-last-weeks=2":   "2024-02-16",
+		"-last-months=1":  "2024-02-01",
+		"-last-months=12": "2023-03-01",
+	} {
+		fs := flag.NewFlagSet("archlog", flag.ContinueOnError)
+		f := addLastFlags(fs)
+		if args != "" {
+			if err := fs.Parse([]string{args}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		since, err := f.since()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if since != expected {
+			t.Fatalf("expected %q for %q, got %q", expected, args, since)
+		}
+	}
+	for _, args := range [][]string{{"-last-days=7", "-last-weeks=1"}, {"-last-days=-1"}} {
+		fs := flag.NewFlagSet("archlog", flag.ContinueOnError)
+		f := addLastFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.since(); exitCode(err) != EXIT_USAGE {
+			t.Fatalf("expected a usage error for %v, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+)
+
+// Set by -quiet, for also silencing the progress indicator
+var quiet bool
+
+// Add the verbosity flags that every command has
+func addLoggingFlags(fs *flag.FlagSet) {
+	fs.Bool("v", false, "verbose output on stderr")
+	fs.Bool("vv", false, "even more verbose output on stderr, for debugging")
+	fs.Bool("quiet", false, "only output errors on stderr")
+}
+
+// Check if a boolean flag is set
+func flagIsSet(fs *flag.FlagSet, name string) bool {
+	f := fs.Lookup(name)
+	return f != nil && f.Value.String() == "true"
+}
+
+// Send all diagnostics to stderr, with the level given by the verbosity
+// flags, so that stdout only contains the ChangeLog
+func setupLogging(fs *flag.FlagSet) {
+	level := slog.LevelWarn
+	switch {
+	case flagIsSet(fs, "quiet"):
+		level = slog.LevelError
+		quiet = true
+	case flagIsSet(fs, "vv"):
+		level = slog.LevelDebug
+	case flagIsSet(fs, "v"):
+		level = slog.LevelInfo
+	}
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// Timestamps are just noise for a command line utility
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	slog.SetDefault(slog.New(handler))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The metrics of archlog serve, which are exported on /metrics in the
+// Prometheus text format
+type serverMetrics struct {
+	mu              sync.Mutex
+	requests        map[[2]string]int // By path and status code
+	generations     int
+	generationTime  time.Duration
+	generationFails int
+	entries         int
+	cacheHits       int
+	cacheMisses     int
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{requests: make(map[[2]string]int)}
+}
+
+// Count a served request. m can be nil.
+func (m *serverMetrics) request(path string, code int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[[2]string{path, strconv.Itoa(code)}]++
+}
+
+// Count a ChangeLog that was found in the cache, or not. m can be nil.
+func (m *serverMetrics) cache(hit bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+// Count a generated ChangeLog, or a failed attempt. m can be nil.
+func (m *serverMetrics) generated(entries int, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.generationFails++
+		return
+	}
+	m.generations++
+	m.generationTime += duration
+	m.entries += entries
+}
+
+// Write a metric with its help and type
+func writeMetric(w io.Writer, name, kind, help string, samples ...string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, sample := range samples {
+		fmt.Fprintln(w, sample)
+	}
+}
+
+// Serve /metrics
+func (s *changeLogServer) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	m := s.metrics
+	m.mu.Lock()
+	keys := make([][2]string, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || (keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1])
+	})
+	requests := make([]string, len(keys))
+	for i, key := range keys {
+		requests[i] = fmt.Sprintf("archlog_requests_total{path=%q,code=%q} %d", key[0], key[1], m.requests[key])
+	}
+	generations, generationTime, generationFails := m.generations, m.generationTime, m.generationFails
+	entries, cacheHits, cacheMisses := m.entries, m.cacheHits, m.cacheMisses
+	m.mu.Unlock()
+	stats := s.names.Stats()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetric(w, "archlog_requests_total", "counter", "The number of served requests, by path and status code.", requests...)
+	writeMetric(w, "archlog_generation_seconds", "summary", "The time spent generating ChangeLogs.",
+		fmt.Sprintf("archlog_generation_seconds_sum %g", generationTime.Seconds()),
+		fmt.Sprintf("archlog_generation_seconds_count %d", generations))
+	writeMetric(w, "archlog_generation_failures_total", "counter", "The number of ChangeLogs that could not be generated.", fmt.Sprintf("archlog_generation_failures_total %d", generationFails))
+	writeMetric(w, "archlog_entries_processed_total", "counter", "The number of log entries in the generated ChangeLogs.", fmt.Sprintf("archlog_entries_processed_total %d", entries))
+	writeMetric(w, "archlog_changelog_cache_hits_total", "counter", "The number of requests for a ChangeLog that was already generated.", fmt.Sprintf("archlog_changelog_cache_hits_total %d", cacheHits))
+	writeMetric(w, "archlog_changelog_cache_misses_total", "counter", "The number of requests that made a ChangeLog be generated.", fmt.Sprintf("archlog_changelog_cache_misses_total %d", cacheMisses))
+	writeMetric(w, "archlog_nick_cache_hits_total", "counter", "The number of nicks that were found in the cache.", fmt.Sprintf("archlog_nick_cache_hits_total %d", stats.Hits))
+	writeMetric(w, "archlog_lookups_total", "counter", "The number of nicks that were looked up.", fmt.Sprintf("archlog_lookups_total %d", stats.Lookups))
+	writeMetric(w, "archlog_lookup_failures_total", "counter", "The number of lookups that failed because of the network.", fmt.Sprintf("archlog_lookup_failures_total %d", stats.Failures))
+}
+
+// Records the status code of a response, and can still be flushed for
+// streamed responses
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Count the requests for the handler
+func (s *changeLogServer) counted(path string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		handler.ServeHTTP(recorder, r)
+		if recorder.code == 0 {
+			recorder.code = http.StatusOK
+		}
+		s.metrics.request(path, recorder.code)
+	})
+}
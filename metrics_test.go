@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestServeMetrics(t *testing.T) {
+	changelog.RegisterSource("metrics-test", &countingSource{})
+	s := &changeLogServer{
+		options: changelog.Options{VCS: "metrics-test"},
+		names:   changelog.NewNames(),
+		maxAge:  time.Minute,
+		cache:   make(map[string]*servedChangeLog),
+		metrics: newServerMetrics(),
+	}
+	handler := s.counted("/changelog", s)
+	for _, query := range []string{"", "", "?format=pdf"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/changelog"+query, nil))
+	}
+	rec := httptest.NewRecorder()
+	s.serveMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range []string{
+		`archlog_requests_total{path="/changelog",code="200"} 2`,
+		`archlog_requests_total{path="/changelog",code="400"} 1`,
+		"archlog_generation_seconds_count 1",
+		"archlog_entries_processed_total 1",
+		"archlog_changelog_cache_hits_total 1",
+		"archlog_changelog_cache_misses_total 1",
+		"# TYPE archlog_lookup_failures_total counter",
+	} {
+		if !strings.Contains(rec.Body.String(), line+"\n") {
+			t.Fatalf("expected %q in the metrics, got:\n%s", line, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The largest response from a chat service or a forge that is read
+const MAX_NOTIFY_RESPONSE = 1 << 16
+
+// Posts the new entries of the ChangeLog somewhere, like to a chat room
+type notifier interface {
+	name() string
+	notify(ctx context.Context, client *http.Client, sections []changelog.Section) error
+}
+
+// The flags for posting the new entries
+type notifyFlags struct {
+	matrixHomeserver *string
+	matrixRoom       *string
+	matrixToken      *string
+	slackWebhook     *string
+	discordWebhook   *string
+}
+
+// Add the -matrix-homeserver, -matrix-room, -matrix-token, -slack-webhook
+// and -discord-webhook flags
+func addNotifyFlags(fs *flag.FlagSet) *notifyFlags {
+	return &notifyFlags{
+		matrixHomeserver: fs.String("matrix-homeserver", "", "the `URL` of the Matrix homeserver to post the new entries to, like https://matrix.org"),
+		matrixRoom:       fs.String("matrix-room", "", "the `id` of the Matrix room to post the new entries to, like !abc123:matrix.org"),
+		matrixToken:      fs.String("matrix-token", "", "the access `token` of the Matrix user that posts"),
+		slackWebhook:     fs.String("slack-webhook", "", "the `URL` of a Slack incoming webhook to post a summary of the new entries to"),
+		discordWebhook:   fs.String("discord-webhook", "", "the `URL` of a Discord webhook to post a summary of the new entries to"),
+	}
+}
+
+// Check that a flag is an http or https URL
+func checkURL(name, address string) error {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		// The URL is not shown, since it can have a secret in it
+		return withCode(EXIT_USAGE, fmt.Errorf("-%s must be an http or https URL", name))
+	}
+	return nil
+}
+
+// The notifiers that are configured with the flags
+func (f *notifyFlags) notifiers() ([]notifier, error) {
+	var notifiers []notifier
+	homeserver, room, token := *f.matrixHomeserver, *f.matrixRoom, *f.matrixToken
+	if homeserver != "" || room != "" || token != "" {
+		if homeserver == "" || room == "" || token == "" {
+			return nil, withCode(EXIT_USAGE, errors.New("-matrix-homeserver, -matrix-room and -matrix-token must be given together"))
+		}
+		if err := checkURL("matrix-homeserver", homeserver); err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, &matrixNotifier{homeserver: strings.TrimSuffix(homeserver, "/"), room: room, token: token})
+	}
+	if *f.slackWebhook != "" {
+		if err := checkURL("slack-webhook", *f.slackWebhook); err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, &slackNotifier{webhook: *f.slackWebhook})
+	}
+	if *f.discordWebhook != "" {
+		if err := checkURL("discord-webhook", *f.discordWebhook); err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, &discordNotifier{webhook: *f.discordWebhook})
+	}
+	return notifiers, nil
+}
+
+// The new entries, as they are in the plain ChangeLog
+func plainSections(sections []changelog.Section) string {
+	var buf bytes.Buffer
+	f, _ := changelog.NewFormatter("plain", &changelog.Options{})
+	f.Begin(&buf)
+	for i := range sections {
+		f.Entry(&buf, &sections[i])
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// The new entries as HTML, for the chat services that show it
+func htmlSections(sections []changelog.Section) string {
+	var buf bytes.Buffer
+	for _, section := range sections {
+		fmt.Fprintf(&buf, "<p><b>%s %s</b></p>\n<ul>\n", html.EscapeString(section.Date), html.EscapeString(section.Name))
+		for _, msg := range section.Messages {
+			fmt.Fprintf(&buf, "<li>%s</li>\n", strings.Replace(html.EscapeString(msg), "\n", "<br>\n", -1))
+		}
+		buf.WriteString("</ul>\n")
+	}
+	return buf.String()
+}
+
+// Send a request with a JSON body, or without a body if it is nil, and fail
+// with the response if it is not a success. The JSON response is decoded
+// into result, unless it is nil.
+func postJSON(ctx context.Context, client *http.Client, method, address string, header http.Header, body, result any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, address, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			// Leave out the URL, since webhook URLs are secret
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	response, _ := ioutil.ReadAll(io.LimitReader(resp.Body, MAX_NOTIFY_RESPONSE))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &responseError{code: resp.StatusCode, status: resp.Status, body: response}
+	}
+	if result != nil {
+		return json.Unmarshal(response, result)
+	}
+	return nil
+}
+
+// A response from a chat service or a forge that is not a success
+type responseError struct {
+	code   int
+	status string
+	body   []byte
+}
+
+func (e *responseError) Error() string {
+	// Matrix explains the error in the "error" field and Discord and GitLab
+	// in the "message" field, while Slack answers with text, like "invalid_token"
+	var reply struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	explanation := strings.TrimSpace(string(e.body))
+	if json.Unmarshal(e.body, &reply) == nil {
+		explanation = reply.Error
+		if explanation == "" {
+			explanation = reply.Message
+		}
+	}
+	if explanation == "" || len(explanation) > 200 || strings.HasPrefix(explanation, "<") {
+		return e.status
+	}
+	return e.status + ": " + changelog.Sanitize(explanation, false)
+}
+
+// Posts the new entries to a Matrix room, as the user of the access token
+type matrixNotifier struct {
+	homeserver string
+	room       string
+	token      string
+}
+
+func (m *matrixNotifier) name() string {
+	return "the Matrix room " + m.room
+}
+
+// Send an m.notice, which bots use, so that other bots don't answer it
+func (m *matrixNotifier) notify(ctx context.Context, client *http.Client, sections []changelog.Section) error {
+	// The transaction id makes the homeserver ignore a request that is sent again
+	txn := "archlog-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	address := m.homeserver + "/_matrix/client/v3/rooms/" + url.PathEscape(m.room) + "/send/m.room.message/" + txn
+	header := http.Header{"Authorization": {"Bearer " + m.token}}
+	return postJSON(ctx, client, http.MethodPut, address, header, map[string]string{
+		"msgtype":        "m.notice",
+		"body":           plainSections(sections),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": htmlSections(sections),
+	}, nil)
+}
+
+// A summary of the new entries in the markup of a chat service, which is
+// cut short after limit bytes, with the number of entries that were left out
+func chatSummary(sections []changelog.Section, bold, item, escape func(string) string, limit int) string {
+	total := 0
+	for _, section := range sections {
+		total += len(section.Messages)
+	}
+	entries := "entries"
+	if total == 1 {
+		entries = "entry"
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%d new ChangeLog %s\n", total, entries)
+	shown := 0
+	for _, section := range sections {
+		var part strings.Builder
+		part.WriteString("\n" + bold(escape(section.Date+" "+section.Name)) + "\n")
+		for _, msg := range section.Messages {
+			part.WriteString(item(escape(msg)) + "\n")
+		}
+		// Room is left for the last line
+		if buf.Len()+part.Len() > limit-40 {
+			fmt.Fprintf(&buf, "\n… and %d more", total-shown)
+			break
+		}
+		buf.WriteString(part.String())
+		shown += len(section.Messages)
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// The longest message that Slack shows without cutting it
+const SLACK_LIMIT = 3000
+
+// Posts a summary of the new entries to a Slack incoming webhook
+type slackNotifier struct {
+	webhook string
+}
+
+func (s *slackNotifier) name() string {
+	return "the Slack webhook"
+}
+
+// Escape the characters that Slack uses for links and mentions
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func (s *slackNotifier) notify(ctx context.Context, client *http.Client, sections []changelog.Section) error {
+	text := chatSummary(sections, func(s string) string {
+		return "*" + s + "*"
+	}, func(s string) string {
+		return "• " + strings.Replace(s, "\n", "\n    ", -1)
+	}, slackEscape, SLACK_LIMIT)
+	return postJSON(ctx, client, http.MethodPost, s.webhook, nil, map[string]any{"text": text, "mrkdwn": true}, nil)
+}
+
+// The longest message that Discord accepts from a webhook
+const DISCORD_LIMIT = 2000
+
+// Posts a summary of the new entries to a Discord webhook
+type discordNotifier struct {
+	webhook string
+}
+
+func (d *discordNotifier) name() string {
+	return "the Discord webhook"
+}
+
+// Escape the characters that Discord uses for markdown
+func discordEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "*", "\\*", "_", "\\_", "~", "\\~", "`", "\\`", "|", "\\|", ">", "\\>").Replace(s)
+}
+
+func (d *discordNotifier) notify(ctx context.Context, client *http.Client, sections []changelog.Section) error {
+	content := chatSummary(sections, func(s string) string {
+		return "**" + s + "**"
+	}, func(s string) string {
+		return "- " + strings.Replace(s, "\n", "\n  ", -1)
+	}, discordEscape, DISCORD_LIMIT)
+	return postJSON(ctx, client, http.MethodPost, d.webhook, nil, map[string]any{
+		"content": content,
+		// Never ping anyone, also not for @everyone in a commit message
+		"allowed_mentions": map[string][]string{"parse": {}},
+	}, nil)
+}
+
+// Read the ChangeLog before it is regenerated, so that only the entries
+// that are not in it are posted
+func previousChangeLog(dest *Destination) (string, error) {
+	if dest.Filename == "" {
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(dest.Filename)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return changelog.UnixLineEndings(string(data)), nil
+}
+
+// Post the entries that were added to the ChangeLog. With -prepend, these
+// are the ones that were prepended, with -o the ones that were not in the
+// previous ChangeLog, and otherwise all of the fetched entries.
+func notifyAll(ctx context.Context, client *http.Client, notifiers []notifier, g *changelog.Generator, entries []changelog.Entry, previous string) error {
+	if len(notifiers) == 0 {
+		return nil
+	}
+	if g.Options.Existing == "" && previous != "" {
+		g.Options.Since, g.Options.Existing = changelog.NewestDate(previous), previous
+	}
+	sections, err := g.Sections(ctx, entries)
+	if err != nil {
+		return err
+	}
+	if len(sections) == 0 {
+		return nil
+	}
+	for _, n := range notifiers {
+		if err := n.notify(ctx, client, sections); err != nil {
+			return withCode(EXIT_NETWORK, fmt.Errorf("Could not post the new entries to %s: %w", n.name(), err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestNotifyFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := addNotifyFlags(fs)
+	if notifiers, err := flags.notifiers(); err != nil || len(notifiers) != 0 {
+		t.Fatalf("expected no notifiers by default, got %v, %v", notifiers, err)
+	}
+	fs.Set("matrix-room", "!room:example.org")
+	if _, err := flags.notifiers(); exitCode(err) != EXIT_USAGE {
+		t.Fatalf("expected a usage error for a room without a homeserver, got %v", err)
+	}
+	fs.Set("matrix-homeserver", "matrix.example.org")
+	fs.Set("matrix-token", "s3cret")
+	if _, err := flags.notifiers(); exitCode(err) != EXIT_USAGE || strings.Contains(err.Error(), "s3cret") {
+		t.Fatalf("expected a usage error for a homeserver that is not a URL, got %v", err)
+	}
+}
+
+func TestMatrixNotifier(t *testing.T) {
+	var message map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errcode":"M_UNKNOWN_TOKEN","error":"Invalid access token"}`))
+			return
+		}
+		if r.Method != http.MethodPut || !strings.HasPrefix(r.URL.EscapedPath(), "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/") {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.EscapedPath())
+		}
+		json.NewDecoder(r.Body).Decode(&message)
+		w.Write([]byte(`{"event_id":"$1"}`))
+	}))
+	defer server.Close()
+
+	g := changelog.New(&changelog.Options{})
+	g.Names.Resolver = changelog.AuthorsFile{"alice": {Name: "Alice", Email: "alice@example.org"}}
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []changelog.Entry{
+		{Revision: 2, Author: "alice", Date: day, Message: "Fix <b>the</b> build"},
+		{Revision: 1, Author: "alice", Date: day, Message: "Initial import"},
+	}
+	previous := "2024-03-01 Alice <alice@example.org>\n    * Initial import\n\n"
+	matrix := &matrixNotifier{homeserver: server.URL, room: "!room:example.org", token: "s3cret"}
+	if err := notifyAll(context.Background(), server.Client(), []notifier{matrix}, g, entries, previous); err != nil {
+		t.Fatal(err)
+	}
+	// Only the entry that is not in the previous ChangeLog is posted
+	if message["msgtype"] != "m.notice" || message["body"] != "2024-03-01 Alice <alice@example.org>\n    * Fix <b>the</b> build" {
+		t.Fatalf("unexpected message: %v", message)
+	}
+	if !strings.Contains(message["formatted_body"], "<li>Fix &lt;b&gt;the&lt;/b&gt; build</li>") {
+		t.Fatalf("expected the HTML to be escaped, got %q", message["formatted_body"])
+	}
+
+	matrix.token = "wrong"
+	err := notifyAll(context.Background(), server.Client(), []notifier{matrix}, g, entries[:1], "")
+	if exitCode(err) != EXIT_NETWORK || !strings.Contains(err.Error(), "Invalid access token") || strings.Contains(err.Error(), "wrong") {
+		t.Fatalf("expected the error from the homeserver, got %v", err)
+	}
+}
+
+func TestWebhookNotifiers(t *testing.T) {
+	var posted map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/revoked" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("invalid_token"))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&posted)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sections := []changelog.Section{{Date: "2024-03-01", Name: "Alice <alice@example.org>", Messages: []string{"Fix *the* build", "Ping <!channel>"}}}
+	if err := (&slackNotifier{webhook: server.URL + "/slack"}).notify(context.Background(), server.Client(), sections); err != nil {
+		t.Fatal(err)
+	}
+	expected := "2 new ChangeLog entries\n\n*2024-03-01 Alice &lt;alice@example.org&gt;*\n• Fix *the* build\n• Ping &lt;!channel&gt;"
+	if posted["text"] != expected {
+		t.Fatalf("expected %q, got %q", expected, posted["text"])
+	}
+	if err := (&discordNotifier{webhook: server.URL + "/discord"}).notify(context.Background(), server.Client(), sections); err != nil {
+		t.Fatal(err)
+	}
+	expected = "2 new ChangeLog entries\n\n**2024-03-01 Alice <alice@example.org\\>**\n- Fix \\*the\\* build\n- Ping <!channel\\>"
+	if posted["content"] != expected {
+		t.Fatalf("expected %q, got %q", expected, posted["content"])
+	}
+	err := (&slackNotifier{webhook: server.URL + "/revoked"}).notify(context.Background(), server.Client(), sections)
+	if err == nil || err.Error() != "403 Forbidden: invalid_token" {
+		t.Fatalf("expected the error from Slack, got %v", err)
+	}
+	// The webhook URL is a secret, and is not in the error
+	err = (&slackNotifier{webhook: "http://127.0.0.1:1/services/s3cret"}).notify(context.Background(), server.Client(), sections)
+	if err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Fatalf("expected an error without the URL, got %v", err)
+	}
+}
+
+func TestChatSummary(t *testing.T) {
+	var sections []changelog.Section
+	for day := 1; day <= 100; day++ {
+		sections = append(sections, changelog.Section{Date: "2024-03-01", Name: "alice", Messages: []string{strings.Repeat("x", 50)}})
+	}
+	same := func(s string) string { return s }
+	summary := chatSummary(sections, same, same, same, DISCORD_LIMIT)
+	if len(summary) > DISCORD_LIMIT || !strings.HasPrefix(summary, "100 new ChangeLog entries\n") || !strings.Contains(summary, "… and ") {
+		t.Fatalf("expected a summary that is cut short, got %d bytes: %q", len(summary), summary)
+	}
+}
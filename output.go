@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"iter"
+	"os"
+	"strings"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The Generator for writing a ChangeLog that is merged with an existing
+// one, with Unix line endings and no byte order mark. They are only added
+// when the result is written.
+func unixGenerator(g *changelog.Generator) *changelog.Generator {
+	opts := *g.Options
+	opts.EOL, opts.BOM = "", false
+	unix := *g
+	unix.Options = &opts
+	return &unix
+}
+
+// Regenerate the ChangeLog for the given file and merge in the manual
+// edits from the existing file, if there is one. Returns the existing
+// contents, the generated ChangeLog and the merged result, all with Unix
+// line endings.
+func regeneratedChangeLog(ctx context.Context, filename string, g *changelog.Generator, entries iter.Seq2[changelog.Entry, error]) (string, string, string, error) {
+	var buf bytes.Buffer
+	if err := unixGenerator(g).WriteStream(ctx, &buf, entries); err != nil {
+		return "", "", "", err
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", "", err
+	}
+	generated := buf.String()
+	merged, err := changelog.MergeWithExisting(filename, generated)
+	if err != nil {
+		return "", "", "", err
+	}
+	return changelog.UnixLineEndings(string(data)), generated, merged, nil
+}
+
+// Write the ChangeLog to stdout, through the pager if it is enabled
+// and stdout is a terminal
+func writeToStdout(ctx context.Context, g *changelog.Generator, entries iter.Seq2[changelog.Entry, error], usePager bool) error {
+	out, closePager := startPager(usePager)
+	bw := bufio.NewWriter(out)
+	err := g.WriteStream(ctx, bw, entries)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		closePager()
+		if pagerQuit(err) {
+			return nil
+		}
+		return err
+	}
+	return closePager()
+}
+
+// Write the ChangeLog to the given file, merging in manual edits
+// if it is in the plain format
+func writeChangeLog(ctx context.Context, dest *Destination, g *changelog.Generator, entries iter.Seq2[changelog.Entry, error]) error {
+	if g.Options.Format != "" && g.Options.Format != "plain" {
+		if !dest.DryRun {
+			return changelog.WriteFileAtomic(dest.Filename, func(w io.Writer) error {
+				return g.WriteStream(ctx, w, entries)
+			})
+		}
+		var buf bytes.Buffer
+		if err := g.WriteStream(ctx, &buf, entries); err != nil {
+			return err
+		}
+		return dest.writeFile(dest.Filename, "", buf.String())
+	}
+	existing, generated, merged, err := regeneratedChangeLog(ctx, dest.Filename, g, entries)
+	if err != nil {
+		return err
+	}
+	if err := dest.writeFile(dest.Filename, existing, g.Options.LineEndings(merged)); err != nil {
+		return err
+	}
+	// Keep the generated version, for merging in manual edits the next time,
+	// but not next to something that is not a file, like /dev/null
+	if fi, err := os.Stat(dest.Filename); err == nil && !fi.Mode().IsRegular() {
+		return nil
+	}
+	return dest.writeFile(changelog.BaseFilename(dest.Filename), "", generated)
+}
+
+// Generate the entries that are newer than the newest entry in an existing
+// ChangeLog and insert them at the top. Returns the existing and the updated
+// contents, with Unix line endings, which are the same if there is nothing new.
+func prependedChangeLog(ctx context.Context, filename string, g *changelog.Generator, entries iter.Seq2[changelog.Entry, error]) (string, string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", err
+	}
+	existing := changelog.UnixLineEndings(string(data))
+	g.Options.Since = changelog.NewestDate(existing)
+	g.Options.Existing = existing
+	var buf bytes.Buffer
+	if err := unixGenerator(g).WriteStream(ctx, &buf, entries); err != nil {
+		return "", "", err
+	}
+	// The maintainers stay at the top, or are replaced with the current ones
+	maintainers, rest := changelog.SplitMaintainers(existing)
+	if len(g.Options.Maintainers) > 0 {
+		maintainers = ""
+	}
+	return existing, maintainers + buf.String() + rest, nil
+}
+
+// Insert the entries that are newer than the newest entry in an existing
+// ChangeLog at the top of it, preserving everything below.
+func prependChangeLog(ctx context.Context, dest *Destination, g *changelog.Generator, entries iter.Seq2[changelog.Entry, error]) error {
+	existing, updated, err := prependedChangeLog(ctx, dest.Prepend, g, entries)
+	if err != nil {
+		return err
+	}
+	if updated == existing {
+		// Nothing new, leave the file as it is
+		if dest.DryRun {
+			fmt.Fprintf(dest.report(), "Would leave %s as it is, there are no new entries\n", dest.Prepend)
+		}
+		return nil
+	}
+	return dest.writeFile(dest.Prepend, existing, g.Options.LineEndings(updated))
+}
+
+// Check that an existing ChangeLog has entries for all the revisions.
+// If not, a diff of the missing entries is written to w and an error is returned.
+func checkChangeLog(ctx context.Context, w io.Writer, filename string, g *changelog.Generator, entries iter.Seq2[changelog.Entry, error]) error {
+	if _, err := os.Stat(filename); err != nil {
+		return err
+	}
+	existing, updated, err := prependedChangeLog(ctx, filename, g, entries)
+	if err != nil {
+		return err
+	}
+	if updated == existing {
+		return nil
+	}
+	if _, err := io.WriteString(w, changelog.UnifiedDiff(filename, filename+" (expected)", existing, updated)); err != nil {
+		return err
+	}
+	return withCode(EXIT_OUTDATED, fmt.Errorf("%s is missing entries for recent revisions", filename))
+}
+
+// Where the generated ChangeLog should go
+type Destination struct {
+	Filename     string // Write the ChangeLog to this file, or to stdout if empty
+	Prepend      string // Add only the new entries to the top of this ChangeLog
+	Check        string // Only check that this ChangeLog is up to date
+	Incremental  bool   // Only fetch the revisions newer than the last run
+	Diff         bool   // Only show what would change in the Filename or Prepend file
+	NoPager      bool   // Never pipe the output through $PAGER
+	RequireNames bool   // Fail if any of the nicks could not be resolved
+	DryRun       bool   // Only report what would be written
+	KeepEntries  bool   // Return the entries from generate, instead of only writing them while they are fetched
+	Report       io.Writer
+	Summary      *changelog.Summarizer // Collects the summary of the entries that generate writes, if set
+}
+
+// Where the -dry-run report goes, stdout by default
+func (dest *Destination) report() io.Writer {
+	if dest.Report == nil {
+		return os.Stdout
+	}
+	return dest.Report
+}
+
+// Write a file atomically, or only report what would be written with -dry-run
+func (dest *Destination) writeFile(filename, existing, contents string) error {
+	if dest.DryRun {
+		_, err := fmt.Fprintf(dest.report(), "Would write %d entries to %s (%d entries before, %d bytes)\n", changelog.CountEntries(contents), filename, changelog.CountEntries(existing), len(contents))
+		return err
+	}
+	return changelog.WriteFileAtomic(filename, func(w io.Writer) error {
+		_, err := io.WriteString(w, contents)
+		return err
+	})
+}
+
+// The file that the ChangeLog is written to with -o or -prepend, or ""
+func (dest *Destination) writtenFile() string {
+	if dest.Prepend != "" {
+		return dest.Prepend
+	}
+	return dest.Filename
+}
+
+// Check if the ChangeLog itself is written to stdout, and not to a file
+func (dest *Destination) writesToStdout() bool {
+	return (dest.Filename == "" || dest.Filename == "-") && dest.Prepend == "" && dest.Check == "" && !dest.Diff
+}
+
+// Write a diff between the existing file and what would be written to it,
+// for the Filename or Prepend destination, without writing anything
+func previewChangeLog(ctx context.Context, w io.Writer, dest *Destination, g *changelog.Generator, entries iter.Seq2[changelog.Entry, error]) error {
+	var (
+		filename, existing, updated string
+		err                         error
+	)
+	switch {
+	case dest.Prepend != "":
+		filename = dest.Prepend
+		existing, updated, err = prependedChangeLog(ctx, filename, g, entries)
+	case dest.Filename != "" && dest.Filename != "-":
+		filename = dest.Filename
+		existing, _, updated, err = regeneratedChangeLog(ctx, filename, g, entries)
+	default:
+		return withCode(EXIT_USAGE, errors.New("-diff needs a file to compare with, given with -o or -prepend"))
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, changelog.UnifiedDiff(filename, filename+" (generated)", existing, updated))
+	return err
+}
+
+// Return an error if any of the authors in the log could not be resolved
+func unresolvedError(summary *changelog.Summary) error {
+	if len(summary.Unresolved) == 0 {
+		return nil
+	}
+	return withCode(EXIT_UNRESOLVED, fmt.Errorf("Could not find the names and e-mail addresses for: %s", strings.Join(summary.Unresolved, ", ")))
+}
+
+// Show the summary of the issues at the end of the run on stderr, unless
+// -quiet is given, and write it to the -report file as JSON, if given
+func writeSummary(dest *Destination, summary *changelog.Summary, filename string) error {
+	if !quiet {
+		summary.WriteText(os.Stderr)
+	}
+	if filename == "" {
+		return nil
+	}
+	if dest.DryRun {
+		_, err := fmt.Fprintf(dest.report(), "Would write the summary to %s\n", filename)
+		return err
+	}
+	err := changelog.WriteFileAtomic(filename, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summary)
+	})
+	if err != nil {
+		return fmt.Errorf("Could not write the summary: %w", err)
+	}
+	return nil
+}
+
+// Fetch the log and write the ChangeLog, either to a file, to stdout or
+// to the top of an existing ChangeLog. In incremental mode, only the
+// revisions newer than the last run are fetched. The entries are written
+// while they are fetched, without keeping them in memory, unless
+// dest.KeepEntries is set or there is a post-generate hook. Returns the
+// entries, if they are kept.
+func generate(ctx context.Context, dest *Destination, g *changelog.Generator) ([]changelog.Entry, error) {
+	if dest.Incremental && g.Options.VCS == "git" && !g.Options.FirstParent {
+		// The numbers of the whole git history change when a merge brings in older commits
+		return nil, withCode(EXIT_USAGE, errors.New("-incremental needs -first-parent with -vcs git"))
+	}
+	if dest.Incremental && dest.Check == "" && !dest.Diff {
+		last, err := loadState(stateFilename(g.Options.Repo))
+		if err != nil {
+			return nil, err
+		}
+		if last == 0 && dest.Prepend != "" {
+			// Start from what the ChangeLog already has
+			if last, err = recordedRevision(ctx, g, dest.Prepend); err != nil {
+				return nil, err
+			}
+		}
+		g.Options.FromRevision = last + 1
+	}
+	if dest.KeepEntries || g.Options.PostGenerateHook != "" {
+		// The post-generate hook gets all of the entries at once
+		entries, err := g.Entries(ctx)
+		status.Done()
+		if err != nil {
+			return nil, err
+		}
+		if dest.Summary != nil {
+			dest.Summary.Add(entries...)
+		}
+		return entries, writeEntries(ctx, dest, g, entries)
+	}
+	entries := g.Stream(ctx)
+	if dest.Summary != nil {
+		entries = dest.Summary.Stream(entries)
+	}
+	if dest.writesToStdout() && isTerminal(os.Stdout) {
+		// The entries are shown while they are fetched, instead of the progress
+		status.Done()
+		status.Enable(false)
+	}
+	return nil, writeStream(ctx, dest, g, entries)
+}
+
+// Write the ChangeLog for the fetched entries to the destination
+func writeEntries(ctx context.Context, dest *Destination, g *changelog.Generator, entries []changelog.Entry) error {
+	return writeStream(ctx, dest, g, func(yield func(changelog.Entry, error) bool) {
+		for _, entry := range entries {
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	})
+}
+
+// Write the ChangeLog to the destination while the entries arrive, and
+// record the newest revision in incremental mode
+func writeStream(ctx context.Context, dest *Destination, g *changelog.Generator, entries iter.Seq2[changelog.Entry, error]) error {
+	var err error
+	defer status.Done()
+	newest := 0
+	entries = withNewestRevision(entries, &newest)
+	switch {
+	case dest.Check != "":
+		// Never update the state when only checking
+		return checkChangeLog(ctx, os.Stdout, dest.Check, g, entries)
+	case dest.Diff:
+		return previewChangeLog(ctx, os.Stdout, dest, g, entries)
+	case dest.Prepend != "":
+		err = prependChangeLog(ctx, dest, g, entries)
+	case dest.writesToStdout() && dest.DryRun:
+		var buf bytes.Buffer
+		if err = g.WriteStream(ctx, &buf, entries); err == nil {
+			_, err = fmt.Fprintf(dest.report(), "Would write %d entries to stdout (%d bytes)\n", changelog.CountEntries(buf.String()), buf.Len())
+		}
+	case dest.writesToStdout():
+		err = writeToStdout(ctx, g, entries, !dest.NoPager)
+	default:
+		err = writeChangeLog(ctx, dest, g, entries)
+	}
+	if err != nil {
+		return err
+	}
+	if dest.Incremental {
+		if newest > 0 && dest.DryRun {
+			fmt.Fprintf(dest.report(), "Would record revision %d in %s\n", newest, stateFilename(g.Options.Repo))
+		} else if newest > 0 {
+			if err := saveState(stateFilename(g.Options.Repo), newest); err != nil {
+				return fmt.Errorf("Could not record the last revision: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// The pager command, from $PAGER, or "less" if it is not set.
+// Returns nil if paging is disabled with PAGER= or PAGER=cat.
+func pagerCommand() []string {
+	pager, ok := os.LookupEnv("PAGER")
+	if !ok {
+		pager = "less"
+	}
+	fields := strings.Fields(pager)
+	if len(fields) == 0 || fields[0] == "cat" {
+		return nil
+	}
+	return fields
+}
+
+// Start the pager, if stdout is a terminal and a pager is available.
+// Returns a writer that goes to the pager, or to stdout if no pager is used,
+// and a function that closes the pager and waits for it to exit.
+func startPager(usePager bool) (io.Writer, func() error) {
+	noPager := func() error { return nil }
+	if !usePager || !isTerminal(os.Stdout) {
+		return os.Stdout, noPager
+	}
+	fields := pagerCommand()
+	if fields == nil {
+		return os.Stdout, noPager
+	}
+	path, err := exec.LookPath(fields[0])
+	if err != nil {
+		return os.Stdout, noPager
+	}
+	cmd := exec.Command(path, fields[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		// Like git: quit if the output fits on one screen, keep colors and
+		// don't clear the screen
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if os.Getenv("LV") == "" {
+		cmd.Env = append(cmd.Env, "LV=-c")
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return os.Stdout, noPager
+	}
+	if err := cmd.Start(); err != nil {
+		return os.Stdout, noPager
+	}
+	return stdin, func() error {
+		stdin.Close()
+		return cmd.Wait()
+	}
+}
+
+// Check if writing failed because the pager was quit before all of the
+// ChangeLog was written to it, which is not an error, like for git
+func pagerQuit(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
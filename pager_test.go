@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestPagerQuit(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	// Like a pager that was quit before all of the ChangeLog was written to it
+	r.Close()
+	_, err = w.Write([]byte("2014-03-17 arodseth\n"))
+	if err == nil {
+		t.Fatal("expected an error when writing to a closed pipe")
+	}
+	if !pagerQuit(err) {
+		t.Fatalf("expected %v to be from a pager that was quit", err)
+	}
+	if pagerQuit(errors.New("disk full")) {
+		t.Fatal("expected other errors to not be from a pager that was quit")
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The GitLab instance with the packaging repositories of Arch Linux
+const ARCH_GITLAB_URL = "https://gitlab.archlinux.org"
+
+// The GitLab group with one packaging repository per pkgbase
+const PACKAGING_GROUP = "archlinux/packaging/packages"
+
+var (
+	// Like gtk2+extra, where the + is replaced with a -
+	plusBetweenWords = regexp.MustCompile(`([a-zA-Z0-9]+)\+([a-zA-Z]+)`)
+	// Characters that can not be in the path of a GitLab project
+	notInProjectPath = regexp.MustCompile(`[^a-zA-Z0-9_\-.]`)
+	// Runs of - and _, which are collapsed into one -
+	repeatedDashes = regexp.MustCompile(`[_\-]{2,}`)
+)
+
+// The characters that package names are made of, as allowed by makepkg
+var validPkgname = regexp.MustCompile(`^[a-zA-Z0-9@_+][a-zA-Z0-9@._+-]*$`)
+
+// The path of the packaging repository of a pkgbase in PACKAGING_GROUP,
+// which is the pkgbase with the characters that GitLab does not allow
+// replaced, the same way as pkgctl does it
+func packagingPath(pkgbase string) string {
+	path := plusBetweenWords.ReplaceAllString(pkgbase, "$1-$2")
+	path = strings.Replace(path, "+", "plus", -1)
+	path = notInProjectPath.ReplaceAllString(path, "-")
+	path = repeatedDashes.ReplaceAllString(path, "-")
+	if path == "tree" {
+		return "unix-tree"
+	}
+	return path
+}
+
+// The parts of a GitLab project that are needed for cloning it
+type gitlabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+}
+
+// Find the packaging repository of a pkgbase with the GitLab API
+func findPackagingRepo(ctx context.Context, client *http.Client, gitlabURL, pkgbase string) (*gitlabProject, error) {
+	var project gitlabProject
+	address := strings.TrimSuffix(gitlabURL, "/") + "/api/v4/projects/" + url.PathEscape(PACKAGING_GROUP+"/"+packagingPath(pkgbase))
+	if err := postJSON(ctx, client, http.MethodGet, address, nil, nil, &project); err != nil {
+		var respErr *responseError
+		if errors.As(err, &respErr) && respErr.code == http.StatusNotFound {
+			return nil, withCode(EXIT_NO_REPO, fmt.Errorf("There is no packaging repository for %s in %s/%s", pkgbase, gitlabURL, PACKAGING_GROUP))
+		}
+		return nil, withCode(EXIT_NETWORK, fmt.Errorf("Could not find the packaging repository for %s: %w", pkgbase, err))
+	}
+	if u, err := url.Parse(project.HTTPURLToRepo); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("GitLab did not say where the packaging repository for %s is", pkgbase)
+	}
+	return &project, nil
+}
+
+// The name of the package in a working copy: the pkgname in the PKGBUILD,
+// or the name of the directory
+func packageName(repo, pkgbuild string) string {
+	if !filepath.IsAbs(pkgbuild) {
+		pkgbuild = filepath.Join(repo, pkgbuild)
+	}
+	if data, err := ioutil.ReadFile(pkgbuild); err == nil {
+		if name, ok := changelog.PkgbuildField(string(data), "pkgname"); ok && validPkgname.MatchString(name) {
+			return name
+		}
+	}
+	return changelog.GuessPackageName(repo)
+}
+
+// The current maintainers of an official package, from the package search,
+// with the names and e-mail addresses found for their nicks. There are none
+// for a package that is not in the repositories, like one in the AUR.
+func packageMaintainers(ctx context.Context, names *changelog.Names, packagesURL, name string) ([]string, error) {
+	web := &changelog.ArchWeb{Client: names.Client, Retries: changelog.DEFAULT_RETRIES, PackagesURL: packagesURL}
+	pkg, err := web.FindPackage(ctx, name)
+	if errors.Is(err, changelog.ErrUnknownPackage) {
+		slog.Warn("The package is not in the repositories, so there are no maintainers to show", "name", name)
+		return nil, nil
+	} else if err != nil {
+		return nil, withCode(EXIT_NETWORK, fmt.Errorf("Could not find the maintainers of %s: %w", name, err))
+	}
+	return resolveMaintainers(ctx, names, pkg.Maintainers)
+}
+
+// The names and e-mail addresses of the maintainers, or their nicks if they
+// could not be found
+func resolveMaintainers(ctx context.Context, names *changelog.Names, nicks []string) ([]string, error) {
+	if err := names.ResolveAll(ctx, nicks, nil); err != nil {
+		return nil, err
+	}
+	maintainers := make([]string, 0, len(nicks))
+	for _, nick := range nicks {
+		maintainers = append(maintainers, changelog.Sanitize(names.Resolve(ctx, nick), false))
+	}
+	return maintainers, nil
+}
+
+// archlog pkg
+func runPkg(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var gitlab_url *string = fs.String("gitlab-url", ARCH_GITLAB_URL, "the `URL` of the GitLab instance with the packaging repositories")
+	var packages_url *string = fs.String("packages-url", changelog.PACKAGES_API_URL, "the `URL` of the package search, for finding the pkgbase of a package")
+	var clone_dir *string = fs.String("clone-dir", filepath.Join(defaultCacheDir(), "packages"), "the `directory` where the packaging repositories are cloned and kept up to date")
+	var output *string = fs.String("o", "", "write the ChangeLog to this `file` (atomically replaced) instead of stdout")
+	var format *string = fs.String("format", "plain", "the output `format`: "+strings.Join(changelog.FormatterNames(), ", "))
+	var versions *bool = fs.Bool("versions", false, "group the entries by the version of the package they were released in")
+	var upgrades *bool = fs.Bool("upgrades", false, "add an \"Upgraded to\" line to the commits that changed the version of the package")
+	var maintainers *bool = fs.Bool("maintainers", false, "write the current maintainers of the package at the top of the ChangeLog")
+	var git_bin *string = addGitBinFlag(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	var unknown_author *string = addUnknownAuthorFlag(fs)
+	var lang *string = addLanguageFlag(fs)
+	var no_progress *bool = fs.Bool("no-progress", false, "do not show the progress on stderr")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	language, err := checkLanguage(*lang)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return withCode(EXIT_USAGE, errors.New("Please provide the name of a package, like archlog.\nUse --help for more info."))
+	}
+	name := fs.Arg(0)
+	if !validPkgname.MatchString(name) {
+		return withCode(EXIT_USAGE, fmt.Errorf("Invalid package name: %s", name))
+	}
+	n, err := parseEntries(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	if _, err := changelog.NewFormatter(*format, &changelog.Options{}); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if err := checkURL("gitlab-url", *gitlab_url); err != nil {
+		return err
+	}
+	if *clone_dir == "" {
+		return withCode(EXIT_USAGE, errors.New("Please provide a directory for the packaging repositories with -clone-dir"))
+	}
+	client := &http.Client{Timeout: *timeout}
+	status.Enable(!*no_progress)
+
+	// The packaging repository is named after the pkgbase, which is not the
+	// name of the package for split packages
+	pkgbase := name
+	web := &changelog.ArchWeb{Client: client, Retries: changelog.DEFAULT_RETRIES, PackagesURL: *packages_url}
+	pkg, err := web.FindPackage(ctx, name)
+	switch {
+	case errors.Is(err, changelog.ErrUnknownPackage):
+		slog.Info("The package is not in the repositories, looking for a packaging repository with the name", "name", name)
+	case err != nil:
+		return withCode(EXIT_NETWORK, fmt.Errorf("Could not search for the package %s: %w", name, err))
+	case !validPkgname.MatchString(pkg.Base):
+		return fmt.Errorf("The package search has an invalid pkgbase for %s: %s", name, pkg.Base)
+	default:
+		pkgbase = pkg.Base
+	}
+	project, err := findPackagingRepo(ctx, client, *gitlab_url, pkgbase)
+	if err != nil {
+		return err
+	}
+	opts := &changelog.Options{
+		Repo:          filepath.Join(*clone_dir, packagingPath(pkgbase)),
+		VCS:           "git",
+		GitBin:        *git_bin,
+		Timeout:       *timeout,
+		Entries:       n,
+		Format:        *format,
+		Versions:      *versions,
+		Upgrades:      *upgrades,
+		UnknownAuthor: *unknown_author,
+		Language:      language,
+		Progress:      status.Report,
+	}
+	status.Printf("Fetching %s", project.PathWithNamespace)
+	err = changelog.CloneGit(ctx, opts, project.HTTPURLToRepo, opts.Repo, 0)
+	status.Done()
+	if err != nil {
+		return err
+	}
+	if *maintainers && pkg != nil {
+		names := changelog.NewNames()
+		names.Client.Timeout = *timeout
+		if opts.Maintainers, err = resolveMaintainers(ctx, names, pkg.Maintainers); err != nil {
+			return err
+		}
+	}
+	g := changelog.New(opts)
+	// The git commits already have the names and e-mail addresses
+	g.Names.Resolver = changelog.AuthorsFile{}
+	_, err = generate(ctx, &Destination{Filename: *output}, g)
+	return err
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPackagingPath(t *testing.T) {
+	for pkgbase, expected := range map[string]string{
+		"archlog":     "archlog",
+		"gtk2+extra":  "gtk2-extra",
+		"libc++":      "libcplusplus",
+		"tree":        "unix-tree",
+		"perl-foo__x": "perl-foo-x",
+	} {
+		if path := packagingPath(pkgbase); path != expected {
+			t.Fatalf("expected %s for %s, got %s", expected, pkgbase, path)
+		}
+	}
+	if validPkgname.MatchString("../etc") || validPkgname.MatchString("-rf") || !validPkgname.MatchString("python-foo.bar+1") {
+		t.Fatal("unexpected package name validation")
+	}
+}
+
+func TestFindPackagingRepo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/archlinux%2Fpackaging%2Fpackages%2Flibcplusplus":
+			w.Write([]byte(`{"path_with_namespace": "archlinux/packaging/packages/libcplusplus", "http_url_to_repo": "https://gitlab.example.org/archlinux/packaging/packages/libcplusplus.git"}`))
+		case "/api/v4/projects/archlinux%2Fpackaging%2Fpackages%2Fext":
+			w.Write([]byte(`{"http_url_to_repo": "ext::sh -c touch% /tmp/pwned"}`))
+		default:
+			http.Error(w, `{"message":"404 Project Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	project, err := findPackagingRepo(ctx, server.Client(), server.URL+"/", "libc++")
+	if err != nil || !strings.HasSuffix(project.HTTPURLToRepo, "/libcplusplus.git") {
+		t.Fatalf("expected the repository of libc++, got %+v, %v", project, err)
+	}
+	if _, err := findPackagingRepo(ctx, server.Client(), server.URL, "missing"); exitCode(err) != EXIT_NO_REPO {
+		t.Fatalf("expected no repository, got %v", err)
+	}
+	// Only http and https repositories are cloned
+	if _, err := findPackagingRepo(ctx, server.Client(), server.URL, "ext"); err == nil {
+		t.Fatal("expected an error for another kind of URL")
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if name := packageName(dir, "PKGBUILD"); name != filepath.Base(dir) {
+		t.Fatalf("expected the name of the directory without a PKGBUILD, got %s", name)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "PKGBUILD"), []byte("pkgname=('foo' 'foo-docs')\npkgver=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if name := packageName(dir, "PKGBUILD"); name != "foo" {
+		t.Fatalf("expected foo, got %s", name)
+	}
+}
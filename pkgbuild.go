@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The ChangeLog of a package, unless the PKGBUILD already has a changelog= field
+const DEFAULT_PACKAGE_CHANGELOG = "ChangeLog"
+
+// A variable like $pkgname or ${pkgname}
+var pkgbuildVariable = regexp.MustCompile(`\$(\w+|\{\w+\})`)
+
+// Expand $pkgname, $pkgbase and the other simple variables of the PKGBUILD
+// in a value, like the one of changelog=$pkgname.changelog
+func expandPkgbuildValue(pkgbuild, value string) (string, error) {
+	var err error
+	expanded := pkgbuildVariable.ReplaceAllStringFunc(value, func(variable string) string {
+		name := strings.Trim(variable, "${}")
+		v, ok := changelog.PkgbuildField(pkgbuild, name)
+		if !ok && name == "pkgbase" {
+			v, ok = changelog.PkgbuildField(pkgbuild, "pkgname")
+		}
+		if !ok || strings.Contains(v, "$") {
+			err = fmt.Errorf("Could not expand %s in %s, please give the file with -changelog", variable, value)
+		}
+		return v
+	})
+	return expanded, err
+}
+
+// A value that does not have to be quoted in a PKGBUILD
+var plainPkgbuildValue = regexp.MustCompile(`^[\w.+/-]+$`)
+
+// Quote a value for a PKGBUILD, if needed, keeping variables in it expanded
+func pkgbuildQuote(value string) string {
+	switch {
+	case plainPkgbuildValue.MatchString(value):
+		return value
+	case strings.Contains(value, "$") && !strings.ContainsAny(value, "\"`\\"):
+		return `"` + value + `"`
+	}
+	return shellQuote(value)
+}
+
+// The fields that come after changelog= in a PKGBUILD, which it is added before
+var afterChangelog = regexp.MustCompile(`^(source(_\w+)?|noextract|validpgpkeys|(md5|sha\d+|b2|ck)sums(_\w+)?)=|^(prepare|pkgver|build|check|package(_\S+)?)\s*\(\)`)
+
+// Set the changelog= field of a PKGBUILD: replace the line it is on, or add
+// it after install=, before the sources and functions, or at the end
+func setPkgbuildChangelog(pkgbuild, value string) string {
+	line := "changelog=" + pkgbuildQuote(value)
+	lines := strings.Split(pkgbuild, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, "changelog=") {
+			lines[i] = line
+			return strings.Join(lines, "\n")
+		}
+	}
+	insert := -1
+	for i, l := range lines {
+		if strings.HasPrefix(l, "install=") {
+			insert = i + 1
+			break
+		}
+		if insert < 0 && afterChangelog.MatchString(l) {
+			insert = i
+		}
+	}
+	if insert < 0 {
+		if !strings.HasSuffix(pkgbuild, "\n") && pkgbuild != "" {
+			pkgbuild += "\n"
+		}
+		return pkgbuild + line + "\n"
+	}
+	lines = append(lines[:insert], append([]string{line}, lines[insert:]...)...)
+	return strings.Join(lines, "\n")
+}
+
+// Check that makepkg can still read the PKGBUILD, and that it has the
+// ChangeLog in its changelog= field
+func verifyPkgbuild(ctx context.Context, makepkg, dir, value string) error {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, makepkg, "--printsrcinfo")
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("makepkg --printsrcinfo failed: %s", strings.Replace(message, "\n", "; ", -1))
+		}
+		return fmt.Errorf("makepkg --printsrcinfo failed: %w", err)
+	}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if strings.TrimSpace(line) == "changelog = "+value {
+			return nil
+		}
+	}
+	return fmt.Errorf("makepkg --printsrcinfo does not have changelog = %s", value)
+}
+
+// archlog pkgbuild
+func runPkgbuild(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var pkgbuild_file *string = fs.String("pkgbuild", "PKGBUILD", "the PKGBUILD `file`, relative to the working copy")
+	var changelog_file *string = fs.String("changelog", "", "the ChangeLog `file`, relative to the PKGBUILD, instead of the one in its changelog= field, or "+DEFAULT_PACKAGE_CHANGELOG)
+	var makepkg_bin *string = fs.String("makepkg-bin", "makepkg", "the makepkg `executable`, for checking the PKGBUILD with makepkg --printsrcinfo")
+	var no_verify *bool = fs.Bool("no-verify", false, "do not check the PKGBUILD with makepkg, for instance where makepkg is not installed")
+	var versions *bool = fs.Bool("versions", false, "group the entries by the version of the package they were released in, from the history of the PKGBUILD and .SRCINFO")
+	var upgrades *bool = fs.Bool("upgrades", false, "add an \"Upgraded to\" line to the commits that changed the version of the package")
+	var maintainers *bool = fs.Bool("maintainers", false, "write the current maintainers of the package at the top of the ChangeLog, from the package search on archlinux.org")
+	var dry_run *bool = fs.Bool("dry-run", false, "fetch the log and resolve the names, but only report what would be written")
+	repo, vcs, svn_bin, git_bin := addSourceFlags(fs)
+	svn_auth := addSvnAuthFlags(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	jobs, stop_on_copy := addFetchFlags(fs)
+	var normalize *bool = fs.Bool("normalize", false, "capitalize the messages, collapse spaces, remove trailing periods and the \"pkgname:\" prefix")
+	cache_dir, no_cache := addCacheFlags(fs)
+	resolver_flags := addResolverFlags(fs)
+	var unknown_author *string = addUnknownAuthorFlag(fs)
+	var lang *string = addLanguageFlag(fs)
+	strict, lenient, input_encoding := addParseFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	language, err := checkLanguage(*lang)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return withCode(EXIT_USAGE, fmt.Errorf("Unexpected argument: %s", fs.Arg(0)))
+	}
+	parsing, err := parseMode(*strict, *lenient)
+	if err != nil {
+		return err
+	}
+	encoding, err := changelog.CheckEncoding(*input_encoding)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if _, err := changelog.LookupSource(*vcs); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	filename := *pkgbuild_file
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(*repo, filename)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return withCode(EXIT_NO_REPO, fmt.Errorf("Could not read the PKGBUILD: %w", err))
+	}
+	existing := string(data)
+	value := *changelog_file
+	current, hasChangelog := changelog.PkgbuildField(existing, "changelog")
+	if value == "" {
+		value = current
+	}
+	if value == "" {
+		value = DEFAULT_PACKAGE_CHANGELOG
+	}
+	expanded, err := expandPkgbuildValue(existing, value)
+	if err != nil {
+		return withCode(EXIT_PARSE, err)
+	}
+	if filepath.IsAbs(expanded) || strings.HasPrefix(filepath.Clean(expanded), "..") {
+		return withCode(EXIT_USAGE, fmt.Errorf("The ChangeLog must be next to the PKGBUILD, not %s", expanded))
+	}
+	dir := filepath.Dir(filename)
+	// The PKGBUILD for -versions is relative to the working copy
+	relative := *pkgbuild_file
+	if filepath.IsAbs(relative) {
+		top, err := filepath.Abs(*repo)
+		if err != nil {
+			return err
+		}
+		if relative, err = filepath.Rel(top, relative); err != nil {
+			return withCode(EXIT_USAGE, err)
+		}
+	}
+
+	norm := &changelog.Normalization{}
+	if *normalize {
+		norm = &changelog.Normalization{Capitalize: true, CollapseSpace: true, StripPeriod: true}
+		if pkgname, ok := changelog.PkgbuildField(existing, "pkgname"); ok && !strings.Contains(pkgname, "$") {
+			norm.Prefixes = []string{pkgname}
+		}
+	}
+	g := changelog.New(&changelog.Options{
+		Repo:          *repo,
+		VCS:           *vcs,
+		SvnBin:        *svn_bin,
+		GitBin:        *git_bin,
+		Timeout:       *timeout,
+		Jobs:          *jobs,
+		StopOnCopy:    *stop_on_copy,
+		Entries:       -1,
+		Normalization: norm,
+		Parsing:       parsing,
+		InputEncoding: encoding,
+		UnknownAuthor: *unknown_author,
+		Language:      language,
+		Versions:      *versions,
+		Upgrades:      *upgrades,
+		Pkgbuild:      relative,
+	})
+	svn_auth.apply(g.Options)
+	g.Names.Client.Timeout = *timeout
+	if err := setupResolvers(g.Names, resolver_flags); err != nil {
+		return err
+	}
+	if err := setupNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	setupPageCache(g.Names, *cache_dir, *no_cache || *dry_run)
+	if *maintainers {
+		if g.Options.Maintainers, err = packageMaintainers(ctx, g.Names, changelog.PACKAGES_API_URL, packageName(*repo, *pkgbuild_file)); err != nil {
+			return err
+		}
+	}
+	// The new entries are added to the top of an existing ChangeLog
+	dest := &Destination{Prepend: filepath.Join(dir, expanded), DryRun: *dry_run}
+	if _, err := generate(ctx, dest, g); err != nil {
+		return err
+	}
+	if !*dry_run {
+		if err := storeNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+			return err
+		}
+	}
+
+	updated := existing
+	if !hasChangelog || current != value {
+		updated = setPkgbuildChangelog(existing, value)
+	}
+	switch {
+	case updated == existing && *dry_run:
+		fmt.Fprintf(dest.report(), "Would leave %s as it is, it has changelog=%s\n", filename, value)
+	case *dry_run:
+		fmt.Fprintf(dest.report(), "Would set changelog=%s in %s\n", value, filename)
+	case updated != existing:
+		err := changelog.WriteFileAtomic(filename, func(w io.Writer) error {
+			_, err := io.WriteString(w, updated)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if *no_verify || *dry_run {
+		return g.Names.NetworkError()
+	}
+	makepkg, err := exec.LookPath(*makepkg_bin)
+	if err != nil {
+		return fmt.Errorf("Could not find makepkg to check the PKGBUILD with, use -no-verify to skip it (%s)", err)
+	}
+	if err := verifyPkgbuild(ctx, makepkg, dir, expanded); err != nil {
+		if updated != existing {
+			// Put back the PKGBUILD that makepkg could read
+			restoreErr := changelog.WriteFileAtomic(filename, func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			})
+			if restoreErr != nil {
+				return errors.Join(err, restoreErr)
+			}
+			err = fmt.Errorf("%w, so the PKGBUILD was left as it was", err)
+		}
+		return withCode(EXIT_PARSE, err)
+	}
+	return g.Names.NetworkError()
+}
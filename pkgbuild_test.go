@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPkgbuild = `pkgname=archlog
+pkgver=1.0
+pkgrel=1
+arch=(x86_64)
+source=("https://example.org/$pkgname-$pkgver.tar.gz")
+sha256sums=('SKIP')
+
+package() {
+  install -Dm755 archlog "$pkgdir/usr/bin/archlog"
+}
+`
+
+func TestExpandPkgbuildValue(t *testing.T) {
+	if value, err := expandPkgbuildValue(testPkgbuild, "${pkgbase}.changelog"); err != nil || value != "archlog.changelog" {
+		t.Fatalf("expected archlog.changelog, got %q, %v", value, err)
+	}
+	if _, err := expandPkgbuildValue(testPkgbuild, "$_name.changelog"); err == nil {
+		t.Fatal("expected an error for a variable that is not set")
+	}
+	for value, expected := range map[string]string{
+		"ChangeLog":           "ChangeLog",
+		"$pkgname.changelog":  `"$pkgname.changelog"`,
+		"the ChangeLog":       "'the ChangeLog'",
+		"docs/ChangeLog.old+": "docs/ChangeLog.old+",
+	} {
+		if quoted := pkgbuildQuote(value); quoted != expected {
+			t.Fatalf("expected %s for %q, got %s", expected, value, quoted)
+		}
+	}
+}
+
+func TestSetPkgbuildChangelog(t *testing.T) {
+	// Before the sources
+	updated := setPkgbuildChangelog(testPkgbuild, "ChangeLog")
+	if !strings.Contains(updated, "arch=(x86_64)\nchangelog=ChangeLog\nsource=(") {
+		t.Fatalf("expected changelog= before source=, got:\n%s", updated)
+	}
+	// After install=
+	updated = setPkgbuildChangelog(strings.Replace(testPkgbuild, "arch=(x86_64)\n", "arch=(x86_64)\ninstall=archlog.install\n", 1), "ChangeLog")
+	if !strings.Contains(updated, "install=archlog.install\nchangelog=ChangeLog\nsource=(") {
+		t.Fatalf("expected changelog= after install=, got:\n%s", updated)
+	}
+	// Replaced
+	updated = setPkgbuildChangelog(updated, "$pkgname.changelog")
+	if strings.Count(updated, "changelog=") != 1 || !strings.Contains(updated, "changelog=\"$pkgname.changelog\"\n") {
+		t.Fatalf("expected the changelog= line to be replaced, got:\n%s", updated)
+	}
+	// At the end
+	if updated := setPkgbuildChangelog("pkgname=archlog", "ChangeLog"); updated != "pkgname=archlog\nchangelog=ChangeLog\n" {
+		t.Fatalf("expected changelog= at the end, got %q", updated)
+	}
+}
+
+func TestVerifyPkgbuild(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the fake makepkg with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// A makepkg that prints the changelog= line of the PKGBUILD, or fails if there is none
+	makepkg := filepath.Join(dir, "makepkg")
+	script := "#!/bin/sh\nline=$(grep '^changelog=' PKGBUILD) || { echo '==> ERROR: Failed to source PKGBUILD' >&2; exit 1; }\necho 'pkgbase = archlog'\necho \"\tchangelog = ${line#changelog=}\"\n"
+	if err := ioutil.WriteFile(makepkg, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	pkgbuild := filepath.Join(dir, "PKGBUILD")
+	if err := ioutil.WriteFile(pkgbuild, []byte(testPkgbuild), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = verifyPkgbuild(context.Background(), makepkg, dir, "ChangeLog")
+	if err == nil || !strings.Contains(err.Error(), "Failed to source PKGBUILD") {
+		t.Fatalf("expected the error from makepkg, got %v", err)
+	}
+	if err := ioutil.WriteFile(pkgbuild, []byte(setPkgbuildChangelog(testPkgbuild, "ChangeLog")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyPkgbuild(context.Background(), makepkg, dir, "ChangeLog"); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyPkgbuild(context.Background(), makepkg, dir, "NEWS"); err == nil {
+		t.Fatal("expected an error for another changelog")
+	}
+}
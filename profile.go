@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime/pprof"
+	"runtime/trace"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// Stops the profiling that was started with -profile or -trace, if any
+var stopProfiling = func() {}
+
+// Add the profiling flags that every command has
+func addProfilingFlags(fs *flag.FlagSet) {
+	fs.String("profile", "", "write a CPU profile to this `file`, for \"go tool pprof\"")
+	fs.String("trace", "", "write an execution trace to this `file`, for \"go tool trace\"")
+}
+
+// Start the CPU profile and the execution trace, if they are asked for
+func setupProfiling(fs *flag.FlagSet) error {
+	var stops []func()
+	stopProfiling = func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+	start := func(name string, begin func(w io.Writer) error, end func()) error {
+		f := fs.Lookup(name)
+		if f == nil || f.Value.String() == "" {
+			return nil
+		}
+		filename := f.Value.String()
+		file, err := os.Create(filename)
+		if err != nil {
+			return fmt.Errorf("Could not create the %s file: %w", name, err)
+		}
+		if err := begin(file); err != nil {
+			file.Close()
+			return fmt.Errorf("Could not start the %s: %w", name, err)
+		}
+		stops = append(stops, func() {
+			end()
+			if err := file.Close(); err != nil {
+				slog.Error("Could not write the "+name+" file", "err", err)
+			}
+		})
+		return nil
+	}
+	if err := start("profile", pprof.StartCPUProfile, pprof.StopCPUProfile); err != nil {
+		return err
+	}
+	return start("trace", trace.Start, trace.Stop)
+}
+
+// Show the time spent in each phase on stderr, with -timing, and the
+// total time since start
+func writeTimings(timings *changelog.Timings, start time.Time) {
+	if timings == nil {
+		return
+	}
+	status.Done()
+	timings.Add("total", time.Since(start))
+	timings.WriteText(os.Stderr)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// How often the progress indicator is updated
+const PROGRESS_INTERVAL = 100 * time.Millisecond
+
+// A progress indicator that is written to stderr, on a single line
+type progress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	enabled bool
+	shown   bool
+	last    time.Time
+}
+
+// The progress indicator for long operations, disabled until enabled by a command
+var status = &progress{w: os.Stderr}
+
+// Enable the progress indicator if stderr is a terminal, and -quiet is not given
+func (p *progress) Enable(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enabled = enabled && !quiet && isTerminal(os.Stderr)
+}
+
+// Show a progress message, replacing the previous one.
+// Updates are throttled, so this can be called often.
+func (p *progress) Printf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.enabled || time.Since(p.last) < PROGRESS_INTERVAL {
+		return
+	}
+	p.last = time.Now()
+	p.shown = true
+	fmt.Fprintf(p.w, "\r\033[K"+format, args...)
+}
+
+// Show the progress of a stage, for the changelog.Options.Progress callback
+func (p *progress) Report(stage string, done, total int) {
+	if total > 0 {
+		p.Printf("%s: %d of %d", stage, done, total)
+	} else {
+		p.Printf("%s: %d", stage, done)
+	}
+}
+
+// Clear the progress message
+func (p *progress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.shown {
+		fmt.Fprint(p.w, "\r\033[K")
+		p.shown = false
+	}
+	p.last = time.Time{}
+}
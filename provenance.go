@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The file with the checksums, in the format of sha256sum
+const CHECKSUMS_FILE = "SHA256SUMS"
+
+// Where a ChangeLog was generated from, in the .provenance.json file next to it
+type provenance struct {
+	Generator  string        `json:"generator"` // archlog and its version
+	Generated  time.Time     `json:"generated"` // $SOURCE_DATE_EPOCH, if it is set
+	VCS        string        `json:"vcs"`
+	Repository string        `json:"repository,omitempty"` // The URL of the repository, if it could be found
+	Revisions  revisionRange `json:"revisions"`
+	Entries    int           `json:"entries"` // The number of entries in the ChangeLog
+	SHA256     string        `json:"sha256"`  // The checksum of the ChangeLog
+}
+
+// The oldest and the newest revision, and the commit hashes for git
+type revisionRange struct {
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	FromCommit string `json:"from_commit,omitempty"`
+	ToCommit   string `json:"to_commit,omitempty"`
+}
+
+// The flags for writing the provenance and the checksums next to the ChangeLog
+type provenanceFlags struct {
+	provenance *bool
+	checksums  *bool
+}
+
+// Add the -provenance and -checksums flags
+func addProvenanceFlags(fs *flag.FlagSet) *provenanceFlags {
+	return &provenanceFlags{
+		provenance: fs.Bool("provenance", false, "write where the ChangeLog was generated from, like the repository and the revisions, to a .provenance.json file next to it"),
+		checksums:  fs.Bool("checksums", false, "write the SHA-256 checksums of the ChangeLog and the files next to it that archlog wrote to "+CHECKSUMS_FILE+", for sha256sum -c"),
+	}
+}
+
+// Check the flags, for the file that is written, if any
+func (f *provenanceFlags) check(dest *Destination) error {
+	if (*f.provenance || *f.checksums) && (dest.Check != "" || dest.Diff || dest.writtenFile() == "") {
+		return withCode(EXIT_USAGE, errors.New("-provenance and -checksums only work when writing the ChangeLog to a file, with -o or -prepend"))
+	}
+	return nil
+}
+
+// The SHA-256 checksum of a file, in hex
+func sha256File(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// The range of the fetched revisions, extended with the oldest revision of
+// the previous run if the ChangeLog was only added to
+func fetchedRevisions(entries []changelog.Entry, previous *revisionRange) revisionRange {
+	var r revisionRange
+	for _, entry := range entries {
+		if r.From == 0 || entry.Revision < r.From {
+			r.From, r.FromCommit = entry.Revision, entry.Commit
+		}
+		if entry.Revision > r.To {
+			r.To, r.ToCommit = entry.Revision, entry.Commit
+		}
+	}
+	if previous != nil && previous.From > 0 {
+		if r.From == 0 || previous.From < r.From {
+			r.From, r.FromCommit = previous.From, previous.FromCommit
+		}
+		if previous.To > r.To {
+			r.To, r.ToCommit = previous.To, previous.ToCommit
+		}
+	}
+	return r
+}
+
+// Write the provenance of the ChangeLog. With -prepend or -incremental,
+// only some revisions are fetched, so the range of the previous
+// provenance file is kept.
+func writeProvenance(ctx context.Context, dest *Destination, g *changelog.Generator, entries []changelog.Entry) (string, error) {
+	filename := dest.writtenFile()
+	target := filename + ".provenance.json"
+	if dest.DryRun {
+		_, err := fmt.Fprintf(dest.report(), "Would write the provenance of %s to %s\n", filename, target)
+		return target, err
+	}
+	var previous *revisionRange
+	if dest.Prepend != "" || dest.Incremental {
+		if data, err := ioutil.ReadFile(target); err == nil {
+			var old provenance
+			if err := json.Unmarshal(data, &old); err == nil && old.VCS == g.Options.VCS {
+				previous = &old.Revisions
+			}
+		}
+	}
+	generated, err := sourceDate()
+	if err != nil {
+		return "", err
+	}
+	p := provenance{
+		Generator: "archlog " + VERSION,
+		Generated: generated,
+		VCS:       g.Options.VCS,
+		Revisions: fetchedRevisions(entries, previous),
+	}
+	if p.VCS == "" {
+		p.VCS = "svn"
+	}
+	if source, err := changelog.LookupSource(p.VCS); err == nil {
+		if located, ok := source.(changelog.LocatedSource); ok {
+			if p.Repository, err = located.RepositoryURL(ctx, g.Options); err != nil {
+				slog.Warn("Could not find the URL of the repository for the provenance", "err", err)
+			}
+		}
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	p.Entries = changelog.CountEntries(string(data))
+	if p.SHA256, err = sha256File(filename); err != nil {
+		return "", err
+	}
+	err = changelog.WriteFileAtomic(target, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p)
+	})
+	if err != nil {
+		return "", fmt.Errorf("Could not write the provenance: %w", err)
+	}
+	return target, nil
+}
+
+// Add or update the checksums of the files in the SHA256SUMS file in their
+// directory. The checksums of other files in it are kept.
+func updateChecksums(files []string) error {
+	dir := filepath.Dir(files[0])
+	sums := filepath.Join(dir, CHECKSUMS_FILE)
+	checksums := make(map[string]string)
+	var names []string
+	for _, file := range files {
+		sum, err := sha256File(file)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(file)
+		checksums[name] = sum
+		names = append(names, name)
+	}
+	var lines []string
+	if f, err := os.Open(sums); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			// Each line is the checksum, a space, and a space or a * before the name
+			if fields := strings.SplitN(line, " ", 2); len(fields) == 2 && len(fields[1]) > 1 {
+				name := fields[1][1:]
+				if sum, ok := checksums[name]; ok {
+					line = sum + "  " + name
+					delete(checksums, name)
+				}
+			}
+			lines = append(lines, line)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	for _, name := range names {
+		if sum, ok := checksums[name]; ok {
+			lines = append(lines, sum+"  "+name)
+		}
+	}
+	return changelog.WriteFileAtomic(sums, func(w io.Writer) error {
+		_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+		return err
+	})
+}
+
+// Write the provenance and the checksums, after the ChangeLog has been
+// written and signed
+func (f *provenanceFlags) write(ctx context.Context, dest *Destination, g *changelog.Generator, entries []changelog.Entry, signature string) error {
+	if !*f.provenance && !*f.checksums {
+		return nil
+	}
+	files := []string{dest.writtenFile()}
+	if signature != "" {
+		files = append(files, signature)
+	}
+	if *f.provenance {
+		target, err := writeProvenance(ctx, dest, g, entries)
+		if err != nil {
+			return err
+		}
+		files = append(files, target)
+	}
+	if !*f.checksums {
+		return nil
+	}
+	if dest.DryRun {
+		_, err := fmt.Fprintf(dest.report(), "Would write the checksums to %s\n", filepath.Join(filepath.Dir(files[0]), CHECKSUMS_FILE))
+		return err
+	}
+	if err := updateChecksums(files); err != nil {
+		return fmt.Errorf("Could not write the checksums: %w", err)
+	}
+	return nil
+}
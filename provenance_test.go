@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestFetchedRevisions(t *testing.T) {
+	entries := []changelog.Entry{{Revision: 9, Commit: "c9"}, {Revision: 8, Commit: "c8"}}
+	if r := fetchedRevisions(entries, nil); r != (revisionRange{From: 8, To: 9, FromCommit: "c8", ToCommit: "c9"}) {
+		t.Fatalf("unexpected range: %+v", r)
+	}
+	// The oldest revision of the previous run is kept
+	previous := &revisionRange{From: 1, To: 7, FromCommit: "c1", ToCommit: "c7"}
+	if r := fetchedRevisions(entries, previous); r != (revisionRange{From: 1, To: 9, FromCommit: "c1", ToCommit: "c9"}) {
+		t.Fatalf("unexpected range: %+v", r)
+	}
+	if r := fetchedRevisions(nil, previous); r != *previous {
+		t.Fatalf("expected the previous range without new entries, got %+v", r)
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	changeLog := filepath.Join(dir, "ChangeLog")
+	if err := ioutil.WriteFile(changeLog, []byte("2024-03-01 alice\n    * Fix the build\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sums := filepath.Join(dir, CHECKSUMS_FILE)
+	if err := ioutil.WriteFile(sums, []byte("0000  ChangeLog\n1111 *archlog.tar.gz\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "1709251200")
+	provenance_flags := &provenanceFlags{provenance: new(bool), checksums: new(bool)}
+	*provenance_flags.provenance, *provenance_flags.checksums = true, true
+	g := changelog.New(&changelog.Options{VCS: "provenance-test"})
+	changelog.RegisterSource("provenance-test", &countingSource{})
+	entries := []changelog.Entry{{Revision: 2}, {Revision: 1}}
+	if err := provenance_flags.write(context.Background(), &Destination{Filename: changeLog}, g, entries, ""); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(changeLog + ".provenance.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p provenance
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatal(err)
+	}
+	sum, _ := sha256File(changeLog)
+	if p.VCS != "provenance-test" || p.Revisions.From != 1 || p.Revisions.To != 2 || p.Entries != 1 || p.SHA256 != sum || p.Generated.Unix() != 1709251200 {
+		t.Fatalf("unexpected provenance: %+v", p)
+	}
+	// The checksum of the ChangeLog is replaced, and the one of another file is kept
+	data, err = ioutil.ReadFile(sums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[0] != sum+"  ChangeLog" || lines[1] != "1111 *archlog.tar.gz" || !strings.HasSuffix(lines[2], "  ChangeLog.provenance.json") {
+		t.Fatalf("unexpected checksums: %q", data)
+	}
+	if err := provenance_flags.check(&Destination{}); exitCode(err) != EXIT_USAGE {
+		t.Fatalf("expected a usage error when writing to stdout, got %v", err)
+	}
+}
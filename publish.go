@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The GitLab instance to publish to, unless $CI_SERVER_URL is set
+const DEFAULT_GITLAB_URL = "https://gitlab.com"
+
+// The GitLab instance from the environment of a GitLab CI job, if any
+func defaultGitlabURL() string {
+	if address := os.Getenv("CI_SERVER_URL"); address != "" {
+		return address
+	}
+	return DEFAULT_GITLAB_URL
+}
+
+// A release on GitLab, for the Releases API
+type gitlabRelease struct {
+	baseURL string // Like https://gitlab.archlinux.org
+	project string // The id of the project, or its path, like archlinux/archlog
+	header  http.Header
+}
+
+// The parts of a release that are sent and answered with
+type gitlabReleaseBody struct {
+	TagName     string `json:"tag_name,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description"`
+	Ref         string `json:"ref,omitempty"`
+	Links       struct {
+		Self string `json:"self"`
+	} `json:"_links"`
+}
+
+// The address of the releases of the project, or of one release
+func (r *gitlabRelease) address(tag string) string {
+	address := r.baseURL + "/api/v4/projects/" + url.PathEscape(r.project) + "/releases"
+	if tag != "" {
+		address += "/" + url.PathEscape(tag)
+	}
+	return address
+}
+
+// Create the release for the tag, or update the name and description of
+// the release if there already is one. Returns the address of the release.
+func (r *gitlabRelease) publish(ctx context.Context, client *http.Client, tag, name, description, ref string) (string, error) {
+	var result gitlabReleaseBody
+	err := postJSON(ctx, client, http.MethodPost, r.address(""), r.header, &gitlabReleaseBody{TagName: tag, Name: name, Description: description, Ref: ref}, &result)
+	var respErr *responseError
+	if errors.As(err, &respErr) && respErr.code == http.StatusConflict {
+		// The release is already there
+		err = postJSON(ctx, client, http.MethodPut, r.address(tag), r.header, &gitlabReleaseBody{Name: name, Description: description}, &result)
+	}
+	if err != nil {
+		return "", err
+	}
+	return result.Links.Self, nil
+}
+
+// The header for authenticating with GitLab: the given token, the token of
+// the CI job, or the password for the host in the .netrc file
+func gitlabAuth(token, netrcFile, host string) (http.Header, error) {
+	if token != "" {
+		return http.Header{"Private-Token": {token}}, nil
+	}
+	if job := os.Getenv("CI_JOB_TOKEN"); job != "" {
+		return http.Header{"Job-Token": {job}}, nil
+	}
+	if netrcFile != "" {
+		netrc, err := changelog.LoadNetrc(netrcFile)
+		if err != nil {
+			return nil, err
+		}
+		if login, ok := netrc.Lookup(host); ok && login.Password != "" {
+			return http.Header{"Private-Token": {login.Password}}, nil
+		}
+	}
+	return nil, withCode(EXIT_USAGE, fmt.Errorf("No token for %s, please provide one with %s", host, envName("gitlab-token")))
+}
+
+// The release notes: the ChangeLog in the markdown format, without the title
+func releaseNotes(ctx context.Context, g *changelog.Generator, entries []changelog.Entry) (string, error) {
+	sections, err := g.Sections(ctx, entries)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	f, _ := changelog.NewFormatter("markdown", g.Options)
+	for i := range sections {
+		if err := f.Entry(&buf, &sections[i]); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(buf.String()) + "\n", nil
+}
+
+// archlog publish
+func runPublish(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var tag *string = fs.String("tag", os.Getenv("CI_COMMIT_TAG"), "the `tag` of the release, which is $CI_COMMIT_TAG by default")
+	var name *string = fs.String("name", "", "the `name` of the release, instead of the tag")
+	var ref *string = fs.String("ref", "", "the branch or commit `ref` to create the tag from, if it does not exist yet")
+	var notes *string = fs.String("notes", "", "a `file` with the release notes, instead of generating them")
+	var since *string = fs.String("since", "", "only the entries from this `date` (YYYY-MM-DD) and later, like the day after the previous release")
+	var gitlab_url *string = fs.String("gitlab-url", defaultGitlabURL(), "the `URL` of the GitLab instance, like https://gitlab.archlinux.org")
+	var gitlab_project *string = fs.String("gitlab-project", os.Getenv("CI_PROJECT_ID"), "the `id` or path of the GitLab project, like archlinux/archlog, which is $CI_PROJECT_ID by default")
+	var gitlab_token *string = fs.String("gitlab-token", "", "a GitLab access `token` with the api scope, instead of $CI_JOB_TOKEN or the password for the host in the .netrc file")
+	var dry_run *bool = fs.Bool("dry-run", false, "only show the release notes, without publishing them")
+	repo, vcs, svn_bin, git_bin := addSourceFlags(fs)
+	svn_auth := addSvnAuthFlags(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	jobs, stop_on_copy := addFetchFlags(fs)
+	cache_dir, no_cache := addCacheFlags(fs)
+	resolver_flags := addResolverFlags(fs)
+	var unknown_author *string = addUnknownAuthorFlag(fs)
+	var lang *string = addLanguageFlag(fs)
+	anonymize := addAnonymizeFlag(fs)
+	var obfuscate_email *string = fs.String("obfuscate-email", "", "obfuscate the e-mail addresses: `at` for \"alice at example dot org\", entities for HTML character references in the html and markdown formats, or drop")
+	var avatars *string = fs.String("avatars", "", "add the avatars of the authors to the html and json formats, from `gravatar` or libravatar")
+	strict, lenient, input_encoding := addParseFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	language, err := checkLanguage(*lang)
+	if err != nil {
+		return err
+	}
+	if err := changelog.CheckObfuscateEmail(*obfuscate_email); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if err := changelog.CheckAvatars(*avatars); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	parsing, err := parseMode(*strict, *lenient)
+	if err != nil {
+		return err
+	}
+	encoding, err := changelog.CheckEncoding(*input_encoding)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	n, err := parseEntries(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *tag == "" {
+		return withCode(EXIT_USAGE, errors.New("Please provide the tag of the release with -tag"))
+	}
+	if *since != "" {
+		if _, err := time.Parse("2006-01-02", *since); err != nil {
+			return withCode(EXIT_USAGE, fmt.Errorf("Invalid -since date, expected YYYY-MM-DD: %s", *since))
+		}
+	}
+	if _, err := changelog.LookupSource(*vcs); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if *notes != "" && (*since != "" || n > 0) {
+		return withCode(EXIT_USAGE, errors.New("-notes can not be used with -since or a number of entries"))
+	}
+	release := &gitlabRelease{baseURL: strings.TrimSuffix(*gitlab_url, "/"), project: *gitlab_project}
+	if !*dry_run {
+		if err := checkURL("gitlab-url", *gitlab_url); err != nil {
+			return err
+		}
+		if release.project == "" {
+			return withCode(EXIT_USAGE, errors.New("Please provide the GitLab project with -gitlab-project"))
+		}
+		u, _ := url.Parse(*gitlab_url)
+		if release.header, err = gitlabAuth(*gitlab_token, *svn_auth.netrc, u.Hostname()); err != nil {
+			return err
+		}
+	}
+
+	var description string
+	if *notes != "" {
+		data, err := ioutil.ReadFile(*notes)
+		if err != nil {
+			return err
+		}
+		description = string(data)
+	} else {
+		g := changelog.New(&changelog.Options{
+			Repo:          *repo,
+			VCS:           *vcs,
+			SvnBin:        *svn_bin,
+			GitBin:        *git_bin,
+			Timeout:       *timeout,
+			Jobs:          *jobs,
+			StopOnCopy:    *stop_on_copy,
+			Entries:       n,
+			Since:         *since,
+			Parsing:       parsing,
+			InputEncoding: encoding,
+			UnknownAuthor: *unknown_author,
+			Language:      language,
+			Anonymize:     anonymize.style,
+			Obfuscate:     *obfuscate_email,
+			Avatars:       *avatars,
+		})
+		svn_auth.apply(g.Options)
+		g.Names.Client.Timeout = *timeout
+		if err := setupResolvers(g.Names, resolver_flags); err != nil {
+			return err
+		}
+		if err := setupNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+			return err
+		}
+		setupPageCache(g.Names, *cache_dir, *no_cache || *dry_run)
+		entries, err := g.Entries(ctx)
+		if err != nil {
+			return err
+		}
+		if description, err = releaseNotes(ctx, g, entries); err != nil {
+			return err
+		}
+		if !*dry_run {
+			if err := storeNickCache(g.Names, *cache_dir, *no_cache); err != nil {
+				return err
+			}
+		}
+	}
+	if *dry_run {
+		fmt.Print(description)
+		return nil
+	}
+	releaseName := *name
+	if releaseName == "" {
+		releaseName = *tag
+	}
+	address, err := release.publish(ctx, &http.Client{Timeout: *timeout}, *tag, releaseName, description, *ref)
+	if err != nil {
+		return withCode(EXIT_NETWORK, fmt.Errorf("Could not publish the release %s: %w", *tag, err))
+	}
+	if address == "" {
+		fmt.Println("Published the release notes for " + *tag)
+	} else {
+		fmt.Println("Published the release notes for " + *tag + " on " + address)
+	}
+	return nil
+}
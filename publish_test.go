@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestGitlabRelease(t *testing.T) {
+	var requests []string
+	var updated gitlabReleaseBody
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		if r.Header.Get("Private-Token") != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"401 Unauthorized"}`))
+			return
+		}
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"Release already exists"}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&updated)
+		w.Write([]byte(`{"_links":{"self":"https://gitlab.example.org/archlinux/archlog/-/releases/v1.0"}}`))
+	}))
+	defer server.Close()
+
+	release := &gitlabRelease{baseURL: server.URL, project: "archlinux/archlog", header: http.Header{"Private-Token": {"s3cret"}}}
+	address, err := release.publish(context.Background(), server.Client(), "v1.0", "Version 1.0", "* Fix the build\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An existing release is updated instead
+	expected := []string{"POST /api/v4/projects/archlinux%2Farchlog/releases", "PUT /api/v4/projects/archlinux%2Farchlog/releases/v1.0"}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected %v, got %v", expected, requests)
+	}
+	if updated.Name != "Version 1.0" || updated.Description != "* Fix the build\n" || address != "https://gitlab.example.org/archlinux/archlog/-/releases/v1.0" {
+		t.Fatalf("unexpected update %+v of %s", updated, address)
+	}
+	release.header = http.Header{"Private-Token": {"wrong"}}
+	if _, err := release.publish(context.Background(), server.Client(), "v1.0", "v1.0", "", ""); err == nil || err.Error() != "401 Unauthorized: 401 Unauthorized" {
+		t.Fatalf("expected the error from GitLab, got %v", err)
+	}
+}
+
+func TestGitlabAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	netrc := filepath.Join(dir, "netrc")
+	if err := ioutil.WriteFile(netrc, []byte("machine gitlab.example.org login bob password glpat-123\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CI_JOB_TOKEN", "")
+	if header, err := gitlabAuth("", netrc, "gitlab.example.org"); err != nil || header.Get("Private-Token") != "glpat-123" {
+		t.Fatalf("expected the password from the .netrc file, got %v, %v", header, err)
+	}
+	if _, err := gitlabAuth("", netrc, "gitlab.com"); exitCode(err) != EXIT_USAGE {
+		t.Fatalf("expected a usage error without a token, got %v", err)
+	}
+	t.Setenv("CI_JOB_TOKEN", "job")
+	if header, err := gitlabAuth("", netrc, "gitlab.example.org"); err != nil || header.Get("Job-Token") != "job" {
+		t.Fatalf("expected the token of the CI job, got %v, %v", header, err)
+	}
+}
+
+func TestReleaseNotes(t *testing.T) {
+	g := changelog.New(&changelog.Options{Since: "2024-03-01"})
+	g.Names.Resolver = changelog.AuthorsFile{"alice": {Name: "Alice", Email: "alice@example.org"}}
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []changelog.Entry{
+		{Revision: 2, Author: "alice", Date: day, Message: "Fix the build"},
+		{Revision: 1, Author: "alice", Date: day.AddDate(0, 0, -1), Message: "Initial import"},
+	}
+	notes, err := releaseNotes(context.Background(), g, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "## 2024-03-01 Alice \\<alice@example.org\\>\n\n* Fix the build\n"
+	if notes != expected {
+		t.Fatalf("expected %q, got %q", expected, notes)
+	}
+}
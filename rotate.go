@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// An age for -keep, like 1y, 6m, 2w or 30d
+var ageRegexp = regexp.MustCompile(`^([0-9]+)([dwmy])$`)
+
+// The date that the entries in the ChangeLog must be at or after to be
+// kept, from an age like 1y before now, or a date like 2024-01-01
+func keepDate(keep string, now time.Time) (string, error) {
+	if _, err := time.Parse("2006-01-02", keep); err == nil {
+		return keep, nil
+	}
+	m := ageRegexp.FindStringSubmatch(keep)
+	if m == nil {
+		return "", fmt.Errorf("Invalid -keep, expected an age like 1y, 6m, 2w or 30d, or a date like 2024-01-01: %s", keep)
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return "", fmt.Errorf("Invalid -keep: %s", keep)
+	}
+	switch m[2] {
+	case "d":
+		now = now.AddDate(0, 0, -n)
+	case "w":
+		now = now.AddDate(0, 0, -7*n)
+	case "m":
+		now = now.AddDate(0, -n, 0)
+	case "y":
+		now = now.AddDate(-n, 0, 0)
+	}
+	return now.UTC().Format("2006-01-02"), nil
+}
+
+// The archives of the ChangeLog that are next to it, like ChangeLog.2023,
+// by year
+func existingArchives(filename string) (map[string]string, error) {
+	matches, err := filepath.Glob(filename + ".[0-9][0-9][0-9][0-9]")
+	if err != nil {
+		return nil, err
+	}
+	archives := make(map[string]string, len(matches))
+	for _, match := range matches {
+		data, err := ioutil.ReadFile(match)
+		if err != nil {
+			return nil, err
+		}
+		archives[match[len(match)-4:]] = string(data)
+	}
+	return archives, nil
+}
+
+// archlog rotate
+func runRotate(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var keep *string = fs.String("keep", "1y", "keep the entries newer than this `age`, like 1y, 6m, 2w or 30d, or at or after a date, like 2024-01-01")
+	var dry_run *bool = fs.Bool("dry-run", false, "only report what would be moved")
+	var lang *string = addLanguageFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	language, err := checkLanguage(*lang)
+	if err != nil {
+		return err
+	}
+	catalog, _ := changelog.LookupLanguage(language)
+	if fs.NArg() > 1 {
+		return withCode(EXIT_USAGE, errors.New("Please provide only one ChangeLog to rotate.\nUse --help for more info."))
+	}
+	filename := "ChangeLog"
+	if fs.NArg() == 1 {
+		filename = fs.Arg(0)
+	}
+	now, err := sourceDate()
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	before, err := keepDate(*keep, now)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	live, moved := changelog.Rotate(string(data), before)
+	archives, err := existingArchives(filename)
+	if err != nil {
+		return err
+	}
+	// The moved entries are newer than the ones that were archived before
+	for year, contents := range moved {
+		archives[year] = contents + archives[year]
+	}
+	names := make([]string, 0, len(archives))
+	for _, year := range changelog.ArchiveYears(archives) {
+		names = append(names, filepath.Base(filename)+"."+year)
+	}
+	if len(names) > 0 {
+		live += catalog.Archives + strings.Join(names, ", ") + "\n\n"
+	}
+	years := changelog.ArchiveYears(moved)
+	sort.Strings(years)
+	for _, year := range years {
+		archive := filename + "." + year
+		if *dry_run {
+			fmt.Printf("Would move %d entries to %s\n", changelog.CountEntries(moved[year]), archive)
+			continue
+		}
+		err := changelog.WriteFileAtomic(archive, func(w io.Writer) error {
+			_, err := io.WriteString(w, archives[year])
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(moved) == 0 {
+		slog.Info("There are no entries to move", "before", before, "file", filename)
+		return nil
+	}
+	if *dry_run {
+		fmt.Printf("Would keep %d entries in %s\n", changelog.CountEntries(live), filename)
+		return nil
+	}
+	// The archives are written first, so that no entries are lost if this fails
+	return changelog.WriteFileAtomic(filename, func(w io.Writer) error {
+		_, err := io.WriteString(w, live)
+		return err
+	})
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeepDate(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	for keep, expected := range map[string]string{
+		"1y":         "2023-03-31",
+		"6m":         "2023-10-01",
+		"2w":         "2024-03-17",
+		"30d":        "2024-03-01",
+		"2024-01-01": "2024-01-01",
+	} {
+		if date, err := keepDate(keep, now); err != nil || date != expected {
+			t.Fatalf("expected %s for %s, got %s (%v)", expected, keep, date, err)
+		}
+	}
+	for _, keep := range []string{"", "1", "1h", "-1y", "2024-13-01"} {
+		if _, err := keepDate(keep, now); err == nil {
+			t.Fatalf("expected an error for %q", keep)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The largest JSON-RPC request that is read
+const MAX_RPC_SIZE = 1 << 20
+
+// The content type for streamed results, with one JSON-RPC response per line
+const NDJSON = "application/x-ndjson"
+
+// The JSON-RPC 2.0 error codes
+const (
+	RPC_PARSE_ERROR      = -32700
+	RPC_INVALID_REQUEST  = -32600
+	RPC_METHOD_NOT_FOUND = -32601
+	RPC_INVALID_PARAMS   = -32602
+	RPC_SERVER_ERROR     = -32000
+)
+
+// A JSON-RPC 2.0 request
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// A JSON-RPC 2.0 response. Streamed results have one response per item.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// The parameters of GenerateChangelog
+type generateParams struct {
+	Format string `json:"format"` // The output format, or "" for plain
+	N      int    `json:"n"`      // The number of entries, or 0 for all
+}
+
+// The result of GenerateChangelog
+type generateResult struct {
+	ChangeLog string    `json:"changelog"`
+	Generated time.Time `json:"generated"`
+}
+
+// The parameters of ResolveNick, either one nick or several
+type resolveParams struct {
+	Nick  string   `json:"nick"`
+	Nicks []string `json:"nicks"`
+}
+
+// The result of ResolveNick, for each nick
+type resolveResult struct {
+	Nick  string `json:"nick"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	Found bool   `json:"found"`
+}
+
+// Writes the results of a request, either as one response, or as one
+// response per item if the results are streamed
+type rpcWriter struct {
+	w       http.ResponseWriter
+	id      json.RawMessage
+	stream  bool
+	results []any
+}
+
+// Add a result, which is sent right away if the results are streamed
+func (rw *rpcWriter) send(result any) error {
+	if !rw.stream {
+		rw.results = append(rw.results, result)
+		return nil
+	}
+	if err := json.NewEncoder(rw.w).Encode(rpcResponse{JSONRPC: "2.0", ID: rw.id, Result: result}); err != nil {
+		return err
+	}
+	if flusher, ok := rw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// Send an error, also after some streamed results
+func (rw *rpcWriter) fail(err error) {
+	var rpcErr *rpcError
+	if !errors.As(err, &rpcErr) {
+		rpcErr = &rpcError{Code: RPC_SERVER_ERROR, Message: changelog.Sanitize(err.Error(), true)}
+	}
+	json.NewEncoder(rw.w).Encode(rpcResponse{JSONRPC: "2.0", ID: rw.id, Error: rpcErr})
+}
+
+// Decode the parameters of a request
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: RPC_INVALID_PARAMS, Message: "Invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+// GenerateChangelog: generate the ChangeLog, or use the one that was
+// generated less than maxAge ago. Streamed, the entries are sent one by
+// one, with the names and e-mail addresses.
+func (s *changeLogServer) rpcGenerate(ctx context.Context, rw *rpcWriter, params json.RawMessage) error {
+	var p generateParams
+	if err := decodeParams(params, &p); err != nil {
+		return err
+	}
+	if p.N < 0 || p.N > SERVE_MAX_ENTRIES {
+		return &rpcError{Code: RPC_INVALID_PARAMS, Message: fmt.Sprintf("Invalid number of entries: %d", p.N)}
+	}
+	n := p.N
+	if n == 0 {
+		n = -1
+	}
+	if rw.stream {
+		return s.streamEntries(ctx, n, func(entry changelog.Entry) error {
+			return rw.send(entry)
+		})
+	}
+	format := p.Format
+	switch format {
+	case "":
+		format = "plain"
+	case "md":
+		format = "markdown"
+	}
+	if _, err := changelog.NewFormatter(format, &changelog.Options{}); err != nil {
+		return &rpcError{Code: RPC_INVALID_PARAMS, Message: err.Error()}
+	}
+	served, err := s.changeLog(ctx, format, n)
+	if err != nil {
+		return err
+	}
+	return rw.send(generateResult{ChangeLog: string(served.body), Generated: served.generated.UTC()})
+}
+
+// Fetch the entries and pass them on with the resolved names
+func (s *changeLogServer) streamEntries(ctx context.Context, n int, send func(changelog.Entry) error) error {
+	s.mu.Lock()
+	start := time.Now()
+	opts := s.options
+	opts.Entries = n
+	g := &changelog.Generator{Options: &opts, Names: s.names}
+	entries, err := g.Entries(ctx)
+	s.metrics.generated(len(entries), time.Since(start), err)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	var nicks []string
+	for _, entry := range entries {
+		if entry.Name == "" && entry.Author != "" {
+			nicks = append(nicks, entry.Author)
+		}
+	}
+	if err := s.names.ResolveAll(ctx, nicks, nil); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name == "" && entry.Author != "" {
+			entry.Name = s.names.Resolve(ctx, entry.Author)
+		}
+		entry.Name, entry.Author = changelog.Sanitize(entry.Name, false), changelog.Sanitize(entry.Author, false)
+		entry.Message = changelog.Sanitize(entry.Message, true)
+		if err := send(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveNick: find the names and e-mail addresses for nicks. The nicks
+// are looked up concurrently, and streamed in the order they were given.
+func (s *changeLogServer) rpcResolve(ctx context.Context, rw *rpcWriter, params json.RawMessage) error {
+	var p resolveParams
+	if err := decodeParams(params, &p); err != nil {
+		return err
+	}
+	nicks := p.Nicks
+	if p.Nick != "" {
+		nicks = append([]string{p.Nick}, nicks...)
+	}
+	if len(nicks) == 0 {
+		return &rpcError{Code: RPC_INVALID_PARAMS, Message: "No nick or nicks given"}
+	}
+	// The lookups are started in the background, and Resolve waits for each of them
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.names.ResolveAll(ctx, nicks, nil)
+	for _, nick := range nicks {
+		value := s.names.Resolve(ctx, nick)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result := resolveResult{Nick: nick}
+		if value != nick {
+			id := changelog.ParseIdentity(value)
+			result.Name, result.Email, result.Found = id.Name, id.Email, true
+		}
+		if err := rw.send(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Serve /rpc, with JSON-RPC 2.0 requests. With "Accept: application/x-ndjson",
+// the results are streamed with one response per line.
+func (s *changeLogServer) serveRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	rw := &rpcWriter{w: w, id: json.RawMessage("null"), stream: strings.Contains(r.Header.Get("Accept"), NDJSON)}
+	if rw.stream {
+		w.Header().Set("Content-Type", NDJSON)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAX_RPC_SIZE))
+	if err != nil {
+		rw.fail(&rpcError{Code: RPC_INVALID_REQUEST, Message: "The request is too large"})
+		return
+	}
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		rw.fail(&rpcError{Code: RPC_PARSE_ERROR, Message: "Parse error: " + err.Error()})
+		return
+	}
+	if len(req.ID) > 0 {
+		rw.id = req.ID
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		rw.fail(&rpcError{Code: RPC_INVALID_REQUEST, Message: "Not a JSON-RPC 2.0 request"})
+		return
+	}
+	methods := map[string]func(context.Context, *rpcWriter, json.RawMessage) error{
+		"GenerateChangelog": s.rpcGenerate,
+		"ResolveNick":       s.rpcResolve,
+	}
+	method, ok := methods[req.Method]
+	if !ok {
+		rw.fail(&rpcError{Code: RPC_METHOD_NOT_FOUND, Message: "Unknown method: " + req.Method})
+		return
+	}
+	if err := method(r.Context(), rw, req.Params); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			slog.Error("The "+req.Method+" call failed", "err", err)
+			rw.fail(err)
+		}
+		return
+	}
+	slog.Info("Served "+req.Method, "remote", r.RemoteAddr)
+	if !rw.stream {
+		// One result, or a list of them for several nicks
+		var result any = rw.results
+		if len(rw.results) == 1 && req.Method == "GenerateChangelog" {
+			result = rw.results[0]
+		}
+		json.NewEncoder(w).Encode(rpcResponse{JSONRPC: "2.0", ID: rw.id, Result: result})
+	}
+}
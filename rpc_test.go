@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestServeRPC(t *testing.T) {
+	source := &countingSource{}
+	changelog.RegisterSource("rpc-test", source)
+	names := changelog.NewNames()
+	names.Resolver = changelog.AuthorsFile{"alice": {Name: "Alice", Email: "alice@example.org"}}
+	s := &changeLogServer{
+		options: changelog.Options{VCS: "rpc-test"},
+		names:   names,
+		maxAge:  time.Minute,
+		cache:   make(map[string]*servedChangeLog),
+	}
+	call := func(body, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		s.serveRPC(rec, req)
+		return rec
+	}
+
+	var generated struct {
+		ID     int            `json:"id"`
+		Result generateResult `json:"result"`
+	}
+	rec := call(`{"jsonrpc":"2.0","id":1,"method":"GenerateChangelog","params":{"format":"md","n":10}}`, "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &generated); err != nil {
+		t.Fatal(err)
+	}
+	if generated.ID != 1 || !strings.Contains(generated.Result.ChangeLog, "# ChangeLog") {
+		t.Fatalf("expected a Markdown ChangeLog, got %s", rec.Body)
+	}
+
+	// The nicks are streamed in order, one response per line
+	rec = call(`{"jsonrpc":"2.0","id":"r","method":"ResolveNick","params":{"nicks":["alice","nobody"]}}`, NDJSON)
+	var results []resolveResult
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var line struct {
+			ID     string        `json:"id"`
+			Result resolveResult `json:"result"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil || line.ID != "r" {
+			t.Fatalf("unexpected line %q: %v", scanner.Text(), err)
+		}
+		results = append(results, line.Result)
+	}
+	expected := []resolveResult{{Nick: "alice", Name: "Alice", Email: "alice@example.org", Found: true}, {Nick: "nobody"}}
+	if len(results) != 2 || results[0] != expected[0] || results[1] != expected[1] {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+
+	for body, code := range map[string]int{
+		`{"jsonrpc":"2.0","id":2,"method":"DropTables"}`:                           RPC_METHOD_NOT_FOUND,
+		`{"jsonrpc":"2.0","id":3,"method":"ResolveNick","params":{}}`:              RPC_INVALID_PARAMS,
+		`{"jsonrpc":"2.0","id":4,"method":"GenerateChangelog","params":{"n":"x"}}`: RPC_INVALID_PARAMS,
+		`not json`: RPC_PARSE_ERROR,
+	} {
+		var response rpcResponse
+		if err := json.Unmarshal(call(body, "").Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Error == nil || response.Error.Code != code {
+			t.Fatalf("expected the error code %d for %s, got %+v", code, body, response.Error)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The ChangeLogs that the self-test repositories should result in, in each format
+//
+//go:embed selftest
+var selftestFiles embed.FS
+
+// A commit in the self-test repositories
+type selftestCommit struct {
+	nick    string
+	date    string // RFC 3339, in UTC
+	message string
+}
+
+// The commits of the self-test repositories, from the oldest to the newest.
+// The messages are normalized with -capitalize and -strip-period.
+var selftestCommits = []selftestCommit{
+	{"arodseth", "2014-01-06T10:00:00Z", "Initial import"},
+	{"felixonmars", "2014-03-17T09:00:00Z", "fix the build."},
+	{"arodseth", "2014-03-17T12:00:00Z", "Update to 4.3.2-1"},
+	{"arodseth", "2014-04-01T08:30:00Z", "Update the description\n\nThe longer description is from the README"},
+}
+
+// The people behind the nicks in the self-test repositories, which are
+// looked up offline
+var selftestAuthors = changelog.AuthorsFile{
+	"arodseth":    {Name: "Alexander Rødseth", Email: "rodseth@gmail.com"},
+	"felixonmars": {Name: "Felix Yan", Email: "felixonmars@archlinux.org"},
+}
+
+// The formats that the ChangeLogs are checked in, with their golden files
+var selftestFormats = map[string]string{
+	"plain":    "selftest/ChangeLog",
+	"markdown": "selftest/ChangeLog.md",
+}
+
+// Run a command for setting up a self-test repository
+func selftestRun(ctx context.Context, dir string, env []string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s failed: %w\n%s", name, strings.Join(args, " "), err, output)
+	}
+	return nil
+}
+
+// Create a git repository with the self-test commits in dir, and return the working copy
+func createGitSelftest(ctx context.Context, gitBin, dir string) (string, error) {
+	wc := filepath.Join(dir, "git")
+	if err := os.MkdirAll(wc, 0755); err != nil {
+		return "", err
+	}
+	if err := selftestRun(ctx, wc, nil, gitBin, "init", "--quiet"); err != nil {
+		return "", err
+	}
+	for i, commit := range selftestCommits {
+		if err := ioutil.WriteFile(filepath.Join(wc, "file"), []byte(strconv.Itoa(i)), 0644); err != nil {
+			return "", err
+		}
+		id := selftestAuthors[commit.nick]
+		env := []string{
+			"GIT_AUTHOR_NAME=" + id.Name, "GIT_AUTHOR_EMAIL=" + id.Email, "GIT_AUTHOR_DATE=" + commit.date,
+			"GIT_COMMITTER_NAME=" + id.Name, "GIT_COMMITTER_EMAIL=" + id.Email, "GIT_COMMITTER_DATE=" + commit.date,
+		}
+		if err := selftestRun(ctx, wc, env, gitBin, "add", "file"); err != nil {
+			return "", err
+		}
+		if err := selftestRun(ctx, wc, env, gitBin, "commit", "--quiet", "--no-verify", "--no-gpg-sign", "-m", commit.message); err != nil {
+			return "", err
+		}
+	}
+	return wc, nil
+}
+
+// Create an svn repository with the self-test commits in dir, with svnadmin,
+// and return an up to date working copy
+func createSvnSelftest(ctx context.Context, svnBin, svnadminBin, dir string) (string, error) {
+	repo, wc := filepath.Join(dir, "svnrepo"), filepath.Join(dir, "svn")
+	if err := selftestRun(ctx, dir, nil, svnadminBin, "create", repo); err != nil {
+		return "", err
+	}
+	// The dates of the revisions can only be changed with a hook that allows it
+	hook := filepath.Join(repo, "hooks", "pre-revprop-change")
+	if err := ioutil.WriteFile(hook, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		return "", err
+	}
+	url := "file://" + filepath.ToSlash(repo)
+	if !strings.HasPrefix(filepath.ToSlash(repo), "/") {
+		// Like file:///C:/Users/...
+		url = "file:///" + filepath.ToSlash(repo)
+	}
+	if err := selftestRun(ctx, dir, nil, svnBin, "checkout", "--quiet", url, wc); err != nil {
+		return "", err
+	}
+	for i, commit := range selftestCommits {
+		if err := ioutil.WriteFile(filepath.Join(wc, "file"), []byte(strconv.Itoa(i)), 0644); err != nil {
+			return "", err
+		}
+		if i == 0 {
+			if err := selftestRun(ctx, wc, nil, svnBin, "add", "--quiet", "file"); err != nil {
+				return "", err
+			}
+		}
+		if err := selftestRun(ctx, wc, nil, svnBin, "commit", "--quiet", "--username", commit.nick, "-m", commit.message); err != nil {
+			return "", err
+		}
+		date := strings.TrimSuffix(commit.date, "Z") + ".000000Z"
+		if err := selftestRun(ctx, wc, nil, svnBin, "propset", "--quiet", "--revprop", "-r", strconv.Itoa(i+1), "svn:date", date, url); err != nil {
+			return "", err
+		}
+	}
+	// The log of the working copy only goes up to the revision it is at
+	if err := selftestRun(ctx, wc, nil, svnBin, "update", "--quiet"); err != nil {
+		return "", err
+	}
+	return wc, nil
+}
+
+// Generate the ChangeLog of a self-test working copy in each format, the
+// whole way from fetching the log to formatting it, and compare it with
+// the golden files. Returns the differences, or "" if there are none.
+func checkSelftest(ctx context.Context, opts changelog.Options) (string, error) {
+	var diffs []string
+	for _, format := range []string{"plain", "markdown"} {
+		opts := opts
+		opts.Format = format
+		opts.Entries = -1
+		opts.Normalization = &changelog.Normalization{Capitalize: true, StripPeriod: true}
+		g := changelog.New(&opts)
+		g.Names.Resolver = selftestAuthors
+		entries, err := g.Entries(ctx)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := g.Write(ctx, &buf, entries); err != nil {
+			return "", err
+		}
+		golden, err := selftestFiles.ReadFile(selftestFormats[format])
+		if err != nil {
+			return "", err
+		}
+		if buf.String() != string(golden) {
+			diffs = append(diffs, changelog.UnifiedDiff(selftestFormats[format], opts.VCS+" "+format, string(golden), buf.String()))
+		}
+	}
+	return strings.Join(diffs, ""), nil
+}
+
+// archlog selftest
+func runSelftest(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var svn_bin *string = fs.String("svn-bin", "svn", "the svn `executable`, either a path or a name to look for in the PATH")
+	var svnadmin_bin *string = fs.String("svnadmin-bin", "svnadmin", "the svnadmin `executable`, for creating the svn repository")
+	var git_bin *string = addGitBinFlag(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	var keep *bool = fs.Bool("keep", false, "keep the repositories, and show where they are")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "archlog-selftest")
+	if err != nil {
+		return err
+	}
+	if *keep {
+		fmt.Printf("The repositories are in %s\n", dir)
+	} else {
+		defer os.RemoveAll(dir)
+	}
+	var (
+		diagnoses []diagnosis
+		diffs     []string
+		tested    int
+	)
+	for _, vcs := range []string{"svn", "git"} {
+		bins := []string{*git_bin}
+		if vcs == "svn" {
+			bins = []string{*svn_bin, *svnadmin_bin}
+		}
+		var missing []string
+		for _, bin := range bins {
+			if _, err := exec.LookPath(bin); err != nil {
+				missing = append(missing, bin)
+			}
+		}
+		if len(missing) > 0 {
+			diagnoses = append(diagnoses, diagnosis{check: vcs, ok: true, details: "skipped, since " + strings.Join(missing, " and ") + " could not be found in the PATH"})
+			continue
+		}
+		var wc string
+		if vcs == "svn" {
+			wc, err = createSvnSelftest(ctx, *svn_bin, *svnadmin_bin, dir)
+		} else {
+			wc, err = createGitSelftest(ctx, *git_bin, dir)
+		}
+		if err != nil {
+			slog.Debug("Could not create the "+vcs+" repository", "err", err)
+			diagnoses = append(diagnoses, diagnosis{check: vcs, details: "could not create the repository: " + err.Error(), fix: "Check that " + vcs + " works, with archlog doctor"})
+			continue
+		}
+		tested++
+		diff, err := checkSelftest(ctx, changelog.Options{Repo: wc, VCS: vcs, SvnBin: *svn_bin, GitBin: *git_bin, Timeout: *timeout})
+		switch {
+		case err != nil:
+			diagnoses = append(diagnoses, diagnosis{check: vcs, details: "could not generate the ChangeLog: " + err.Error()})
+		case diff != "":
+			diagnoses = append(diagnoses, diagnosis{check: vcs, details: "the ChangeLog is not as expected, see the diff below", fix: "Report it, with the diff and the output of " + vcs + " --version"})
+			diffs = append(diffs, diff)
+		default:
+			diagnoses = append(diagnoses, diagnosis{check: vcs, ok: true, details: fmt.Sprintf("the ChangeLog of %d commits is as expected, in the plain and markdown formats", len(selftestCommits))})
+		}
+	}
+	if err := writeDiagnoses(os.Stdout, diagnoses); err != nil {
+		return err
+	}
+	for _, diff := range diffs {
+		fmt.Print(diff)
+	}
+	for _, d := range diagnoses {
+		if !d.ok {
+			return errors.New("The self-test failed")
+		}
+	}
+	if tested == 0 {
+		return withCode(EXIT_VCS, errors.New("Could not find svn or git, so nothing could be tested"))
+	}
+	return nil
+}
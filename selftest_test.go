@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestGitSelftest(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	wc, err := createGitSelftest(ctx, "git", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := checkSelftest(ctx, changelog.Options{Repo: wc, VCS: "git", GitBin: "git", Timeout: DEFAULT_TIMEOUT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Fatalf("the ChangeLog differs from the golden files:\n%s", diff)
+	}
+}
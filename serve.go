@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The default -max-age for the ChangeLogs that are served
+const DEFAULT_MAX_AGE = time.Minute
+
+// The largest number of entries that can be requested, with ?n=
+const SERVE_MAX_ENTRIES = 1000
+
+// How many of the generated ChangeLogs are kept, for the different formats
+// and numbers of entries
+const SERVE_CACHE_SIZE = 16
+
+// The content types of the output formats, for serving them. Other
+// registered formats are served as plain text.
+var contentTypes = map[string]string{
+	"plain":    "text/plain; charset=utf-8",
+	"markdown": "text/markdown; charset=utf-8",
+	"html":     "text/html; charset=utf-8",
+	"json":     "application/json",
+}
+
+// A ChangeLog that has been generated by the server
+type servedChangeLog struct {
+	body      []byte
+	generated time.Time
+}
+
+// Serves ChangeLogs for a working copy, which are generated when they are
+// requested, and then kept for up to maxAge
+type changeLogServer struct {
+	options  changelog.Options // Copied for each ChangeLog, with the requested format and number of entries
+	names    *changelog.Names  // Shared by all the requests, so that each nick is only looked up once
+	maxAge   time.Duration
+	cacheDir string // Where the nick cache is stored after each ChangeLog, or "" for nowhere
+
+	// Webhooks are accepted on /hook if hookSecret is set. They make the
+	// ChangeLog be written to output, if set, and committed if commit is set.
+	hookSecret string
+	hooks      chan struct{}
+	output     string
+	commit     bool
+
+	metrics *serverMetrics // Exported on /metrics, can be nil
+
+	// Held while a ChangeLog is generated, so that only one svn runs at a time
+	mu    sync.Mutex
+	cache map[string]*servedChangeLog
+}
+
+// Parse the format and number of entries of a request, like ?format=md&n=50
+func parseChangeLogQuery(r *http.Request) (string, int, error) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	switch format {
+	case "":
+		format = "plain"
+	case "md":
+		format = "markdown"
+	case "txt", "text":
+		format = "plain"
+	}
+	if _, err := changelog.NewFormatter(format, &changelog.Options{}); err != nil {
+		return "", 0, err
+	}
+	n := -1
+	if value := query.Get("n"); value != "" {
+		var err error
+		if n, err = strconv.Atoi(value); err != nil || n <= 0 || n > SERVE_MAX_ENTRIES {
+			return "", 0, fmt.Errorf("Invalid number of entries: %q", value)
+		}
+	}
+	return format, n, nil
+}
+
+// Generate a ChangeLog, or use the one that was generated less than
+// maxAge ago
+func (s *changeLogServer) changeLog(ctx context.Context, format string, n int) (*servedChangeLog, error) {
+	key := format + " " + strconv.Itoa(n)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if served, ok := s.cache[key]; ok && time.Since(served.generated) < s.maxAge {
+		s.metrics.cache(true)
+		return served, nil
+	}
+	s.metrics.cache(false)
+	s.evict()
+	start := time.Now()
+	opts := s.options
+	opts.Format, opts.Entries = format, n
+	g := &changelog.Generator{Options: &opts, Names: s.names}
+	entries, err := g.Entries(ctx)
+	var buf bytes.Buffer
+	if err == nil {
+		err = g.Write(ctx, &buf, entries)
+	}
+	s.metrics.generated(len(entries), time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+	if err := storeNickCache(s.names, s.cacheDir, s.cacheDir == ""); err != nil {
+		slog.Warn(err.Error())
+	}
+	served := &servedChangeLog{body: buf.Bytes(), generated: time.Now()}
+	s.cache[key] = served
+	return served, nil
+}
+
+// Forget the ChangeLogs that are older than maxAge, and the oldest one if
+// there is no room for another one. s.mu must be held.
+func (s *changeLogServer) evict() {
+	oldest := ""
+	for key, served := range s.cache {
+		if time.Since(served.generated) >= s.maxAge {
+			delete(s.cache, key)
+		} else if oldest == "" || served.generated.Before(s.cache[oldest].generated) {
+			oldest = key
+		}
+	}
+	if len(s.cache) >= SERVE_CACHE_SIZE {
+		delete(s.cache, oldest)
+	}
+}
+
+// Serve /changelog
+func (s *changeLogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Only GET and HEAD are supported", http.StatusMethodNotAllowed)
+		return
+	}
+	format, n, err := parseChangeLogQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	served, err := s.changeLog(r.Context(), format, n)
+	if errors.Is(err, context.Canceled) {
+		// The client went away
+		return
+	} else if err != nil {
+		slog.Error("Could not generate the ChangeLog", "url", r.URL.String(), "err", err)
+		http.Error(w, changelog.Sanitize(err.Error(), true), http.StatusInternalServerError)
+		return
+	}
+	slog.Info("Served "+r.URL.String(), "remote", r.RemoteAddr, "bytes", len(served.body))
+	contentType, ok := contentTypes[format]
+	if !ok {
+		contentType = contentTypes["plain"]
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(s.maxAge.Seconds())))
+	http.ServeContent(w, r, "", served.generated, bytes.NewReader(served.body))
+}
+
+// Serve the ChangeLogs on the listener, until ctx is canceled
+func serveChangeLogs(ctx context.Context, listener net.Listener, s *changeLogServer) error {
+	mux := http.NewServeMux()
+	mux.Handle("/changelog", s.counted("/changelog", s))
+	mux.Handle("/rpc", s.counted("/rpc", http.HandlerFunc(s.serveRPC)))
+	if s.hookSecret != "" {
+		mux.Handle("/hook", s.counted("/hook", http.HandlerFunc(s.serveHook)))
+		go s.handleHooks(ctx)
+	}
+	if s.metrics != nil {
+		mux.HandleFunc("/metrics", s.serveMetrics)
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	err := server.Serve(listener)
+	if err == http.ErrServerClosed {
+		<-done
+		// Being stopped is the normal way for the server to end
+		return nil
+	}
+	return err
+}
+
+// archlog serve
+func runServe(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var listen *string = fs.String("listen", "localhost:8080", "the `address` to listen on, like localhost:8080, or :8080 for all of the network interfaces")
+	var max_age *time.Duration = fs.Duration("max-age", DEFAULT_MAX_AGE, "how long a generated ChangeLog is served before it is generated again, as a `duration`")
+	repo, vcs, svn_bin, git_bin := addSourceFlags(fs)
+	svn_auth := addSvnAuthFlags(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	jobs, stop_on_copy := addFetchFlags(fs)
+	cache_dir, no_cache := addCacheFlags(fs)
+	resolver_flags := addResolverFlags(fs)
+	var unknown_author *string = addUnknownAuthorFlag(fs)
+	var lang *string = addLanguageFlag(fs)
+	anonymize := addAnonymizeFlag(fs)
+	var obfuscate_email *string = fs.String("obfuscate-email", "", "obfuscate the e-mail addresses: `at` for \"alice at example dot org\", entities for HTML character references in the html and markdown formats, or drop")
+	var avatars *string = fs.String("avatars", "", "add the avatars of the authors to the html and json formats, from `gravatar` or libravatar")
+	var hook_secret *string = fs.String("hook-secret", "", "accept webhooks on /hook that are signed with or include this `secret`, and regenerate the ChangeLog for each of them")
+	var output *string = fs.String("o", "", "write the ChangeLog to this `file` for each webhook")
+	var commit *bool = fs.Bool("commit", false, "commit the ChangeLog that is written with -o, and push it with git")
+	strict, lenient, input_encoding := addParseFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	language, err := checkLanguage(*lang)
+	if err != nil {
+		return err
+	}
+	if err := changelog.CheckObfuscateEmail(*obfuscate_email); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if err := changelog.CheckAvatars(*avatars); err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if fs.NArg() > 0 {
+		return withCode(EXIT_USAGE, fmt.Errorf("Unexpected argument: %s", fs.Arg(0)))
+	}
+	if (*output != "" || *commit) && *hook_secret == "" {
+		return withCode(EXIT_USAGE, errors.New("-o and -commit are only used for webhooks, which need -hook-secret"))
+	}
+	if *commit && *output == "" {
+		return withCode(EXIT_USAGE, errors.New("-commit needs the file to commit, with -o"))
+	}
+	parsing, err := parseMode(*strict, *lenient)
+	if err != nil {
+		return err
+	}
+	encoding, err := changelog.CheckEncoding(*input_encoding)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	source, err := changelog.LookupSource(*vcs)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	if _, ok := source.(changelog.WorkingCopy); *commit && !ok {
+		return withCode(EXIT_USAGE, fmt.Errorf("-commit does not work with -vcs %s", *vcs))
+	}
+	if *output != "" {
+		// The file is committed from the working copy, not the current directory
+		if *output, err = filepath.Abs(*output); err != nil {
+			return err
+		}
+	}
+	s := &changeLogServer{
+		options: changelog.Options{
+			Repo:          *repo,
+			VCS:           *vcs,
+			SvnBin:        *svn_bin,
+			GitBin:        *git_bin,
+			Timeout:       *timeout,
+			Jobs:          *jobs,
+			StopOnCopy:    *stop_on_copy,
+			Parsing:       parsing,
+			InputEncoding: encoding,
+			UnknownAuthor: *unknown_author,
+			Language:      language,
+			Anonymize:     anonymize.style,
+			Obfuscate:     *obfuscate_email,
+			Avatars:       *avatars,
+		},
+		names:      changelog.NewNames(),
+		maxAge:     *max_age,
+		cache:      make(map[string]*servedChangeLog),
+		hookSecret: *hook_secret,
+		hooks:      make(chan struct{}, 1),
+		metrics:    newServerMetrics(),
+		output:     *output,
+		commit:     *commit,
+	}
+	svn_auth.apply(&s.options)
+	if *commit {
+		// Leave out the commits of the ChangeLog itself, which would otherwise
+		// make each of them be followed by another one
+		rule := fmt.Sprintf("drop if message ~ %q", `^\s*`+regexp.QuoteMeta(HOOK_COMMIT_MESSAGE)+`\s*$`)
+		if s.options.Transform, err = changelog.ParseTransform(strings.NewReader(rule)); err != nil {
+			return err
+		}
+	}
+	s.names.Client.Timeout = *timeout
+	if err := setupResolvers(s.names, resolver_flags); err != nil {
+		return err
+	}
+	if err := setupNickCache(s.names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	setupPageCache(s.names, *cache_dir, *no_cache)
+	if !*no_cache {
+		s.cacheDir = *cache_dir
+	}
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	fmt.Fprintf(os.Stderr, "Serving the ChangeLog on http://%s/changelog\n", listener.Addr())
+	return serveChangeLogs(ctx, listener, s)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// A Source with one entry, that counts how many times it is used
+type countingSource struct {
+	fetches int
+}
+
+func (s *countingSource) Entries(ctx context.Context, opts *changelog.Options) (iter.Seq2[changelog.Entry, error], error) {
+	s.fetches++
+	entry := changelog.Entry{Revision: 1, Author: "alice", Name: "Alice <alice@example.org>", Date: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), Message: "Initial import"}
+	return func(yield func(changelog.Entry, error) bool) {
+		yield(entry, nil)
+	}, nil
+}
+
+func TestServeChangeLog(t *testing.T) {
+	source := &countingSource{}
+	changelog.RegisterSource("serve-test", source)
+	s := &changeLogServer{
+		options: changelog.Options{VCS: "serve-test"},
+		names:   changelog.NewNames(),
+		maxAge:  time.Minute,
+		cache:   make(map[string]*servedChangeLog),
+	}
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/changelog?format=md&n=50", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		if rec.Header().Get("Content-Type") != "text/markdown; charset=utf-8" || !strings.Contains(rec.Body.String(), "Initial import") {
+			t.Fatalf("expected a Markdown ChangeLog, got %s", rec.Body)
+		}
+	}
+	if source.fetches != 1 {
+		t.Fatalf("expected the ChangeLog to be generated once, got %d", source.fetches)
+	}
+	for _, query := range []string{"?format=pdf", "?n=0", "?n=many", "?n=1001"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/changelog"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", query, rec.Code)
+		}
+	}
+	// Only the newest ChangeLogs are kept
+	for n := 1; n <= 2*SERVE_CACHE_SIZE; n++ {
+		if _, err := s.changeLog(context.Background(), "plain", n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.cache) != SERVE_CACHE_SIZE || s.cache["plain "+strconv.Itoa(2*SERVE_CACHE_SIZE)] == nil {
+		t.Fatalf("expected the %d newest ChangeLogs to be kept, got %d", SERVE_CACHE_SIZE, len(s.cache))
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// The value of -sign: off, on with the default key of gpg, or on with a
+// key id. It is a boolean flag, so that -sign can be given without a key.
+type signKey struct {
+	enabled bool
+	key     string
+}
+
+func (k *signKey) String() string {
+	if k == nil || !k.enabled {
+		return ""
+	}
+	return k.key
+}
+
+func (k *signKey) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "", "false", "0", "no":
+		k.enabled, k.key = false, ""
+	case "true", "1", "yes":
+		k.enabled, k.key = true, ""
+	default:
+		k.enabled, k.key = true, value
+	}
+	return nil
+}
+
+func (k *signKey) IsBoolFlag() bool {
+	return true
+}
+
+// The flags for signing the written ChangeLog with gpg
+type signFlags struct {
+	key    *signKey
+	mode   *string
+	gpgBin *string
+}
+
+// Add the -sign, -sign-mode and -gpg-bin flags
+func addSignFlags(fs *flag.FlagSet) *signFlags {
+	flags := &signFlags{key: &signKey{}}
+	fs.Var(flags.key, "sign", "sign the ChangeLog that is written with -o or -prepend with gpg, with the default key, or with the key of -sign=KEYID")
+	flags.mode = fs.String("sign-mode", "detached", "how to sign the ChangeLog: `detached` for a signature in a .asc file next to it, or clear for a clearsigned copy in the .asc file")
+	flags.gpgBin = fs.String("gpg-bin", "gpg", "the gpg `executable`, either a path or a name to look for in the PATH")
+	return flags
+}
+
+// Check the flags, for the file that is written, if any
+func (f *signFlags) check(dest *Destination) error {
+	if !f.key.enabled {
+		return nil
+	}
+	if *f.mode != "detached" && *f.mode != "clear" {
+		return withCode(EXIT_USAGE, fmt.Errorf("Invalid -sign-mode, expected detached or clear: %s", *f.mode))
+	}
+	if dest.Check != "" || dest.Diff || dest.writtenFile() == "" {
+		return withCode(EXIT_USAGE, errors.New("-sign only works when writing the ChangeLog to a file, with -o or -prepend"))
+	}
+	return nil
+}
+
+// The arguments for gpg, for signing filename into output
+func gpgArgs(key, mode, filename, output string) []string {
+	args := []string{"--batch", "--yes", "--armor"}
+	if mode == "clear" {
+		args = append(args, "--clearsign")
+	} else {
+		args = append(args, "--detach-sign")
+	}
+	if key != "" {
+		args = append(args, "--local-user", key)
+	}
+	return append(args, "--output", output, "--", filename)
+}
+
+// Sign the written ChangeLog into a .asc file next to it, which is replaced
+// only if gpg succeeds. Returns the name of the .asc file, or "" if the
+// ChangeLog is not signed.
+func (f *signFlags) sign(ctx context.Context, dest *Destination) (string, error) {
+	if !f.key.enabled {
+		return "", nil
+	}
+	filename := dest.writtenFile()
+	signature := filename + ".asc"
+	if dest.DryRun {
+		_, err := fmt.Fprintf(dest.report(), "Would sign %s into %s\n", filename, signature)
+		return signature, err
+	}
+	temp := signature + ".tmp"
+	defer os.Remove(temp)
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, *f.gpgBin, gpgArgs(f.key.key, *f.mode, filename, temp)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("Could not sign %s with gpg: %s", filename, strings.Replace(message, "\n", "; ", -1))
+		}
+		return "", fmt.Errorf("Could not sign %s with gpg: %w", filename, err)
+	}
+	return signature, os.Rename(temp, signature)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSignFlags(t *testing.T) {
+	for _, test := range []struct {
+		args    []string
+		enabled bool
+		key     string
+	}{
+		{nil, false, ""},
+		{[]string{"-sign"}, true, ""},
+		{[]string{"-sign=0xDEADBEEF"}, true, "0xDEADBEEF"},
+		{[]string{"-sign=false"}, false, ""},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags := addSignFlags(fs)
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		if flags.key.enabled != test.enabled || flags.key.key != test.key {
+			t.Fatalf("expected %v and %q for %v, got %+v", test.enabled, test.key, test.args, flags.key)
+		}
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := addSignFlags(fs)
+	fs.Parse([]string{"-sign"})
+	if err := flags.check(&Destination{}); exitCode(err) != EXIT_USAGE {
+		t.Fatalf("expected a usage error for signing stdout, got %v", err)
+	}
+	if err := flags.check(&Destination{Prepend: "ChangeLog"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "--batch --yes --armor --clearsign --local-user bob --output ChangeLog.asc -- ChangeLog"
+	if args := strings.Join(gpgArgs("bob", "clear", "ChangeLog", "ChangeLog.asc"), " "); args != expected {
+		t.Fatalf("expected %q, got %q", expected, args)
+	}
+}
+
+func TestSign(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the fake gpg with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// A gpg that writes its arguments as the signature, or fails for the key "missing"
+	gpg := filepath.Join(dir, "gpg")
+	script := "#!/bin/sh\ncase \"$*\" in *missing*) echo 'gpg: signing failed: No secret key' >&2; exit 2;; esac\nwhile [ \"$1\" != --output ]; do shift; done\necho signed > \"$2\"\n"
+	if err := ioutil.WriteFile(gpg, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	changeLog := filepath.Join(dir, "ChangeLog")
+	flags := &signFlags{key: &signKey{enabled: true}, mode: new(string), gpgBin: &gpg}
+	*flags.mode = "detached"
+	if signature, err := flags.sign(context.Background(), &Destination{Filename: changeLog}); err != nil || signature != changeLog+".asc" {
+		t.Fatalf("expected %s.asc, got %q, %v", changeLog, signature, err)
+	}
+	if data, err := ioutil.ReadFile(changeLog + ".asc"); err != nil || string(data) != "signed\n" {
+		t.Fatalf("expected the signature, got %q, %v", data, err)
+	}
+	flags.key.key = "missing"
+	_, err = flags.sign(context.Background(), &Destination{Filename: changeLog})
+	if err == nil || !strings.Contains(err.Error(), "No secret key") {
+		t.Fatalf("expected the error from gpg, got %v", err)
+	}
+	// The previous signature is kept
+	if _, err := os.Stat(changeLog + ".asc"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The ways the ChangeLog can be split with -split-by
+const (
+	SPLIT_PACKAGE   = "package"   // One ChangeLog per directory with a PKGBUILD
+	SPLIT_DIRECTORY = "directory" // One ChangeLog per top level directory
+	SPLIT_YEAR      = "year"      // One ChangeLog per year, like ChangeLog.2023
+)
+
+// The extension of the ChangeLog files in the -out-dir directory, as used
+// for the changelog= field in PKGBUILDs, like archlog.changelog
+const SPLIT_EXTENSION = ".changelog"
+
+// Find the packages in the working copy, as a map from the directory of
+// each package, relative to the working copy with forward slashes, to its
+// name. For the package layout of svn, where the PKGBUILD is in
+// archlog/trunk, the package is all of archlog, with the repos directory.
+func findPackageDirs(repo, splitBy string) (map[string]string, error) {
+	if repo == "" {
+		repo = "."
+	}
+	dirs := make(map[string]string)
+	if splitBy == SPLIT_DIRECTORY {
+		infos, err := os.ReadDir(repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+				dirs[info.Name()] = info.Name()
+			}
+		}
+		return dirs, nil
+	}
+	names := make(map[string]string)
+	err := filepath.WalkDir(repo, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != repo && (strings.HasPrefix(d.Name(), ".") || d.Name() == "repos") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "PKGBUILD")); err != nil {
+			return nil
+		}
+		dir := path
+		if d.Name() == "trunk" {
+			dir = filepath.Dir(path)
+		}
+		rel, err := filepath.Rel(repo, dir)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		name := changelog.GuessPackageName(dir)
+		if other, ok := names[name]; ok {
+			slog.Warn("There are two packages with the same name, only using the first one", "name", name, "first", other, "second", rel)
+			return nil
+		}
+		names[name], dirs[rel] = rel, name
+		return nil
+	})
+	return dirs, err
+}
+
+// Write one ChangeLog per package or directory to the files in outDir, with
+// the entries that changed files in it. The log is only fetched once.
+// Returns all of the entries, for summarizing the run.
+func splitChangeLogs(ctx context.Context, dest *Destination, g *changelog.Generator, splitBy, outDir string) ([]changelog.Entry, error) {
+	dirs, err := findPackageDirs(g.Options.Repo, splitBy)
+	if err != nil {
+		return nil, fmt.Errorf("Could not find the packages to split the ChangeLog by: %w", err)
+	}
+	if len(dirs) == 0 {
+		return nil, withCode(EXIT_NO_REPO, fmt.Errorf("Could not find any %s to split the ChangeLog by in %s", splitBy, g.Options.Repo))
+	}
+	keys := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		keys = append(keys, dir)
+	}
+	sort.Strings(keys)
+	entries, err := g.Entries(ctx)
+	status.Done()
+	if err != nil {
+		return nil, err
+	}
+	split, err := g.SplitEntries(ctx, entries, keys)
+	if err != nil {
+		return entries, err
+	}
+	if !dest.DryRun {
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return entries, err
+		}
+	}
+	for _, dir := range keys {
+		if len(split[dir]) == 0 {
+			slog.Info("No entries for "+dir+", not writing a ChangeLog for it", "name", dirs[dir])
+			continue
+		}
+		d := *dest
+		d.Filename = filepath.Join(outDir, dirs[dir]+SPLIT_EXTENSION)
+		if err := writeEntries(ctx, &d, g, split[dir]); err != nil {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
+
+// Write the entries of the newest year to the file and the entries of each
+// earlier year to the file with the year added, like ChangeLog.2023, the way
+// GNU projects do it. The file ends with where the older entries are.
+// Returns all of the entries, for summarizing the run.
+func splitChangeLogByYear(ctx context.Context, dest *Destination, g *changelog.Generator) ([]changelog.Entry, error) {
+	entries, err := g.Entries(ctx)
+	status.Done()
+	if err != nil {
+		return nil, err
+	}
+	// The entries are ordered from the newest to the oldest
+	var (
+		years  []string
+		byYear = make(map[string][]changelog.Entry)
+	)
+	for _, entry := range entries {
+		year := entry.Day()[:4]
+		if _, ok := byYear[year]; !ok {
+			years = append(years, year)
+		}
+		byYear[year] = append(byYear[year], entry)
+	}
+	if len(years) == 0 {
+		return entries, writeEntries(ctx, dest, g, entries)
+	}
+	for i := len(years) - 1; i > 0; i-- {
+		d := *dest
+		d.Filename = dest.Filename + "." + years[i]
+		if err := writeEntries(ctx, &d, g, byYear[years[i]]); err != nil {
+			return entries, err
+		}
+	}
+	archives := make([]string, 0, len(years)-1)
+	for _, year := range years[1:] {
+		archives = append(archives, filepath.Base(dest.Filename)+"."+year)
+	}
+	g.Options.Archives = archives
+	defer func() { g.Options.Archives = nil }()
+	return entries, writeEntries(ctx, dest, g, byYear[years[0]])
+}
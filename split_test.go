@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestFindPackageDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"foo/PKGBUILD", "bar/trunk/PKGBUILD", "bar/repos/extra-x86_64/PKGBUILD", ".git/PKGBUILD", "docs/README"} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirs, err := findPackageDirs(dir, SPLIT_PACKAGE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 2 || dirs["foo"] != "foo" || dirs["bar"] != "bar" {
+		t.Fatalf("unexpected packages: %v", dirs)
+	}
+	if dirs, err = findPackageDirs(dir, SPLIT_DIRECTORY); err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 3 || dirs["docs"] != "docs" {
+		t.Fatalf("unexpected directories: %v", dirs)
+	}
+}
+
+func TestSplitChangeLogByYear(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git to make a repository with")
+	}
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	g := changelog.New(&changelog.Options{Repo: dir, VCS: "git", Entries: -1})
+	g.Names.Resolver = changelog.AuthorsFile{}
+	git := func(env string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Alice A", "GIT_AUTHOR_EMAIL=alice@example.org", "GIT_COMMITTER_NAME=Alice A", "GIT_COMMITTER_EMAIL=alice@example.org", env)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, output)
+		}
+	}
+	git("", "init", "--quiet")
+	for _, year := range []string{"2022", "2023", "2024"} {
+		git("GIT_AUTHOR_DATE="+year+"-05-01T10:00:00Z", "commit", "--quiet", "--allow-empty", "-m", "Change in "+year)
+	}
+	filename := filepath.Join(dir, "ChangeLog")
+	if _, err := splitChangeLogByYear(ctx, &Destination{Filename: filename}, g); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]string{
+		"ChangeLog":      "2024-05-01 Alice A <alice@example.org>\n    * Change in 2024\n\nOlder entries are in ChangeLog.2023, ChangeLog.2022\n\n",
+		"ChangeLog.2022": "2022-05-01 Alice A <alice@example.org>\n    * Change in 2022\n\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Fatalf("unexpected %s:\n%s", name, data)
+		}
+	}
+}
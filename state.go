@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"iter"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The file where the last processed revision is recorded, for -incremental
+const STATE_FILE = ".archlog.state"
+
+// The state file in the given working copy
+func stateFilename(repo string) string {
+	return filepath.Join(repo, STATE_FILE)
+}
+
+// Read the last processed revision from the state file.
+// Returns 0 if the file does not exist yet.
+func loadState(filename string) (int, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	revision, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || revision < 0 {
+		return 0, withCode(EXIT_PARSE, fmt.Errorf("Invalid revision in %s: %q", filename, strings.TrimSpace(string(data))))
+	}
+	return revision, nil
+}
+
+// Record the last processed revision in the state file
+func saveState(filename string, revision int) error {
+	return changelog.WriteFileAtomic(filename, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, revision)
+		return err
+	})
+}
+
+// Pass the entries on, while keeping track of the newest revision
+func withNewestRevision(entries iter.Seq2[changelog.Entry, error], newest *int) iter.Seq2[changelog.Entry, error] {
+	return func(yield func(changelog.Entry, error) bool) {
+		for entry, err := range entries {
+			if err == nil && entry.Revision > *newest {
+				*newest = entry.Revision
+			}
+			if !yield(entry, err) {
+				return
+			}
+		}
+	}
+}
+
+// Find the last processed revision from an existing ChangeLog, for when
+// there is no state file yet: the newest revision that is recorded in it,
+// or else the newest revision before the day of its newest entry, since
+// not all of the entries of that day may be in it. Returns 0 if the
+// ChangeLog does not exist or does not tell.
+func recordedRevision(ctx context.Context, g *changelog.Generator, filename string) (int, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	recorded := changelog.ParseRecorded(changelog.UnixLineEndings(string(data)))
+	switch {
+	case recorded.Revision > 0:
+		return recorded.Revision, nil
+	case recorded.Date != "":
+		return g.RevisionBefore(ctx, recorded.Date)
+	}
+	return 0, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The message of a release tag: the name of the tag, and the ChangeLog of
+// the release
+func tagMessage(ctx context.Context, g *changelog.Generator, name string, entries []changelog.Entry) (string, error) {
+	var buf bytes.Buffer
+	if err := g.Write(ctx, &buf, entries); err != nil {
+		return "", err
+	}
+	return name + "\n\n" + strings.TrimRight(buf.String(), "\n") + "\n", nil
+}
+
+// archlog tag
+func runTag(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var ref *string = fs.String("ref", "", "the commit, branch or tag to tag, instead of the one of an existing tag with the name, or HEAD")
+	var repo *string = fs.String("repo", "", "the `directory` of the working copy, instead of the current directory")
+	var vcs *string = fs.String("vcs", "git", "the `name` of the version control system, which must support tags: "+strings.Join(changelog.SourceNames(), ", "))
+	var git_bin *string = addGitBinFlag(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	sign := &signKey{}
+	fs.Var(sign, "sign", "sign the tag with gpg, with the default key, or with the key of -sign=KEYID")
+	var dry_run *bool = fs.Bool("dry-run", false, "only show the message of the tag, without tagging")
+	var unknown_author *string = addUnknownAuthorFlag(fs)
+	var lang *string = addLanguageFlag(fs)
+	anonymize := addAnonymizeFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	language, err := checkLanguage(*lang)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return withCode(EXIT_USAGE, errors.New("Please provide the name of the tag, like v1.2.3.\nUse --help for more info."))
+	}
+	name := fs.Arg(0)
+	for _, ref := range []string{name, *ref} {
+		if err := checkRef(ref); err != nil {
+			return withCode(EXIT_USAGE, err)
+		}
+	}
+	source, err := changelog.LookupSource(*vcs)
+	if err != nil {
+		return withCode(EXIT_USAGE, err)
+	}
+	tags, ok := source.(changelog.TagSource)
+	if !ok {
+		return withCode(EXIT_USAGE, fmt.Errorf("Tags can not be written for -vcs %s", *vcs))
+	}
+	opts := &changelog.Options{
+		Repo:          *repo,
+		VCS:           *vcs,
+		GitBin:        *git_bin,
+		Timeout:       *timeout,
+		Entries:       -1,
+		UnknownAuthor: *unknown_author,
+		Language:      language,
+		Anonymize:     anonymize.style,
+	}
+	// An existing tag is replaced with one for the same commit
+	existing, err := tags.TagCommit(ctx, opts, name)
+	if err != nil {
+		return err
+	}
+	target := *ref
+	if target == "" {
+		target = existing
+	}
+	if target == "" {
+		target = "HEAD"
+	}
+	previous, revision, err := tags.PreviousTag(ctx, opts, target)
+	if err != nil {
+		return err
+	}
+	if previous == name {
+		return fmt.Errorf("%s is already the tag of an older commit, give another -ref or another name", name)
+	}
+	opts.Ref, opts.FromRevision = target, revision+1
+	g := changelog.New(opts)
+	g.Names.Resolver = changelog.AuthorsFile{}
+	entries, err := g.Entries(ctx)
+	if err != nil {
+		return err
+	}
+	message, err := tagMessage(ctx, g, name, entries)
+	if err != nil {
+		return err
+	}
+	if *dry_run {
+		fmt.Print(message)
+		return nil
+	}
+	if err := tags.WriteTag(ctx, opts, name, target, message, sign.enabled, sign.key); err != nil {
+		return err
+	}
+	since := "from the start"
+	if previous != "" {
+		since = "since " + previous
+	}
+	action := "Created"
+	if existing != "" {
+		action = "Updated"
+	}
+	fmt.Printf("%s the tag %s with %d entries %s. Push it with: git push origin %s\n", action, name, changelog.CountEntries(message), since, name)
+	if existing != "" {
+		fmt.Println("Use git push --force to replace a tag that has already been pushed.")
+	}
+	return nil
+}
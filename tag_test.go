@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestTagMessage(t *testing.T) {
+	g := changelog.New(&changelog.Options{})
+	g.Names.Resolver = changelog.AuthorsFile{}
+	entries := []changelog.Entry{{Revision: 2, Author: "Alice A", Name: "Alice A <alice@example.org>", Date: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), Message: "Fix the build\n"}}
+	message, err := tagMessage(context.Background(), g, "v1.2.3", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "v1.2.3\n\n2024-03-01 Alice A <alice@example.org>\n    * Fix the build\n"
+	if message != expected {
+		t.Fatalf("expected %q, got %q", expected, message)
+	}
+}
+
+func TestTagRef(t *testing.T) {
+	for _, args := range [][]string{{"-ref=--output=/tmp/x", "v1.2.3"}, {"--", "--output=/tmp/x"}} {
+		if err := runTag(context.Background(), findCommand("tag"), args); exitCode(err) != EXIT_USAGE {
+			t.Fatalf("expected a usage error for %v, got %v", args, err)
+		}
+	}
+}
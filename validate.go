@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// Check that each of the files in the json format follows the JSON Schema,
+// and write either that it does or its problems, one per line. Returns the
+// number of files that do not follow it, or could not be read.
+func validateFiles(w io.Writer, filenames []string) (int, error) {
+	invalid := 0
+	for _, filename := range filenames {
+		var (
+			data []byte
+			err  error
+		)
+		if filename == "-" {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(filename)
+		}
+		if err == nil {
+			err = changelog.ValidateJSON(data)
+		}
+		var schemaErr *changelog.SchemaError
+		switch {
+		case errors.As(err, &schemaErr):
+			invalid++
+			for _, problem := range schemaErr.Problems {
+				if _, err := fmt.Fprintf(w, "%s: %s\n", filename, problem); err != nil {
+					return invalid, err
+				}
+			}
+		case err != nil:
+			invalid++
+			if _, err := fmt.Fprintf(w, "%s: %v\n", filename, err); err != nil {
+				return invalid, err
+			}
+		default:
+			if _, err := fmt.Fprintf(w, "%s: follows schema version %d\n", filename, changelog.JSON_SCHEMA_VERSION); err != nil {
+				return invalid, err
+			}
+		}
+	}
+	return invalid, nil
+}
+
+// archlog validate
+func runValidate(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	var schema *bool = fs.Bool("schema", false, "only write the JSON Schema of the json format to stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *schema {
+		_, err := os.Stdout.Write(changelog.JSONSchema())
+		return err
+	}
+	if fs.NArg() == 0 {
+		return withCode(EXIT_USAGE, errors.New("Please provide the ChangeLogs in the json format to validate, or - for stdin.\nUse --help for more info."))
+	}
+	invalid, err := validateFiles(os.Stdout, fs.Args())
+	if err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return withCode(EXIT_PARSE, fmt.Errorf("%d of the %d files do not follow the schema", invalid, fs.NArg()))
+	}
+	return nil
+}
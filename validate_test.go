@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	valid, invalid := filepath.Join(dir, "valid.json"), filepath.Join(dir, "invalid.json")
+	if err := ioutil.WriteFile(valid, []byte(`{"schema_version": 1, "sections": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(invalid, []byte(`[]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := validateFiles(&buf, []string{valid, invalid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := valid + ": follows schema version 1\n" + invalid + ": the document: expected object, got array\n"
+	if n != 1 || buf.String() != expected {
+		t.Fatalf("unexpected output for %d invalid files:\n%s", n, buf.String())
+	}
+}
@@ -0,0 +1,57 @@
+//go:build js && wasm
+
+// A WebAssembly build of the formatting core of archlog, for converting
+// a dump of "svn log --xml" or "git log" to a ChangeLog in the browser.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o wasm/archlog.wasm ./wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" wasm/
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"syscall/js"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// archlogConvert(log, format) converts a log dump to a ChangeLog in the
+// given format, and returns {changelog: "..."} or {error: "..."}
+func convert(this js.Value, args []js.Value) any {
+	if len(args) < 1 {
+		return map[string]any{"error": "archlogConvert(log, format) needs a log"}
+	}
+	format := "plain"
+	if len(args) > 1 && args[1].String() != "" {
+		format = args[1].String()
+	}
+	entries, err := changelog.ParseLog([]byte(args[0].String()))
+	if err != nil {
+		return map[string]any{"error": "Could not parse the log: " + err.Error()}
+	}
+	g := changelog.New(&changelog.Options{Entries: -1, Format: format})
+	// The Arch Linux web pages can not be reached from other sites, so the nicks are kept as they are
+	g.Names.Resolver = changelog.Chain{}
+	var buf bytes.Buffer
+	if err := g.Write(context.Background(), &buf, entries); err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	return map[string]any{"changelog": buf.String()}
+}
+
+func main() {
+	// Writing to the console from within a JavaScript callback blocks, so don't log
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	formats := []any{}
+	for _, name := range changelog.FormatterNames() {
+		formats = append(formats, name)
+	}
+	js.Global().Set("archlogFormats", js.ValueOf(formats))
+	js.Global().Set("archlogConvert", js.FuncOf(convert))
+	// Keep the functions available
+	select {}
+}
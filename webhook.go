@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// The largest webhook payload that is read
+const MAX_HOOK_SIZE = 1 << 20
+
+// The commit message for the regenerated ChangeLog, with -commit
+const HOOK_COMMIT_MESSAGE = "Update the ChangeLog"
+
+// Check that a webhook comes from someone who knows the secret, either as
+// the HMAC signature of GitHub, the token of GitLab or as the
+// X-Archlog-Token header, for svn post-commit hooks that use curl
+func verifyHook(r *http.Request, body []byte, secret string) bool {
+	if signature := r.Header.Get("X-Hub-Signature-256"); signature != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(signature), []byte(expected))
+	}
+	token := r.Header.Get("X-Gitlab-Token")
+	if token == "" {
+		token = r.Header.Get("X-Archlog-Token")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
+
+// Serve /hook, which makes the ChangeLog be regenerated in the background
+func (s *changeLogServer) serveHook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAX_HOOK_SIZE))
+	if err != nil {
+		http.Error(w, "The payload is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !verifyHook(r, body, s.hookSecret) {
+		slog.Warn("Refused a webhook with an invalid signature or token", "remote", r.RemoteAddr)
+		http.Error(w, "Invalid signature or token", http.StatusForbidden)
+		return
+	}
+	select {
+	case s.hooks <- struct{}{}:
+	default:
+		// A regeneration is already pending, which will include this change
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Forget the generated ChangeLogs, so that they are generated again
+func (s *changeLogServer) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache = make(map[string]*servedChangeLog)
+}
+
+// Update the working copy and regenerate the ChangeLog after a webhook.
+// With an output file, the ChangeLog is written to it, and committed if
+// commit is set.
+func (s *changeLogServer) regenerate(ctx context.Context) error {
+	// The working copies of the registered sources can be updated
+	opts := s.options
+	source, err := changelog.LookupSource(opts.VCS)
+	if err != nil {
+		return err
+	}
+	if wc, ok := source.(changelog.WorkingCopy); ok {
+		if err := wc.Update(ctx, &opts); err != nil {
+			return err
+		}
+	}
+	s.invalidate()
+	if s.output == "" {
+		return nil
+	}
+	served, err := s.changeLog(ctx, "plain", -1)
+	if err != nil {
+		return err
+	}
+	if existing, err := ioutil.ReadFile(s.output); err == nil && string(existing) == string(served.body) {
+		slog.Debug("The ChangeLog is up to date", "file", s.output)
+	} else {
+		err := changelog.WriteFileAtomic(s.output, func(w io.Writer) error {
+			_, err := w.Write(served.body)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("Wrote the ChangeLog", "file", s.output)
+	}
+	// Also commit a ChangeLog that is up to date, in case the last commit failed
+	if !s.commit {
+		return nil
+	}
+	return source.(changelog.WorkingCopy).Commit(ctx, &opts, s.output, HOOK_COMMIT_MESSAGE)
+}
+
+// Regenerate the ChangeLog for each webhook, until ctx is canceled
+func (s *changeLogServer) handleHooks(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.hooks:
+			if err := s.regenerate(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Could not regenerate the ChangeLog", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestServeHook(t *testing.T) {
+	s := &changeLogServer{hookSecret: "s3cret", hooks: make(chan struct{}, 1)}
+	const payload = `{"ref":"refs/heads/main"}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(payload))
+	for _, test := range []struct {
+		header, value string
+		code          int
+	}{
+		{"X-Hub-Signature-256", "sha256=" + hex.EncodeToString(mac.Sum(nil)), http.StatusAccepted},
+		{"X-Hub-Signature-256", "sha256=00", http.StatusForbidden},
+		{"X-Gitlab-Token", "s3cret", http.StatusAccepted},
+		{"X-Archlog-Token", "guess", http.StatusForbidden},
+		{"", "", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(payload))
+		if test.header != "" {
+			req.Header.Set(test.header, test.value)
+		}
+		rec := httptest.NewRecorder()
+		s.serveHook(rec, req)
+		if rec.Code != test.code {
+			t.Fatalf("expected %d with %s, got %d", test.code, test.header, rec.Code)
+		}
+	}
+	// The accepted hooks are coalesced into one pending regeneration
+	if len(s.hooks) != 1 {
+		t.Fatalf("expected one pending regeneration, got %d", len(s.hooks))
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := &countingSource{}
+	changelog.RegisterSource("hook-test", source)
+	s := &changeLogServer{
+		options: changelog.Options{VCS: "hook-test"},
+		names:   changelog.NewNames(),
+		maxAge:  time.Hour,
+		cache:   make(map[string]*servedChangeLog),
+		output:  filepath.Join(dir, "ChangeLog"),
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.regenerate(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := ioutil.ReadFile(s.output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Initial import") {
+		t.Fatalf("expected the ChangeLog to be written, got %q", data)
+	}
+	// The cached ChangeLog is not used after a webhook
+	if source.fetches != 2 {
+		t.Fatalf("expected the ChangeLog to be generated for each webhook, got %d", source.fetches)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+// Describe a resolver in the chain, for archlog whois
+func resolverName(resolver changelog.Resolver, authorsFile string) string {
+	switch resolver.(type) {
+	case changelog.AuthorsFile:
+		return "the authors file " + authorsFile
+	case *changelog.ArchWeb:
+		return "the web"
+	}
+	return fmt.Sprintf("%T", resolver)
+}
+
+// Find the name and e-mail address of a nick the way a ChangeLog would,
+// first in the nick cache and then with each resolver in the chain, and
+// tell where it was found. Returns changelog.ErrNotFound if none of them
+// know the nick, together with where that was found out.
+func whois(ctx context.Context, names *changelog.Names, nick, authorsFile, cacheFile string) (string, string, error) {
+	if value, ok := names.Cached(nick); ok {
+		source := "the nick cache " + cacheFile
+		if names.Unresolved(nick) {
+			return nick, source + ", which has recorded that it could not be found, use -no-cache to look it up again", changelog.ErrNotFound
+		}
+		return value, source, nil
+	}
+	chain, ok := names.Resolver.(changelog.Chain)
+	if !ok {
+		chain = changelog.Chain{names.Resolver}
+	}
+	// Like changelog.Chain, the first failure that is not ErrNotFound is
+	// returned if none of the resolvers know the nick
+	var (
+		tried   []string
+		failure error = changelog.ErrNotFound
+	)
+	for _, resolver := range chain {
+		source := resolverName(resolver, authorsFile)
+		var (
+			id  changelog.Identity
+			err error
+		)
+		if web, ok := resolver.(*changelog.ArchWeb); ok {
+			var url string
+			if id, url, err = web.ResolveFrom(ctx, nick); err == nil {
+				source += ", on " + url
+			}
+		} else {
+			id, err = resolver.Resolve(ctx, nick)
+		}
+		if err == nil {
+			return id.String(), source, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nick, "none", ctxErr
+		}
+		if errors.Is(failure, changelog.ErrNotFound) && !errors.Is(err, changelog.ErrNotFound) {
+			failure = err
+		}
+		tried = append(tried, source)
+	}
+	if len(tried) == 0 {
+		return nick, "none, since there are no resolvers", failure
+	}
+	return nick, "none, after trying " + strings.Join(tried, " and "), failure
+}
+
+// archlog whois
+func runWhois(ctx context.Context, cmd *command, args []string) error {
+	fs := cmd.flagSet()
+	cache_dir, no_cache := addCacheFlags(fs)
+	var timeout *time.Duration = addTimeoutFlag(fs)
+	resolver_flags := addResolverFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return withCode(EXIT_USAGE, errors.New("Please provide one nick to look up.\nUse --help for more info."))
+	}
+	nick := fs.Arg(0)
+	names := changelog.NewNames()
+	names.Client.Timeout = *timeout
+	if err := setupResolvers(names, resolver_flags); err != nil {
+		return err
+	}
+	if err := setupNickCache(names, *cache_dir, *no_cache); err != nil {
+		return err
+	}
+	setupPageCache(names, *cache_dir, *no_cache)
+	value, source, err := whois(ctx, names, nick, *resolver_flags.authors, nickCacheFilename(*cache_dir))
+	fmt.Printf("%s\t%s\n", nick, value)
+	fmt.Printf("Source: %s\n", source)
+	if errors.Is(err, changelog.ErrNotFound) {
+		return withCode(EXIT_UNRESOLVED, fmt.Errorf("Could not find the name and e-mail address for %s", nick))
+	}
+	if err != nil {
+		return withCode(EXIT_NETWORK, fmt.Errorf("Could not look up %s: %w", nick, err))
+	}
+	return nil
+}
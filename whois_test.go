@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xyproto/archlog/changelog"
+)
+
+func TestWhois(t *testing.T) {
+	names := changelog.NewNames()
+	names.Resolver = changelog.Chain{changelog.AuthorsFile{"alice": {Name: "Alice", Email: "alice@example.org"}}}
+	ctx := context.Background()
+	value, source, err := whois(ctx, names, "alice", "/tmp/authors", "/tmp/nicks")
+	if err != nil || value != "Alice <alice@example.org>" || source != "the authors file /tmp/authors" {
+		t.Fatalf("unexpected result: %q, %q, %v", value, source, err)
+	}
+	value, source, err = whois(ctx, names, "bob", "/tmp/authors", "/tmp/nicks")
+	if !errors.Is(err, changelog.ErrNotFound) || value != "bob" || source != "none, after trying the authors file /tmp/authors" {
+		t.Fatalf("unexpected result: %q, %q, %v", value, source, err)
+	}
+	// A cached nick is not looked up again
+	names.Resolve(ctx, "bob")
+	if _, source, err := whois(ctx, names, "bob", "/tmp/authors", "/tmp/nicks"); !errors.Is(err, changelog.ErrNotFound) || !strings.HasPrefix(source, "the nick cache /tmp/nicks, which has recorded") {
+		t.Fatalf("unexpected result: %q, %v", source, err)
+	}
+}